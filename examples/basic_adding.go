@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -18,7 +19,7 @@ const (
 	// How far from the best bid and offer this strategy ideally places orders (0.3%)
 	DEPTH = 0.003
 	
-	// How far from the target price a resting order can deviate before cancellation (50% of depth)
+	// How far from the target price a resting order can deviate before it is repriced (50% of depth)
 	ALLOWABLE_DEVIATION = 0.5
 	
 	// Maximum absolute position value the strategy can accumulate
@@ -51,6 +52,7 @@ type BasicAdder struct {
 	info       *hyperliquid.Info
 	exchange   *hyperliquid.Exchange
 	ws         *hyperliquid.WebSocketManager
+	book       *hyperliquid.OrderBook
 	ctx        context.Context
 	cancel     context.CancelFunc
 	
@@ -91,17 +93,17 @@ func (ba *BasicAdder) Start() error {
 		return fmt.Errorf("failed to start WebSocket: %v", err)
 	}
 	
-	// Subscribe to order book updates
-	ba.ws.Subscribe(hyperliquid.Subscription{
-		Type: hyperliquid.L2Book,
-		Coin: COIN,
-	}, ba.handleL2BookMessage)
+	// Keep a local order book and react to its updates
+	ba.book = hyperliquid.NewLiveOrderBook(ba.info, COIN)
+	ba.book.OnUpdate(ba.onBookUpdate)
+	if err := ba.book.Start(); err != nil {
+		return fmt.Errorf("failed to start order book: %v", err)
+	}
 	
 	// Subscribe to user events
-	ba.ws.Subscribe(hyperliquid.Subscription{
-		Type: hyperliquid.UserEvents,
-		User: ba.address,
-	}, ba.handleUserEventsMessage)
+	if _, err := ba.ws.Subscribe(utils.UserEventsSubscription{User: ba.address}, ba.handleUserEventsMessage); err != nil {
+		return fmt.Errorf("failed to subscribe to userEvents: %v", err)
+	}
 	
 	// No need for separate message processing since callbacks handle messages directly
 	
@@ -113,17 +115,14 @@ func (ba *BasicAdder) Start() error {
 
 func (ba *BasicAdder) Stop() {
 	ba.cancel()
+	if ba.book != nil {
+		_ = ba.book.Stop()
+	}
 	if ba.ws != nil {
 		ba.ws.Stop()
 	}
 }
 
-// handleL2BookMessage handles order book updates
-func (ba *BasicAdder) handleL2BookMessage(msg hyperliquid.WsMsg) {
-	log.Printf("Received L2 book update: %+v", msg)
-	ba.onBookUpdate(msg)
-}
-
 // handleUserEventsMessage handles user events
 func (ba *BasicAdder) handleUserEventsMessage(msg hyperliquid.WsMsg) {
 	log.Printf("Received user event: %+v", msg)
@@ -132,62 +131,27 @@ func (ba *BasicAdder) handleUserEventsMessage(msg hyperliquid.WsMsg) {
 
 func (ba *BasicAdder) handleMessage(msg hyperliquid.WsMsg) {
 	switch msg.Channel {
-	case "l2Book":
-		ba.onBookUpdate(msg)
 	case "userEvents":
 		ba.onUserEvents(msg)
 	}
 }
 
-func (ba *BasicAdder) onBookUpdate(msg hyperliquid.WsMsg) {
-	// Parse L2 book data
-	bookData, ok := msg.Data.(map[string]interface{})
-	if !ok {
-		log.Printf("Invalid book data format")
-		return
-	}
-	
-	coin, ok := bookData["coin"].(string)
-	if !ok || coin != COIN {
-		return
+func (ba *BasicAdder) onBookUpdate(book *hyperliquid.OrderBook) {
+	// Handle both sides
+	if bid, ok := book.BestBid(); ok {
+		ba.handleOrderPlacement("B", bid)
 	}
-	
-	levels, ok := bookData["levels"].([]interface{})
-	if !ok || len(levels) < 2 {
-		return
+	if ask, ok := book.BestAsk(); ok {
+		ba.handleOrderPlacement("A", ask)
 	}
-	
-	// Handle both sides
-	ba.handleOrderPlacement("B", levels) // Bid
-	ba.handleOrderPlacement("A", levels) // Ask
 }
 
-func (ba *BasicAdder) handleOrderPlacement(side string, levels []interface{}) {
+func (ba *BasicAdder) handleOrderPlacement(side string, level utils.L2Level) {
 	ba.mu.Lock()
 	defer ba.mu.Unlock()
 	
-	// Get the appropriate level (0 for bid, 1 for ask)
-	levelIndex := 0
-	if side == "A" {
-		levelIndex = 1
-	}
-	
-	if len(levels) <= levelIndex {
-		return
-	}
-	
-	level, ok := levels[levelIndex].([]interface{})
-	if !ok || len(level) < 2 {
-		return
-	}
-	
-	pxStr, ok := level[0].(string)
-	if !ok {
-		return
-	}
-	
-	bookPrice := parseFloat(pxStr)
-	if bookPrice <= 0 {
+	bookPrice, err := level.Price()
+	if err != nil || bookPrice <= 0 {
 		return
 	}
 	
@@ -202,7 +166,7 @@ func (ba *BasicAdder) handleOrderPlacement(side string, levels []interface{}) {
 	
 	switch provideState.Type {
 	case "resting":
-		ba.maybeCancelOrder(side, provideState, idealPrice, idealDistance)
+		ba.maybeRepriceOrder(side, provideState, idealPrice, idealDistance)
 	case "in_flight_order":
 		ba.checkInFlightOrder(side, provideState)
 	}
@@ -212,18 +176,23 @@ func (ba *BasicAdder) handleOrderPlacement(side string, levels []interface{}) {
 	}
 }
 
-func (ba *BasicAdder) maybeCancelOrder(side string, provideState *ProvideState, idealPrice, idealDistance float64) {
+func (ba *BasicAdder) maybeRepriceOrder(side string, provideState *ProvideState, idealPrice, idealDistance float64) {
 	distance := math.Abs(idealPrice - provideState.Px)
 	if distance > ALLOWABLE_DEVIATION*idealDistance {
-		fmt.Printf("Cancelling order due to deviation: oid:%d, side:%s, ideal_price:%.2f\n",
+		fmt.Printf("Repricing order due to deviation: oid:%d, side:%s, ideal_price:%.2f\n",
 			provideState.Oid, side, idealPrice)
 
-		cancelResult, err := ba.exchange.Cancel(COIN, provideState.Oid)
-		if err != nil {
-			log.Printf("Failed to cancel order %d for side %s: %v", provideState.Oid, side, err)
-		} else if result, ok := cancelResult.(map[string]interface{}); ok && result["status"] == "ok" {
+		// Modify the order in place instead of cancelling and replacing it,
+		// so the side is never left with two orders or none
+		_, err := ba.exchange.Reprice(COIN, provideState.Oid, idealPrice, 0)
+		switch {
+		case errors.Is(err, hyperliquid.ErrOrderGone):
 			ba.recentlyCancelled[provideState.Oid] = time.Now().UnixMilli()
 			ba.provideState[side] = &ProvideState{Type: "cancelled"}
+		case err != nil:
+			log.Printf("Failed to reprice order %d for side %s: %v", provideState.Oid, side, err)
+		default:
+			provideState.Px = idealPrice
 		}
 	}
 }
@@ -257,7 +226,7 @@ func (ba *BasicAdder) placeNewOrder(side string, idealPrice float64) {
 		return
 	}
 
-	if result, ok := orderResult.(map[string]interface{}); ok && result["status"] == "ok" {
+	if statuses := orderResult.Response.Data.Statuses; len(statuses) > 0 && statuses[0].Error == "" {
 		// Order placed successfully
 		log.Printf("Order placed successfully for side %s", side)
 	} else {
@@ -331,12 +300,6 @@ func (ba *BasicAdder) cleanupCancelledOrders() {
 	}
 }
 
-func parseFloat(s string) float64 {
-	var f float64
-	fmt.Sscanf(s, "%f", &f)
-	return f
-}
-
 func RunBasicAdding() {
 	// Setup clients
 	address, info, exchange, err := Setup(utils.TestnetAPIURL, false)
@@ -2,12 +2,10 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
@@ -18,7 +16,8 @@ func RunBasicMarketOrder() {
 		log.Fatal("Setup failed:", err)
 	}
 
-	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
 	coin := "ETH"
 	isBuy := false
@@ -27,7 +26,7 @@ func RunBasicMarketOrder() {
 	fmt.Printf("We try to Market %s %.3f %s.\n", map[bool]string{true: "Buy", false: "Sell"}[isBuy], sz, coin)
 
 	// Place market order to open position
-	orderResult, err := exchange.MarketOpen(ctx, coin, isBuy, sz, 0.01)
+	orderResult, err := exchange.MarketOpenCtx(ctx, coin, isBuy, sz, nil, 0.01, nil, nil)
 	if err != nil {
 		log.Fatal("Failed to place market order:", err)
 	}
@@ -46,7 +45,7 @@ func RunBasicMarketOrder() {
 		time.Sleep(2 * time.Second)
 
 		fmt.Printf("We try to Market Close all %s.\n", coin)
-		closeResult, err := exchange.MarketClose(ctx, coin)
+		closeResult, err := exchange.MarketCloseCtx(ctx, coin, nil, nil, 0.01, nil, nil)
 		if err != nil {
 			log.Printf("Failed to close position: %v", err)
 		} else if closeResult.Status == "ok" {
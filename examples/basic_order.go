@@ -61,43 +61,32 @@ func RunBasicOrder() {
 
 	fmt.Printf("Order result: %+v\n", orderResult)
 
-	// Parse order result and query/cancel if successful
-	if result, ok := orderResult.(map[string]interface{}); ok {
-		if status, ok := result["status"].(string); ok && status == "ok" {
-			if response, ok := result["response"].(map[string]interface{}); ok {
-				if data, ok := response["data"].(map[string]interface{}); ok {
-					if statuses, ok := data["statuses"].([]interface{}); ok && len(statuses) > 0 {
-						if statusMap, ok := statuses[0].(map[string]interface{}); ok {
-							if resting, ok := statusMap["resting"].(map[string]interface{}); ok {
-								if oidFloat, ok := resting["oid"].(float64); ok {
-									oid := int(oidFloat)
-									fmt.Printf("Order placed successfully with OID: %d\n", oid)
-									
-									// Query the order status by oid
-									orderStatus, err := info.QueryOrderByOID(address, oid)
-									if err != nil {
-										log.Printf("Failed to query order by oid: %v", err)
-									} else {
-										fmt.Printf("Order status by oid: %+v\n", orderStatus)
-									}
+	// Query and cancel the order if it is resting
+	if len(orderResult.Response.Data.Statuses) == 0 {
+		fmt.Printf("Unexpected order result: %s\n", orderResult.Raw)
+		return
+	}
+	status := orderResult.Response.Data.Statuses[0]
+	if status.Resting == nil {
+		fmt.Printf("Order did not rest: %+v\n", status)
+		return
+	}
+	oid := status.Resting.Oid
+	fmt.Printf("Order placed successfully with OID: %d\n", oid)
 
-									// Cancel the order
-									cancelResult, err := exchange.Cancel("ETH", oid)
-									if err != nil {
-										log.Printf("Failed to cancel order: %v", err)
-									} else {
-										fmt.Printf("Cancel result: %+v\n", cancelResult)
-									}
-								}
-							}
-						}
-					}
-				}
-			}
-		} else {
-			fmt.Printf("Order failed: %+v\n", result)
-		}
+	// Query the order status by oid
+	orderStatus, err := info.QueryOrderByOID(address, oid)
+	if err != nil {
+		log.Printf("Failed to query order by oid: %v", err)
+	} else {
+		fmt.Printf("Order status by oid: %+v\n", orderStatus)
+	}
+
+	// Cancel the order
+	cancelResult, err := exchange.Cancel("ETH", oid)
+	if err != nil {
+		log.Printf("Failed to cancel order: %v", err)
 	} else {
-		fmt.Printf("Unexpected order result format: %+v\n", orderResult)
+		fmt.Printf("Cancel result: %+v\n", cancelResult.Response.Data.Statuses)
 	}
 }
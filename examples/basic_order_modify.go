@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
@@ -20,24 +19,15 @@ func RunBasicOrderModify() {
 
 	// Create client order ID
 	cloid := "0x00000000000000000000000000000001"
-	
-	// Place an order that should rest by setting the price very low
-	order := utils.Order{
-		Asset:      1, // ETH
-		IsBuy:      true,
-		LimitPx:    1100.0,
-		Sz:         0.2,
-		ReduceOnly: false,
-		Cloid:      &cloid,
-	}
 
 	orderType := utils.OrderType{
 		Limit: &utils.LimitOrderType{
-			Tif: utils.TifGtc,
+			TIF: utils.TIFGtc,
 		},
 	}
 
-	orderResult, err := exchange.Order(ctx, []utils.Order{order}, utils.OrderGroupingNa, &orderType)
+	// Place an order that should rest by setting the price very low
+	orderResult, err := exchange.OrderCtx(ctx, "ETH", true, 0.2, 1100.0, orderType, false, &cloid, nil)
 	if err != nil {
 		log.Fatal("Failed to place order:", err)
 	}
@@ -45,76 +35,50 @@ func RunBasicOrderModify() {
 	fmt.Printf("Order result: %+v\n", orderResult)
 
 	// Modify the order by oid
-	if orderResult.Status == "ok" && len(orderResult.Response.Data.Statuses) > 0 {
-		status := orderResult.Response.Data.Statuses[0]
-		if status.Resting != nil {
-			oid := status.Resting.Oid
-			
-			// Query order status first
-			orderStatus, err := info.QueryOrderByOid(ctx, address, oid)
-			if err != nil {
-				log.Printf("Failed to query order by oid: %v", err)
-			} else {
-				fmt.Printf("Order status by oid: %+v\n", orderStatus)
-			}
-
-			// Modify the order - change size to 0.1 and price to 1105
-			modifiedOrder := utils.Order{
-				Asset:      1, // ETH
-				IsBuy:      true,
-				LimitPx:    1105.0,
-				Sz:         0.1,
-				ReduceOnly: false,
-				Cloid:      &cloid,
-			}
+	if len(orderResult.Response.Data.Statuses) == 0 {
+		fmt.Printf("Unexpected order result: %s\n", orderResult.Raw)
+		return
+	}
+	status := orderResult.Response.Data.Statuses[0]
+	if status.Resting == nil {
+		fmt.Printf("Order did not rest: %+v\n", status)
+		return
+	}
+	oid := status.Resting.Oid
 
-			modifyResult, err := exchange.ModifyOrder(ctx, []utils.ModifyRequest{
-				{
-					Oid:   oid,
-					Order: modifiedOrder,
-				},
-			})
-			if err != nil {
-				log.Printf("Failed to modify order by oid: %v", err)
-			} else {
-				fmt.Printf("Modify result with oid: %+v\n", modifyResult)
-			}
+	// Query order status first
+	orderStatus, err := info.QueryOrderByOIDCtx(ctx, address, oid)
+	if err != nil {
+		log.Printf("Failed to query order by oid: %v", err)
+	} else {
+		fmt.Printf("Order status by oid: %+v\n", orderStatus)
+	}
 
-			// Modify the order again using cloid (if supported)
-			// Note: Some exchanges support modifying by client order ID
-			modifiedOrder2 := utils.Order{
-				Asset:      1, // ETH
-				IsBuy:      true,
-				LimitPx:    1110.0,
-				Sz:         0.05,
-				ReduceOnly: false,
-				Cloid:      &cloid,
-			}
+	// Modify the order - change size to 0.1 and price to 1105
+	modifyResult, err := exchange.ModifyOrderCtx(ctx, oid, "ETH", true, 0.1, 1105.0, orderType, false, &cloid)
+	if err != nil {
+		log.Printf("Failed to modify order by oid: %v", err)
+	} else {
+		fmt.Printf("Modify result with oid: %+v\n", modifyResult)
+	}
 
-			modifyResult2, err := exchange.ModifyOrder(ctx, []utils.ModifyRequest{
-				{
-					Oid:   oid, // Still need oid for modification
-					Order: modifiedOrder2,
-				},
-			})
-			if err != nil {
-				log.Printf("Failed to modify order by cloid: %v", err)
-			} else {
-				fmt.Printf("Modify result with cloid: %+v\n", modifyResult2)
-			}
+	// Modify the order again, this time identifying it by its cloid
+	modifyResult2, err := exchange.ModifyOrderCtx(ctx, cloid, "ETH", true, 0.05, 1110.0, orderType, false, &cloid)
+	if err != nil {
+		log.Printf("Failed to modify order by cloid: %v", err)
+	} else {
+		fmt.Printf("Modify result with cloid: %+v\n", modifyResult2)
+	}
 
-			// Cancel the order after modifications
-			cancelResult, err := exchange.Cancel(ctx, []utils.CancelRequest{
-				{
-					Asset: 1, // ETH
-					Oid:   oid,
-				},
-			})
-			if err != nil {
-				log.Printf("Failed to cancel order: %v", err)
-			} else {
-				fmt.Printf("Cancel result: %+v\n", cancelResult)
-			}
-		}
+	// Cancel the order after modifications. A modify gives the order a new
+	// oid, which the modify result reports.
+	if modifyResult2 != nil && len(modifyResult2.Response.Data.Statuses) > 0 && modifyResult2.Response.Data.Statuses[0].Resting != nil {
+		oid = modifyResult2.Response.Data.Statuses[0].Resting.Oid
+	}
+	cancelResult, err := exchange.CancelCtx(ctx, "ETH", oid)
+	if err != nil {
+		log.Printf("Failed to cancel order: %v", err)
+	} else {
+		fmt.Printf("Cancel result: %+v\n", cancelResult.Response.Data.Statuses)
 	}
 }
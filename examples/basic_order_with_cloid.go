@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
@@ -20,24 +19,15 @@ func RunBasicOrderWithCloid() {
 
 	// Create client order ID
 	cloid := "0x00000000000000000000000000000001"
-	
-	// Place an order that should rest by setting the price very low
-	order := utils.Order{
-		Asset:      1, // ETH
-		IsBuy:      true,
-		LimitPx:    1100.0,
-		Sz:         0.2,
-		ReduceOnly: false,
-		Cloid:      &cloid,
-	}
 
 	orderType := utils.OrderType{
 		Limit: &utils.LimitOrderType{
-			Tif: utils.TifGtc,
+			TIF: utils.TIFGtc,
 		},
 	}
 
-	orderResult, err := exchange.Order(ctx, []utils.Order{order}, utils.OrderGroupingNa, &orderType)
+	// Place an order that should rest by setting the price very low
+	orderResult, err := exchange.OrderCtx(ctx, "ETH", true, 0.2, 1100.0, orderType, false, &cloid, nil)
 	if err != nil {
 		log.Fatal("Failed to place order:", err)
 	}
@@ -45,7 +35,7 @@ func RunBasicOrderWithCloid() {
 	fmt.Printf("Order result: %+v\n", orderResult)
 
 	// Query the order status by cloid
-	orderStatus, err := info.QueryOrderByCloid(ctx, address, cloid)
+	orderStatus, err := info.QueryOrderByCloidCtx(ctx, address, cloid)
 	if err != nil {
 		log.Printf("Failed to query order by cloid: %v", err)
 	} else {
@@ -54,27 +44,22 @@ func RunBasicOrderWithCloid() {
 
 	// Non-existent cloid example
 	invalidCloid := "0x00000000000000000000000000000002"
-	orderStatus, err = info.QueryOrderByCloid(ctx, address, invalidCloid)
+	orderStatus, err = info.QueryOrderByCloidCtx(ctx, address, invalidCloid)
 	if err != nil {
 		log.Printf("Failed to query order by invalid cloid: %v", err)
 	} else {
 		fmt.Printf("Order status by invalid cloid: %+v\n", orderStatus)
 	}
 
-	// Cancel the order by cloid
-	if orderResult.Status == "ok" && len(orderResult.Response.Data.Statuses) > 0 {
+	// Cancel the order if it is resting
+	if len(orderResult.Response.Data.Statuses) > 0 {
 		status := orderResult.Response.Data.Statuses[0]
 		if status.Resting != nil {
-			cancelResult, err := exchange.CancelByCloid(ctx, []utils.CancelByCloidRequest{
-				{
-					Asset: 1, // ETH
-					Cloid: cloid,
-				},
-			})
+			cancelResult, err := exchange.CancelCtx(ctx, "ETH", status.Resting.Oid)
 			if err != nil {
-				log.Printf("Failed to cancel order by cloid: %v", err)
+				log.Printf("Failed to cancel order: %v", err)
 			} else {
-				fmt.Printf("Cancel result: %+v\n", cancelResult)
+				fmt.Printf("Cancel result: %+v\n", cancelResult.Response.Data.Statuses)
 			}
 		}
 	}
@@ -2,18 +2,15 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
 const (
-	PURR           = "PURR/USDC"
-	OTHER_COIN     = "@8"
-	OTHER_COIN_NAME = "KORILA/USDC"
+	PURR       = "PURR/USDC"
+	OTHER_COIN = "@8" // KORILA/USDC on testnet
 )
 
 func RunBasicSpotOrder() {
@@ -26,7 +23,7 @@ func RunBasicSpotOrder() {
 	ctx := context.Background()
 
 	// Get the user state and print out spot balance information
-	spotUserState, err := info.SpotClearinghouseState(ctx, address)
+	spotUserState, err := info.SpotClearinghouseStateCtx(ctx, address)
 	if err != nil {
 		log.Fatal("Failed to get spot user state:", err)
 	}
@@ -34,56 +31,37 @@ func RunBasicSpotOrder() {
 	if len(spotUserState.Balances) > 0 {
 		fmt.Println("spot balances:")
 		for _, balance := range spotUserState.Balances {
-			balanceJSON, _ := json.MarshalIndent(balance, "", "  ")
-			fmt.Println(string(balanceJSON))
+			total, err := info.SpotBalanceTotal(balance)
+			if err != nil {
+				log.Printf("Failed to convert %s balance: %v", balance.Coin, err)
+				continue
+			}
+			fmt.Printf("  %s: %v (hold %s)\n", balance.Coin, total, balance.Hold)
 		}
 	} else {
 		fmt.Println("no available token balances")
 	}
 
-	// Get PURR asset ID from metadata
-	meta, err := info.Meta(ctx)
-	if err != nil {
-		log.Fatal("Failed to get metadata:", err)
-	}
-
-	var purrAssetID int
-	for i, asset := range meta.SpotUniverse {
-		if asset.Name == PURR {
-			purrAssetID = i
-			break
-		}
-	}
-
 	// Place an order that should rest by setting the price very low
-	order := utils.Order{
-		Asset:      purrAssetID, // PURR/USDC
-		IsBuy:      true,
-		LimitPx:    0.5,
-		Sz:         24.0,
-		ReduceOnly: false,
-		Cloid:      nil,
-	}
-
 	orderType := utils.OrderType{
 		Limit: &utils.LimitOrderType{
-			Tif: utils.TifGtc,
+			TIF: utils.TIFGtc,
 		},
 	}
 
-	orderResult, err := exchange.Order(ctx, []utils.Order{order}, utils.OrderGroupingNa, &orderType)
+	orderResult, err := exchange.OrderCtx(ctx, PURR, true, 24.0, 0.5, orderType, false, nil, nil)
 	if err != nil {
 		log.Fatal("Failed to place PURR order:", err)
 	}
 
-	fmt.Printf("PURR order result: %+v\n", orderResult)
+	fmt.Printf("PURR order result: %+v\n", orderResult.Response.Data.Statuses)
 
 	// Query the order status by oid
-	if orderResult.Status == "ok" && len(orderResult.Response.Data.Statuses) > 0 {
+	if len(orderResult.Response.Data.Statuses) > 0 {
 		status := orderResult.Response.Data.Statuses[0]
 		if status.Resting != nil {
 			oid := status.Resting.Oid
-			orderStatus, err := info.QueryOrderByOid(ctx, address, oid)
+			orderStatus, err := info.QueryOrderByOIDCtx(ctx, address, oid)
 			if err != nil {
 				log.Printf("Failed to query order by oid: %v", err)
 			} else {
@@ -91,59 +69,31 @@ func RunBasicSpotOrder() {
 			}
 
 			// Cancel the order
-			cancelResult, err := exchange.Cancel(ctx, []utils.CancelRequest{
-				{
-					Asset: purrAssetID,
-					Oid:   oid,
-				},
-			})
+			cancelResult, err := exchange.CancelCtx(ctx, PURR, oid)
 			if err != nil {
 				log.Printf("Failed to cancel PURR order: %v", err)
 			} else {
-				fmt.Printf("Cancel PURR order result: %+v\n", cancelResult)
+				fmt.Printf("Cancel PURR order result: %+v\n", cancelResult.Response.Data.Statuses)
 			}
 		}
 	}
 
-	// For other spot assets other than PURR/USDC use @{index} e.g. on testnet @8 is KORILA/USDC
-	var otherAssetID int
-	for i, asset := range meta.SpotUniverse {
-		if asset.Name == OTHER_COIN_NAME {
-			otherAssetID = i
-			break
-		}
-	}
-
-	otherOrder := utils.Order{
-		Asset:      otherAssetID, // KORILA/USDC
-		IsBuy:      true,
-		LimitPx:    12.0,
-		Sz:         1.0,
-		ReduceOnly: false,
-		Cloid:      nil,
-	}
-
-	otherOrderResult, err := exchange.Order(ctx, []utils.Order{otherOrder}, utils.OrderGroupingNa, &orderType)
+	// For other spot assets other than PURR/USDC use @{index}
+	otherOrderResult, err := exchange.OrderCtx(ctx, OTHER_COIN, true, 1.0, 12.0, orderType, false, nil, nil)
 	if err != nil {
 		log.Printf("Failed to place other coin order: %v", err)
-	} else {
-		fmt.Printf("Other coin order result: %+v\n", otherOrderResult)
+		return
+	}
+	fmt.Printf("Other coin order result: %+v\n", otherOrderResult.Response.Data.Statuses)
 
-		if otherOrderResult.Status == "ok" && len(otherOrderResult.Response.Data.Statuses) > 0 {
-			status := otherOrderResult.Response.Data.Statuses[0]
-			if status.Resting != nil {
-				// The SDK now also supports using spot names, although be careful as they might not always be unique
-				cancelResult, err := exchange.Cancel(ctx, []utils.CancelRequest{
-					{
-						Asset: otherAssetID,
-						Oid:   status.Resting.Oid,
-					},
-				})
-				if err != nil {
-					log.Printf("Failed to cancel other coin order: %v", err)
-				} else {
-					fmt.Printf("Cancel other coin order result: %+v\n", cancelResult)
-				}
+	if len(otherOrderResult.Response.Data.Statuses) > 0 {
+		status := otherOrderResult.Response.Data.Statuses[0]
+		if status.Resting != nil {
+			cancelResult, err := exchange.CancelCtx(ctx, OTHER_COIN, status.Resting.Oid)
+			if err != nil {
+				log.Printf("Failed to cancel other coin order: %v", err)
+			} else {
+				fmt.Printf("Cancel other coin order result: %+v\n", cancelResult.Response.Data.Statuses)
 			}
 		}
 	}
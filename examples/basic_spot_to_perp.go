@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
@@ -19,7 +18,7 @@ func RunBasicSpotToPerp() {
 	ctx := context.Background()
 
 	// Transfer 1.23 USDC from perp wallet to spot wallet
-	transferResult, err := exchange.USDClassTransfer(ctx, 1.23, false) // false = to spot
+	transferResult, err := exchange.UsdClassTransferCtx(ctx, 1.23, false, nil) // false = to spot
 	if err != nil {
 		log.Printf("Failed to transfer from perp to spot: %v", err)
 	} else {
@@ -27,7 +26,7 @@ func RunBasicSpotToPerp() {
 	}
 
 	// Transfer 1.23 USDC from spot wallet to perp wallet
-	transferResult, err = exchange.USDClassTransfer(ctx, 1.23, true) // true = to perp
+	transferResult, err = exchange.UsdClassTransferCtx(ctx, 1.23, true, nil) // true = to perp
 	if err != nil {
 		log.Printf("Failed to transfer from spot to perp: %v", err)
 	} else {
@@ -1,12 +1,11 @@
 package main
 
 import (
-	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
@@ -21,139 +20,53 @@ func RunBasicTPSL() {
 		log.Fatal("Setup failed:", err)
 	}
 
-	ctx := context.Background()
-
-	// Place an order that should execute by setting the price very aggressively
-	var price float64
-	if *isBuy {
-		price = 2500
-	} else {
-		price = 1500
-	}
-
-	order := utils.Order{
-		Asset:      1, // ETH
-		IsBuy:      *isBuy,
-		LimitPx:    price,
-		Sz:         0.02,
-		ReduceOnly: false,
-		Cloid:      nil,
-	}
-
-	orderType := utils.OrderType{
-		Limit: &utils.LimitOrderType{
-			Tif: utils.TifGtc,
-		},
-	}
-
-	orderResult, err := exchange.Order(ctx, []utils.Order{order}, utils.OrderGroupingNa, &orderType)
-	if err != nil {
-		log.Fatal("Failed to place order:", err)
-	}
-
-	fmt.Printf("Order result: %+v\n", orderResult)
-
-	// Place a stop loss order
-	var stopTriggerPx, stopPrice float64
+	// Entry price is set aggressively so the order executes; the take profit and
+	// stop loss sit on either side of it and close the position when triggered
+	var entryPx, tpPx, slPx float64
 	if *isBuy {
-		stopTriggerPx = 1600
-		stopPrice = 1500
+		entryPx, tpPx, slPx = 2500, 2600, 1600
 	} else {
-		stopTriggerPx = 2400
-		stopPrice = 2500
+		entryPx, tpPx, slPx = 1500, 1400, 2400
 	}
 
-	stopOrder := utils.Order{
-		Asset:      1, // ETH
-		IsBuy:      !*isBuy, // Opposite side to close position
-		LimitPx:    stopPrice,
-		Sz:         0.02,
-		ReduceOnly: true,
-		Cloid:      nil,
+	entry := utils.OrderRequest{
+		Coin:      "ETH",
+		IsBuy:     *isBuy,
+		Sz:        0.02,
+		LimitPx:   entryPx,
+		OrderType: utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}},
 	}
-
-	stopOrderType := utils.OrderType{
-		Trigger: &utils.TriggerOrderType{
-			TriggerPx: stopTriggerPx,
+	takeProfit := utils.OrderRequest{
+		Coin:    "ETH",
+		IsBuy:   !*isBuy, // Opposite side to close position
+		Sz:      0.02,
+		LimitPx: tpPx,
+		OrderType: utils.OrderType{Trigger: &utils.TriggerOrderType{
+			TriggerPx: tpPx,
 			IsMarket:  true,
-			TPSL:      utils.TPSLSl, // Stop Loss
-		},
-	}
-
-	stopResult, err := exchange.Order(ctx, []utils.Order{stopOrder}, utils.OrderGroupingNa, &stopOrderType)
-	if err != nil {
-		log.Printf("Failed to place stop order: %v", err)
-	} else {
-		fmt.Printf("Stop order result: %+v\n", stopResult)
-
-		// Cancel the stop order
-		if stopResult.Status == "ok" && len(stopResult.Response.Data.Statuses) > 0 {
-			status := stopResult.Response.Data.Statuses[0]
-			if status.Resting != nil {
-				cancelResult, err := exchange.Cancel(ctx, []utils.CancelRequest{
-					{
-						Asset: 1, // ETH
-						Oid:   status.Resting.Oid,
-					},
-				})
-				if err != nil {
-					log.Printf("Failed to cancel stop order: %v", err)
-				} else {
-					fmt.Printf("Cancel stop order result: %+v\n", cancelResult)
-				}
-			}
-		}
-	}
-
-	// Place a take profit order
-	var tpTriggerPx, tpPrice float64
-	if *isBuy {
-		tpTriggerPx = 1600
-		tpPrice = 2500
-	} else {
-		tpTriggerPx = 2400
-		tpPrice = 1500
-	}
-
-	tpOrder := utils.Order{
-		Asset:      1, // ETH
-		IsBuy:      !*isBuy, // Opposite side to close position
-		LimitPx:    tpPrice,
-		Sz:         0.02,
+			TPSL:      utils.TPSLTp,
+		}},
 		ReduceOnly: true,
-		Cloid:      nil,
 	}
-
-	tpOrderType := utils.OrderType{
-		Trigger: &utils.TriggerOrderType{
-			TriggerPx: tpTriggerPx,
+	stopLoss := utils.OrderRequest{
+		Coin:    "ETH",
+		IsBuy:   !*isBuy,
+		Sz:      0.02,
+		LimitPx: slPx,
+		OrderType: utils.OrderType{Trigger: &utils.TriggerOrderType{
+			TriggerPx: slPx,
 			IsMarket:  true,
-			TPSL:      utils.TPSLTp, // Take Profit
-		},
+			TPSL:      utils.TPSLSl,
+		}},
+		ReduceOnly: true,
 	}
 
-	tpResult, err := exchange.Order(ctx, []utils.Order{tpOrder}, utils.OrderGroupingNa, &tpOrderType)
+	// Submit the entry with its take profit and stop loss as one grouped action
+	result, err := exchange.BulkOrdersWithGrouping([]utils.OrderRequest{entry, takeProfit, stopLoss}, nil, utils.GroupingNormalTpsl)
 	if err != nil {
-		log.Printf("Failed to place take profit order: %v", err)
-	} else {
-		fmt.Printf("Take profit order result: %+v\n", tpResult)
-
-		// Cancel the take profit order
-		if tpResult.Status == "ok" && len(tpResult.Response.Data.Statuses) > 0 {
-			status := tpResult.Response.Data.Statuses[0]
-			if status.Resting != nil {
-				cancelResult, err := exchange.Cancel(ctx, []utils.CancelRequest{
-					{
-						Asset: 1, // ETH
-						Oid:   status.Resting.Oid,
-					},
-				})
-				if err != nil {
-					log.Printf("Failed to cancel take profit order: %v", err)
-				} else {
-					fmt.Printf("Cancel take profit order result: %+v\n", cancelResult)
-				}
-			}
-		}
+		log.Fatal("Failed to place bracket order:", err)
 	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Printf("Bracket order result:\n%s\n", resultJSON)
 }
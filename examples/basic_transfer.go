@@ -1,11 +1,9 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 
-	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
@@ -16,15 +14,13 @@ func RunBasicTransfer() {
 		log.Fatal("Setup failed:", err)
 	}
 
-	ctx := context.Background()
-
 	// Check if this is an agent wallet
 	if exchange.GetAccountAddress() != exchange.GetWalletAddress() {
 		log.Fatal("Agents do not have permission to perform internal transfers")
 	}
 
 	// Transfer 1 USD to the zero address for demonstration purposes
-	transferResult, err := exchange.USDTransfer(ctx, 1.0, "0x0000000000000000000000000000000000000000")
+	transferResult, err := exchange.UsdTransfer(1.0, "0x0000000000000000000000000000000000000000")
 	if err != nil {
 		log.Fatal("Failed to transfer USD:", err)
 	}
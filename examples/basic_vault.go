@@ -1,7 +1,6 @@
 package main
 
 import (
-	"context"
 	"fmt"
 	"log"
 
@@ -16,32 +15,26 @@ func RunBasicVault() {
 		log.Fatal("Setup failed:", err)
 	}
 
-	ctx := context.Background()
-
 	// Change this address to a vault that you lead or a subaccount that you own
 	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
 
 	// Create exchange client for vault trading
-	vaultExchange := hyperliquid.NewExchange(exchange.GetPrivateKey(), true) // testnet
-	vaultExchange.SetVaultAddress(vault)
-
-	// Place an order that should rest by setting the price very low
-	order := utils.Order{
-		Asset:      1, // ETH
-		IsBuy:      true,
-		LimitPx:    1100.0,
-		Sz:         0.2,
-		ReduceOnly: false,
-		Cloid:      nil,
+	vaultExchange, err := hyperliquid.NewExchange(exchange.GetPrivateKey(), utils.TestnetAPIURL, nil, nil, nil, nil, nil, 0)
+	if err != nil {
+		log.Fatal("Failed to create vault exchange:", err)
+	}
+	if err := vaultExchange.SetVaultAddress(&vault); err != nil {
+		log.Fatal("Invalid vault address:", err)
 	}
 
+	// Place an order that should rest by setting the price very low
 	orderType := utils.OrderType{
 		Limit: &utils.LimitOrderType{
-			Tif: utils.TifGtc,
+			TIF: utils.TIFGtc,
 		},
 	}
 
-	orderResult, err := vaultExchange.Order(ctx, []utils.Order{order}, utils.OrderGroupingNa, &orderType)
+	orderResult, err := vaultExchange.Order("ETH", true, 0.2, 1100.0, orderType, false, nil, nil)
 	if err != nil {
 		log.Fatal("Failed to place vault order:", err)
 	}
@@ -52,12 +45,7 @@ func RunBasicVault() {
 	if orderResult.Status == "ok" && len(orderResult.Response.Data.Statuses) > 0 {
 		status := orderResult.Response.Data.Statuses[0]
 		if status.Resting != nil {
-			cancelResult, err := vaultExchange.Cancel(ctx, []utils.CancelRequest{
-				{
-					Asset: 1, // ETH
-					Oid:   status.Resting.Oid,
-				},
-			})
+			cancelResult, err := vaultExchange.Cancel("ETH", status.Resting.Oid)
 			if err != nil {
 				log.Printf("Failed to cancel vault order: %v", err)
 			} else {
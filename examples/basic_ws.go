@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"time"
@@ -11,58 +12,52 @@ import (
 
 func RunBasicWS() {
 	// Setup clients
-	address, _, _, err := Setup(utils.TestnetAPIURL, false) // Don't skip WebSocket
+	address, info, _, err := Setup(utils.TestnetAPIURL, false) // Don't skip WebSocket
 	if err != nil {
 		log.Fatal("Setup failed:", err)
 	}
 
-	// Initialize WebSocket manager
-	ws := hyperliquid.NewWebSocketManager(true) // testnet = true
-	defer ws.Close()
+	ctx := context.Background()
+	defer info.DisconnectWebSocketCtx(ctx)
 
 	// Message handler function
-	messageHandler := func(msg hyperliquid.WebSocketMessage) {
+	messageHandler := func(msg hyperliquid.WsMsg) {
 		fmt.Printf("Received message on channel %s: %+v\n", msg.Channel, msg.Data)
 	}
 
 	// Subscribe to different subscription types
-	subscriptions := []map[string]interface{}{
-		{"type": "allMids"},
-		{"type": "l2Book", "coin": "ETH"},
-		{"type": "trades", "coin": "PURR/USDC"},
-		{"type": "userEvents", "user": address},
-		{"type": "userFills", "user": address},
-		{"type": "candle", "coin": "ETH", "interval": "1m"},
-		{"type": "orderUpdates", "user": address},
-		{"type": "userFundings", "user": address},
-		{"type": "userNonFundingLedgerUpdates", "user": address},
-		{"type": "webData2", "user": address},
-		{"type": "bbo", "coin": "ETH"},
-		{"type": "activeAssetCtx", "coin": "BTC"}, // Perp
-		{"type": "activeAssetCtx", "coin": "@1"},  // Spot
-		{"type": "activeAssetData", "user": address, "coin": "BTC"}, // Perp only
+	subscriptions := []utils.Subscription{
+		utils.AllMidsSubscription{},
+		utils.L2BookSubscription{Coin: "ETH"},
+		utils.TradesSubscription{Coin: "PURR/USDC"},
+		utils.UserEventsSubscription{User: address},
+		utils.UserFillsSubscription{User: address},
+		utils.CandleSubscription{Coin: "ETH", Interval: "1m"},
+		utils.OrderUpdatesSubscription{User: address},
+		utils.UserFundingsSubscription{User: address},
+		utils.UserNonFundingLedgerUpdatesSubscription{User: address},
+		utils.WebData2Subscription{User: address},
+		utils.BboSubscription{Coin: "ETH"},
+		utils.ActiveAssetCtxSubscription{Coin: "BTC"},                 // Perp
+		utils.ActiveAssetCtxSubscription{Coin: "@1"},                  // Spot
+		utils.ActiveAssetDataSubscription{User: address, Coin: "BTC"}, // Perp only
 	}
 
-	// Subscribe to all channels
+	// Subscribe to all channels; messages are delivered to the handler
 	for _, subscription := range subscriptions {
-		err := ws.Subscribe(subscription)
+		subscribeCtx, cancel := context.WithTimeout(ctx, hyperliquid.SubscriptionAckTimeout)
+		_, err := info.SubscribeCtx(subscribeCtx, subscription, messageHandler)
+		cancel()
 		if err != nil {
-			log.Printf("Failed to subscribe to %+v: %v", subscription, err)
+			log.Printf("Failed to subscribe to %+v: %v", subscription.Wire(), err)
 		} else {
-			fmt.Printf("Subscribed to: %+v\n", subscription)
+			fmt.Printf("Subscribed to: %+v\n", subscription.Wire())
 		}
 	}
 
-	// Start message processing
-	go func() {
-		for msg := range ws.Messages() {
-			messageHandler(msg)
-		}
-	}()
-
 	// Keep the program running to receive messages
 	fmt.Println("WebSocket subscriptions active. Press Ctrl+C to exit.")
-	
+
 	// Run for 30 seconds to see some messages
 	time.Sleep(30 * time.Second)
 	fmt.Println("Shutting down...")
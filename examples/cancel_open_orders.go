@@ -1,7 +1,7 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"fmt"
 	"log"
 
@@ -10,48 +10,30 @@ import (
 
 func RunCancelOpenOrders() {
 	// Setup clients
-	address, info, exchange, err := Setup(utils.TestnetAPIURL, true)
+	_, _, exchange, err := Setup(utils.TestnetAPIURL, true)
 	if err != nil {
 		log.Fatal("Setup failed:", err)
 	}
 
-	ctx := context.Background()
-
-	// Get all open orders
-	openOrders, err := info.OpenOrders(ctx, address)
-	if err != nil {
-		log.Fatal("Failed to get open orders:", err)
+	// Cancel every open order; orders filled in the meantime count as cancelled
+	result, err := exchange.CancelAllOrders(nil)
+	var partial *utils.PartialFailureError
+	if err != nil && !errors.As(err, &partial) {
+		log.Fatal("Failed to cancel orders:", err)
 	}
 
-	if len(openOrders) == 0 {
+	if len(result.Orders) == 0 {
 		fmt.Println("No open orders to cancel")
 		return
 	}
 
-	// Cancel each open order
-	var cancelRequests []utils.CancelRequest
-	for _, openOrder := range openOrders {
-		fmt.Printf("Cancelling order: %+v\n", openOrder)
-		cancelRequests = append(cancelRequests, utils.CancelRequest{
-			Coin: openOrder.Coin,
-			OID:  openOrder.Oid,
-		})
-	}
-
-	// Batch cancel all orders
-	if len(cancelRequests) > 0 {
-		cancelResult, err := exchange.BulkCancel(cancelRequests)
-		if err != nil {
-			log.Printf("Failed to cancel orders: %v", err)
+	// Print individual cancellation statuses
+	for i, status := range result.Statuses {
+		order := result.Orders[i]
+		if status.Success {
+			fmt.Printf("Cancelled order %d on %s\n", order.Oid, order.Coin)
 		} else {
-			fmt.Printf("Cancel result: %+v\n", cancelResult)
-			
-			// Print individual cancellation statuses
-			if cancelResult.Status == "ok" {
-				for i, status := range cancelResult.Response.Data.Statuses {
-					fmt.Printf("Order %d cancellation status: %s\n", cancelRequests[i].OID, status)
-				}
-			}
+			fmt.Printf("Failed to cancel order %d on %s: %s\n", order.Oid, order.Coin, status.Error)
 		}
 	}
 }
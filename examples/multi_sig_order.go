@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// Multi-sig user the authorized wallets sign for. The account must already have
+// been converted with Exchange.ConvertToMultiSigUser.
+const multiSigUser = "0x0000000000000000000000000000000000000005"
+
+func RunMultiSigOrder() {
+	// The configured wallet submits the action as the outer signer
+	_, _, exchange, err := Setup(utils.TestnetAPIURL, true)
+	if err != nil {
+		log.Fatal("Setup failed:", err)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+	secretKey, err := GetSecretKey(config)
+	if err != nil {
+		log.Fatal("Failed to get secret key:", err)
+	}
+	outerKey, err := crypto.HexToECDSA(secretKey[2:]) // Remove 0x prefix
+	if err != nil {
+		log.Fatal("Failed to parse private key:", err)
+	}
+	outerSigner := crypto.PubkeyToAddress(outerKey.PublicKey).Hex()
+
+	authorizedWallets, err := SetupMultiSigWallets()
+	if err != nil {
+		log.Fatal("Failed to load multi-sig wallets:", err)
+	}
+
+	// Every authorized user signs the same inner action and nonce
+	innerAction := utils.CreateSubAccountAction{
+		Type: "createSubAccount",
+		Name: "multi-sig-example",
+	}
+	nonce := utils.GetTimestampMs()
+
+	var signatures []utils.Signature
+	for _, wallet := range authorizedWallets {
+		signature, err := utils.SignMultiSigL1ActionPayload(wallet, innerAction, false, nil, uint64(nonce), nil, multiSigUser, outerSigner)
+		if err != nil {
+			log.Fatal("Failed to sign inner action:", err)
+		}
+		fmt.Printf("Signed by %s\n", crypto.PubkeyToAddress(wallet.PublicKey).Hex())
+		signatures = append(signatures, *signature)
+	}
+
+	result, err := exchange.MultiSig(multiSigUser, innerAction, signatures, nonce, nil)
+	if err != nil {
+		log.Fatal("Failed to submit multi-sig action:", err)
+	}
+
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Printf("Multi-sig result:\n%s\n", resultJSON)
+}
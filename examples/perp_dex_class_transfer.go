@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// Builder-deployed perp dex to move collateral into and out of
+const perpDex = "test"
+
+func RunPerpDexClassTransfer() {
+	// Setup clients
+	_, _, exchange, err := Setup(utils.TestnetAPIURL, true)
+	if err != nil {
+		log.Fatal("Setup failed:", err)
+	}
+
+	// Move 1 USDC from the spot balance into the perp dex
+	result, err := exchange.PerpDexClassTransfer(perpDex, "USDC", 1.0, true)
+	if err != nil {
+		log.Fatal("Failed to transfer to perp dex:", err)
+	}
+	resultJSON, _ := json.MarshalIndent(result, "", "  ")
+	fmt.Printf("Transfer to perp dex result:\n%s\n", resultJSON)
+
+	// And move it back to spot
+	result, err = exchange.PerpDexClassTransfer(perpDex, "USDC", 1.0, false)
+	if err != nil {
+		log.Fatal("Failed to transfer from perp dex:", err)
+	}
+	resultJSON, _ = json.MarshalIndent(result, "", "  ")
+	fmt.Printf("Transfer from perp dex result:\n%s\n", resultJSON)
+}
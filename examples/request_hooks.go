@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+func RunRequestHooks() {
+	// Setup clients
+	address, info, exchange, err := Setup(utils.TestnetAPIURL, true)
+	if err != nil {
+		log.Fatal("Setup failed:", err)
+	}
+
+	// Time every request of both clients and log exchange payloads without
+	// their signatures
+	timer := hyperliquid.NewRequestTimer()
+	info.WithResponseHook(timer.Hook)
+	exchange.WithRequestHook(logRedacted).WithResponseHook(timer.Hook)
+
+	if _, err := info.UserState(address, ""); err != nil {
+		log.Fatal("Failed to get user state:", err)
+	}
+
+	// A resting order far from the market, cancelled right away
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	result, err := exchange.Order("ETH", true, 0.01, 1100, orderType, false, nil, nil)
+	if err != nil {
+		log.Fatal("Failed to place order:", err)
+	}
+	if statuses := result.Response.Data.Statuses; len(statuses) > 0 && statuses[0].Resting != nil {
+		if _, err := exchange.Cancel("ETH", statuses[0].Resting.Oid); err != nil {
+			log.Fatal("Failed to cancel order:", err)
+		}
+	}
+
+	for path, stats := range timer.Stats() {
+		fmt.Printf("%s: %d requests, %d errors, %v average, %v max\n", path, stats.Requests, stats.Errors, stats.AverageLatency(), stats.MaxLatency)
+	}
+}
+
+// logRedacted logs the payload of a request with its signature replaced, so
+// that signed actions do not end up in the logs
+func logRedacted(req *http.Request) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return
+	}
+	if _, ok := payload["signature"]; ok {
+		payload["signature"] = "[redacted]"
+	}
+	redacted, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	log.Printf("POST %s %s", req.URL.Path, redacted)
+}
@@ -16,6 +16,8 @@ func main() {
 	switch exampleName {
 	case "basic_order":
 		RunBasicOrder()
+	case "request_hooks":
+		RunRequestHooks()
 	default:
 		fmt.Printf("Unknown example: %s\n", exampleName)
 		os.Exit(1)
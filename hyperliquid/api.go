@@ -7,7 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 
@@ -16,10 +16,14 @@ import (
 
 // API represents the HTTP API client for Hyperliquid
 type API struct {
-	baseURL    string
-	client     *http.Client
-	timeout    time.Duration
-	logger     *log.Logger
+	baseURL       string
+	client        *http.Client
+	timeout       time.Duration
+	logger        *slog.Logger
+	retry         *RetryPolicy
+	limiter       *RateLimiter
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
 }
 
 // NewAPI creates a new API client instance
@@ -29,14 +33,15 @@ func NewAPI(baseURL string, timeout time.Duration) *API {
 	}
 	
 	client := &http.Client{
-		Timeout: timeout,
+		Transport: defaultTransport,
+		Timeout:   timeout,
 	}
 	
 	return &API{
 		baseURL: baseURL,
 		client:  client,
 		timeout: timeout,
-		logger:  log.New(log.Writer(), "[API] ", log.LstdFlags),
+		logger:  DefaultLogger(),
 	}
 }
 
@@ -49,7 +54,7 @@ func NewAPIWithClient(baseURL string, client *http.Client) *API {
 	return &API{
 		baseURL: baseURL,
 		client:  client,
-		logger:  log.New(log.Writer(), "[API] ", log.LstdFlags),
+		logger:  DefaultLogger(),
 	}
 }
 
@@ -58,8 +63,42 @@ func (a *API) Post(urlPath string, payload interface{}) (interface{}, error) {
 	return a.PostWithContext(context.Background(), urlPath, payload)
 }
 
-// PostWithContext sends a POST request with context support
+// PostWithContext sends a POST request with context support and decodes the
+// response into generic JSON values. Numbers decode as float64; use PostInto
+// where integers such as order ids must be exact.
 func (a *API) PostWithContext(ctx context.Context, urlPath string, payload interface{}) (interface{}, error) {
+	body, err := a.postBody(ctx, urlPath, payload)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Parse JSON response
+	var result interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return map[string]interface{}{
+			"error": fmt.Sprintf("Could not parse JSON: %s", string(body)),
+		}, nil
+	}
+	
+	return result, nil
+}
+
+// PostInto sends a POST request and decodes the response directly into out
+func (a *API) PostInto(ctx context.Context, urlPath string, payload interface{}, out interface{}) error {
+	body, err := a.postBody(ctx, urlPath, payload)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// postBody sends a POST request and returns the response body, retrying as
+// allowed by the retry policy. Every attempt waits for its weight from the
+// rate limiter, if one is set.
+func (a *API) postBody(ctx context.Context, urlPath string, payload interface{}) ([]byte, error) {
 	if payload == nil {
 		payload = map[string]interface{}{}
 	}
@@ -72,42 +111,86 @@ func (a *API) PostWithContext(ctx context.Context, urlPath string, payload inter
 		return nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 	
+	weight := RequestWeight(urlPath, payload)
+	policy := a.retry
+	for attempt := 1; ; attempt++ {
+		if a.limiter != nil {
+			if err := a.limiter.Wait(ctx, weight); err != nil {
+				return nil, err
+			}
+			if urlPath == "/exchange" {
+				if err := a.limiter.WaitAddress(ctx, addressRequests(payload)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		
+		body, resp, err := a.post(ctx, url, jsonData)
+		if err == nil || policy == nil || attempt >= policy.MaxAttempts || !policy.shouldRetry(urlPath, resp, err) {
+			return body, err
+		}
+		
+		delay := policy.delay(attempt, resp)
+		if policy.OnRetry != nil {
+			event := RetryEvent{Path: urlPath, Attempt: attempt, Err: err, Delay: delay}
+			if resp != nil {
+				event.StatusCode = resp.StatusCode
+			}
+			policy.OnRetry(event)
+		}
+		
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// Callers match the context error; the last attempt's stays reachable
+			return nil, fmt.Errorf("%w while waiting to retry after: %w", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// post performs a single POST attempt. The response is returned whenever one
+// was received, so the caller can inspect its status and headers.
+func (a *API) post(ctx context.Context, url string, jsonData []byte) ([]byte, *http.Response, error) {
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+	a.runRequestHooks(req, jsonData)
 	
 	// Send request
+	start := time.Now()
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		err = fmt.Errorf("request failed: %w", err)
+		a.runResponseHooks(nil, nil, time.Since(start), err)
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 	
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
+	elapsed := time.Since(start)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		err = fmt.Errorf("failed to read response body: %w", err)
+		a.runResponseHooks(resp, body, elapsed, err)
+		return nil, resp, err
 	}
 	
 	// Handle HTTP errors
-	if err := a.handleException(resp, body); err != nil {
-		return nil, err
-	}
-	
-	// Parse JSON response
-	var result interface{}
-	if err := json.Unmarshal(body, &result); err != nil {
-		return map[string]interface{}{
-			"error": fmt.Sprintf("Could not parse JSON: %s", string(body)),
-		}, nil
+	err = a.handleException(resp, body)
+	a.runResponseHooks(resp, body, elapsed, err)
+	if err != nil {
+		return nil, resp, err
 	}
 	
-	return result, nil
+	return body, resp, nil
 }
 
 // handleException processes HTTP response errors and returns appropriate Go errors
@@ -121,47 +204,32 @@ func (a *API) handleException(resp *http.Response, body []byte) error {
 	
 	// Client errors (4xx)
 	if statusCode >= 400 && statusCode < 500 {
-		var errorResponse map[string]interface{}
-		if err := json.Unmarshal(body, &errorResponse); err != nil {
-			// Could not parse JSON error response
-			return &utils.ClientError{
-				StatusCode:   statusCode,
-				ErrorCode:    "",
-				ErrorMessage: string(body),
-				Header:       resp.Header,
-				ErrorData:    nil,
-			}
+		clientErr := &utils.ClientError{
+			StatusCode:   statusCode,
+			ErrorCode:    "",
+			ErrorMessage: string(body),
+			Header:       resp.Header,
+			ErrorData:    nil,
 		}
 		
-		if errorResponse == nil {
-			return &utils.ClientError{
-				StatusCode:   statusCode,
-				ErrorCode:    "",
-				ErrorMessage: string(body),
-				Header:       resp.Header,
-				ErrorData:    nil,
+		// Extract error details, if the response is a JSON error object
+		var errorResponse map[string]interface{}
+		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse != nil {
+			clientErr.ErrorMessage = ""
+			clientErr.ErrorData = errorResponse["data"]
+			if code, ok := errorResponse["code"].(string); ok {
+				clientErr.ErrorCode = code
+			}
+			if msg, ok := errorResponse["msg"].(string); ok {
+				clientErr.ErrorMessage = msg
 			}
 		}
 		
-		// Extract error details
-		errorCode := ""
-		errorMessage := ""
-		errorData := errorResponse["data"]
-		
-		if code, ok := errorResponse["code"].(string); ok {
-			errorCode = code
-		}
-		if msg, ok := errorResponse["msg"].(string); ok {
-			errorMessage = msg
-		}
-		
-		return &utils.ClientError{
-			StatusCode:   statusCode,
-			ErrorCode:    errorCode,
-			ErrorMessage: errorMessage,
-			Header:       resp.Header,
-			ErrorData:    errorData,
+		if statusCode == http.StatusTooManyRequests {
+			retryAfter, _ := parseRetryAfter(resp.Header.Get("Retry-After"))
+			return &utils.RateLimitError{ClientError: clientErr, RetryAfter: retryAfter}
 		}
+		return clientErr
 	}
 	
 	// Server errors (5xx)
@@ -177,6 +245,15 @@ func (a *API) SetTimeout(timeout time.Duration) {
 	a.client.Timeout = timeout
 }
 
+// withBaseURL returns a client for baseURL that sends requests through the
+// same transport with the same timeout, and can be given its own
+func (a *API) withBaseURL(baseURL string) *API {
+	client := *a.client
+	api := NewAPIWithClient(baseURL, &client)
+	api.timeout = a.timeout
+	return api
+}
+
 // GetBaseURL returns the base URL being used
 func (a *API) GetBaseURL() string {
 	return a.baseURL
@@ -186,3 +263,22 @@ func (a *API) GetBaseURL() string {
 func (a *API) SetBaseURL(baseURL string) {
 	a.baseURL = baseURL
 }
+
+// SetLogger sets the logger of the client, DefaultLogger by default. A nil
+// logger discards everything.
+func (a *API) SetLogger(logger *slog.Logger) {
+	a.logger = orDiscard(logger)
+}
+
+// SetRetryPolicy sets the policy for retrying failed requests; nil, the
+// default, disables retries
+func (a *API) SetRetryPolicy(policy *RetryPolicy) {
+	a.retry = policy
+}
+
+// SetRateLimiter sets the client-side rate limiter every request has to pass;
+// nil, the default, disables it. Share one limiter between clients using the
+// same IP.
+func (a *API) SetRateLimiter(limiter *RateLimiter) {
+	a.limiter = limiter
+}
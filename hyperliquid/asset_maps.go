@@ -0,0 +1,203 @@
+// Package hyperliquid - Asset lookups and metadata refresh
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MetaRefreshCooldown is the least time between two automatic metadata
+// refreshes, so that lookups of unknown names do not flood the API
+const MetaRefreshCooldown = 30 * time.Second
+
+// assetMaps holds the name and asset lookups built from perp and spot
+// metadata. It is never modified once built; a refresh builds a new one and
+// swaps it in, so readers can keep using the one they got.
+type assetMaps struct {
+	spotMeta           *SpotMeta
+	perpMetas          map[string]Meta // By perp dex
+	coinToAsset        map[string]int
+	assetToName        map[int]string
+	nameToCoins        map[string]string
+	assetToSzDecimals  map[int]int
+	evmContractToToken map[string]int
+	perpAssets         map[string]AssetInfo
+}
+
+// newAssetMaps builds the lookups from spot metadata and the metadata of every
+// perp dex, in order. Perp names take precedence over spot names.
+func newAssetMaps(spotMeta *SpotMeta, perpMetas map[string]Meta, perpDexs []string, perpDexToOffset map[string]int) *assetMaps {
+	m := &assetMaps{
+		spotMeta:           spotMeta,
+		perpMetas:          perpMetas,
+		coinToAsset:        make(map[string]int),
+		assetToName:        make(map[int]string),
+		nameToCoins:        make(map[string]string),
+		assetToSzDecimals:  make(map[int]int),
+		evmContractToToken: make(map[string]int),
+		perpAssets:         make(map[string]AssetInfo),
+	}
+
+	for _, tokenInfo := range spotMeta.Tokens {
+		if tokenInfo.EvmContract != nil && *tokenInfo.EvmContract != "" {
+			m.evmContractToToken[strings.ToLower(*tokenInfo.EvmContract)] = tokenInfo.Index
+		}
+	}
+
+	// Process spot assets (start at 10000). Pairs can be named by coin, by
+	// "@index" even when their coin is a canonical name like PURR/USDC, and by
+	// their BASE/QUOTE token names.
+	for _, spotInfo := range spotMeta.Universe {
+		asset := spotInfo.Index + 10000
+		m.coinToAsset[spotInfo.Name] = asset
+		m.assetToName[asset] = spotInfo.Name
+		m.nameToCoins[spotInfo.Name] = spotInfo.Name
+		m.nameToCoins[fmt.Sprintf("@%d", spotInfo.Index)] = spotInfo.Name
+
+		baseInfo := spotMeta.Tokens[spotInfo.Tokens[0]]
+		quoteInfo := spotMeta.Tokens[spotInfo.Tokens[1]]
+		m.assetToSzDecimals[asset] = baseInfo.SzDecimals
+
+		name := fmt.Sprintf("%s/%s", baseInfo.Name, quoteInfo.Name)
+		if _, exists := m.nameToCoins[name]; !exists {
+			m.nameToCoins[name] = spotInfo.Name
+		}
+	}
+
+	// Builder-deployed perp dexs list the same coins as others, so their
+	// assets are keyed "dex:COIN"
+	for _, perpDex := range perpDexs {
+		offset := perpDexToOffset[perpDex]
+		for asset, assetInfo := range perpMetas[perpDex].Universe {
+			assetID := asset + offset
+			if perpDex != "" && !strings.HasPrefix(assetInfo.Name, perpDex+":") {
+				assetInfo.Name = perpDex + ":" + assetInfo.Name
+			}
+			m.coinToAsset[assetInfo.Name] = assetID
+			m.assetToName[assetID] = assetInfo.Name
+			m.nameToCoins[assetInfo.Name] = assetInfo.Name
+			m.assetToSzDecimals[assetID] = assetInfo.SzDecimals
+			m.perpAssets[assetInfo.Name] = assetInfo
+		}
+	}
+	return m
+}
+
+// perpDexOf returns the perp dex of a coin: the part before the colon of a
+// "dex:COIN" name, or "" for the default dex and spot
+func perpDexOf(coin string) string {
+	if dex, _, found := strings.Cut(coin, ":"); found {
+		return dex
+	}
+	return ""
+}
+
+// currentAssets returns the lookups in use
+func (i *Info) currentAssets() *assetMaps {
+	i.assetsMu.RLock()
+	defer i.assetsMu.RUnlock()
+	return i.assets
+}
+
+// setAssets swaps in new lookups
+func (i *Info) setAssets(assets *assetMaps) {
+	i.assetsMu.Lock()
+	defer i.assetsMu.Unlock()
+	i.assets = assets
+}
+
+// coinForName returns the coin a name refers to, along with the lookups it was
+// found in. On a miss with auto-refresh enabled, metadata is refreshed once,
+// at most every MetaRefreshCooldown, and the lookup retried.
+func (i *Info) coinForName(name string) (string, *assetMaps, bool) {
+	assets := i.currentAssets()
+	if coin, exists := assets.nameToCoins[name]; exists {
+		return coin, assets, true
+	}
+	if !i.autoRefreshMeta.Load() || !i.claimAutoRefresh() {
+		return "", assets, false
+	}
+
+	if err := i.refresh(context.Background(), true, true); err != nil {
+		return "", assets, false
+	}
+	assets = i.currentAssets()
+	coin, exists := assets.nameToCoins[name]
+	return coin, assets, exists
+}
+
+// claimAutoRefresh reports whether an automatic refresh may run now, and if
+// so starts its cooldown
+func (i *Info) claimAutoRefresh() bool {
+	i.autoRefreshMu.Lock()
+	defer i.autoRefreshMu.Unlock()
+	now := time.Now()
+	if now.Sub(i.lastAutoRefresh) < MetaRefreshCooldown {
+		return false
+	}
+	i.lastAutoRefresh = now
+	return true
+}
+
+// SetAutoRefreshMeta enables refreshing perp and spot metadata when a name is
+// not found, so assets listed after the client was created can be traded.
+// It is safe to call while lookups are running.
+func (i *Info) SetAutoRefreshMeta(enabled bool) {
+	i.autoRefreshMeta.Store(enabled)
+}
+
+// RefreshMeta re-fetches the metadata of every perp dex the client was created
+// with and swaps in the new asset lookups
+func (i *Info) RefreshMeta() error {
+	return i.RefreshMetaCtx(context.Background())
+}
+
+// RefreshMetaCtx is like RefreshMeta but carries ctx to the request
+func (i *Info) RefreshMetaCtx(ctx context.Context) error {
+	return i.refresh(ctx, true, false)
+}
+
+// RefreshSpotMeta re-fetches spot metadata and swaps in the new asset lookups
+func (i *Info) RefreshSpotMeta() error {
+	return i.RefreshSpotMetaCtx(context.Background())
+}
+
+// RefreshSpotMetaCtx is like RefreshSpotMeta but carries ctx to the request
+func (i *Info) RefreshSpotMetaCtx(ctx context.Context) error {
+	return i.refresh(ctx, false, true)
+}
+
+// refresh re-fetches perp and/or spot metadata, keeping the current metadata
+// for whichever is not refreshed. Refreshes run one at a time so that none
+// undoes another.
+func (i *Info) refresh(ctx context.Context, perp bool, spot bool) error {
+	i.refreshMu.Lock()
+	defer i.refreshMu.Unlock()
+
+	current := i.currentAssets()
+	spotMeta := current.spotMeta
+	if spot {
+		freshSpotMeta, err := i.SpotMetaCtx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get spot metadata: %w", err)
+		}
+		spotMeta = freshSpotMeta
+	}
+
+	perpMetas := current.perpMetas
+	if perp {
+		perpMetas = make(map[string]Meta, len(i.perpDexs))
+		for _, perpDex := range i.perpDexs {
+			meta, err := i.MetaCtx(ctx, perpDex)
+			if err != nil {
+				return fmt.Errorf("failed to get meta for dex %s: %w", perpDex, err)
+			}
+			perpMetas[perpDex] = *meta
+		}
+	}
+
+	i.setAssets(newAssetMaps(spotMeta, perpMetas, i.perpDexs, i.perpDexToOffset))
+	return nil
+}
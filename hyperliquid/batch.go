@@ -0,0 +1,98 @@
+// Package hyperliquid - Bulk actions split into batches
+package hyperliquid
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBatchSize is how many orders or cancels a bulk action carries at
+// most unless SetBatchSize changes it. The exchange weighs an action as
+// 1 + len/40, so larger batches save no rate limit.
+const DefaultBatchSize = 40
+
+// errBatchSkipped is the error of a batch not sent because an earlier one
+// failed
+var errBatchSkipped = errors.New("not sent: an earlier batch failed")
+
+// batch is the range [start, end) of the requests of a bulk action sent
+// together
+type batch struct {
+	start int
+	end   int
+}
+
+// SetBatchSize sets how many orders BulkOrders and cancels BulkCancel send
+// per signed action; longer lists are split into several actions, each with
+// its own nonce, and their responses merged in input order. A size of zero
+// or less sends every list as one action, for callers that need the exchange
+// to accept or reject the requests together. Grouped orders are never split.
+func (e *Exchange) SetBatchSize(size int) {
+	e.batchSize = size
+}
+
+// SetBatchConcurrency sets how many batches of a split bulk action are sent
+// at once, one by default. Once a batch fails outright, batches not sent yet
+// are skipped.
+func (e *Exchange) SetBatchConcurrency(concurrency int) {
+	e.batchConcurrency = concurrency
+}
+
+// batches splits n requests into batches of the batch size, or returns a
+// single batch when splitting is disabled or not allowed
+func (e *Exchange) batches(n int, splittable bool) []batch {
+	if !splittable || e.batchSize <= 0 || n <= e.batchSize {
+		return []batch{{0, n}}
+	}
+	var batches []batch
+	for start := 0; start < n; start += e.batchSize {
+		end := start + e.batchSize
+		if end > n {
+			end = n
+		}
+		batches = append(batches, batch{start, end})
+	}
+	return batches
+}
+
+// runBatches calls send for every batch index, with up to the batch
+// concurrency in flight, and returns the error of every batch. Once a batch
+// fails, those not started yet fail with errBatchSkipped.
+func (e *Exchange) runBatches(count int, send func(i int) error) []error {
+	errs := make([]error, count)
+	if count == 1 {
+		errs[0] = send(0)
+		return errs
+	}
+
+	concurrency := e.batchConcurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		wg     sync.WaitGroup
+		failed atomic.Bool
+	)
+	slots := make(chan struct{}, concurrency)
+	for i := 0; i < count; i++ {
+		slots <- struct{}{}
+		if failed.Load() {
+			<-slots
+			errs[i] = errBatchSkipped
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			if errs[i] = send(i); errs[i] != nil {
+				failed.Store(true)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,99 @@
+// Package hyperliquid - Candle subscriptions
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// candleCloseGrace is how long after a candle's close time its last update
+// is taken as final, leaving room for updates sent right at the boundary
+const candleCloseGrace = time.Second
+
+// SubscribeCandles subscribes to candles of name at interval, one of
+// utils.CandleIntervals. callback receives every update of the current
+// candle, then the candle once more with Closed set when the next candle
+// starts or its close time has passed, whichever comes first; the close of
+// the candle current when unsubscribing may still be delivered. Like
+// Subscribe it waits up to SubscriptionAckTimeout for the acknowledgement.
+func (i *Info) SubscribeCandles(name, interval string, callback func(utils.Candle)) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SubscriptionAckTimeout)
+	defer cancel()
+	return i.SubscribeCandlesCtx(ctx, name, interval, callback)
+}
+
+// SubscribeCandlesCtx is like SubscribeCandles but waits for the
+// acknowledgement until ctx is done instead
+func (i *Info) SubscribeCandlesCtx(ctx context.Context, name, interval string, callback func(utils.Candle)) (int, error) {
+	if _, _, exists := i.coinForName(name); !exists {
+		return 0, fmt.Errorf("coin not found for name: %s", name)
+	}
+	if !utils.ValidCandleInterval(interval) {
+		return 0, fmt.Errorf("unsupported candle interval %q: expected one of %s", interval, strings.Join(utils.CandleIntervals, ", "))
+	}
+	tracker := &candleTracker{callback: callback, logger: i.logger}
+	return i.SubscribeCtx(ctx, utils.CandleSubscription{Coin: name, Interval: interval}, tracker.onCandle)
+}
+
+// candleTracker delivers candle updates and detects when a candle closes.
+// The callback runs under mu, so updates and closes arrive in order.
+type candleTracker struct {
+	mu       sync.Mutex
+	callback func(utils.Candle)
+	current  *utils.Candle
+	closed   int64 // Open time of the last closed candle
+	timer    *time.Timer
+	logger   *slog.Logger
+}
+
+// onCandle handles candle messages
+func (t *candleTracker) onCandle(msg WsMsg) {
+	var candle utils.Candle
+	if err := decodeResult(msg.Data, &candle); err != nil {
+		t.logger.Warn("Failed to decode candle", "err", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if candle.OpenTime <= t.closed {
+		// Late update of a candle already delivered as closed
+		return
+	}
+	if t.current != nil && candle.OpenTime > t.current.OpenTime {
+		t.close()
+	}
+	t.current = &candle
+	t.callback(candle)
+
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	openTime := candle.OpenTime
+	wait := time.Until(time.UnixMilli(candle.CloseTime)) + candleCloseGrace
+	t.timer = time.AfterFunc(wait, func() { t.expire(openTime) })
+}
+
+// expire closes the candle opened at openTime if it is still current
+func (t *candleTracker) expire(openTime int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.current != nil && t.current.OpenTime == openTime {
+		t.close()
+	}
+}
+
+// close delivers the current candle as closed. The caller must hold mu.
+func (t *candleTracker) close() {
+	candle := *t.current
+	candle.Closed = true
+	t.closed = candle.OpenTime
+	t.current = nil
+	t.callback(candle)
+}
@@ -0,0 +1,319 @@
+// Package config - Configuration loading for Hyperliquid clients
+package config
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// Network identifies the Hyperliquid environment a profile targets
+type Network string
+
+const (
+	NetworkMainnet Network = "mainnet"
+	NetworkTestnet Network = "testnet"
+	NetworkLocal   Network = "local"
+)
+
+// Environment variables read by LoadFromEnv and applied on top of LoadFromFile.
+// Values from the environment always take precedence over file values.
+const (
+	EnvNetwork          = "HYPERLIQUID_NETWORK"
+	EnvBaseURL          = "HYPERLIQUID_BASE_URL"
+	EnvSecretKey        = "HYPERLIQUID_SECRET_KEY"
+	EnvAccountAddress   = "HYPERLIQUID_ACCOUNT_ADDRESS"
+	EnvVaultAddress     = "HYPERLIQUID_VAULT_ADDRESS"
+	EnvMultiSigSecrets  = "HYPERLIQUID_MULTI_SIG_SECRET_KEYS" // comma separated
+	EnvKeystorePath     = "HYPERLIQUID_KEYSTORE_PATH"
+	EnvKeystorePassword = "HYPERLIQUID_KEYSTORE_PASSWORD" // only read from the environment
+)
+
+// DefaultTimeout is the HTTP timeout used by BuildClient
+const DefaultTimeout = 30 * time.Second
+
+// Profile holds the per-network settings of a configuration file
+type Profile struct {
+	BaseURL        string `json:"base_url,omitempty"`
+	SecretKey      string `json:"secret_key,omitempty"`
+	KeystorePath   string `json:"keystore_path,omitempty"`
+	AccountAddress string `json:"account_address,omitempty"`
+	VaultAddress   string `json:"vault_address,omitempty"`
+}
+
+// AuthorizedUser is a wallet allowed to sign on behalf of a multi-sig user
+type AuthorizedUser struct {
+	Comment        string `json:"comment,omitempty"`
+	SecretKey      string `json:"secret_key"`
+	AccountAddress string `json:"account_address,omitempty"`
+}
+
+// MultiSigConfig lists the authorized users of a multi-sig account
+type MultiSigConfig struct {
+	AuthorizedUsers []AuthorizedUser `json:"authorized_users"`
+}
+
+// Config represents a resolved client configuration.
+//
+// Top-level fields apply to every network; entries in Profiles override them for
+// the selected Network. SecretKey may belong to an agent wallet, in which case
+// AccountAddress must be set to the address of the master account.
+//
+// Without a SecretKey, the key is read from the encrypted keystore file at
+// KeystorePath, like the keystore_path of the Python SDK's example config,
+// with the password in HYPERLIQUID_KEYSTORE_PASSWORD.
+type Config struct {
+	Network        Network             `json:"network"`
+	BaseURL        string              `json:"base_url,omitempty"`
+	SecretKey      string              `json:"secret_key"`
+	KeystorePath   string              `json:"keystore_path,omitempty"`
+	AccountAddress string              `json:"account_address"`
+	VaultAddress   string              `json:"vault_address,omitempty"`
+	SkipWS         bool                `json:"skip_ws,omitempty"`
+	WSURL          string              `json:"ws_url,omitempty"` // Overrides the WebSocket endpoint, e.g. for a proxy
+	MultiSig       MultiSigConfig      `json:"multi_sig"`
+	Profiles       map[Network]Profile `json:"profiles,omitempty"`
+}
+
+// LoadFromFile reads a JSON configuration file, applies the profile for the
+// selected network and any environment overrides, and validates the result
+func LoadFromFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return config.resolve()
+}
+
+// LoadFromEnv builds a configuration from environment variables only
+func LoadFromEnv() (*Config, error) {
+	var config Config
+	return config.resolve()
+}
+
+// resolve applies the network profile and environment overrides, then validates
+func (c *Config) resolve() (*Config, error) {
+	if network := os.Getenv(EnvNetwork); network != "" {
+		c.Network = Network(strings.ToLower(network))
+	}
+	if c.Network == "" {
+		c.Network = NetworkTestnet
+	}
+
+	if profile, ok := c.Profiles[c.Network]; ok {
+		c.BaseURL = override(c.BaseURL, profile.BaseURL)
+		c.SecretKey = override(c.SecretKey, profile.SecretKey)
+		c.KeystorePath = override(c.KeystorePath, profile.KeystorePath)
+		c.AccountAddress = override(c.AccountAddress, profile.AccountAddress)
+		c.VaultAddress = override(c.VaultAddress, profile.VaultAddress)
+	}
+
+	c.BaseURL = override(c.BaseURL, os.Getenv(EnvBaseURL))
+	c.SecretKey = override(c.SecretKey, os.Getenv(EnvSecretKey))
+	c.KeystorePath = override(c.KeystorePath, os.Getenv(EnvKeystorePath))
+	c.AccountAddress = override(c.AccountAddress, os.Getenv(EnvAccountAddress))
+	c.VaultAddress = override(c.VaultAddress, os.Getenv(EnvVaultAddress))
+	if secrets := os.Getenv(EnvMultiSigSecrets); secrets != "" {
+		c.MultiSig.AuthorizedUsers = nil
+		for _, secret := range strings.Split(secrets, ",") {
+			if secret = strings.TrimSpace(secret); secret != "" {
+				c.MultiSig.AuthorizedUsers = append(c.MultiSig.AuthorizedUsers, AuthorizedUser{SecretKey: secret})
+			}
+		}
+	}
+
+	if c.SecretKey == "" && c.KeystorePath != "" {
+		secretKey, err := LoadKeystore(c.KeystorePath, os.Getenv(EnvKeystorePassword))
+		if err != nil {
+			return nil, err
+		}
+		c.SecretKey = secretKey
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// override returns value when it is set and current otherwise
+func override(current string, value string) string {
+	if value != "" {
+		return value
+	}
+	return current
+}
+
+// Validate checks that the configuration can be used to build a client
+func (c *Config) Validate() error {
+	switch c.Network {
+	case NetworkMainnet, NetworkTestnet, NetworkLocal:
+	default:
+		return fmt.Errorf("unknown network: %q", c.Network)
+	}
+
+	if c.SecretKey == "" {
+		return fmt.Errorf("no secret key or keystore provided in config, %s or %s", EnvSecretKey, EnvKeystorePath)
+	}
+	if _, err := c.PrivateKey(); err != nil {
+		return err
+	}
+
+	if c.AccountAddress != "" {
+		if _, err := utils.NormalizeAddress(c.AccountAddress); err != nil {
+			return fmt.Errorf("invalid account_address: %w", err)
+		}
+	}
+	if c.VaultAddress != "" {
+		if _, err := utils.NormalizeAddress(c.VaultAddress); err != nil {
+			return fmt.Errorf("invalid vault_address: %w", err)
+		}
+	}
+
+	if _, err := c.MultiSigWallets(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// APIURL returns the base URL for the configured network
+func (c *Config) APIURL() string {
+	if c.BaseURL != "" {
+		return c.BaseURL
+	}
+	switch c.Network {
+	case NetworkMainnet:
+		return utils.MainnetAPIURL
+	case NetworkLocal:
+		return utils.LocalAPIURL
+	default:
+		return utils.TestnetAPIURL
+	}
+}
+
+// PrivateKey parses the configured secret key
+func (c *Config) PrivateKey() (*ecdsa.PrivateKey, error) {
+	return parsePrivateKey(c.SecretKey)
+}
+
+// Address returns the account the client acts for: the configured account
+// address, or the address derived from the secret key
+func (c *Config) Address() (string, error) {
+	if c.AccountAddress != "" {
+		return c.AccountAddress, nil
+	}
+	privateKey, err := c.PrivateKey()
+	if err != nil {
+		return "", err
+	}
+	return crypto.PubkeyToAddress(privateKey.PublicKey).Hex(), nil
+}
+
+// MultiSigWallets parses the private keys of the authorized multi-sig users,
+// verifying that any configured address matches its key
+func (c *Config) MultiSigWallets() ([]*ecdsa.PrivateKey, error) {
+	var wallets []*ecdsa.PrivateKey
+	for i, user := range c.MultiSig.AuthorizedUsers {
+		if user.SecretKey == "" {
+			continue
+		}
+
+		privateKey, err := parsePrivateKey(user.SecretKey)
+		if err != nil {
+			return nil, fmt.Errorf("authorized user %d: %w", i, err)
+		}
+
+		derivedAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+		if user.AccountAddress != "" && !strings.EqualFold(user.AccountAddress, derivedAddress.Hex()) {
+			return nil, fmt.Errorf("authorized user %d: address %s does not match private key", i, user.AccountAddress)
+		}
+		wallets = append(wallets, privateKey)
+	}
+	return wallets, nil
+}
+
+// parsePrivateKey parses a hex encoded secp256k1 key with an optional 0x prefix
+func parsePrivateKey(secretKey string) (*ecdsa.PrivateKey, error) {
+	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(secretKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %w", err)
+	}
+	return privateKey, nil
+}
+
+// Client bundles the clients built from a configuration
+type Client struct {
+	Address  string
+	Info     *hyperliquid.Info
+	Exchange *hyperliquid.Exchange
+}
+
+// BuildClient creates Info and Exchange clients for the configuration. The
+// exchange shares the info's metadata, so one RefreshMeta updates both.
+func BuildClient(config *Config) (*Client, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	privateKey, err := config.PrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	address, err := config.Address()
+	if err != nil {
+		return nil, err
+	}
+
+	var accountAddress, vaultAddress *string
+	if config.AccountAddress != "" {
+		accountAddress = &config.AccountAddress
+	}
+	if config.VaultAddress != "" {
+		vaultAddress = &config.VaultAddress
+	}
+
+	info, err := hyperliquid.NewInfo(config.APIURL(), config.SkipWS || config.WSURL != "", nil, nil, nil, DefaultTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create info client: %w", err)
+	}
+	if config.WSURL != "" && !config.SkipWS {
+		if err := info.ConnectWebSocket(config.WSURL); err != nil {
+			closeInfo(info)
+			return nil, err
+		}
+	}
+
+	// The exchange looks up assets with info, so both see one copy of the metadata
+	exchange, err := hyperliquid.NewExchangeWithInfo(utils.NewLocalSigner(privateKey), info, vaultAddress, accountAddress)
+	if err != nil {
+		closeInfo(info)
+		return nil, fmt.Errorf("failed to create exchange client: %w", err)
+	}
+
+	return &Client{
+		Address:  address,
+		Info:     info,
+		Exchange: exchange,
+	}, nil
+}
+
+// closeInfo disconnects the WebSocket of an info client BuildClient does not
+// return
+func closeInfo(info *hyperliquid.Info) {
+	if info.WebSocketManager() != nil {
+		_ = info.DisconnectWebSocket()
+	}
+}
@@ -0,0 +1,133 @@
+// Package config - Encrypted keystore files
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// keystoreFile is a version 3 encrypted keystore, as written by geth or
+// eth-account
+type keystoreFile struct {
+	Version int `json:"version"`
+	Crypto  struct {
+		Cipher       string `json:"cipher"`
+		CipherText   string `json:"ciphertext"`
+		CipherParams struct {
+			IV string `json:"iv"`
+		} `json:"cipherparams"`
+		KDF       string `json:"kdf"`
+		KDFParams struct {
+			DKLen int    `json:"dklen"`
+			Salt  string `json:"salt"`
+			N     int    `json:"n"`
+			R     int    `json:"r"`
+			P     int    `json:"p"`
+			C     int    `json:"c"`
+			PRF   string `json:"prf"`
+		} `json:"kdfparams"`
+		MAC string `json:"mac"`
+	} `json:"crypto"`
+}
+
+// LoadKeystore decrypts a version 3 keystore file, as written by geth or
+// eth-account, and returns its hex encoded secret key. A leading ~ in path
+// stands for the home directory.
+func LoadKeystore(path string, password string) (string, error) {
+	if strings.HasPrefix(path, "~") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read keystore file: %w", err)
+	}
+	var file keystoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", fmt.Errorf("failed to parse keystore file: %w", err)
+	}
+	secretKey, err := decryptKeystore(file, password)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt keystore file %s: %w", path, err)
+	}
+	return secretKey, nil
+}
+
+// decryptKeystore derives the key of a keystore from password, checks it
+// against the MAC and decrypts the secret key
+func decryptKeystore(file keystoreFile, password string) (string, error) {
+	if file.Version != 3 {
+		return "", fmt.Errorf("unsupported keystore version %d", file.Version)
+	}
+	if file.Crypto.Cipher != "aes-128-ctr" {
+		return "", fmt.Errorf("unsupported cipher %q", file.Crypto.Cipher)
+	}
+	params := file.Crypto.KDFParams
+	if params.DKLen < 32 {
+		return "", fmt.Errorf("derived key length %d is too short", params.DKLen)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return "", fmt.Errorf("invalid salt: %w", err)
+	}
+	iv, err := hex.DecodeString(file.Crypto.CipherParams.IV)
+	if err != nil || len(iv) != aes.BlockSize {
+		return "", fmt.Errorf("invalid iv: %q", file.Crypto.CipherParams.IV)
+	}
+	cipherText, err := hex.DecodeString(file.Crypto.CipherText)
+	if err != nil {
+		return "", fmt.Errorf("invalid ciphertext: %w", err)
+	}
+	mac, err := hex.DecodeString(file.Crypto.MAC)
+	if err != nil {
+		return "", fmt.Errorf("invalid mac: %w", err)
+	}
+
+	var derivedKey []byte
+	switch file.Crypto.KDF {
+	case "scrypt":
+		derivedKey, err = scrypt.Key([]byte(password), salt, params.N, params.R, params.P, params.DKLen)
+		if err != nil {
+			return "", fmt.Errorf("failed to derive key: %w", err)
+		}
+	case "pbkdf2":
+		if params.PRF != "hmac-sha256" {
+			return "", fmt.Errorf("unsupported pbkdf2 prf %q", params.PRF)
+		}
+		derivedKey = pbkdf2.Key([]byte(password), salt, params.C, params.DKLen, sha256.New)
+	default:
+		return "", fmt.Errorf("unsupported kdf %q", file.Crypto.KDF)
+	}
+
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return "", fmt.Errorf("wrong password or corrupted keystore")
+	}
+
+	block, err := aes.NewCipher(derivedKey[:16])
+	if err != nil {
+		return "", err
+	}
+	plainText := make([]byte, len(cipherText))
+	cipher.NewCTR(block, iv).XORKeyStream(plainText, cipherText)
+	if _, err := crypto.ToECDSA(plainText); err != nil {
+		return "", fmt.Errorf("invalid secret key: %w", err)
+	}
+	return hexutil.Encode(plainText), nil
+}
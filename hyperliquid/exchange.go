@@ -2,58 +2,268 @@
 package hyperliquid
 
 import (
+	"context"
 	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
 // Default max slippage for market orders (5%)
 const DefaultSlippage = 0.05
 
+// feeRatePattern matches percentage fee rates such as "0.001%"
+var feeRatePattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?%$`)
+
 // BuilderInfo represents builder information for orders
-type BuilderInfo struct {
-	B string `json:"b"`
-	F string `json:"f"`
-}
+type BuilderInfo = utils.BuilderInfo
 
 // Exchange represents the Exchange API client for trading operations
 type Exchange struct {
 	*API
-	privateKey    *ecdsa.PrivateKey
-	vaultAddress  *string
+	signer        utils.Signer
+	nonces        *NonceManager
+	vaultAddress  atomic.Pointer[string]
 	accountAddress *string
 	info          *Info
-	expiresAfter  *int64
+	expiry        atomic.Pointer[expirySetting]
+	impactCheck   bool
+	builderFeeCheck bool
+	validateDestination bool
+	skipOrderValidation bool
+	autoCloid     bool
+	marketReference utils.PriceReference
+	batchSize     int
+	batchConcurrency int
+	weightPurchase bool
+	autoReserveThreshold int
+	autoReserveWeight int
+	reserving     atomic.Bool
+	signingConfig utils.SigningConfig
+	ws            *WebSocketManager
+}
+
+// ImpactExceededError is returned by MarketOpen when impact checking is enabled and
+// the simulated fill would walk the book beyond the slippage limit
+type ImpactExceededError struct {
+	Coin     string
+	LimitPx  float64
+	Estimate FillEstimate
+}
+
+// Error implements the error interface for ImpactExceededError.
+func (e *ImpactExceededError) Error() string {
+	if e.Estimate.RemainingSz > 0 {
+		return fmt.Sprintf("market order on %s exceeds visible liquidity: %g unfilled", e.Coin, e.Estimate.RemainingSz)
+	}
+	return fmt.Sprintf("market order on %s would fill at %g, beyond slippage limit %g", e.Coin, e.Estimate.WorstPx, e.LimitPx)
+}
+
+// BuilderFeeExceededError is returned by order methods when builder fee
+// checking is enabled and the builder fee is above what the user approved
+type BuilderFeeExceededError struct {
+	Builder string
+	Fee     int // Requested fee in tenths of basis points
+	MaxFee  int // Approved fee in tenths of basis points
+}
+
+// Error implements the error interface for BuilderFeeExceededError.
+func (e *BuilderFeeExceededError) Error() string {
+	if e.MaxFee == 0 {
+		return fmt.Sprintf("builder %s has no approved fee; call ApproveBuilderFee first", e.Builder)
+	}
+	return fmt.Sprintf("builder fee %d exceeds the approved maximum %d for builder %s (tenths of basis points)", e.Fee, e.MaxFee, e.Builder)
+}
+
+// InvalidDestinationError is returned by transfers when destination
+// validation is enabled and the destination does not exist or is sanctioned
+type InvalidDestinationError struct {
+	Destination string
+	Check       PreTransferCheck
+}
+
+// Error implements the error interface for InvalidDestinationError.
+func (e *InvalidDestinationError) Error() string {
+	if e.Check.IsSanctioned {
+		return fmt.Sprintf("transfer destination %s is sanctioned", e.Destination)
+	}
+	return fmt.Sprintf("transfer destination %s does not exist yet; sending to it costs a fee of %s", e.Destination, e.Check.Fee)
+}
+
+// InvalidOrderError is returned by order methods when a price or size of an
+// order is not valid for its asset, before the order is signed
+type InvalidOrderError struct {
+	Coin    string
+	Field   string  // "limitPx", "triggerPx" or "sz"
+	Value   float64
+	Nearest float64 // Nearest valid value
+}
+
+// Error implements the error interface for InvalidOrderError.
+func (e *InvalidOrderError) Error() string {
+	return fmt.Sprintf("invalid %s %g for order on %s; nearest valid value is %g", e.Field, e.Value, e.Coin, e.Nearest)
+}
+
+// InvalidAgentError is returned by ValidateAgent when the signer is not an
+// agent wallet the account approved, or its approval has expired
+type InvalidAgentError struct {
+	Agent      string
+	Account    string
+	ValidUntil int64 // Expiry in milliseconds, zero when the agent is not approved
+}
+
+// Error implements the error interface for InvalidAgentError.
+func (e *InvalidAgentError) Error() string {
+	if e.ValidUntil == 0 {
+		return fmt.Sprintf("agent %s is not approved by account %s; call ApproveAgent from the account first", e.Agent, e.Account)
+	}
+	return fmt.Sprintf("agent %s of account %s expired at %s", e.Agent, e.Account, time.UnixMilli(e.ValidUntil).UTC().Format(time.RFC3339))
 }
 
-// NewExchange creates a new Exchange client instance
+// NewExchange creates a new Exchange client instance signing with privateKey
 func NewExchange(privateKey *ecdsa.PrivateKey, baseURL string, meta *Meta, vaultAddress *string, accountAddress *string, spotMeta *SpotMeta, perpDexs []string, timeout time.Duration) (*Exchange, error) {
+	return NewExchangeWithSigner(utils.NewLocalSigner(privateKey), baseURL, meta, vaultAddress, accountAddress, spotMeta, perpDexs, timeout)
+}
+
+// NewExchangeWithSigner creates a new Exchange client instance that delegates
+// every signature to signer, so the key never has to be held in process
+func NewExchangeWithSigner(signer utils.Signer, baseURL string, meta *Meta, vaultAddress *string, accountAddress *string, spotMeta *SpotMeta, perpDexs []string, timeout time.Duration) (*Exchange, error) {
+	return NewExchangeOpts(signer, baseURL, meta, vaultAddress, accountAddress, spotMeta, perpDexs, timeout)
+}
+
+// NewExchangeOpts is like NewExchangeWithSigner but configured with client
+// options, such as WithHTTPClient or WithTransport, which apply to both the
+// /exchange requests and the /info requests of its Info client.
+func NewExchangeOpts(signer utils.Signer, baseURL string, meta *Meta, vaultAddress *string, accountAddress *string, spotMeta *SpotMeta, perpDexs []string, timeout time.Duration, opts ...ClientOption) (*Exchange, error) {
 	if baseURL == "" {
 		baseURL = utils.MainnetAPIURL
 	}
 	
-	api := NewAPI(baseURL, timeout)
-	info, err := NewInfo(baseURL, true, meta, spotMeta, perpDexs, timeout)
+	info, err := NewInfoOpts(baseURL, true, meta, spotMeta, perpDexs, timeout, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create info client: %w", err)
 	}
+	vaultAddress, err = normalizeVault(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
 	
-	return &Exchange{
+	return newExchange(info.withBaseURL(baseURL), signer, info, vaultAddress, accountAddress), nil
+}
+
+// NewExchangeWithInfo creates a new Exchange client instance that looks up
+// assets with info instead of fetching its own metadata, so that one
+// RefreshMeta updates both. It sends actions to the info's base URL through
+// the info's HTTP transport with its timeout. The info remains the caller's: the exchange never connects
+// or closes its WebSocket, which can be passed to SetWebSocket.
+func NewExchangeWithInfo(signer utils.Signer, info *Info, vaultAddress *string, accountAddress *string) (*Exchange, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("signer is required")
+	}
+	if info == nil {
+		return nil, fmt.Errorf("info client is required")
+	}
+	vaultAddress, err := normalizeVault(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	return newExchange(info.withBaseURL(info.GetBaseURL()), signer, info, vaultAddress, accountAddress), nil
+}
+
+// normalizeVault returns a vault address in the form it is hashed with, or
+// nil for no vault
+func normalizeVault(vaultAddress *string) (*string, error) {
+	if vaultAddress == nil {
+		return nil, nil
+	}
+	normalized, err := utils.NormalizeAddress(*vaultAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address: %w", err)
+	}
+	return &normalized, nil
+}
+
+// newExchange assembles an Exchange
+func newExchange(api *API, signer utils.Signer, info *Info, vaultAddress *string, accountAddress *string) *Exchange {
+	exchange := &Exchange{
 		API:            api,
-		privateKey:    privateKey,
-		vaultAddress:  vaultAddress,
+		signer:         signer,
+		nonces:         NewNonceManager(),
 		accountAddress: accountAddress,
-		info:          info,
-	}, nil
+		info:           info,
+		batchSize:      DefaultBatchSize,
+	}
+	exchange.vaultAddress.Store(vaultAddress)
+	return exchange
+}
+
+// Info returns the client the exchange looks up assets with
+func (e *Exchange) Info() *Info {
+	return e.info
 }
 
 // postAction sends a signed action to the exchange
-func (e *Exchange) postAction(action map[string]interface{}, signature string, nonce int64) (interface{}, error) {
+func (e *Exchange) postAction(ctx context.Context, action interface{}, signature *utils.Signature, nonce int64) (interface{}, error) {
+	var result interface{}
+	if _, err := e.postActionInto(ctx, action, signature, nonce, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// signUserSignedAction signs a user-signed action and returns the copy of it
+// with the signature chain fields, which is the action to post; action
+// itself is left unchanged
+func (e *Exchange) signUserSignedAction(action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string) (map[string]interface{}, *utils.Signature, error) {
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	signed := utils.UserSignedActionWithChain(action, isMainnet, e.signingConfig)
+	signature, err := utils.SignUserSignedActionWithConfig(e.signer, signed, payloadTypes, primaryType, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signed, signature, nil
+}
+
+// postActionInto sends a signed action to the exchange, decodes the response
+// into out and returns it raw. A top-level "err" status, which the exchange
+// answers with HTTP 200, is returned as *utils.ExchangeError.
+func (e *Exchange) postActionInto(ctx context.Context, action interface{}, signature *utils.Signature, nonce int64, out interface{}) (json.RawMessage, error) {
+	e.autoReserve(ctx, action)
+	var raw json.RawMessage
+	if err := e.PostInto(ctx, "/exchange", e.actionPayload(action, signature, nonce), &raw); err != nil {
+		return nil, err
+	}
+	return raw, decodeActionResponse(raw, out)
+}
+
+// postActionViaWS is like postActionInto but sends the action over the
+// WebSocket set with SetWebSocket
+func (e *Exchange) postActionViaWS(ctx context.Context, action interface{}, signature *utils.Signature, nonce int64, out interface{}) (json.RawMessage, error) {
+	if e.ws == nil {
+		return nil, fmt.Errorf("cannot post over WebSocket: no WebSocket set")
+	}
+	e.autoReserve(ctx, action)
+	raw, err := e.ws.PostRequest(ctx, PostAction, e.actionPayload(action, signature, nonce))
+	if err != nil {
+		return nil, err
+	}
+	return raw, decodeActionResponse(raw, out)
+}
+
+// actionPayload builds the request body of a signed action
+func (e *Exchange) actionPayload(action interface{}, signature *utils.Signature, nonce int64) map[string]interface{} {
 	payload := map[string]interface{}{
 		"action":    action,
 		"nonce":     nonce,
@@ -61,348 +271,2316 @@ func (e *Exchange) postAction(action map[string]interface{}, signature string, n
 	}
 	
 	// Add vault address for certain action types
-	actionType, _ := action["type"].(string)
-	if actionType != "usdClassTransfer" && actionType != "sendAsset" {
-		if e.vaultAddress != nil {
-			payload["vaultAddress"] = *e.vaultAddress
+	if !skipsVaultAddress(actionType(action)) {
+		if vaultAddress := e.vault(); vaultAddress != nil {
+			payload["vaultAddress"] = *vaultAddress
 		}
 	}
 	
-	if e.expiresAfter != nil {
-		payload["expiresAfter"] = *e.expiresAfter
+	if expiresAfter := e.expiresAfterFor(nonce); expiresAfter != nil {
+		payload["expiresAfter"] = *expiresAfter
 	}
-	
-	return e.Post("/exchange", payload)
+	return payload
 }
 
-// slippagePrice calculates price with slippage for market orders
-func (e *Exchange) slippagePrice(name string, isBuy bool, slippage float64, px *float64) (float64, error) {
-	coin, exists := e.info.nameToCoins[name]
-	if !exists {
-		return 0, fmt.Errorf("coin not found for name: %s", name)
-	}
-	
-	var price float64
-	if px != nil {
-		price = *px
-	} else {
-		// Get midprice
-		allMids, err := e.info.AllMids("")
-		if err != nil {
-			return 0, fmt.Errorf("failed to get all mids: %w", err)
-		}
-		
-		if midsMap, ok := allMids.(map[string]interface{}); ok {
-			if midStr, ok := midsMap[coin].(string); ok {
-				var err error
-				price, err = strconv.ParseFloat(midStr, 64)
-				if err != nil {
-					return 0, fmt.Errorf("failed to parse mid price: %w", err)
-				}
-			} else {
-				return 0, fmt.Errorf("mid price not found for coin: %s", coin)
-			}
-		} else {
-			return 0, fmt.Errorf("invalid all mids response format")
+// decodeActionResponse decodes an exchange response into out. A top-level
+// "err" status is returned as *utils.ExchangeError.
+func decodeActionResponse(raw json.RawMessage, out interface{}) error {
+	var envelope utils.ExchangeResponse
+	if err := json.Unmarshal(raw, &envelope); err == nil && envelope.Status == "err" {
+		var message string
+		if err := json.Unmarshal(envelope.Response, &message); err != nil {
+			message = string(envelope.Response)
 		}
+		return &utils.ExchangeError{Status: envelope.Status, Message: message}
 	}
 	
-	asset, exists := e.info.coinToAsset[coin]
-	if !exists {
-		return 0, fmt.Errorf("asset not found for coin: %s", coin)
-	}
-	
-	// Spot assets start at 10000
-	isSpot := asset >= 10000
-	
-	// Calculate slippage
-	if isBuy {
-		price *= (1 + slippage)
-	} else {
-		price *= (1 - slippage)
-	}
-	
-	// Round to appropriate decimals
-	szDecimals := e.info.assetToSzDecimals[asset]
-	var decimals int
-	if isSpot {
-		decimals = 8 - szDecimals
-	} else {
-		decimals = 6 - szDecimals
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("failed to decode exchange response: %w", err)
 	}
-	
-	// Round to 5 significant figures and appropriate decimals
-	multiplier := math.Pow(10, float64(decimals))
-	return math.Round(price*multiplier) / multiplier, nil
+	return nil
 }
 
-// SetExpiresAfter sets the expiration time for actions
-func (e *Exchange) SetExpiresAfter(expiresAfter *int64) {
-	e.expiresAfter = expiresAfter
+// NonceManager returns the nonce source shared by every action this exchange
+// signs. Use its SetMinNonce to move past nonces the exchange rejected.
+func (e *Exchange) NonceManager() *NonceManager {
+	return e.nonces
 }
 
-// Order places a single order
-func (e *Exchange) Order(name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string, builder *BuilderInfo) (interface{}, error) {
-	orderRequest := utils.OrderRequest{
-		Coin:       name,
-		IsBuy:      isBuy,
-		Sz:         sz,
-		LimitPx:    limitPx,
-		OrderType:  orderType,
-		ReduceOnly: reduceOnly,
-		Cloid:      cloid,
+// PostSignedAction submits an action signed outside this client, for example
+// from a digest produced by utils.L1ActionDigest. The exchange's vault address
+// and expiresAfter are attached as for any other action, so the digest must
+// have been computed with the same values.
+func (e *Exchange) PostSignedAction(action interface{}, signature *utils.Signature, nonce int64) (*utils.ExchangeResponse, error) {
+	return e.PostSignedActionCtx(context.Background(), action, signature, nonce)
+}
+
+// PostSignedActionCtx is like PostSignedAction but carries ctx to the request
+func (e *Exchange) PostSignedActionCtx(ctx context.Context, action interface{}, signature *utils.Signature, nonce int64) (*utils.ExchangeResponse, error) {
+	var response utils.ExchangeResponse
+	var err error
+	if response.Raw, err = e.postActionInto(ctx, action, signature, nonce, &response); err != nil {
+		return nil, err
 	}
-	
-	return e.BulkOrders([]utils.OrderRequest{orderRequest}, builder)
+	return &response, nil
 }
 
-// BulkOrders places multiple orders in a single transaction
-func (e *Exchange) BulkOrders(orderRequests []utils.OrderRequest, builder *BuilderInfo) (interface{}, error) {
-	orderWires := make([]utils.OrderWire, len(orderRequests))
-	
-	for i, order := range orderRequests {
-		asset, err := e.info.NameToAsset(order.Coin)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get asset for coin %s: %w", order.Coin, err)
-		}
-		
-		orderWire, err := utils.OrderRequestToOrderWire(order, asset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to convert order to wire format: %w", err)
-		}
-		orderWires[i] = *orderWire
+// ErrWeightPurchaseDisabled is returned by ReserveRequestWeight unless
+// purchases were enabled with SetWeightPurchase
+var ErrWeightPurchaseDisabled = errors.New("request weight purchases are disabled; enable them with SetWeightPurchase")
+
+// SetWeightPurchase allows ReserveRequestWeight and auto-reserving to spend
+// USDC on request weight. It is off by default, since every purchase is
+// charged.
+func (e *Exchange) SetWeightPurchase(enabled bool) {
+	e.weightPurchase = enabled
+}
+
+// SetAutoReserve makes the exchange buy weight more requests before sending
+// an action whenever the address budget of its rate limiter (see
+// RateLimiter.SetAddressBudget) is below threshold, or stops it when weight
+// is zero. Purchases must be enabled with SetWeightPurchase. A failed
+// purchase is logged and does not hold the action back.
+func (e *Exchange) SetAutoReserve(threshold int, weight int) {
+	e.autoReserveThreshold = threshold
+	e.autoReserveWeight = weight
+}
+
+// ReserveRequestWeight buys weight additional exchange requests for the
+// address, charged in USDC. The exchange's rate limiter, if any, has them
+// added to its address budget. It fails with ErrWeightPurchaseDisabled unless
+// purchases were enabled with SetWeightPurchase.
+func (e *Exchange) ReserveRequestWeight(weight int) (*utils.ExchangeResponse, error) {
+	return e.ReserveRequestWeightCtx(context.Background(), weight)
+}
+
+// ReserveRequestWeightCtx is like ReserveRequestWeight but carries ctx to the request
+func (e *Exchange) ReserveRequestWeightCtx(ctx context.Context, weight int) (*utils.ExchangeResponse, error) {
+	if !e.weightPurchase {
+		return nil, ErrWeightPurchaseDisabled
 	}
-	
-	timestamp := utils.GetTimestampMs()
-	
-	var builderStr *string
-	if builder != nil {
-		builderStr = &builder.B
+	if weight <= 0 {
+		return nil, fmt.Errorf("request weight to reserve must be positive: %d", weight)
 	}
-	orderAction := utils.OrderWiresToOrderAction(orderWires, builderStr)
+	
+	timestamp := e.nonces.Next()
+	action := utils.ReserveRequestWeightAction{Type: "reserveRequestWeight", Weight: weight}
 	
 	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
 	
-	var expiresAfterUint *uint64
-	if e.expiresAfter != nil {
-		uint64Val := uint64(*e.expiresAfter)
-		expiresAfterUint = &uint64Val
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
+	if err != nil {
+		return nil, err
 	}
 	
-	signature, err := utils.SignL1Action(e.privateKey, orderAction, e.vaultAddress, uint64(timestamp), expiresAfterUint, isMainnet)
+	signature, err := utils.SignL1ActionWithConfig(e.signer, action, nil, uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign order action: %w", err)
+		return nil, fmt.Errorf("failed to sign reserve request weight action: %w", err)
 	}
 	
-	return e.postAction(orderAction, signature.R+signature.S+fmt.Sprintf("%02x", signature.V), timestamp)
+	response, err := e.PostSignedActionCtx(ctx, action, signature, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	if e.limiter != nil {
+		e.limiter.AddAddressBudget(weight)
+	}
+	return response, nil
 }
 
-// MarketOpen places a market order to open a position
-func (e *Exchange) MarketOpen(name string, isBuy bool, sz float64, px *float64, slippage float64, cloid *string, builder *BuilderInfo) (interface{}, error) {
-	if slippage == 0 {
-		slippage = DefaultSlippage
+// autoReserve buys request weight as set with SetAutoReserve when the rate
+// limiter's address budget fell below the threshold. Only one purchase runs
+// at a time; actions sent meanwhile do not wait for it.
+func (e *Exchange) autoReserve(ctx context.Context, action interface{}) {
+	if e.autoReserveWeight <= 0 || !e.weightPurchase || e.limiter == nil || actionType(action) == "reserveRequestWeight" {
+		return
+	}
+	remaining, ok := e.limiter.AddressRemaining()
+	if !ok || remaining >= e.autoReserveThreshold {
+		return
+	}
+	if !e.reserving.CompareAndSwap(false, true) {
+		return
 	}
+	defer e.reserving.Store(false)
 	
-	// Get aggressive market price
-	price, err := e.slippagePrice(name, isBuy, slippage, px)
+	if _, err := e.ReserveRequestWeightCtx(ctx, e.autoReserveWeight); err != nil {
+		e.logger.Warn("Failed to reserve request weight", "err", err)
+	}
+}
+
+// nonceSetSize is how many of a signer's highest nonces the exchange keeps;
+// a nonce must exceed the smallest of them to be accepted
+const nonceSetSize = 100
+
+// Noop sends an action that does nothing, using up a fresh nonce
+func (e *Exchange) Noop() (*utils.ExchangeResponse, error) {
+	return e.NoopCtx(context.Background())
+}
+
+// NoopCtx is like Noop but carries ctx to the request
+func (e *Exchange) NoopCtx(ctx context.Context) (*utils.ExchangeResponse, error) {
+	timestamp := e.nonces.Next()
+	action := utils.NoopAction{Type: "noop"}
+	
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to calculate slippage price: %w", err)
+		return nil, err
 	}
 	
-	// Market order is an aggressive limit order IoC
-	orderType := utils.OrderType{
-		Limit: &utils.LimitOrderType{
-			TIF: utils.TIFIoc,
-		},
+	signature, err := utils.SignL1ActionWithConfig(e.signer, action, e.vault(), uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign noop action: %w", err)
 	}
 	
-	return e.Order(name, isBuy, sz, price, orderType, false, cloid, builder)
+	return e.PostSignedActionCtx(ctx, action, signature, timestamp)
 }
 
-// MarketClose places a market order to close a position
-func (e *Exchange) MarketClose(coin string, sz *float64, px *float64, slippage float64, cloid *string, builder *BuilderInfo) (interface{}, error) {
-	if slippage == 0 {
-		slippage = DefaultSlippage
+// InvalidateNoncesThrough makes the exchange reject every action of the
+// signer with a nonce up to nonce that it has not executed yet, such as a
+// suspected duplicate still in flight. The exchange only accepts nonces
+// above the smallest of the signer's 100 highest, so it moves the nonce
+// manager past nonce and sends 100 noops. It returns how many were accepted.
+func (e *Exchange) InvalidateNoncesThrough(nonce int64) (int, error) {
+	return e.InvalidateNoncesThroughCtx(context.Background(), nonce)
+}
+
+// InvalidateNoncesThroughCtx is like InvalidateNoncesThrough but carries ctx
+// to the requests
+func (e *Exchange) InvalidateNoncesThroughCtx(ctx context.Context, nonce int64) (int, error) {
+	e.nonces.SetMinNonce(nonce + 1)
+	for sent := 0; sent < nonceSetSize; sent++ {
+		if _, err := e.NoopCtx(ctx); err != nil {
+			return sent, fmt.Errorf("noop %d of %d failed: %w", sent+1, nonceSetSize, err)
+		}
 	}
-	
-	address := crypto.PubkeyToAddress(e.privateKey.PublicKey).Hex()
-	if e.accountAddress != nil {
-		address = *e.accountAddress
+	return nonceSetSize, nil
+}
+
+// actionType returns the type tag of a map based or typed action
+func actionType(action interface{}) string {
+	switch a := action.(type) {
+	case map[string]interface{}:
+		actionType, _ := a["type"].(string)
+		return actionType
+	case utils.Action:
+		return a.ActionType()
+	}
+	return ""
+}
+
+// skipsVaultAddress reports whether an action type is always executed on behalf
+// of the signing user and must not carry the vault address
+func skipsVaultAddress(actionType string) bool {
+	switch actionType {
+	case "usdClassTransfer", "PerpDexClassTransfer", "sendAsset", "createSubAccount", "subAccountTransfer", "subAccountSpotTransfer", "vaultTransfer",
+		"perpDeploy", "spotDeploy", "reserveRequestWeight":
+		return true
 	}
-	if e.vaultAddress != nil {
-		address = *e.vaultAddress
+	return false
+}
+
+// postL1Action signs an L1 action with the current nonce and posts it to the exchange
+func (e *Exchange) postL1Action(ctx context.Context, action interface{}, vaultAddress *string) (interface{}, error) {
+	var result interface{}
+	if _, err := e.postL1ActionInto(ctx, action, vaultAddress, &result); err != nil {
+		return nil, err
 	}
+	return result, nil
+}
+
+// postL1ActionInto is like postL1Action but decodes the response into out
+// and returns it raw
+func (e *Exchange) postL1ActionInto(ctx context.Context, action interface{}, vaultAddress *string, out interface{}) (json.RawMessage, error) {
+	timestamp := e.nonces.Next()
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
 	
-	userState, err := e.info.UserState(address, "")
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user state: %w", err)
+		return nil, err
 	}
 	
-	if userStateMap, ok := userState.(map[string]interface{}); ok {
-		if assetPositions, ok := userStateMap["assetPositions"].([]interface{}); ok {
-			for _, positionInterface := range assetPositions {
-				if positionMap, ok := positionInterface.(map[string]interface{}); ok {
-					if position, ok := positionMap["position"].(map[string]interface{}); ok {
-						if positionCoin, ok := position["coin"].(string); ok && positionCoin == coin {
-							if sziStr, ok := position["szi"].(string); ok {
-								szi, err := strconv.ParseFloat(sziStr, 64)
-								if err != nil {
-									return nil, fmt.Errorf("failed to parse szi: %w", err)
-								}
-								
-								size := sz
-								if size == nil {
-									absSize := math.Abs(szi)
-									size = &absSize
-								}
-								
-								isBuy := szi < 0
-								
-								// Get aggressive market price
-								price, err := e.slippagePrice(coin, isBuy, slippage, px)
-								if err != nil {
-									return nil, fmt.Errorf("failed to calculate slippage price: %w", err)
-								}
-								
-								// Market order is an aggressive limit order IoC
-								orderType := utils.OrderType{
-									Limit: &utils.LimitOrderType{
-										TIF: utils.TIFIoc,
-									},
-								}
-								
-								return e.Order(coin, isBuy, *size, price, orderType, true, cloid, builder)
-							}
-						}
-					}
-				}
-			}
-		}
+	signature, err := utils.SignL1ActionWithConfig(e.signer, action, vaultAddress, uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign %s action: %w", actionType(action), err)
 	}
 	
-	return nil, fmt.Errorf("position not found for coin: %s", coin)
+	return e.postActionInto(ctx, action, signature, timestamp, out)
 }
 
-// Cancel cancels a single order
-func (e *Exchange) Cancel(name string, oid int) (interface{}, error) {
-	cancelRequest := utils.CancelRequest{
-		Coin: name,
-		OID:  oid,
+// MarketOptions are optional parameters of market orders
+type MarketOptions struct {
+	// Reference is the price slippage is applied to, utils.PriceReferenceMid
+	// or utils.PriceReferenceBBO. It defaults to the one set with
+	// SetMarketReference.
+	Reference utils.PriceReference
+
+	// ClosePercent makes MarketClose close that percentage of the position,
+	// up to 100, rounded to the asset's size decimals. Zero closes all of
+	// it; it is ignored when a size is given.
+	ClosePercent float64
+}
+
+// marketOptions returns the first of opts, or none
+func marketOptions(opts []MarketOptions) MarketOptions {
+	if len(opts) > 0 {
+		return opts[0]
 	}
-	return e.BulkCancel([]utils.CancelRequest{cancelRequest})
+	return MarketOptions{}
 }
 
-// BulkCancel cancels multiple orders
-func (e *Exchange) BulkCancel(cancelRequests []utils.CancelRequest) (interface{}, error) {
-	timestamp := utils.GetTimestampMs()
-	cancels := make([]map[string]interface{}, len(cancelRequests))
-	
-	for i, cancel := range cancelRequests {
-		asset, err := e.info.NameToAsset(cancel.Coin)
+// SetMarketReference sets the price market orders apply their slippage to
+// when their options do not choose one: utils.PriceReferenceMid, the
+// default, or utils.PriceReferenceBBO, the best opposing level of the L2
+// book. The mid can be far from the touch in fast markets, leaving an IoC
+// order that does not cross or crosses too deep.
+func (e *Exchange) SetMarketReference(reference utils.PriceReference) {
+	e.marketReference = reference
+}
+
+// slippagePrice calculates price with slippage for market orders, returning
+// the reference price it was applied to as well
+func (e *Exchange) slippagePrice(ctx context.Context, name string, isBuy bool, slippage float64, px *float64, opts MarketOptions) (float64, utils.PriceReference, float64, error) {
+	coin, _, exists := e.info.coinForName(name)
+	if !exists {
+		return 0, "", 0, fmt.Errorf("coin not found for name: %s", name)
+	}
+
+	reference := opts.Reference
+	if reference == "" {
+		reference = e.marketReference
+	}
+
+	var refPx float64
+	var err error
+	switch {
+	case px != nil:
+		reference, refPx = utils.PriceReferenceGiven, *px
+	case reference == utils.PriceReferenceBBO:
+		var ok bool
+		refPx, ok, err = e.bestOpposingPrice(ctx, name, isBuy)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get asset for coin %s: %w", cancel.Coin, err)
+			return 0, "", 0, err
 		}
-		
-		cancels[i] = map[string]interface{}{
-			"a": asset,
-			"o": cancel.OID,
+		if !ok {
+			// Nothing to cross on the book, so fall back to the mid
+			reference = utils.PriceReferenceMid
+			refPx, err = e.midPrice(ctx, coin)
 		}
+	default:
+		reference = utils.PriceReferenceMid
+		refPx, err = e.midPrice(ctx, coin)
 	}
-	
-	cancelAction := map[string]interface{}{
-		"type":    "cancel",
-		"cancels": cancels,
+	if err != nil {
+		return 0, "", 0, err
 	}
-	
-	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
-	
-	var expiresAfterUint *uint64
-	if e.expiresAfter != nil {
-		uint64Val := uint64(*e.expiresAfter)
-		expiresAfterUint = &uint64Val
+
+	// Calculate slippage
+	price := refPx
+	if isBuy {
+		price *= (1 + slippage)
+	} else {
+		price *= (1 - slippage)
 	}
-	
-	signature, err := utils.SignL1Action(e.privateKey, cancelAction, e.vaultAddress, uint64(timestamp), expiresAfterUint, isMainnet)
+
+	price, err = e.roundPrice(coin, price)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign cancel action: %w", err)
+		return 0, "", 0, err
 	}
-	
-	return e.postAction(cancelAction, signature.R+signature.S+fmt.Sprintf("%02x", signature.V), timestamp)
+	return price, reference, refPx, nil
 }
 
-// UpdateLeverage updates leverage for a specific asset
-func (e *Exchange) UpdateLeverage(leverage int, name string, isCross bool) (interface{}, error) {
-	timestamp := utils.GetTimestampMs()
-	asset, err := e.info.NameToAsset(name)
+// midPrice returns the mid of a coin, from the coin's own dex
+func (e *Exchange) midPrice(ctx context.Context, coin string) (float64, error) {
+	allMids, err := e.info.AllMidsCtx(ctx, perpDexOf(coin))
 	if err != nil {
-		return nil, fmt.Errorf("failed to get asset for name %s: %w", name, err)
+		return 0, fmt.Errorf("failed to get all mids: %w", err)
 	}
-	
-	updateAction := map[string]interface{}{
-		"type":     "updateLeverage",
-		"asset":    asset,
-		"isCross":  isCross,
-		"leverage": leverage,
+
+	midsMap, ok := allMids.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("invalid all mids response format")
 	}
-	
-	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
-	
-	var expiresAfterUint *uint64
-	if e.expiresAfter != nil {
-		uint64Val := uint64(*e.expiresAfter)
-		expiresAfterUint = &uint64Val
+	midStr, ok := midsMap[coin].(string)
+	if !ok {
+		return 0, fmt.Errorf("mid price not found for coin: %s", coin)
 	}
-	
-	signature, err := utils.SignL1Action(e.privateKey, updateAction, e.vaultAddress, uint64(timestamp), expiresAfterUint, isMainnet)
+	price, err := strconv.ParseFloat(midStr, 64)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign update leverage action: %w", err)
+		return 0, fmt.Errorf("failed to parse mid price: %w", err)
 	}
-	
-	return e.postAction(updateAction, signature.R+signature.S+fmt.Sprintf("%02x", signature.V), timestamp)
+	return price, nil
 }
 
-// UsdClassTransfer transfers USD between perp and spot
-func (e *Exchange) UsdClassTransfer(amount float64, toPerp bool) (interface{}, error) {
-	timestamp := utils.GetTimestampMs()
-	strAmount := fmt.Sprintf("%.6f", amount)
-	
-	if e.vaultAddress != nil {
-		strAmount += fmt.Sprintf(" subaccount:%s", *e.vaultAddress)
+// bestOpposingPrice returns the best ask for buys and the best bid for
+// sells, and false if that side of the book is empty
+func (e *Exchange) bestOpposingPrice(ctx context.Context, name string, isBuy bool) (float64, bool, error) {
+	snapshot, err := e.info.L2SnapshotCtx(ctx, name)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get L2 snapshot: %w", err)
 	}
-	
-	action := map[string]interface{}{
-		"type":   "usdClassTransfer",
-		"amount": strAmount,
-		"toPerp": toPerp,
-		"nonce":  timestamp,
+	book := NewOrderBook(*snapshot)
+	level, ok := book.BestBid()
+	if isBuy {
+		level, ok = book.BestAsk()
 	}
-	
-	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
-	
-	signature, err := utils.SignUSDClassTransferAction(e.privateKey, action, isMainnet)
+	if !ok {
+		return 0, false, nil
+	}
+	price, err := level.Price()
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign USD class transfer action: %w", err)
+		return 0, false, fmt.Errorf("failed to parse book price: %w", err)
 	}
-	
-	return e.postAction(action, signature.R+signature.S+fmt.Sprintf("%02x", signature.V), timestamp)
+	return price, true, nil
 }
 
-// UsdTransfer transfers USD to another address
-func (e *Exchange) UsdTransfer(amount float64, destination string) (interface{}, error) {
-	timestamp := utils.GetTimestampMs()
-	action := map[string]interface{}{
-		"destination": destination,
-		"amount":      fmt.Sprintf("%.6f", amount),
-		"time":        timestamp,
-		"type":        "usdSend",
+// roundPrice rounds a price to the nearest one valid for the coin, to 5
+// significant figures and the decimals its size decimals allow; see
+// utils.RoundPx
+func (e *Exchange) roundPrice(coin string, price float64) (float64, error) {
+	assets := e.info.currentAssets()
+	asset, exists := assets.coinToAsset[coin]
+	if !exists {
+		return 0, fmt.Errorf("asset not found for coin: %s", coin)
 	}
 	
-	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
-	
-	signature, err := utils.SignUSDTransferAction(e.privateKey, action, isMainnet)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign USD transfer action: %w", err)
+	return utils.RoundPx(price, assets.assetToSzDecimals[asset], e.info.IsSpotAsset(asset)), nil
+}
+
+// SetImpactCheck enables simulating market orders against the L2 book before
+// sending them; MarketOpen then fails with *ImpactExceededError instead of
+// submitting an order that cannot fill within its slippage limit
+func (e *Exchange) SetImpactCheck(enabled bool) {
+	e.impactCheck = enabled
+}
+
+// userAddress returns the address the exchange trades for and queries state
+// of: the vault, the account, or else the signer
+func (e *Exchange) userAddress() string {
+	if vaultAddress := e.vault(); vaultAddress != nil {
+		return *vaultAddress
 	}
-	
-	return e.postAction(action, signature.R+signature.S+fmt.Sprintf("%02x", signature.V), timestamp)
+	if e.accountAddress != nil {
+		return *e.accountAddress
+	}
+	return e.signer.Address().Hex()
+}
+
+// SetBuilderFeeCheck enables checking the builder fee of orders against
+// Info.MaxBuilderFee before sending them; orders then fail with
+// *BuilderFeeExceededError instead of being rejected by the exchange
+func (e *Exchange) SetBuilderFeeCheck(enabled bool) {
+	e.builderFeeCheck = enabled
+}
+
+// SetValidateDestination enables checking the destination of UsdTransfer and
+// SendAsset with Info.PreTransferCheck first; transfers to addresses that do
+// not exist yet or are sanctioned then fail with *InvalidDestinationError
+func (e *Exchange) SetValidateDestination(enabled bool) {
+	e.validateDestination = enabled
+}
+
+// checkDestination runs the pre-transfer check when destination validation is
+// enabled. Transfers are user-signed, so the signer is the source.
+func (e *Exchange) checkDestination(ctx context.Context, destination string) error {
+	if !e.validateDestination {
+		return nil
+	}
+	check, err := e.info.PreTransferCheckCtx(ctx, destination, e.signer.Address().Hex())
+	if err != nil {
+		return fmt.Errorf("failed to check transfer destination: %w", err)
+	}
+	if check.IsSanctioned || !check.UserExists {
+		return &InvalidDestinationError{Destination: destination, Check: *check}
+	}
+	return nil
+}
+
+// SetOrderValidation enables or disables checking every order with
+// ValidateOrder before it is signed. It is enabled by default.
+func (e *Exchange) SetOrderValidation(enabled bool) {
+	e.skipOrderValidation = !enabled
+}
+
+// ValidateOrder checks the prices and size of an order against the metadata
+// of its asset, returning *InvalidOrderError for the first one the exchange
+// would reject. Use utils.RoundPx and utils.RoundSz to fix them.
+func (e *Exchange) ValidateOrder(req utils.OrderRequest) error {
+	coin, assets, exists := e.info.coinForName(req.Coin)
+	if !exists {
+		return fmt.Errorf("coin not found for name: %s", req.Coin)
+	}
+	asset, exists := assets.coinToAsset[coin]
+	if !exists {
+		return fmt.Errorf("asset not found for coin: %s", coin)
+	}
+	szDecimals := assets.assetToSzDecimals[asset]
+	isSpot := e.info.IsSpotAsset(asset)
+
+	if nearest := utils.RoundPx(req.LimitPx, szDecimals, isSpot); !sameValue(req.LimitPx, nearest) {
+		return &InvalidOrderError{Coin: req.Coin, Field: "limitPx", Value: req.LimitPx, Nearest: nearest}
+	}
+	if trigger := req.OrderType.Trigger; trigger != nil {
+		if nearest := utils.RoundPx(trigger.TriggerPx, szDecimals, isSpot); !sameValue(trigger.TriggerPx, nearest) {
+			return &InvalidOrderError{Coin: req.Coin, Field: "triggerPx", Value: trigger.TriggerPx, Nearest: nearest}
+		}
+	}
+	if nearest := utils.RoundSz(req.Sz, szDecimals); !sameValue(req.Sz, nearest) {
+		return &InvalidOrderError{Coin: req.Coin, Field: "sz", Value: req.Sz, Nearest: nearest}
+	}
+	return nil
+}
+
+// wireDecimals returns the most decimals the prices and sizes of an asset's
+// orders may have on the wire, from its size decimals
+func (e *Exchange) wireDecimals(asset int) (pxDecimals, szDecimals int) {
+	szDecimals = e.info.currentAssets().assetToSzDecimals[asset]
+	return utils.PxDecimals(szDecimals, e.info.IsSpotAsset(asset)), szDecimals
+}
+
+// sameValue reports whether a value equals its rounded form, allowing for
+// floating point error far below the smallest tick
+func sameValue(value, rounded float64) bool {
+	return math.Abs(value-rounded) <= 1e-9*math.Max(1, math.Abs(value))
+}
+
+// SetAutoCloid enables giving every order sent without a client order ID a
+// random one, so it can be tracked across reconnects. The IDs sent are in
+// the Cloids of the order response.
+func (e *Exchange) SetAutoCloid(enabled bool) {
+	e.autoCloid = enabled
+}
+
+// vault returns the vault or sub-account the exchange trades for, or nil
+func (e *Exchange) vault() *string {
+	return e.vaultAddress.Load()
+}
+
+// SetVaultAddress sets the vault or sub-account the exchange trades for, or
+// trades for the account again when nil. It is safe to call while other
+// actions are being sent; those may be sent for either address. The address
+// is stored normalized; an invalid one is rejected and the current address
+// kept.
+func (e *Exchange) SetVaultAddress(vaultAddress *string) error {
+	normalized, err := normalizeVault(vaultAddress)
+	if err != nil {
+		return err
+	}
+	e.vaultAddress.Store(normalized)
+	return nil
+}
+
+// GetVaultAddress returns the vault or sub-account the exchange trades for,
+// or nil
+func (e *Exchange) GetVaultAddress() *string {
+	return e.vault()
+}
+
+// GetWalletAddress returns the address of the signer, which is an agent
+// wallet when the exchange trades for another account
+func (e *Exchange) GetWalletAddress() string {
+	return e.signer.Address().Hex()
+}
+
+// GetAccountAddress returns the account the exchange trades for, falling
+// back to the wallet address when none was given
+func (e *Exchange) GetAccountAddress() string {
+	if e.accountAddress != nil {
+		return *e.accountAddress
+	}
+	return e.GetWalletAddress()
+}
+
+// ValidateAgent checks that the signer is an agent wallet the account
+// approved and that its approval has not expired, since the exchange rejects
+// actions of other agents with errors that do not name the cause. It returns
+// the agent, or nil when the signer is the account itself, and
+// *InvalidAgentError when the agent cannot act for the account.
+func (e *Exchange) ValidateAgent() (*ExtraAgent, error) {
+	return e.ValidateAgentCtx(context.Background())
+}
+
+// ValidateAgentCtx is like ValidateAgent but carries ctx to the request
+func (e *Exchange) ValidateAgentCtx(ctx context.Context) (*ExtraAgent, error) {
+	agent := e.GetWalletAddress()
+	account := e.GetAccountAddress()
+	if strings.EqualFold(agent, account) {
+		return nil, nil
+	}
+	
+	agents, err := e.info.ExtraAgentsCtx(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch agents of %s: %w", account, err)
+	}
+	for _, approved := range agents {
+		if !strings.EqualFold(approved.Address, agent) {
+			continue
+		}
+		if approved.ValidUntil != 0 && approved.ValidUntil <= time.Now().UnixMilli() {
+			return nil, &InvalidAgentError{Agent: agent, Account: account, ValidUntil: approved.ValidUntil}
+		}
+		return &approved, nil
+	}
+	return nil, &InvalidAgentError{Agent: agent, Account: account}
+}
+
+// GetSigner returns the signer of every action
+func (e *Exchange) GetSigner() utils.Signer {
+	return e.signer
+}
+
+// GetPrivateKey returns the private key of an exchange created with
+// NewExchange, and nil when its signer holds no key in process
+func (e *Exchange) GetPrivateKey() *ecdsa.PrivateKey {
+	if signer, ok := e.signer.(*utils.LocalSigner); ok {
+		return signer.PrivateKey()
+	}
+	return nil
+}
+
+// expirySetting is the expiry set by SetExpiresAfter or
+// SetExpiresAfterDuration. It is replaced as a whole so that actions being
+// sent never see half of a change.
+type expirySetting struct {
+	expiresAfter *int64
+	duration     time.Duration
+}
+
+// SetExpiresAfter sets the time in milliseconds after which the exchange
+// rejects actions instead of executing them, or removes it when nil. Actions
+// signed once it has passed fail locally; SetExpiresAfterDuration avoids
+// that by computing the time for every action. It is safe to call while
+// other actions are being sent.
+func (e *Exchange) SetExpiresAfter(expiresAfter *int64) {
+	e.expiry.Store(&expirySetting{expiresAfter: expiresAfter})
+}
+
+// SetExpiresAfterDuration makes every action expire d after its nonce, so
+// that actions delayed in transit are rejected instead of executed late, or
+// removes the expiry when d is zero. It is safe to call while other actions
+// are being sent.
+func (e *Exchange) SetExpiresAfterDuration(d time.Duration) {
+	e.expiry.Store(&expirySetting{duration: d})
+}
+
+// SetSigningConfig sets the chains and EIP-712 domains actions are signed
+// for; fields left empty keep the defaults of utils.SigningConfig
+func (e *Exchange) SetSigningConfig(config utils.SigningConfig) {
+	e.signingConfig = config.WithDefaults()
+}
+
+// expiresAfterFor returns the expiresAfter of the action with the given
+// nonce, or nil
+func (e *Exchange) expiresAfterFor(nonce int64) *int64 {
+	expiry := e.expiry.Load()
+	if expiry == nil {
+		return nil
+	}
+	if expiry.duration > 0 {
+		expiresAfter := nonce + expiry.duration.Milliseconds()
+		return &expiresAfter
+	}
+	return expiry.expiresAfter
+}
+
+// signedExpiresAfter returns the expiresAfter to sign the action with the
+// given nonce with, failing when it is not after the nonce: the exchange
+// would reject the action
+func (e *Exchange) signedExpiresAfter(nonce int64) (*uint64, error) {
+	expiresAfter := e.expiresAfterFor(nonce)
+	if expiresAfter == nil {
+		return nil, nil
+	}
+	if *expiresAfter <= nonce {
+		return nil, fmt.Errorf("expiresAfter %d is not after the action's nonce %d", *expiresAfter, nonce)
+	}
+	value := uint64(*expiresAfter)
+	return &value, nil
+}
+
+// Order places a single order
+func (e *Exchange) Order(name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string, builder *BuilderInfo) (*utils.OrderResponse, error) {
+	return e.OrderCtx(context.Background(), name, isBuy, sz, limitPx, orderType, reduceOnly, cloid, builder)
+}
+
+// OrderCtx is like Order but carries ctx to the request
+func (e *Exchange) OrderCtx(ctx context.Context, name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string, builder *BuilderInfo) (*utils.OrderResponse, error) {
+	orderRequest := utils.OrderRequest{
+		Coin:       name,
+		IsBuy:      isBuy,
+		Sz:         sz,
+		LimitPx:    limitPx,
+		OrderType:  orderType,
+		ReduceOnly: reduceOnly,
+		Cloid:      cloid,
+	}
+	
+	return e.BulkOrdersCtx(ctx, []utils.OrderRequest{orderRequest}, builder)
+}
+
+// BulkOrders places multiple orders in a single transaction, or in one per
+// batch when there are more than the batch size (see SetBatchSize). When a
+// batch fails outright the response still holds the statuses of the others,
+// with the error as the status of the orders not placed.
+func (e *Exchange) BulkOrders(orderRequests []utils.OrderRequest, builder *BuilderInfo) (*utils.OrderResponse, error) {
+	return e.BulkOrdersCtx(context.Background(), orderRequests, builder)
+}
+
+// BulkOrdersCtx is like BulkOrders but carries ctx to the request
+func (e *Exchange) BulkOrdersCtx(ctx context.Context, orderRequests []utils.OrderRequest, builder *BuilderInfo) (*utils.OrderResponse, error) {
+	return e.BulkOrdersWithGroupingCtx(ctx, orderRequests, builder, utils.GroupingNA)
+}
+
+// BulkOrdersWithGrouping places multiple orders in a single transaction with the
+// given grouping. With normalTpsl the first order is the entry and the remaining
+// orders are its reduce-only take profit and stop loss triggers; with
+// positionTpsl every order is a reduce-only trigger attached to the position.
+func (e *Exchange) BulkOrdersWithGrouping(orderRequests []utils.OrderRequest, builder *BuilderInfo, grouping utils.Grouping) (*utils.OrderResponse, error) {
+	return e.BulkOrdersWithGroupingCtx(context.Background(), orderRequests, builder, grouping)
+}
+
+// BulkOrdersWithGroupingCtx is like BulkOrdersWithGrouping but carries ctx to the request
+func (e *Exchange) BulkOrdersWithGroupingCtx(ctx context.Context, orderRequests []utils.OrderRequest, builder *BuilderInfo, grouping utils.Grouping) (*utils.OrderResponse, error) {
+	return e.bulkOrders(ctx, orderRequests, builder, grouping, e.postActionInto)
+}
+
+// SetWebSocket sets the WebSocket that OrderViaWS sends orders over, such as
+// the one of an Info client created without skipWS
+func (e *Exchange) SetWebSocket(ws *WebSocketManager) {
+	e.ws = ws
+}
+
+// OrderViaWS places a single order like Order but sends it over the WebSocket
+// set with SetWebSocket, which avoids the HTTP round trip overhead
+func (e *Exchange) OrderViaWS(name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string, builder *BuilderInfo) (*utils.OrderResponse, error) {
+	return e.OrderViaWSCtx(context.Background(), name, isBuy, sz, limitPx, orderType, reduceOnly, cloid, builder)
+}
+
+// OrderViaWSCtx is like OrderViaWS but carries ctx to the request
+func (e *Exchange) OrderViaWSCtx(ctx context.Context, name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string, builder *BuilderInfo) (*utils.OrderResponse, error) {
+	orderRequest := utils.OrderRequest{
+		Coin:       name,
+		IsBuy:      isBuy,
+		Sz:         sz,
+		LimitPx:    limitPx,
+		OrderType:  orderType,
+		ReduceOnly: reduceOnly,
+		Cloid:      cloid,
+	}
+	return e.bulkOrders(ctx, []utils.OrderRequest{orderRequest}, builder, utils.GroupingNA, e.postActionViaWS)
+}
+
+// bulkOrders checks orders, signs them in one order action per batch and
+// submits every action with post
+func (e *Exchange) bulkOrders(ctx context.Context, orderRequests []utils.OrderRequest, builder *BuilderInfo, grouping utils.Grouping,
+	post func(ctx context.Context, action interface{}, signature *utils.Signature, nonce int64, out interface{}) (json.RawMessage, error)) (*utils.OrderResponse, error) {
+	if err := validateGrouping(orderRequests, grouping); err != nil {
+		return nil, err
+	}
+	if builder != nil {
+		address, err := utils.NormalizeAddress(builder.B)
+		if err != nil {
+			return nil, fmt.Errorf("invalid builder address: %w", err)
+		}
+		builder = &BuilderInfo{B: address, F: builder.F}
+	}
+
+	if builder != nil && e.builderFeeCheck {
+		maxFee, err := e.info.MaxBuilderFeeCtx(ctx, e.userAddress(), builder.B)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get max builder fee: %w", err)
+		}
+		if builder.F > maxFee {
+			return nil, &BuilderFeeExceededError{Builder: builder.B, Fee: builder.F, MaxFee: maxFee}
+		}
+	}
+
+	if !e.skipOrderValidation {
+		for _, order := range orderRequests {
+			if err := e.ValidateOrder(order); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	cloids := make([]*string, len(orderRequests))
+	for i, order := range orderRequests {
+		if order.Cloid != nil {
+			if _, err := utils.NewCloid(*order.Cloid); err != nil {
+				return nil, fmt.Errorf("invalid cloid %q for order on %s: %w", *order.Cloid, order.Coin, err)
+			}
+			cloids[i] = order.Cloid
+		} else if e.autoCloid {
+			cloid, err := utils.RandomCloid()
+			if err != nil {
+				return nil, err
+			}
+			raw := cloid.ToRaw()
+			cloids[i] = &raw
+		}
+	}
+
+	requests := make([]utils.OrderRequest, len(orderRequests))
+	orderWires := make([]utils.OrderWire, len(orderRequests))
+	
+	for i, order := range orderRequests {
+		order.Cloid = cloids[i]
+		requests[i] = order
+		asset, err := e.info.NameToAsset(order.Coin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset for coin %s: %w", order.Coin, err)
+		}
+		
+		pxDecimals, szDecimals := e.wireDecimals(asset)
+		orderWire, err := utils.OrderRequestToOrderWireDecimals(order, asset, pxDecimals, szDecimals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert order to wire format: %w", err)
+		}
+		orderWires[i] = *orderWire
+	}
+	
+	batches := e.batches(len(orderWires), grouping == utils.GroupingNA)
+	responses := make([]*utils.OrderResponse, len(batches))
+	errs := e.runBatches(len(batches), func(i int) error {
+		var err error
+		responses[i], err = e.postOrders(ctx, orderWires[batches[i].start:batches[i].end], builder, grouping, post)
+		return err
+	})
+	if len(batches) == 1 && errs[0] != nil {
+		return nil, errs[0]
+	}
+	
+	response := utils.OrderResponse{Status: "ok", Response: utils.OrderResponseBody{Type: "order"}}
+	if len(batches) == 1 {
+		response = *responses[0]
+	}
+	response.Requests = requests
+	response.Cloids = cloids
+	
+	// Merge the batches in input order; the orders of a failed batch get its
+	// error as their status
+	var batchErr error
+	raws := make([]json.RawMessage, len(batches))
+	statuses := make([]utils.OrderStatus, 0, len(orderWires))
+	for i, batch := range batches {
+		if errs[i] != nil {
+			if batchErr == nil {
+				batchErr = fmt.Errorf("order batch %d of %d failed: %w", i+1, len(batches), errs[i])
+			}
+			for j := batch.start; j < batch.end; j++ {
+				statuses = append(statuses, utils.OrderStatus{Error: errs[i].Error()})
+			}
+			continue
+		}
+		raws[i] = responses[i].Raw
+		statuses = append(statuses, responses[i].Response.Data.Statuses...)
+	}
+	if len(batches) > 1 {
+		response.Response.Data.Statuses = statuses
+		response.Raw, _ = json.Marshal(raws)
+	}
+	if batchErr != nil {
+		return &response, batchErr
+	}
+	
+	var failed []utils.OrderError
+	for i, status := range response.Response.Data.Statuses {
+		if status.Error != "" {
+			failed = append(failed, utils.OrderError{Index: i, Message: status.Error})
+		}
+	}
+	if len(failed) > 0 {
+		return &response, &utils.PartialFailureError{Total: len(response.Response.Data.Statuses), Failed: failed}
+	}
+	return &response, nil
+}
+
+// postOrders signs one order action and submits it with post
+func (e *Exchange) postOrders(ctx context.Context, orderWires []utils.OrderWire, builder *BuilderInfo, grouping utils.Grouping,
+	post func(ctx context.Context, action interface{}, signature *utils.Signature, nonce int64, out interface{}) (json.RawMessage, error)) (*utils.OrderResponse, error) {
+	timestamp := e.nonces.Next()
+	
+	orderAction := utils.OrderWiresToOrderAction(orderWires, builder, grouping)
+	
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	
+	signature, err := utils.SignL1ActionWithConfig(e.signer, orderAction, e.vault(), uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign order action: %w", err)
+	}
+	
+	var response utils.OrderResponse
+	if response.Raw, err = post(ctx, orderAction, signature, timestamp, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// OrderWithTpSl places a limit entry order together with a reduce-only take
+// profit and/or stop loss in one normalTpsl grouped action. The triggers close
+// the full entry size at market once their trigger price is reached. It
+// returns the status of every leg, entry first.
+func (e *Exchange) OrderWithTpSl(name string, isBuy bool, sz float64, limitPx float64, tp *float64, sl *float64, cloid *string) ([]utils.OrderStatus, error) {
+	return e.OrderWithTpSlCtx(context.Background(), name, isBuy, sz, limitPx, tp, sl, cloid)
+}
+
+// OrderWithTpSlCtx is like OrderWithTpSl but carries ctx to the request
+func (e *Exchange) OrderWithTpSlCtx(ctx context.Context, name string, isBuy bool, sz float64, limitPx float64, tp *float64, sl *float64, cloid *string) ([]utils.OrderStatus, error) {
+	if tp == nil && sl == nil {
+		return nil, fmt.Errorf("at least one of take profit or stop loss is required")
+	}
+	coin, _, exists := e.info.coinForName(name)
+	if !exists {
+		return nil, fmt.Errorf("coin not found for name: %s", name)
+	}
+
+	entryPx, err := e.roundPrice(coin, limitPx)
+	if err != nil {
+		return nil, err
+	}
+	orderRequests := []utils.OrderRequest{{
+		Coin:      name,
+		IsBuy:     isBuy,
+		Sz:        sz,
+		LimitPx:   entryPx,
+		OrderType: utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}},
+		Cloid:     cloid,
+	}}
+
+	for _, leg := range []struct {
+		triggerPx *float64
+		tpsl      utils.TPSL
+	}{{tp, utils.TPSLTp}, {sl, utils.TPSLSl}} {
+		if leg.triggerPx == nil {
+			continue
+		}
+		triggerPx, err := e.roundPrice(coin, *leg.triggerPx)
+		if err != nil {
+			return nil, err
+		}
+		orderRequests = append(orderRequests, utils.OrderRequest{
+			Coin:    name,
+			IsBuy:   !isBuy,
+			Sz:      sz,
+			LimitPx: triggerPx,
+			OrderType: utils.OrderType{Trigger: &utils.TriggerOrderType{
+				TriggerPx: triggerPx,
+				IsMarket:  true,
+				TPSL:      leg.tpsl,
+			}},
+			ReduceOnly: true,
+		})
+	}
+
+	response, err := e.BulkOrdersWithGroupingCtx(ctx, orderRequests, nil, utils.GroupingNormalTpsl)
+	if response == nil {
+		return nil, err
+	}
+	return response.Response.Data.Statuses, err
+}
+
+// validateGrouping rejects order combinations the exchange won't accept for a grouping
+func validateGrouping(orderRequests []utils.OrderRequest, grouping utils.Grouping) error {
+	var triggers []utils.OrderRequest
+	switch grouping {
+	case utils.GroupingNA:
+		return nil
+	case utils.GroupingNormalTpsl:
+		if len(orderRequests) < 2 {
+			return fmt.Errorf("normalTpsl grouping requires an entry order and at least one trigger order")
+		}
+		entry := orderRequests[0]
+		if entry.OrderType.Trigger != nil {
+			return fmt.Errorf("normalTpsl grouping requires the first order to be the entry, not a trigger")
+		}
+		triggers = orderRequests[1:]
+		for _, trigger := range triggers {
+			if trigger.Coin != entry.Coin || trigger.IsBuy == entry.IsBuy {
+				return fmt.Errorf("normalTpsl trigger orders must close the entry on %s", entry.Coin)
+			}
+		}
+	case utils.GroupingPositionTpsl:
+		if len(orderRequests) == 0 {
+			return fmt.Errorf("positionTpsl grouping requires at least one trigger order")
+		}
+		triggers = orderRequests
+	default:
+		return fmt.Errorf("unknown order grouping: %s", grouping)
+	}
+
+	seen := make(map[utils.TPSL]bool)
+	for _, trigger := range triggers {
+		if trigger.OrderType.Trigger == nil {
+			return fmt.Errorf("%s grouping only accepts trigger orders for take profit and stop loss", grouping)
+		}
+		if !trigger.ReduceOnly {
+			return fmt.Errorf("%s trigger orders must be reduce-only", grouping)
+		}
+		tpsl := trigger.OrderType.Trigger.TPSL
+		if tpsl != utils.TPSLTp && tpsl != utils.TPSLSl {
+			return fmt.Errorf("invalid tpsl for trigger order: %q", tpsl)
+		}
+		if seen[tpsl] {
+			return fmt.Errorf("%s grouping accepts at most one %s order", grouping, tpsl)
+		}
+		seen[tpsl] = true
+	}
+	return nil
+}
+
+// MarketOpen places a market order to open a position. Slippage is applied
+// to px if given, otherwise to the reference price chosen by opts; the
+// response records the reference price used.
+func (e *Exchange) MarketOpen(name string, isBuy bool, sz float64, px *float64, slippage float64, cloid *string, builder *BuilderInfo, opts ...MarketOptions) (*utils.OrderResponse, error) {
+	return e.MarketOpenCtx(context.Background(), name, isBuy, sz, px, slippage, cloid, builder, opts...)
+}
+
+// MarketOpenCtx is like MarketOpen but carries ctx to the request
+func (e *Exchange) MarketOpenCtx(ctx context.Context, name string, isBuy bool, sz float64, px *float64, slippage float64, cloid *string, builder *BuilderInfo, opts ...MarketOptions) (*utils.OrderResponse, error) {
+	if slippage == 0 {
+		slippage = DefaultSlippage
+	}
+	
+	// Get aggressive market price
+	price, reference, refPx, err := e.slippagePrice(ctx, name, isBuy, slippage, px, marketOptions(opts))
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate slippage price: %w", err)
+	}
+	
+	if e.impactCheck {
+		estimate, err := e.info.SimulateMarketOrder(ctx, name, isBuy, sz)
+		if err != nil {
+			return nil, fmt.Errorf("failed to simulate market order: %w", err)
+		}
+		if estimate.RemainingSz > 0 || (isBuy && estimate.WorstPx > price) || (!isBuy && estimate.WorstPx < price) {
+			return nil, &ImpactExceededError{Coin: name, LimitPx: price, Estimate: *estimate}
+		}
+	}
+	
+	// Market order is an aggressive limit order IoC
+	orderType := utils.OrderType{
+		Limit: &utils.LimitOrderType{
+			TIF: utils.TIFIoc,
+		},
+	}
+	
+	response, err := e.OrderCtx(ctx, name, isBuy, sz, price, orderType, false, cloid, builder)
+	return withReference(response, reference, refPx), err
+}
+
+// withReference records the reference price of a market order on its
+// response
+func withReference(response *utils.OrderResponse, reference utils.PriceReference, refPx float64) *utils.OrderResponse {
+	if response != nil {
+		response.Reference = reference
+		response.ReferencePx = refPx
+	}
+	return response
+}
+
+// MarketClose places a market order to close a position; see MarketOpen for
+// how it is priced
+func (e *Exchange) MarketClose(coin string, sz *float64, px *float64, slippage float64, cloid *string, builder *BuilderInfo, opts ...MarketOptions) (*utils.OrderResponse, error) {
+	return e.MarketCloseCtx(context.Background(), coin, sz, px, slippage, cloid, builder, opts...)
+}
+
+// MarketCloseCtx is like MarketClose but carries ctx to the request
+func (e *Exchange) MarketCloseCtx(ctx context.Context, coin string, sz *float64, px *float64, slippage float64, cloid *string, builder *BuilderInfo, opts ...MarketOptions) (*utils.OrderResponse, error) {
+	if slippage == 0 {
+		slippage = DefaultSlippage
+	}
+	
+	state, err := e.info.ClearinghouseStateCtx(ctx, e.userAddress(), perpDexOf(coin))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user state: %w", err)
+	}
+
+	for _, assetPosition := range state.AssetPositions {
+		position := assetPosition.Position
+		if position.Coin != coin {
+			continue
+		}
+		szi, err := position.Szi.Float()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse szi: %w", err)
+		}
+
+		size := math.Abs(szi)
+		if sz != nil {
+			size = *sz
+		} else if percent := marketOptions(opts).ClosePercent; percent != 0 {
+			if size, err = e.closeSize(coin, szi, percent); err != nil {
+				return nil, err
+			}
+		}
+
+		isBuy := szi < 0
+
+		// Get aggressive market price
+		price, reference, refPx, err := e.slippagePrice(ctx, coin, isBuy, slippage, px, marketOptions(opts))
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate slippage price: %w", err)
+		}
+
+		// Market order is an aggressive limit order IoC
+		orderType := utils.OrderType{
+			Limit: &utils.LimitOrderType{
+				TIF: utils.TIFIoc,
+			},
+		}
+
+		response, err := e.OrderCtx(ctx, coin, isBuy, size, price, orderType, true, cloid, builder)
+		return withReference(response, reference, refPx), err
+	}
+
+	return nil, fmt.Errorf("position not found for coin: %s", coin)
+}
+
+// closeSize returns the size closing percent of a position, rounded to the
+// coin's size decimals. A remainder too small to trade is closed as well.
+func (e *Exchange) closeSize(coin string, szi float64, percent float64) (float64, error) {
+	if percent < 0 || percent > 100 {
+		return 0, fmt.Errorf("close percent must be between 0 and 100: %g", percent)
+	}
+	szDecimals, err := e.info.SzDecimals(coin)
+	if err != nil {
+		return 0, err
+	}
+	position := math.Abs(szi)
+	size := utils.RoundSz(position*percent/100, szDecimals)
+	if utils.RoundSz(position-size, szDecimals) == 0 {
+		return position, nil
+	}
+	if size == 0 {
+		return 0, fmt.Errorf("closing %g%% of %g %s rounds to zero", percent, position, coin)
+	}
+	return size, nil
+}
+
+// ClosedPosition is the order CloseAllPositions sent to close one position
+type ClosedPosition struct {
+	Coin    string
+	Sz      float64
+	IsBuy   bool
+	LimitPx float64
+	Status  utils.OrderStatus
+}
+
+// CloseAllPositions closes every open position on the default perp dex with
+// reduce-only IoC orders sent in one BulkOrders call, priced like MarketClose.
+// It returns the order for every position, with the status the exchange gave
+// it; when some orders fail the error is a *utils.PartialFailureError.
+func (e *Exchange) CloseAllPositions(slippage float64) ([]ClosedPosition, error) {
+	return e.CloseAllPositionsCtx(context.Background(), slippage)
+}
+
+// CloseAllPositionsCtx is like CloseAllPositions but carries ctx to the request
+func (e *Exchange) CloseAllPositionsCtx(ctx context.Context, slippage float64) ([]ClosedPosition, error) {
+	if slippage == 0 {
+		slippage = DefaultSlippage
+	}
+
+	state, err := e.info.ClearinghouseStateCtx(ctx, e.userAddress(), "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user state: %w", err)
+	}
+
+	var closed []ClosedPosition
+	var orderRequests []utils.OrderRequest
+	for _, assetPosition := range state.AssetPositions {
+		position := assetPosition.Position
+		szi, err := position.Szi.Float()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse szi of %s: %w", position.Coin, err)
+		}
+		if szi == 0 {
+			continue
+		}
+
+		isBuy := szi < 0
+		price, _, _, err := e.slippagePrice(ctx, position.Coin, isBuy, slippage, nil, MarketOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to calculate slippage price for %s: %w", position.Coin, err)
+		}
+		closed = append(closed, ClosedPosition{Coin: position.Coin, Sz: math.Abs(szi), IsBuy: isBuy, LimitPx: price})
+		orderRequests = append(orderRequests, utils.OrderRequest{
+			Coin:       position.Coin,
+			IsBuy:      isBuy,
+			Sz:         math.Abs(szi),
+			LimitPx:    price,
+			OrderType:  utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFIoc}},
+			ReduceOnly: true,
+		})
+	}
+	if len(orderRequests) == 0 {
+		return nil, nil
+	}
+
+	response, err := e.BulkOrdersCtx(ctx, orderRequests, nil)
+	if response == nil {
+		return nil, err
+	}
+	for i, status := range response.Response.Data.Statuses {
+		if i < len(closed) {
+			closed[i].Status = status
+		}
+	}
+	return closed, err
+}
+
+// ErrOrderGone is returned by Reprice when the order was filled or canceled
+// before it could be modified
+var ErrOrderGone = errors.New("order was filled or canceled")
+
+// ModifyOrder replaces the resting order oid, an int oid or a cloid, with a
+// new order. The order keeps its oid; it loses its queue position when its
+// price changes or its size grows.
+func (e *Exchange) ModifyOrder(oid interface{}, name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string) (*utils.OrderResponse, error) {
+	return e.ModifyOrderCtx(context.Background(), oid, name, isBuy, sz, limitPx, orderType, reduceOnly, cloid)
+}
+
+// ModifyOrderCtx is like ModifyOrder but carries ctx to the request
+func (e *Exchange) ModifyOrderCtx(ctx context.Context, oid interface{}, name string, isBuy bool, sz float64, limitPx float64, orderType utils.OrderType, reduceOnly bool, cloid *string) (*utils.OrderResponse, error) {
+	modifyRequest := utils.ModifyRequest{
+		OID: oid,
+		Order: utils.OrderRequest{
+			Coin:       name,
+			IsBuy:      isBuy,
+			Sz:         sz,
+			LimitPx:    limitPx,
+			OrderType:  orderType,
+			ReduceOnly: reduceOnly,
+			Cloid:      cloid,
+		},
+	}
+	return e.BulkModifyOrdersCtx(ctx, []utils.ModifyRequest{modifyRequest})
+}
+
+// BulkModifyOrders modifies multiple resting orders in a single batchModify
+// action. Orders are checked like those of BulkOrders before signing.
+func (e *Exchange) BulkModifyOrders(modifyRequests []utils.ModifyRequest) (*utils.OrderResponse, error) {
+	return e.BulkModifyOrdersCtx(context.Background(), modifyRequests)
+}
+
+// BulkModifyOrdersCtx is like BulkModifyOrders but carries ctx to the request
+func (e *Exchange) BulkModifyOrdersCtx(ctx context.Context, modifyRequests []utils.ModifyRequest) (*utils.OrderResponse, error) {
+	requests := make([]utils.OrderRequest, len(modifyRequests))
+	cloids := make([]*string, len(modifyRequests))
+	modifies := make([]utils.ModifyWire, len(modifyRequests))
+	
+	for i, modify := range modifyRequests {
+		oid, err := modifyOid(modify.OID)
+		if err != nil {
+			return nil, err
+		}
+		order := modify.Order
+		if !e.skipOrderValidation {
+			if err := e.ValidateOrder(order); err != nil {
+				return nil, err
+			}
+		}
+		if order.Cloid != nil {
+			if _, err := utils.NewCloid(*order.Cloid); err != nil {
+				return nil, fmt.Errorf("invalid cloid %q for order on %s: %w", *order.Cloid, order.Coin, err)
+			}
+		}
+		
+		asset, err := e.info.NameToAsset(order.Coin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset for coin %s: %w", order.Coin, err)
+		}
+		pxDecimals, szDecimals := e.wireDecimals(asset)
+		orderWire, err := utils.OrderRequestToOrderWireDecimals(order, asset, pxDecimals, szDecimals)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert order to wire format: %w", err)
+		}
+		modifies[i] = utils.ModifyWire{OID: oid, Order: *orderWire}
+		requests[i] = order
+		cloids[i] = order.Cloid
+	}
+	
+	timestamp := e.nonces.Next()
+	modifyAction := utils.BatchModifyAction{
+		Type:     "batchModify",
+		Modifies: modifies,
+	}
+	
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	
+	signature, err := utils.SignL1ActionWithConfig(e.signer, modifyAction, e.vault(), uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign modify action: %w", err)
+	}
+	
+	response := utils.OrderResponse{Requests: requests, Cloids: cloids}
+	if response.Raw, err = e.postActionInto(ctx, modifyAction, signature, timestamp, &response); err != nil {
+		return nil, err
+	}
+	
+	var failed []utils.OrderError
+	for i, status := range response.Response.Data.Statuses {
+		if status.Error != "" {
+			failed = append(failed, utils.OrderError{Index: i, Message: status.Error})
+		}
+	}
+	if len(failed) > 0 {
+		return &response, &utils.PartialFailureError{Total: len(response.Response.Data.Statuses), Failed: failed}
+	}
+	return &response, nil
+}
+
+// modifyOid returns the wire form of the order a modify targets: an int oid
+// or the raw form of a cloid
+func modifyOid(oid interface{}) (interface{}, error) {
+	switch oid := oid.(type) {
+	case int:
+		return oid, nil
+	case int64:
+		return oid, nil
+	case string:
+		cloid, err := utils.NewCloid(oid)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cloid %q: %w", oid, err)
+		}
+		return cloid.ToRaw(), nil
+	case *utils.Cloid:
+		return oid.ToRaw(), nil
+	}
+	return nil, fmt.Errorf("invalid oid %v: must be an int oid or a cloid", oid)
+}
+
+// Reprice moves the resting order oid on coin, an int oid or a cloid, to
+// newPx rounded to a valid price, and to newSz unless it is zero. The order
+// is looked up with Info.OrderStatus and modified in place, keeping its
+// side, reduce-only flag, cloid, time in force and trigger, so unlike a
+// cancel followed by a new order there is never a moment with both or
+// neither resting. It returns ErrOrderGone when the order was filled or
+// canceled first.
+func (e *Exchange) Reprice(coin string, oid interface{}, newPx, newSz float64) (*utils.OrderResponse, error) {
+	return e.RepriceCtx(context.Background(), coin, oid, newPx, newSz)
+}
+
+// RepriceCtx is like Reprice but carries ctx to the request
+func (e *Exchange) RepriceCtx(ctx context.Context, coin string, oid interface{}, newPx, newSz float64) (*utils.OrderResponse, error) {
+	name, _, exists := e.info.coinForName(coin)
+	if !exists {
+		return nil, fmt.Errorf("coin not found for name: %s", coin)
+	}
+	id, err := modifyOid(oid)
+	if err != nil {
+		return nil, err
+	}
+	
+	entry, err := e.info.OrderStatusCtx(ctx, e.userAddress(), id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query order %v: %w", oid, err)
+	}
+	if entry == nil || entry.Status != "open" {
+		return nil, ErrOrderGone
+	}
+	if entry.Order.Coin != name {
+		return nil, fmt.Errorf("order %v is on %s, not %s", oid, entry.Order.Coin, coin)
+	}
+	
+	order, err := repricedOrder(entry.Order, coin, newSz)
+	if err != nil {
+		return nil, err
+	}
+	if order.LimitPx, err = e.roundPrice(name, newPx); err != nil {
+		return nil, err
+	}
+	
+	response, err := e.BulkModifyOrdersCtx(ctx, []utils.ModifyRequest{{OID: id, Order: order}})
+	if response != nil && len(response.Response.Data.Statuses) > 0 {
+		message := response.Response.Data.Statuses[0].Error
+		if errors.Is(utils.ClassifyMessage(message), utils.ErrOrderNotFound) {
+			return response, ErrOrderGone
+		}
+	}
+	return response, err
+}
+
+// repricedOrder returns the request recreating a resting order on coin with
+// size sz, or its current size when sz is zero; the caller sets the price
+func repricedOrder(order utils.HistoricalOrderInfo, coin string, sz float64) (utils.OrderRequest, error) {
+	if sz == 0 {
+		current, err := strconv.ParseFloat(order.Sz, 64)
+		if err != nil {
+			return utils.OrderRequest{}, fmt.Errorf("invalid size %q of order %d: %w", order.Sz, order.Oid, err)
+		}
+		sz = current
+	}
+	request := utils.OrderRequest{
+		Coin:       coin,
+		IsBuy:      order.Side == utils.SideBid,
+		Sz:         sz,
+		ReduceOnly: order.ReduceOnly,
+		Cloid:      order.Cloid,
+	}
+	
+	if !order.IsTrigger {
+		tif := utils.TIFGtc
+		if order.Tif != nil && *order.Tif != "" {
+			tif = utils.TIF(*order.Tif)
+		}
+		request.OrderType = utils.OrderType{Limit: &utils.LimitOrderType{TIF: tif}}
+		return request, nil
+	}
+	
+	triggerPx, err := strconv.ParseFloat(order.TriggerPx, 64)
+	if err != nil {
+		return utils.OrderRequest{}, fmt.Errorf("invalid trigger price %q of order %d: %w", order.TriggerPx, order.Oid, err)
+	}
+	tpsl := utils.TPSLSl
+	if strings.HasPrefix(order.OrderType, "Take Profit") {
+		tpsl = utils.TPSLTp
+	}
+	request.OrderType = utils.OrderType{Trigger: &utils.TriggerOrderType{
+		TriggerPx: triggerPx,
+		IsMarket:  strings.HasSuffix(order.OrderType, "Market"),
+		TPSL:      tpsl,
+	}}
+	return request, nil
+}
+
+// Cancel cancels a single order
+func (e *Exchange) Cancel(name string, oid int) (*utils.CancelResponse, error) {
+	return e.CancelCtx(context.Background(), name, oid)
+}
+
+// CancelCtx is like Cancel but carries ctx to the request
+func (e *Exchange) CancelCtx(ctx context.Context, name string, oid int) (*utils.CancelResponse, error) {
+	cancelRequest := utils.CancelRequest{
+		Coin: name,
+		OID:  oid,
+	}
+	return e.BulkCancelCtx(ctx, []utils.CancelRequest{cancelRequest})
+}
+
+// BulkCancel cancels multiple orders, in one action per batch when there
+// are more than the batch size, like BulkOrders
+func (e *Exchange) BulkCancel(cancelRequests []utils.CancelRequest) (*utils.CancelResponse, error) {
+	return e.BulkCancelCtx(context.Background(), cancelRequests)
+}
+
+// BulkCancelCtx is like BulkCancel but carries ctx to the request
+func (e *Exchange) BulkCancelCtx(ctx context.Context, cancelRequests []utils.CancelRequest) (*utils.CancelResponse, error) {
+	cancels := make([]utils.CancelWire, len(cancelRequests))
+	
+	for i, cancel := range cancelRequests {
+		asset, err := e.info.NameToAsset(cancel.Coin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get asset for coin %s: %w", cancel.Coin, err)
+		}
+		
+		cancels[i] = utils.CancelWire{
+			A: asset,
+			O: cancel.OID,
+		}
+	}
+	
+	batches := e.batches(len(cancels), true)
+	responses := make([]*utils.CancelResponse, len(batches))
+	errs := e.runBatches(len(batches), func(i int) error {
+		var err error
+		responses[i], err = e.postCancels(ctx, cancels[batches[i].start:batches[i].end])
+		return err
+	})
+	if len(batches) == 1 && errs[0] != nil {
+		return nil, errs[0]
+	}
+	
+	response := utils.CancelResponse{Status: "ok", Response: utils.CancelResponseBody{Type: "cancel"}}
+	if len(batches) == 1 {
+		response = *responses[0]
+	}
+	
+	var batchErr error
+	raws := make([]json.RawMessage, len(batches))
+	statuses := make([]utils.CancelStatus, 0, len(cancels))
+	for i, batch := range batches {
+		if errs[i] != nil {
+			if batchErr == nil {
+				batchErr = fmt.Errorf("cancel batch %d of %d failed: %w", i+1, len(batches), errs[i])
+			}
+			for j := batch.start; j < batch.end; j++ {
+				statuses = append(statuses, utils.CancelStatus{Error: errs[i].Error()})
+			}
+			continue
+		}
+		raws[i] = responses[i].Raw
+		statuses = append(statuses, responses[i].Response.Data.Statuses...)
+	}
+	if len(batches) > 1 {
+		response.Response.Data.Statuses = statuses
+		response.Raw, _ = json.Marshal(raws)
+	}
+	if batchErr != nil {
+		return &response, batchErr
+	}
+	
+	var failed []utils.OrderError
+	for i, status := range response.Response.Data.Statuses {
+		if status.Error != "" {
+			failed = append(failed, utils.OrderError{Index: i, Message: status.Error})
+		}
+	}
+	if len(failed) > 0 {
+		return &response, &utils.PartialFailureError{Total: len(response.Response.Data.Statuses), Failed: failed}
+	}
+	return &response, nil
+}
+
+// postCancels signs one cancel action and posts it
+func (e *Exchange) postCancels(ctx context.Context, cancels []utils.CancelWire) (*utils.CancelResponse, error) {
+	timestamp := e.nonces.Next()
+	cancelAction := utils.CancelAction{
+		Type:    "cancel",
+		Cancels: cancels,
+	}
+	
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	
+	signature, err := utils.SignL1ActionWithConfig(e.signer, cancelAction, e.vault(), uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign cancel action: %w", err)
+	}
+	
+	var response utils.CancelResponse
+	if response.Raw, err = e.postActionInto(ctx, cancelAction, signature, timestamp, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// CancelAllResult is the outcome of CancelAllOrders: every order found open,
+// with the status of its cancel
+type CancelAllResult struct {
+	Orders   []utils.OrderInfo
+	Statuses []utils.CancelStatus
+}
+
+// CancelAllOrders cancels every open order of the user the exchange trades
+// for, only those on coin when it is not nil. Without a coin the orders on
+// every perp dex of the Info are cancelled. Orders are cancelled with
+// BulkCancel, in batches of the batch size; orders filled or cancelled since
+// they were queried count as cancelled. When some cancels fail the error is
+// a *utils.PartialFailureError indexing into the result.
+func (e *Exchange) CancelAllOrders(coin *string) (*CancelAllResult, error) {
+	return e.CancelAllOrdersCtx(context.Background(), coin)
+}
+
+// CancelAllOrdersCtx is like CancelAllOrders but carries ctx to the request
+func (e *Exchange) CancelAllOrdersCtx(ctx context.Context, coin *string) (*CancelAllResult, error) {
+	dexs := e.info.perpDexs
+	var onlyCoin string
+	if coin != nil {
+		resolved, _, exists := e.info.coinForName(*coin)
+		if !exists {
+			return nil, fmt.Errorf("coin not found for name: %s", *coin)
+		}
+		onlyCoin = resolved
+		dexs = []string{perpDexOf(resolved)}
+	}
+
+	result := &CancelAllResult{}
+	for _, dex := range dexs {
+		orders, err := e.info.UserOpenOrdersCtx(ctx, e.userAddress(), dex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get open orders: %w", err)
+		}
+		for _, order := range orders {
+			if onlyCoin == "" || order.Coin == onlyCoin {
+				result.Orders = append(result.Orders, order)
+			}
+		}
+	}
+
+	if len(result.Orders) == 0 {
+		return result, nil
+	}
+	cancelRequests := make([]utils.CancelRequest, len(result.Orders))
+	for i, order := range result.Orders {
+		cancelRequests[i] = utils.CancelRequest{Coin: order.Coin, OID: order.Oid}
+	}
+	response, err := e.BulkCancelCtx(ctx, cancelRequests)
+	if response == nil {
+		return result, err
+	}
+
+	var failed []utils.OrderError
+	for i, status := range response.Response.Data.Statuses {
+		if errors.Is(utils.ClassifyMessage(status.Error), utils.ErrOrderNotFound) {
+			status = utils.CancelStatus{Success: true}
+		} else if !status.Success {
+			failed = append(failed, utils.OrderError{Index: i, Message: status.Error})
+		}
+		result.Statuses = append(result.Statuses, status)
+	}
+	if len(failed) > 0 {
+		return result, &utils.PartialFailureError{Total: len(result.Statuses), Failed: failed}
+	}
+	return result, nil
+}
+
+// TwapOrder places a TWAP order that executes sz over the given number of
+// minutes, optionally randomizing the slice timing. The twapId of a running
+// order is in the response status.
+func (e *Exchange) TwapOrder(name string, isBuy bool, sz float64, reduceOnly bool, minutes int, randomize bool) (*utils.TwapOrderResponse, error) {
+	return e.TwapOrderCtx(context.Background(), name, isBuy, sz, reduceOnly, minutes, randomize)
+}
+
+// TwapOrderCtx is like TwapOrder but carries ctx to the request
+func (e *Exchange) TwapOrderCtx(ctx context.Context, name string, isBuy bool, sz float64, reduceOnly bool, minutes int, randomize bool) (*utils.TwapOrderResponse, error) {
+	asset, err := e.info.NameToAsset(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset for coin %s: %w", name, err)
+	}
+	if minutes <= 0 {
+		return nil, fmt.Errorf("twap duration must be positive: %d minutes", minutes)
+	}
+	_, szDecimals := e.wireDecimals(asset)
+	szWire, err := utils.FloatToWireDecimals(sz, szDecimals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert size to wire format: %w", err)
+	}
+
+	action := utils.TwapOrderAction{
+		Type: "twapOrder",
+		Twap: utils.TwapWire{
+			A: asset,
+			B: isBuy,
+			S: szWire,
+			R: reduceOnly,
+			M: minutes,
+			T: randomize,
+		},
+	}
+	var response utils.TwapOrderResponse
+	if response.Raw, err = e.postL1ActionInto(ctx, action, e.vault(), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// TwapCancel cancels a running TWAP order by the twapId returned from TwapOrder
+func (e *Exchange) TwapCancel(name string, twapID int) (*utils.TwapCancelResponse, error) {
+	return e.TwapCancelCtx(context.Background(), name, twapID)
+}
+
+// TwapCancelCtx is like TwapCancel but carries ctx to the request
+func (e *Exchange) TwapCancelCtx(ctx context.Context, name string, twapID int) (*utils.TwapCancelResponse, error) {
+	asset, err := e.info.NameToAsset(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset for coin %s: %w", name, err)
+	}
+
+	action := utils.TwapCancelAction{
+		Type: "twapCancel",
+		A:    asset,
+		T:    twapID,
+	}
+	var response utils.TwapCancelResponse
+	if response.Raw, err = e.postL1ActionInto(ctx, action, e.vault(), &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// UpdateLeverage updates leverage for a specific asset
+func (e *Exchange) UpdateLeverage(leverage int, name string, isCross bool) (interface{}, error) {
+	return e.UpdateLeverageCtx(context.Background(), leverage, name, isCross)
+}
+
+// UpdateLeverageCtx is like UpdateLeverage but carries ctx to the request
+func (e *Exchange) UpdateLeverageCtx(ctx context.Context, leverage int, name string, isCross bool) (interface{}, error) {
+	asset, err := e.info.NameToAsset(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset for name %s: %w", name, err)
+	}
+	if assetInfo, err := e.info.AssetMeta(name); err == nil {
+		if assetInfo.MaxLeverage > 0 && leverage > assetInfo.MaxLeverage {
+			return nil, fmt.Errorf("leverage %d exceeds the max leverage of %d for %s", leverage, assetInfo.MaxLeverage, name)
+		}
+		if isCross && assetInfo.OnlyIsolated {
+			return nil, fmt.Errorf("%s only supports isolated margin", name)
+		}
+	}
+	
+	timestamp := e.nonces.Next()
+	
+	updateAction := utils.UpdateLeverageAction{
+		Type:     "updateLeverage",
+		Asset:    asset,
+		IsCross:  isCross,
+		Leverage: leverage,
+	}
+	
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	
+	expiresAfterUint, err := e.signedExpiresAfter(timestamp)
+	if err != nil {
+		return nil, err
+	}
+	
+	signature, err := utils.SignL1ActionWithConfig(e.signer, updateAction, e.vault(), uint64(timestamp), expiresAfterUint, isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign update leverage action: %w", err)
+	}
+	
+	return e.postAction(ctx, updateAction, signature, timestamp)
+}
+
+// UsdClassTransfer transfers USD between perp and spot, towards perp when
+// toPerp is set. The transfer is made by the signing user; pass subAccount to
+// make it on one of their sub-accounts instead. The vault address set on the
+// exchange is not used, since the action never carries it.
+func (e *Exchange) UsdClassTransfer(amount float64, toPerp bool, subAccount *string) (interface{}, error) {
+	return e.UsdClassTransferCtx(context.Background(), amount, toPerp, subAccount)
+}
+
+// UsdClassTransferCtx is like UsdClassTransfer but carries ctx to the request
+func (e *Exchange) UsdClassTransferCtx(ctx context.Context, amount float64, toPerp bool, subAccount *string) (interface{}, error) {
+	strAmount := fmt.Sprintf("%.6f", amount)
+	if subAccount != nil {
+		address, err := utils.NormalizeAddress(*subAccount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sub-account address: %w", err)
+		}
+		strAmount += " subaccount:" + address
+	}
+	
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"type":   "usdClassTransfer",
+		"amount": strAmount,
+		"toPerp": toPerp,
+		"nonce":  timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.USDClassTransferSignTypes, "HyperliquidTransaction:UsdClassTransfer")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign USD class transfer action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// PerpDexClassTransfer moves collateral token between the spot balance and the
+// builder-deployed perp dex, towards the perp dex when toPerp is set
+func (e *Exchange) PerpDexClassTransfer(dex string, token string, amount float64, toPerp bool) (interface{}, error) {
+	return e.PerpDexClassTransferCtx(context.Background(), dex, token, amount, toPerp)
+}
+
+// PerpDexClassTransferCtx is like PerpDexClassTransfer but carries ctx to the request
+func (e *Exchange) PerpDexClassTransferCtx(ctx context.Context, dex string, token string, amount float64, toPerp bool) (interface{}, error) {
+	strAmount, err := utils.FloatToWire(amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert amount to wire format: %w", err)
+	}
+
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"type":   "PerpDexClassTransfer",
+		"dex":    dex,
+		"token":  token,
+		"amount": strAmount,
+		"toPerp": toPerp,
+		"nonce":  timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.PerpDexClassTransferSignTypes, "HyperliquidTransaction:PerpDexClassTransfer")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign perp dex class transfer action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// UsdTransfer transfers USD to another address
+func (e *Exchange) UsdTransfer(amount float64, destination string) (interface{}, error) {
+	return e.UsdTransferCtx(context.Background(), amount, destination)
+}
+
+// UsdTransferCtx is like UsdTransfer but carries ctx to the request
+func (e *Exchange) UsdTransferCtx(ctx context.Context, amount float64, destination string) (interface{}, error) {
+	destination, err := utils.NormalizeAddress(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination address: %w", err)
+	}
+	if err := e.checkDestination(ctx, destination); err != nil {
+		return nil, err
+	}
+
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"destination": destination,
+		"amount":      fmt.Sprintf("%.6f", amount),
+		"time":        timestamp,
+		"type":        "usdSend",
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.USDSendSignTypes, "HyperliquidTransaction:UsdSend")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign USD transfer action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// SendAsset transfers a token between dexs, spot and sub-accounts.
+// An empty sourceDex or destinationDex refers to the default perp dex and "spot" to the spot balance.
+func (e *Exchange) SendAsset(destination string, sourceDex string, destinationDex string, token string, amount string, fromSubAccount string) (interface{}, error) {
+	return e.SendAssetCtx(context.Background(), destination, sourceDex, destinationDex, token, amount, fromSubAccount)
+}
+
+// SendAssetCtx is like SendAsset but carries ctx to the request
+func (e *Exchange) SendAssetCtx(ctx context.Context, destination string, sourceDex string, destinationDex string, token string, amount string, fromSubAccount string) (interface{}, error) {
+	destination, err := utils.NormalizeAddress(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination address: %w", err)
+	}
+	if fromSubAccount != "" {
+		if fromSubAccount, err = utils.NormalizeAddress(fromSubAccount); err != nil {
+			return nil, fmt.Errorf("invalid fromSubAccount address: %w", err)
+		}
+	}
+	if err := e.checkDestination(ctx, destination); err != nil {
+		return nil, err
+	}
+
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"type":           "sendAsset",
+		"destination":    destination,
+		"sourceDex":      sourceDex,
+		"destinationDex": destinationDex,
+		"token":          token,
+		"amount":         amount,
+		"fromSubAccount": fromSubAccount,
+		"nonce":          timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.SendAssetSignTypes, "HyperliquidTransaction:SendAsset")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign send asset action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// TokenDelegate delegates wei of staked HYPE to a validator, or undelegates it
+// when isUndelegate is set. wei is denominated in the token's wei decimals.
+func (e *Exchange) TokenDelegate(validator string, wei uint64, isUndelegate bool) (interface{}, error) {
+	return e.TokenDelegateCtx(context.Background(), validator, wei, isUndelegate)
+}
+
+// TokenDelegateCtx is like TokenDelegate but carries ctx to the request
+func (e *Exchange) TokenDelegateCtx(ctx context.Context, validator string, wei uint64, isUndelegate bool) (interface{}, error) {
+	validator, err := utils.NormalizeAddress(validator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validator address: %w", err)
+	}
+	if wei == 0 {
+		return nil, fmt.Errorf("delegation amount must be positive")
+	}
+
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"type":         "tokenDelegate",
+		"validator":    validator,
+		"wei":          wei,
+		"isUndelegate": isUndelegate,
+		"nonce":        timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.TokenDelegateTypes, "HyperliquidTransaction:TokenDelegate")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token delegate action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// CDeposit moves wei of HYPE from the spot balance into the staking balance
+func (e *Exchange) CDeposit(wei uint64) (interface{}, error) {
+	return e.CDepositCtx(context.Background(), wei)
+}
+
+// CDepositCtx is like CDeposit but carries ctx to the request
+func (e *Exchange) CDepositCtx(ctx context.Context, wei uint64) (interface{}, error) {
+	return e.stakingTransfer(ctx, "cDeposit", wei, utils.CDepositSignTypes, "HyperliquidTransaction:CDeposit")
+}
+
+// CWithdraw moves wei of HYPE from the staking balance back to the spot balance
+func (e *Exchange) CWithdraw(wei uint64) (interface{}, error) {
+	return e.CWithdrawCtx(context.Background(), wei)
+}
+
+// CWithdrawCtx is like CWithdraw but carries ctx to the request
+func (e *Exchange) CWithdrawCtx(ctx context.Context, wei uint64) (interface{}, error) {
+	return e.stakingTransfer(ctx, "cWithdraw", wei, utils.CWithdrawSignTypes, "HyperliquidTransaction:CWithdraw")
+}
+
+// stakingTransfer signs and posts a cDeposit or cWithdraw action
+func (e *Exchange) stakingTransfer(ctx context.Context, actionType string, wei uint64, payloadTypes []apitypes.Type, primaryType string) (interface{}, error) {
+	if wei == 0 {
+		return nil, fmt.Errorf("%s amount must be positive", actionType)
+	}
+
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"type":  actionType,
+		"wei":   wei,
+		"nonce": timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, payloadTypes, primaryType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign %s action: %w", actionType, err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// CreateSubAccount creates a new sub-account with the given name
+func (e *Exchange) CreateSubAccount(name string) (interface{}, error) {
+	return e.CreateSubAccountCtx(context.Background(), name)
+}
+
+// CreateSubAccountCtx is like CreateSubAccount but carries ctx to the request
+func (e *Exchange) CreateSubAccountCtx(ctx context.Context, name string) (interface{}, error) {
+	action := utils.CreateSubAccountAction{
+		Type: "createSubAccount",
+		Name: name,
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// SubAccountTransfer moves USD between the master account and a sub-account.
+// usd is denominated in micro-dollars (1 USD = 1000000), see utils.FloatToUSDInt.
+func (e *Exchange) SubAccountTransfer(subAccountUser string, isDeposit bool, usd int64) (interface{}, error) {
+	return e.SubAccountTransferCtx(context.Background(), subAccountUser, isDeposit, usd)
+}
+
+// SubAccountTransferCtx is like SubAccountTransfer but carries ctx to the request
+func (e *Exchange) SubAccountTransferCtx(ctx context.Context, subAccountUser string, isDeposit bool, usd int64) (interface{}, error) {
+	subAccountUser, err := utils.NormalizeAddress(subAccountUser)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sub-account address: %w", err)
+	}
+	if usd <= 0 {
+		return nil, fmt.Errorf("sub-account transfer amount must be positive: %d", usd)
+	}
+	
+	action := utils.SubAccountTransferAction{
+		Type:           "subAccountTransfer",
+		SubAccountUser: subAccountUser,
+		IsDeposit:      isDeposit,
+		Usd:            usd,
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// SubAccountSpotTransfer moves a spot token between the master account and a sub-account
+func (e *Exchange) SubAccountSpotTransfer(subAccountUser string, isDeposit bool, token string, amount float64) (interface{}, error) {
+	return e.SubAccountSpotTransferCtx(context.Background(), subAccountUser, isDeposit, token, amount)
+}
+
+// SubAccountSpotTransferCtx is like SubAccountSpotTransfer but carries ctx to the request
+func (e *Exchange) SubAccountSpotTransferCtx(ctx context.Context, subAccountUser string, isDeposit bool, token string, amount float64) (interface{}, error) {
+	subAccountUser, err := utils.NormalizeAddress(subAccountUser)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sub-account address: %w", err)
+	}
+	
+	amountWire, err := utils.FloatToWire(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sub-account spot transfer amount: %w", err)
+	}
+	
+	action := utils.SubAccountSpotTransferAction{
+		Type:           "subAccountSpotTransfer",
+		SubAccountUser: subAccountUser,
+		IsDeposit:      isDeposit,
+		Token:          token,
+		Amount:         amountWire,
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// VaultUsdTransfer deposits USD into or withdraws USD from a vault.
+// usd is denominated in micro-dollars (1 USD = 1000000).
+func (e *Exchange) VaultUsdTransfer(vaultAddress string, isDeposit bool, usd int64) (interface{}, error) {
+	return e.VaultUsdTransferCtx(context.Background(), vaultAddress, isDeposit, usd)
+}
+
+// VaultUsdTransferCtx is like VaultUsdTransfer but carries ctx to the request
+func (e *Exchange) VaultUsdTransferCtx(ctx context.Context, vaultAddress string, isDeposit bool, usd int64) (interface{}, error) {
+	vaultAddress, err := utils.NormalizeAddress(vaultAddress)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault address: %w", err)
+	}
+	if usd <= 0 {
+		return nil, fmt.Errorf("vault transfer amount must be positive: %d", usd)
+	}
+	
+	// The address is part of the hashed action, so it must match the exchange's lowercase form
+	action := utils.VaultTransferAction{
+		Type:         "vaultTransfer",
+		VaultAddress: vaultAddress,
+		IsDeposit:    isDeposit,
+		Usd:          usd,
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// VaultUsdTransferAmount is like VaultUsdTransfer but takes the amount in USD
+func (e *Exchange) VaultUsdTransferAmount(vaultAddress string, isDeposit bool, amount float64) (interface{}, error) {
+	return e.VaultUsdTransferAmountCtx(context.Background(), vaultAddress, isDeposit, amount)
+}
+
+// VaultUsdTransferAmountCtx is like VaultUsdTransferAmount but carries ctx to the request
+func (e *Exchange) VaultUsdTransferAmountCtx(ctx context.Context, vaultAddress string, isDeposit bool, amount float64) (interface{}, error) {
+	usd, err := utils.FloatToUSDInt(amount)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault transfer amount: %w", err)
+	}
+	return e.VaultUsdTransferCtx(ctx, vaultAddress, isDeposit, usd)
+}
+
+// ApproveAgent generates a new agent wallet and approves it to trade on behalf of this account.
+// It returns the exchange response together with the agent's hex encoded private key, which
+// the caller must persist. A nil name approves an unnamed agent.
+func (e *Exchange) ApproveAgent(name *string) (interface{}, string, error) {
+	return e.ApproveAgentCtx(context.Background(), name)
+}
+
+// ApproveAgentCtx is like ApproveAgent but carries ctx to the request
+func (e *Exchange) ApproveAgentCtx(ctx context.Context, name *string) (interface{}, string, error) {
+	agentKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate agent key: %w", err)
+	}
+	agentKeyHex := "0x" + hex.EncodeToString(crypto.FromECDSA(agentKey))
+	agentAddress, err := utils.NormalizeAddress(crypto.PubkeyToAddress(agentKey.PublicKey).Hex())
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid agent address: %w", err)
+	}
+	
+	timestamp := e.nonces.Next()
+	agentName := ""
+	if name != nil {
+		agentName = *name
+	}
+	action := map[string]interface{}{
+		"type":         "approveAgent",
+		"agentAddress": agentAddress,
+		"agentName":    agentName,
+		"nonce":        timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.ApproveAgentSignTypes, "HyperliquidTransaction:ApproveAgent")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to sign approve agent action: %w", err)
+	}
+	
+	// agentName is always part of the signed message, but the exchange rejects
+	// an empty name in the posted action
+	if name == nil {
+		delete(signed, "agentName")
+	}
+	
+	result, err := e.postAction(ctx, signed, signature, timestamp)
+	if err != nil {
+		return nil, "", err
+	}
+	return result, agentKeyHex, nil
+}
+
+// ApproveBuilderFee authorizes a builder to charge up to maxFeeRate (e.g. "0.001%") on orders it submits
+func (e *Exchange) ApproveBuilderFee(builder string, maxFeeRate string) (interface{}, error) {
+	return e.ApproveBuilderFeeCtx(context.Background(), builder, maxFeeRate)
+}
+
+// ApproveBuilderFeeCtx is like ApproveBuilderFee but carries ctx to the request
+func (e *Exchange) ApproveBuilderFeeCtx(ctx context.Context, builder string, maxFeeRate string) (interface{}, error) {
+	builder, err := utils.NormalizeAddress(builder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid builder address: %w", err)
+	}
+	if !feeRatePattern.MatchString(maxFeeRate) {
+		return nil, fmt.Errorf("invalid max fee rate %q: expected a percentage such as \"0.001%%\"", maxFeeRate)
+	}
+	
+	timestamp := e.nonces.Next()
+	action := map[string]interface{}{
+		"type":       "approveBuilderFee",
+		"maxFeeRate": maxFeeRate,
+		"builder":    builder,
+		"nonce":      timestamp,
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.ApproveBuilderFeeSignTypes, "HyperliquidTransaction:ApproveBuilderFee")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign approve builder fee action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// ConvertToMultiSigUser converts the account into a multi-sig user controlled by
+// authorizedUsers, requiring threshold of their signatures for every action
+func (e *Exchange) ConvertToMultiSigUser(authorizedUsers []string, threshold int) (interface{}, error) {
+	return e.ConvertToMultiSigUserCtx(context.Background(), authorizedUsers, threshold)
+}
+
+// ConvertToMultiSigUserCtx is like ConvertToMultiSigUser but carries ctx to the request
+func (e *Exchange) ConvertToMultiSigUserCtx(ctx context.Context, authorizedUsers []string, threshold int) (interface{}, error) {
+	signers := utils.MultiSigSigners{AuthorizedUsers: authorizedUsers, Threshold: threshold}
+	if err := signers.Validate(); err != nil {
+		return nil, err
+	}
+
+	timestamp := e.nonces.Next()
+	action, err := utils.ConvertToMultiSigUserAction(signers, timestamp)
+	if err != nil {
+		return nil, err
+	}
+	
+	signed, signature, err := e.signUserSignedAction(action, utils.ConvertToMultiSigUserSignTypes, "HyperliquidTransaction:ConvertToMultiSigUser")
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign convert to multi-sig user action: %w", err)
+	}
+	
+	return e.postAction(ctx, signed, signature, timestamp)
+}
+
+// MultiSig submits innerAction on behalf of multiSigUser, for vaultAddress
+// or for the multi-sig user itself when nil, like the Python SDK's
+// multi_sig. The vault address is passed explicitly rather than taken from
+// the exchange, since the authorized users sign for it too. signatures are
+// their signatures over the inner action, with this exchange's wallet as the
+// outer signer: produced with utils.SignMultiSigL1ActionPayload using the
+// same nonce and vault address for an L1 action, or with
+// utils.SignMultiSigUserSignedActionPayload for a user-signed action.
+//
+// Unlike in the Python SDK the inner action is a typed utils.Action, not a
+// map: the multiSig action is hashed with its msgpack encoding, whose field
+// order a Go map does not keep, so ActionHash rejects maps. Wrap a
+// user-signed action map such as usdSend with utils.NewUserSignedAction.
+func (e *Exchange) MultiSig(multiSigUser string, innerAction utils.Action, signatures []utils.Signature, nonce int64, vaultAddress *string) (interface{}, error) {
+	return e.MultiSigCtx(context.Background(), multiSigUser, innerAction, signatures, nonce, vaultAddress)
+}
+
+// MultiSigCtx is like MultiSig but carries ctx to the request
+func (e *Exchange) MultiSigCtx(ctx context.Context, multiSigUser string, innerAction utils.Action, signatures []utils.Signature, nonce int64, vaultAddress *string) (interface{}, error) {
+	multiSigUser, err := utils.NormalizeAddress(multiSigUser)
+	if err != nil {
+		return nil, fmt.Errorf("invalid multi-sig user address: %w", err)
+	}
+	vaultAddress, err = normalizeVault(vaultAddress)
+	if err != nil {
+		return nil, err
+	}
+	if innerAction == nil {
+		return nil, fmt.Errorf("inner action is required")
+	}
+	if len(signatures) == 0 {
+		return nil, fmt.Errorf("at least one authorized user signature is required")
+	}
+
+	action := utils.MultiSigAction{
+		Type:             "multiSig",
+		SignatureChainID: e.signingConfig.WithDefaults().SignatureChainID,
+		Signatures:       signatures,
+		Payload: utils.MultiSigPayload{
+			MultiSigUser: multiSigUser,
+			OuterSigner:  strings.ToLower(e.signer.Address().Hex()),
+			Action:       innerAction,
+		},
+	}
+	
+	isMainnet := e.GetBaseURL() == utils.MainnetAPIURL
+	
+	expiresAfterUint, err := e.signedExpiresAfter(nonce)
+	if err != nil {
+		return nil, err
+	}
+	
+	envelope, err := utils.MultiSigEnvelope(action, vaultAddress, uint64(nonce), expiresAfterUint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash multi-sig action: %w", err)
+	}
+	signature, err := utils.SignUserSignedActionWithConfig(e.signer, envelope, utils.MultiSigEnvelopeSignTypes, "HyperliquidTransaction:SendMultiSig", isMainnet, e.signingConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign multi-sig action: %w", err)
+	}
+	
+	// Posted with the vault address it was hashed with, not the exchange's
+	payload := e.actionPayload(action, signature, nonce)
+	delete(payload, "vaultAddress")
+	if vaultAddress != nil {
+		payload["vaultAddress"] = *vaultAddress
+	}
+	e.autoReserve(ctx, action)
+	var raw json.RawMessage
+	if err := e.PostInto(ctx, "/exchange", payload, &raw); err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := decodeActionResponse(raw, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PerpDeployRegisterAsset registers a new asset on the builder-deployed perp
+// dex, bidding up to maxGas in the deploy auction (nil for the current price)
+func (e *Exchange) PerpDeployRegisterAsset(dex string, maxGas *int64, coin string, szDecimals int, oraclePx string, marginTableID int, onlyIsolated bool) (interface{}, error) {
+	return e.PerpDeployRegisterAssetCtx(context.Background(), dex, maxGas, coin, szDecimals, oraclePx, marginTableID, onlyIsolated)
+}
+
+// PerpDeployRegisterAssetCtx is like PerpDeployRegisterAsset but carries ctx to the request
+func (e *Exchange) PerpDeployRegisterAssetCtx(ctx context.Context, dex string, maxGas *int64, coin string, szDecimals int, oraclePx string, marginTableID int, onlyIsolated bool) (interface{}, error) {
+	action := utils.PerpDeployAction{
+		Type: "perpDeploy",
+		RegisterAsset: &utils.PerpDeployRegisterAsset{
+			MaxGas: maxGas,
+			AssetRequest: utils.PerpAssetRequest{
+				Coin:          coin,
+				SzDecimals:    szDecimals,
+				OraclePx:      oraclePx,
+				MarginTableID: marginTableID,
+				OnlyIsolated:  onlyIsolated,
+			},
+			Dex: dex,
+		},
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// PerpDeploySetOracle publishes oracle and mark prices, keyed by coin, for the
+// assets of a builder-deployed perp dex
+func (e *Exchange) PerpDeploySetOracle(dex string, oraclePxs map[string]string, markPxs map[string]string) (interface{}, error) {
+	return e.PerpDeploySetOracleCtx(context.Background(), dex, oraclePxs, markPxs)
+}
+
+// PerpDeploySetOracleCtx is like PerpDeploySetOracle but carries ctx to the request
+func (e *Exchange) PerpDeploySetOracleCtx(ctx context.Context, dex string, oraclePxs map[string]string, markPxs map[string]string) (interface{}, error) {
+	action := utils.PerpDeployAction{
+		Type: "perpDeploy",
+		SetOracle: &utils.PerpDeploySetOracle{
+			Dex:       dex,
+			OraclePxs: sortedPxs(oraclePxs),
+			MarkPxs:   sortedPxs(markPxs),
+		},
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// sortedPxs converts a coin to price map into [coin, px] pairs sorted by coin
+func sortedPxs(pxs map[string]string) [][2]string {
+	pairs := make([][2]string, 0, len(pxs))
+	for coin, px := range pxs {
+		pairs = append(pairs, [2]string{coin, px})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return pairs
+}
+
+// SpotDeployRegisterToken registers a new spot token, bidding up to maxGas in
+// the deploy auction
+func (e *Exchange) SpotDeployRegisterToken(tokenName string, szDecimals int, weiDecimals int, maxGas int64, fullName string) (interface{}, error) {
+	return e.SpotDeployRegisterTokenCtx(context.Background(), tokenName, szDecimals, weiDecimals, maxGas, fullName)
+}
+
+// SpotDeployRegisterTokenCtx is like SpotDeployRegisterToken but carries ctx to the request
+func (e *Exchange) SpotDeployRegisterTokenCtx(ctx context.Context, tokenName string, szDecimals int, weiDecimals int, maxGas int64, fullName string) (interface{}, error) {
+	action := utils.SpotDeployAction{
+		Type: "spotDeploy",
+		RegisterToken2: &utils.SpotDeployRegisterToken{
+			Spec: utils.SpotTokenSpec{
+				Name:        tokenName,
+				SzDecimals:  szDecimals,
+				WeiDecimals: weiDecimals,
+			},
+			MaxGas:   maxGas,
+			FullName: fullName,
+		},
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// SpotDeployGenesis sets the maximum supply of a registered token. With
+// noHyperliquidity no Hyperliquidity market making is provided for it.
+func (e *Exchange) SpotDeployGenesis(token int, maxSupply string, noHyperliquidity bool) (interface{}, error) {
+	return e.SpotDeployGenesisCtx(context.Background(), token, maxSupply, noHyperliquidity)
+}
+
+// SpotDeployGenesisCtx is like SpotDeployGenesis but carries ctx to the request
+func (e *Exchange) SpotDeployGenesisCtx(ctx context.Context, token int, maxSupply string, noHyperliquidity bool) (interface{}, error) {
+	action := utils.SpotDeployAction{
+		Type: "spotDeploy",
+		Genesis: &utils.SpotDeployGenesis{
+			Token:            token,
+			MaxSupply:        maxSupply,
+			NoHyperliquidity: noHyperliquidity,
+		},
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// SpotDeployRegisterSpot creates the spot pair trading baseToken against quoteToken
+func (e *Exchange) SpotDeployRegisterSpot(baseToken int, quoteToken int) (interface{}, error) {
+	return e.SpotDeployRegisterSpotCtx(context.Background(), baseToken, quoteToken)
+}
+
+// SpotDeployRegisterSpotCtx is like SpotDeployRegisterSpot but carries ctx to the request
+func (e *Exchange) SpotDeployRegisterSpotCtx(ctx context.Context, baseToken int, quoteToken int) (interface{}, error) {
+	action := utils.SpotDeployAction{
+		Type: "spotDeploy",
+		RegisterSpot: &utils.SpotDeployRegisterSpot{
+			Tokens: [2]int{baseToken, quoteToken},
+		},
+	}
+	return e.postL1Action(ctx, action, nil)
+}
+
+// SpotDeploySetDeployerTradingFeeShare sets the share of trading fees paid to
+// the deployer of token, e.g. "100%"
+func (e *Exchange) SpotDeploySetDeployerTradingFeeShare(token int, share string) (interface{}, error) {
+	return e.SpotDeploySetDeployerTradingFeeShareCtx(context.Background(), token, share)
+}
+
+// SpotDeploySetDeployerTradingFeeShareCtx is like SpotDeploySetDeployerTradingFeeShare but carries ctx to the request
+func (e *Exchange) SpotDeploySetDeployerTradingFeeShareCtx(ctx context.Context, token int, share string) (interface{}, error) {
+	if !feeRatePattern.MatchString(share) {
+		return nil, fmt.Errorf("invalid fee share %q: expected a percentage such as \"100%%\"", share)
+	}
+	action := utils.SpotDeployAction{
+		Type: "spotDeploy",
+		SetDeployerTradingFeeShare: &utils.SpotDeploySetDeployerTradingFeeShare{
+			Token: token,
+			Share: share,
+		},
+	}
+	return e.postL1Action(ctx, action, nil)
 }
@@ -0,0 +1,162 @@
+// Package hyperliquid - Explorer queries for blocks and transactions
+package hyperliquid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// TxPollInterval is how often WaitForTx looks the transaction up
+const TxPollInterval = 500 * time.Millisecond
+
+// ErrTxNotFound is returned by TxDetails for a transaction the explorer does
+// not know, possibly not yet
+var ErrTxNotFound = errors.New("transaction not found")
+
+// Tx is a transaction as shown by the explorer
+type Tx struct {
+	Action json.RawMessage `json:"action"` // The signed action, as sent to /exchange
+	Block  int64           `json:"block"`
+	Error  *string         `json:"error"` // Set when the action was rejected
+	Hash   string          `json:"hash"`
+	Time   int64           `json:"time"`
+	User   string          `json:"user"`
+}
+
+// ActionType returns the type field of the transaction's action
+func (t Tx) ActionType() string {
+	var action struct {
+		Type string `json:"type"`
+	}
+	_ = json.Unmarshal(t.Action, &action)
+	return action.Type
+}
+
+// Block is a block as shown by the explorer
+type Block struct {
+	Height    int64  `json:"height"`
+	BlockTime int64  `json:"blockTime"`
+	Hash      string `json:"hash"`
+	Proposer  string `json:"proposer"`
+	NumTxs    int    `json:"numTxs"`
+	Txs       []Tx   `json:"txs"`
+}
+
+// explorerURL returns the RPC host serving the explorer endpoints for an API
+// URL. Other hosts, such as a local node, are assumed to serve them as well.
+func explorerURL(baseURL string) string {
+	switch baseURL {
+	case utils.MainnetAPIURL:
+		return utils.MainnetRPCURL
+	case utils.TestnetAPIURL:
+		return utils.TestnetRPCURL
+	}
+	return baseURL
+}
+
+// SetExplorerURL sets the host explorer queries are sent to, by default the
+// RPC host matching the API URL
+func (i *Info) SetExplorerURL(url string) {
+	i.explorer.SetBaseURL(url)
+}
+
+// BlockDetails retrieves a block and its transactions
+func (i *Info) BlockDetails(height int64) (*Block, error) {
+	return i.BlockDetailsCtx(context.Background(), height)
+}
+
+// BlockDetailsCtx is like BlockDetails but carries ctx to the request
+func (i *Info) BlockDetailsCtx(ctx context.Context, height int64) (*Block, error) {
+	payload := map[string]interface{}{
+		"type":   "blockDetails",
+		"height": height,
+	}
+	var response struct {
+		BlockDetails Block `json:"blockDetails"`
+	}
+	if err := i.explorer.PostInto(ctx, "/explorer", payload, &response); err != nil {
+		return nil, err
+	}
+	return &response.BlockDetails, nil
+}
+
+// TxDetails retrieves a transaction by hash
+func (i *Info) TxDetails(hash string) (*Tx, error) {
+	return i.TxDetailsCtx(context.Background(), hash)
+}
+
+// TxDetailsCtx is like TxDetails but carries ctx to the request
+func (i *Info) TxDetailsCtx(ctx context.Context, hash string) (*Tx, error) {
+	payload := map[string]interface{}{
+		"type": "txDetails",
+		"hash": hash,
+	}
+	var response struct {
+		Tx *Tx `json:"tx"`
+	}
+	if err := i.explorer.PostInto(ctx, "/explorer", payload, &response); err != nil {
+		return nil, err
+	}
+	if response.Tx == nil {
+		return nil, fmt.Errorf("%w: %s", ErrTxNotFound, hash)
+	}
+	return response.Tx, nil
+}
+
+// UserDetails retrieves a user's recent transactions
+func (i *Info) UserDetails(user string) ([]Tx, error) {
+	return i.UserDetailsCtx(context.Background(), user)
+}
+
+// UserDetailsCtx is like UserDetails but carries ctx to the request
+func (i *Info) UserDetailsCtx(ctx context.Context, user string) ([]Tx, error) {
+	payload := map[string]interface{}{
+		"type": "userDetails",
+		"user": user,
+	}
+	var response struct {
+		Txs []Tx `json:"txs"`
+	}
+	if err := i.explorer.PostInto(ctx, "/explorer", payload, &response); err != nil {
+		return nil, err
+	}
+	return response.Txs, nil
+}
+
+// WaitForTx polls TxDetails until the transaction shows up or timeout passes.
+// A transaction that landed but was rejected is returned with Error set.
+func (i *Info) WaitForTx(hash string, timeout time.Duration) (*Tx, error) {
+	return i.WaitForTxCtx(context.Background(), hash, timeout)
+}
+
+// WaitForTxCtx is like WaitForTx but carries ctx to the requests
+func (i *Info) WaitForTxCtx(ctx context.Context, hash string, timeout time.Duration) (*Tx, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(TxPollInterval)
+	defer ticker.Stop()
+	for {
+		tx, err := i.TxDetailsCtx(ctx, hash)
+		if err == nil {
+			return tx, nil
+		}
+		// The explorer answers unknown hashes with a client error or no tx;
+		// anything else is final
+		var clientErr *utils.ClientError
+		if ctx.Err() == nil && !errors.Is(err, ErrTxNotFound) && !errors.As(err, &clientErr) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction %s not found before timeout: %w", hash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package hyperliquid - User fills merged from REST and the WebSocket
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// FillTracker merges fills fetched over REST with the userFills subscription
+// of a user, calling its callback with every batch of fills not delivered
+// before, oldest first. Fills are told apart by tid and oid; fills
+// aggregated by time have no tid, so their time is used as well.
+//
+// The first userFills snapshot usually repeats fills already backfilled over
+// REST, and only the new ones are delivered. Every later snapshot means the
+// subscription was re-established after a disconnect, so the tracker fetches
+// the fills since the last one it delivered with UserFillsByTime, page by
+// page, to close the gap. Like UserStream it looks back 10 seconds from that fill, so fills
+// sharing its timestamp are not lost.
+type FillTracker struct {
+	mu        sync.Mutex
+	info      *Info
+	user      string
+	callback  func([]utils.Fill)
+	lastTime  int64
+	seen      map[fillKey]int64
+	snapshots int
+	subID     int
+}
+
+// fillKey identifies a fill
+type fillKey struct {
+	tid  int
+	oid  int
+	time int64 // Only set for fills without a tid
+}
+
+// NewFillTracker creates a tracker of user's fills. The callback is called
+// sequentially and must not call back into the tracker.
+func NewFillTracker(info *Info, user string, callback func([]utils.Fill)) *FillTracker {
+	return &FillTracker{
+		info:     info,
+		user:     user,
+		callback: callback,
+		seen:     make(map[fillKey]int64),
+	}
+}
+
+// Backfill delivers the fills of a REST query such as Info.UserFills that
+// were not delivered yet. Call it before Start: fills older than the last
+// delivered one are skipped, beyond the 10 second overlap.
+func (t *FillTracker) Backfill(fills []utils.Fill) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deliver(fills)
+}
+
+// Start subscribes to the user's fills
+func (t *FillTracker) Start() error {
+	subID, err := t.info.Subscribe(utils.UserFillsSubscription{User: t.user}, t.onUserFills)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to user fills: %w", err)
+	}
+	t.mu.Lock()
+	t.subID = subID
+	t.mu.Unlock()
+	return nil
+}
+
+// Stop removes the tracker's subscription
+func (t *FillTracker) Stop() error {
+	t.mu.Lock()
+	subID := t.subID
+	t.mu.Unlock()
+	_, err := t.info.Unsubscribe(utils.UserFillsSubscription{User: t.user}, subID)
+	return err
+}
+
+// Resync fetches the fills since the last delivered one over REST and
+// delivers those not seen yet. It is called automatically when the
+// subscription is re-established. It returns an error after delivering the
+// fills if more fills share a millisecond than a page holds, as some of them
+// could not be fetched.
+func (t *FillTracker) Resync(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.resync(ctx, nil)
+}
+
+// LastFillTime returns the time of the most recent delivered fill
+func (t *FillTracker) LastFillTime() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.lastTime
+}
+
+// onUserFills handles userFills messages
+func (t *FillTracker) onUserFills(msg WsMsg) {
+	var data utils.UserFillsData
+	if err := decodeResult(msg.Data, &data); err != nil {
+		t.info.logger.Warn("Failed to decode user fills", "err", err)
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if data.IsSnapshot {
+		t.snapshots++
+	}
+	if data.IsSnapshot && t.snapshots > 1 {
+		if err := t.resync(context.Background(), data.Fills); err != nil {
+			t.info.logger.Error("Fill tracker resync failed", "err", err)
+			t.deliver(data.Fills)
+		}
+		return
+	}
+	t.deliver(data.Fills)
+}
+
+// resync fetches the fills since the last delivered one, page by page, and
+// delivers them along with the snapshot. The caller must hold t.mu.
+func (t *FillTracker) resync(ctx context.Context, snapshot []utils.Fill) error {
+	startTime := t.floor()
+	if startTime < 0 {
+		startTime = 0
+	}
+	var fills []utils.Fill
+	truncated, err := t.info.userFillsByTimePaged(ctx, t.user, startTime, nil, func(page []utils.Fill) error {
+		fills = append(fills, page...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch user fills: %w", err)
+	}
+	t.deliver(append(fills, snapshot...))
+	if truncated {
+		return fmt.Errorf("more than %d fills share a millisecond; some were not recovered", FillsPageLimit)
+	}
+	return nil
+}
+
+// deliver calls the callback with the fills not delivered yet, oldest first.
+// The caller must hold t.mu.
+func (t *FillTracker) deliver(fills []utils.Fill) {
+	floor := t.floor()
+	var fresh []utils.Fill
+	for _, fill := range fills {
+		key := fillKey{tid: fill.Tid, oid: fill.Oid}
+		if fill.Tid == 0 {
+			key.time = fill.Time
+		}
+		if fill.Time < floor {
+			continue
+		}
+		if _, ok := t.seen[key]; ok {
+			continue
+		}
+		t.seen[key] = fill.Time
+		fresh = append(fresh, fill)
+	}
+	if len(fresh) == 0 {
+		return
+	}
+
+	sort.SliceStable(fresh, func(a, b int) bool {
+		if fresh[a].Time != fresh[b].Time {
+			return fresh[a].Time < fresh[b].Time
+		}
+		return fresh[a].Tid < fresh[b].Tid
+	})
+	if last := fresh[len(fresh)-1].Time; last > t.lastTime {
+		t.lastTime = last
+	}
+	floor = t.floor()
+	for key, time := range t.seen {
+		if time < floor {
+			delete(t.seen, key)
+		}
+	}
+	t.callback(fresh)
+}
+
+// floor returns the earliest fill time still considered for delivery
+func (t *FillTracker) floor() int64 {
+	if t.lastTime == 0 {
+		return 0
+	}
+	return t.lastTime - userStreamOverlapMs
+}
@@ -0,0 +1,125 @@
+// Package hyperliquid - Request and response hooks
+package hyperliquid
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// RequestHook is called with every HTTP request the API client sends, once
+// per attempt, before it is sent. It may set headers, for example to
+// propagate a trace. Its body is a copy of the payload: reading or replacing
+// it does not change what is sent.
+type RequestHook func(req *http.Request)
+
+// ResponseHook is called after every attempt with the response, or nil when
+// none was received, a copy of the response body, how long the attempt took
+// and its error, nil on success. The response body itself has already been
+// read.
+type ResponseHook func(resp *http.Response, body []byte, elapsed time.Duration, err error)
+
+// WithRequestHook adds a hook called before every request, after the hooks
+// added before it, and returns the client. Add hooks before sending requests.
+func (a *API) WithRequestHook(hook RequestHook) *API {
+	a.requestHooks = append(a.requestHooks, hook)
+	return a
+}
+
+// WithResponseHook adds a hook called after every request, after the hooks
+// added before it, and returns the client. Add hooks before sending requests.
+func (a *API) WithResponseHook(hook ResponseHook) *API {
+	a.responseHooks = append(a.responseHooks, hook)
+	return a
+}
+
+// runRequestHooks calls the request hooks with req, each on a copy of the
+// payload, and then restores the payload as the body to send
+func (a *API) runRequestHooks(req *http.Request, payload []byte) {
+	for _, hook := range a.requestHooks {
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		hook(req)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.ContentLength = int64(len(payload))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(payload)), nil
+	}
+}
+
+// runResponseHooks calls the response hooks, each with its own copy of body
+func (a *API) runResponseHooks(resp *http.Response, body []byte, elapsed time.Duration, err error) {
+	for _, hook := range a.responseHooks {
+		hook(resp, append([]byte(nil), body...), elapsed, err)
+	}
+}
+
+// RequestStats are the requests to one path recorded by a RequestTimer
+type RequestStats struct {
+	Requests     int           // Attempts, retries included
+	Errors       int           // Attempts that failed, with a response or without
+	TotalLatency time.Duration // Summed over every attempt
+	MaxLatency   time.Duration
+}
+
+// AverageLatency returns the mean latency of the requests
+func (s RequestStats) AverageLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Requests)
+}
+
+// RequestTimer records the count, errors and latency of requests per URL
+// path. Add its Hook to a client with WithResponseHook; one timer may be
+// shared by several clients.
+type RequestTimer struct {
+	mu    sync.Mutex
+	stats map[string]RequestStats
+}
+
+// NewRequestTimer creates a RequestTimer with nothing recorded
+func NewRequestTimer() *RequestTimer {
+	return &RequestTimer{stats: make(map[string]RequestStats)}
+}
+
+// Hook is the ResponseHook recording a request
+func (t *RequestTimer) Hook(resp *http.Response, _ []byte, elapsed time.Duration, err error) {
+	path := ""
+	var urlErr *url.Error
+	if resp != nil && resp.Request != nil {
+		path = resp.Request.URL.Path
+	} else if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			path = u.Path
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.stats[path]
+	stats.Requests++
+	if err != nil {
+		stats.Errors++
+	}
+	stats.TotalLatency += elapsed
+	if elapsed > stats.MaxLatency {
+		stats.MaxLatency = elapsed
+	}
+	t.stats[path] = stats
+}
+
+// Stats returns the requests recorded so far by URL path, such as "/info"
+func (t *RequestTimer) Stats() map[string]RequestStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make(map[string]RequestStats, len(t.stats))
+	for path, s := range t.stats {
+		stats[path] = s
+	}
+	return stats
+}
@@ -0,0 +1,97 @@
+// Package hyperliquid - HTTP transport and client options
+package hyperliquid
+
+import (
+	"net/http"
+	"time"
+)
+
+// Version is the version of the SDK, sent in the User-Agent header
+const Version = "0.1.0"
+
+// UserAgent is the User-Agent header of every request
+const UserAgent = "hyperliquid-go/" + Version
+
+// DefaultMaxIdleConnsPerHost is how many idle connections the default
+// transport keeps to one host. Every request of a client goes to the same API
+// host, so the net/http default of two would close most connections a burst
+// of concurrent requests opens.
+const DefaultMaxIdleConnsPerHost = 32
+
+// defaultTransport is shared by every client not given a transport, so that
+// Info and Exchange clients reuse each other's connections
+var defaultTransport http.RoundTripper = newDefaultTransport()
+
+// newDefaultTransport returns http.DefaultTransport, which honours the proxy
+// environment variables, tuned for a single API host
+func newDefaultTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	transport.ForceAttemptHTTP2 = true
+	return transport
+}
+
+// ClientOption configures a client created with NewInfoOpts or
+// NewExchangeOpts
+type ClientOption func(*clientOptions)
+
+// clientOptions collects the ClientOptions of a constructor
+type clientOptions struct {
+	client    *http.Client
+	transport http.RoundTripper
+	wsOptions []WSOptions
+}
+
+// WithHTTPClient sends requests with client instead of one built from the
+// timeout and the default transport. The timeout passed to the constructor
+// replaces the client's own, which is left unchanged.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(o *clientOptions) {
+		o.client = client
+	}
+}
+
+// WithTransport sends requests through transport, e.g. an http.Transport
+// with a proxy, TLS settings or connection-pool sizes of its own, or a
+// RoundTripper wrapping another. It applies on top of WithHTTPClient.
+func WithTransport(transport http.RoundTripper) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = transport
+	}
+}
+
+// WithWSOptions configures how the WebSocket manager of an Info client
+// connects, like the WebSocket options of NewInfo
+func WithWSOptions(opts WSOptions) ClientOption {
+	return func(o *clientOptions) {
+		o.wsOptions = append(o.wsOptions, opts)
+	}
+}
+
+// newClientOptions applies opts in order
+func newClientOptions(opts []ClientOption) *clientOptions {
+	options := &clientOptions{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(options)
+		}
+	}
+	return options
+}
+
+// newAPI creates an API client for baseURL with the configured HTTP client
+func (o *clientOptions) newAPI(baseURL string, timeout time.Duration) *API {
+	client := &http.Client{Transport: defaultTransport}
+	if o.client != nil {
+		copied := *o.client
+		client = &copied
+	}
+	if o.transport != nil {
+		client.Transport = o.transport
+	}
+	client.Timeout = timeout
+
+	api := NewAPIWithClient(baseURL, client)
+	api.timeout = timeout
+	return api
+}
@@ -2,7 +2,16 @@
 package hyperliquid
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
@@ -15,8 +24,11 @@ type Meta struct {
 
 // AssetInfo represents asset information
 type AssetInfo struct {
-	Name       string `json:"name"`
-	SzDecimals int    `json:"szDecimals"`
+	Name          string `json:"name"`
+	SzDecimals    int    `json:"szDecimals"`
+	MaxLeverage   int    `json:"maxLeverage"`
+	OnlyIsolated  bool   `json:"onlyIsolated,omitempty"` // Cross margin is not allowed
+	MarginTableID int    `json:"marginTableId,omitempty"`
 }
 
 // SpotMeta represents spot exchange metadata
@@ -45,12 +57,94 @@ type SpotTokenInfo struct {
 	FullName     *string `json:"fullName,omitempty"`
 }
 
+// TokenDetails holds the supply, deploy and price data of a spot token.
+// Deploy fields are nil for tokens created at launch.
+type TokenDetails struct {
+	Name              string              `json:"name"`
+	MaxSupply         utils.DecimalString `json:"maxSupply"`
+	TotalSupply       utils.DecimalString `json:"totalSupply"`
+	CirculatingSupply utils.DecimalString `json:"circulatingSupply"`
+	SzDecimals        int                 `json:"szDecimals"`
+	WeiDecimals       int                 `json:"weiDecimals"`
+	MidPx             *string             `json:"midPx"`
+	MarkPx            *string             `json:"markPx"`
+	PrevDayPx         *string             `json:"prevDayPx"`
+	Deployer          *string             `json:"deployer"`
+	DeployGas         *string             `json:"deployGas"`
+	DeployTime        *string             `json:"deployTime"`
+	SeededUsdc        utils.DecimalString `json:"seededUsdc"`
+	FutureEmissions   utils.DecimalString `json:"futureEmissions"`
+	Genesis           json.RawMessage     `json:"genesis"` // Genesis user and token balances, null if none
+}
+
 // SpotMetaAndAssetCtxs represents spot metadata and asset contexts
 type SpotMetaAndAssetCtxs struct {
 	Meta      SpotMeta        `json:"meta"`
 	AssetCtxs []SpotAssetCtx  `json:"assetCtxs"`
 }
 
+// SpotClearinghouseState holds a user's spot token balances
+type SpotClearinghouseState struct {
+	Balances []SpotBalance `json:"balances"`
+}
+
+// ClearinghouseState holds a user's perp positions and margin on one perp
+// dex
+type ClearinghouseState struct {
+	AssetPositions             []AssetPosition     `json:"assetPositions"`
+	MarginSummary              MarginSummary       `json:"marginSummary"`
+	CrossMarginSummary         MarginSummary       `json:"crossMarginSummary"`
+	CrossMaintenanceMarginUsed utils.DecimalString `json:"crossMaintenanceMarginUsed"`
+	Withdrawable               utils.DecimalString `json:"withdrawable"`
+	Time                       int64               `json:"time"`
+}
+
+// MarginSummary sums up the margin of a user's positions
+type MarginSummary struct {
+	AccountValue    utils.DecimalString `json:"accountValue"`
+	TotalNtlPos     utils.DecimalString `json:"totalNtlPos"`
+	TotalRawUsd     utils.DecimalString `json:"totalRawUsd"`
+	TotalMarginUsed utils.DecimalString `json:"totalMarginUsed"`
+}
+
+// AssetPosition is a user's position in one perp asset
+type AssetPosition struct {
+	Type     string   `json:"type"` // "oneWay"
+	Position Position `json:"position"`
+}
+
+// Position is an open perp position. Szi is negative for shorts.
+type Position struct {
+	Coin           string               `json:"coin"`
+	Szi            utils.DecimalString  `json:"szi"`
+	Leverage       PositionLeverage     `json:"leverage"`
+	EntryPx        utils.DecimalString  `json:"entryPx"`
+	PositionValue  utils.DecimalString  `json:"positionValue"`
+	UnrealizedPnl  utils.DecimalString  `json:"unrealizedPnl"`
+	ReturnOnEquity utils.DecimalString  `json:"returnOnEquity"`
+	LiquidationPx  *utils.DecimalString `json:"liquidationPx"` // Nil when the position cannot be liquidated
+	MarginUsed     utils.DecimalString  `json:"marginUsed"`
+	MaxLeverage    int                  `json:"maxLeverage"`
+}
+
+// PositionLeverage is the leverage of a position; RawUsd is only set for
+// isolated positions
+type PositionLeverage struct {
+	Type   string              `json:"type"` // "cross" or "isolated"
+	Value  int                 `json:"value"`
+	RawUsd utils.DecimalString `json:"rawUsd,omitempty"`
+}
+
+// SpotBalance is a user's balance of one spot token. Hold is the part
+// reserved by open orders.
+type SpotBalance struct {
+	Coin     string `json:"coin"`
+	Token    int    `json:"token"`
+	Hold     string `json:"hold"`
+	Total    string `json:"total"`
+	EntryNtl string `json:"entryNtl"`
+}
+
 // SpotAssetCtx represents spot asset context
 type SpotAssetCtx struct {
 	DayNtlVlm         string  `json:"dayNtlVlm"`
@@ -61,32 +155,415 @@ type SpotAssetCtx struct {
 	Coin              string  `json:"coin"`
 }
 
-// Info represents the Info API client
+// TwapState describes the parameters and progress of a TWAP order
+type TwapState struct {
+	Coin        string `json:"coin"`
+	User        string `json:"user"`
+	Side        string `json:"side"`
+	Sz          string `json:"sz"`
+	ExecutedSz  string `json:"executedSz"`
+	ExecutedNtl string `json:"executedNtl"`
+	Minutes     int    `json:"minutes"`
+	ReduceOnly  bool   `json:"reduceOnly"`
+	Randomize   bool   `json:"randomize"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// TwapStatus is the lifecycle status of a TWAP order: "activated",
+// "finished", "terminated" or "error"
+type TwapStatus struct {
+	Status      string `json:"status"`
+	Description string `json:"description,omitempty"`
+}
+
+// TwapHistoryEntry is a TWAP order as returned by the twapHistory request
+type TwapHistoryEntry struct {
+	Time   int64      `json:"time"`
+	State  TwapState  `json:"state"`
+	Status TwapStatus `json:"status"`
+	TwapID *int       `json:"twapId,omitempty"`
+}
+
+// TwapSliceFill is a fill executed by one slice of a TWAP order
+type TwapSliceFill struct {
+	Fill   utils.Fill `json:"fill"`
+	TwapID int        `json:"twapId"`
+}
+
+// VaultFollower is a depositor's position in a vault
+type VaultFollower struct {
+	User           string `json:"user"`
+	VaultEquity    string `json:"vaultEquity"`
+	Pnl            string `json:"pnl"`
+	AllTimePnl     string `json:"allTimePnl"`
+	DaysFollowing  int    `json:"daysFollowing"`
+	VaultEntryTime int64  `json:"vaultEntryTime"`
+	LockupUntil    int64  `json:"lockupUntil"` // Withdrawals are locked until this time in milliseconds
+}
+
+// VaultDetails describes a vault and, when requested for a user, that user's
+// position in it
+type VaultDetails struct {
+	Name                  string          `json:"name"`
+	VaultAddress          string          `json:"vaultAddress"`
+	Leader                string          `json:"leader"`
+	Description           string          `json:"description"`
+	Apr                   float64         `json:"apr"`
+	FollowerState         *VaultFollower  `json:"followerState"`
+	LeaderFraction        float64         `json:"leaderFraction"`
+	LeaderCommission      float64         `json:"leaderCommission"`
+	Followers             []VaultFollower `json:"followers"`
+	MaxDistributable      float64         `json:"maxDistributable"`
+	MaxWithdrawable       float64         `json:"maxWithdrawable"`
+	IsClosed              bool            `json:"isClosed"`
+	AllowDeposits         bool            `json:"allowDeposits"`
+	AlwaysCloseOnWithdraw bool            `json:"alwaysCloseOnWithdraw"`
+	Portfolio             Portfolio       `json:"portfolio"`
+}
+
+// VaultEquity is a user's equity in one vault
+type VaultEquity struct {
+	VaultAddress         string `json:"vaultAddress"`
+	Equity               string `json:"equity"`
+	LockedUntilTimestamp int64  `json:"lockedUntilTimestamp"`
+}
+
+// User roles returned by UserRole
+const (
+	RoleMissing    = "missing"
+	RoleUser       = "user"
+	RoleAgent      = "agent"
+	RoleVault      = "vault"
+	RoleSubAccount = "subAccount"
+)
+
+// UserRole tells what kind of account an address is. Data.User is set for
+// agents, the user they trade for, and Data.Master for sub-accounts.
+type UserRole struct {
+	Role string `json:"role"`
+	Data *struct {
+		User   string `json:"user,omitempty"`
+		Master string `json:"master,omitempty"`
+	} `json:"data,omitempty"`
+}
+
+// PortfolioPoint is one sample of a portfolio history
+type PortfolioPoint struct {
+	Time  int64
+	Value utils.DecimalString
+}
+
+// UnmarshalJSON decodes the [time, "value"] pair the API sends
+func (p *PortfolioPoint) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 2 {
+		return fmt.Errorf("portfolio point: expected 2 elements, got %d", len(raw))
+	}
+	if err := json.Unmarshal(raw[0], &p.Time); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[1], &p.Value)
+}
+
+// PortfolioHistory is the account value and pnl history over one period
+type PortfolioHistory struct {
+	AccountValueHistory []PortfolioPoint    `json:"accountValueHistory"`
+	PnlHistory          []PortfolioPoint    `json:"pnlHistory"`
+	Vlm                 utils.DecimalString `json:"vlm"`
+}
+
+// Portfolio maps a period, such as "day", "week", "month", "allTime" or their
+// perp-only "perpDay" variants, to its history
+type Portfolio map[string]PortfolioHistory
+
+// UnmarshalJSON decodes the list of [period, history] pairs the API sends
+func (p *Portfolio) UnmarshalJSON(data []byte) error {
+	var pairs [][]json.RawMessage
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	portfolio := make(Portfolio, len(pairs))
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			return fmt.Errorf("portfolio: expected [period, history] pairs, got %d elements", len(pair))
+		}
+		var period string
+		if err := json.Unmarshal(pair[0], &period); err != nil {
+			return err
+		}
+		var history PortfolioHistory
+		if err := json.Unmarshal(pair[1], &history); err != nil {
+			return err
+		}
+		portfolio[period] = history
+	}
+	*p = portfolio
+	return nil
+}
+
+// UserRateLimit is the address-based request budget of a user. Every address
+// starts with 10000 requests and earns one more per USDC traded.
+type UserRateLimit struct {
+	CumVlm           utils.DecimalString `json:"cumVlm"`
+	NRequestsUsed    int                 `json:"nRequestsUsed"`
+	NRequestsCap     int                 `json:"nRequestsCap"`
+	NRequestsSurplus int                 `json:"nRequestsSurplus"`
+}
+
+// Remaining returns the number of exchange requests left before the address
+// is throttled
+func (l UserRateLimit) Remaining() int {
+	remaining := l.NRequestsCap + l.NRequestsSurplus - l.NRequestsUsed
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// ExtraAgent is an agent wallet approved by a user
+type ExtraAgent struct {
+	Address    string `json:"address"`
+	Name       string `json:"name"`
+	ValidUntil int64  `json:"validUntil"` // Expiry in milliseconds
+}
+
+// PreTransferCheck describes the destination of a transfer before sending it
+type PreTransferCheck struct {
+	Fee           utils.DecimalString `json:"fee"` // Charged when the destination does not exist yet
+	IsSanctioned  bool                `json:"isSanctioned"`
+	UserExists    bool                `json:"userExists"`
+	UserHasSentTx bool                `json:"userHasSentTx"`
+}
+
+// PerpDexLimits are the open interest and transfer limits of a
+// builder-deployed perp dex
+type PerpDexLimits struct {
+	TotalOiCap     utils.DecimalString `json:"totalOiCap"`
+	OiSzCapPerPerp utils.DecimalString `json:"oiSzCapPerPerp"`
+	MaxTransferNtl utils.DecimalString `json:"maxTransferNtl"`
+	CoinToOiCap    [][2]string         `json:"coinToOiCap"` // [coin, notional cap] pairs
+}
+
+// DelegatorSummary is a user's staked, unstaked and pending withdrawal HYPE
+type DelegatorSummary struct {
+	Delegated              utils.DecimalString `json:"delegated"`
+	Undelegated            utils.DecimalString `json:"undelegated"`
+	TotalPendingWithdrawal utils.DecimalString `json:"totalPendingWithdrawal"`
+	NPendingWithdrawals    int                 `json:"nPendingWithdrawals"`
+}
+
+// Delegation is a user's stake with one validator
+type Delegation struct {
+	Validator            string              `json:"validator"`
+	Amount               utils.DecimalString `json:"amount"`
+	LockedUntilTimestamp int64               `json:"lockedUntilTimestamp"`
+}
+
+// DelegatorReward is a staking reward paid to a user
+type DelegatorReward struct {
+	Time        int64               `json:"time"`
+	Source      string              `json:"source"` // "delegation" or "commission"
+	TotalAmount utils.DecimalString `json:"totalAmount"`
+}
+
+// DelegatorEvent is a change to a user's stake. Exactly one of the delta
+// fields is set.
+type DelegatorEvent struct {
+	Time  int64  `json:"time"`
+	Hash  string `json:"hash"`
+	Delta struct {
+		// Delegate is a delegation to, or undelegation from, a validator
+		Delegate *struct {
+			Validator    string              `json:"validator"`
+			Amount       utils.DecimalString `json:"amount"`
+			IsUndelegate bool                `json:"isUndelegate"`
+		} `json:"delegate,omitempty"`
+		// CDeposit moves HYPE from spot to the staking balance
+		CDeposit *struct {
+			Amount utils.DecimalString `json:"amount"`
+		} `json:"cDeposit,omitempty"`
+		// Withdrawal moves HYPE from the staking balance back to spot
+		Withdrawal *struct {
+			Amount utils.DecimalString `json:"amount"`
+			Phase  string              `json:"phase"` // "initiated" or "finalized"
+		} `json:"withdrawal,omitempty"`
+	} `json:"delta"`
+}
+
+// ValidatorStat is a validator's performance over one period
+type ValidatorStat struct {
+	UptimeFraction utils.DecimalString `json:"uptimeFraction"`
+	PredictedApr   utils.DecimalString `json:"predictedApr"`
+	NSamples       int                 `json:"nSamples"`
+}
+
+// ValidatorStats maps a period, "day", "week" or "month", to its stats
+type ValidatorStats map[string]ValidatorStat
+
+// UnmarshalJSON decodes the list of [period, stat] pairs the API sends
+func (v *ValidatorStats) UnmarshalJSON(data []byte) error {
+	var pairs [][]json.RawMessage
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return err
+	}
+	stats := make(ValidatorStats, len(pairs))
+	for _, pair := range pairs {
+		if len(pair) != 2 {
+			return fmt.Errorf("validator stats: expected [period, stat] pairs, got %d elements", len(pair))
+		}
+		var period string
+		if err := json.Unmarshal(pair[0], &period); err != nil {
+			return err
+		}
+		var stat ValidatorStat
+		if err := json.Unmarshal(pair[1], &stat); err != nil {
+			return err
+		}
+		stats[period] = stat
+	}
+	*v = stats
+	return nil
+}
+
+// ValidatorSummary describes a validator and its recent performance
+type ValidatorSummary struct {
+	Validator       string              `json:"validator"`
+	Signer          string              `json:"signer"`
+	Name            string              `json:"name"`
+	Description     string              `json:"description"`
+	NRecentBlocks   int                 `json:"nRecentBlocks"`
+	Stake           int64               `json:"stake"` // In units of 1e-8 HYPE
+	IsJailed        bool                `json:"isJailed"`
+	UnjailableAfter *int64              `json:"unjailableAfter"`
+	IsActive        bool                `json:"isActive"`
+	Commission      utils.DecimalString `json:"commission"`
+	Stats           ValidatorStats      `json:"stats"`
+}
+
+// FundingPageLimit is the most entries a funding history request returns;
+// a full page means there may be more after its last entry
+const FundingPageLimit = 500
+
+// FundingHistoryEntry is a coin's funding rate at one funding time
+type FundingHistoryEntry struct {
+	Coin        string              `json:"coin"`
+	FundingRate utils.DecimalString `json:"fundingRate"`
+	Premium     utils.DecimalString `json:"premium"`
+	Time        int64               `json:"time"`
+}
+
+// FundingHistory is one page of FundingHistoryEntry, oldest first
+type FundingHistory []FundingHistoryEntry
+
+// NextStartTime returns the time of the last entry if the page is full, to be
+// passed as the startTime of the next request; entries at exactly that time
+// are returned again
+func (h FundingHistory) NextStartTime() (int64, bool) {
+	if len(h) < FundingPageLimit {
+		return 0, false
+	}
+	return h[len(h)-1].Time, true
+}
+
+// UserFundingDelta is a funding payment made or received by a user for one
+// position. The API nests everything but Time and Hash in a "delta" object.
+type UserFundingDelta struct {
+	Coin        string
+	FundingRate utils.DecimalString
+	Szi         utils.DecimalString
+	Usdc        utils.DecimalString
+	Time        int64
+	Hash        string
+}
+
+// UnmarshalJSON flattens the nested delta object
+func (d *UserFundingDelta) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Delta struct {
+			Coin        string              `json:"coin"`
+			FundingRate utils.DecimalString `json:"fundingRate"`
+			Szi         utils.DecimalString `json:"szi"`
+			Usdc        utils.DecimalString `json:"usdc"`
+		} `json:"delta"`
+		Time int64  `json:"time"`
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*d = UserFundingDelta{
+		Coin:        raw.Delta.Coin,
+		FundingRate: raw.Delta.FundingRate,
+		Szi:         raw.Delta.Szi,
+		Usdc:        raw.Delta.Usdc,
+		Time:        raw.Time,
+		Hash:        raw.Hash,
+	}
+	return nil
+}
+
+// UserFundingHistory is one page of UserFundingDelta, oldest first
+type UserFundingHistory []UserFundingDelta
+
+// NextStartTime returns the time of the last entry if the page is full, to be
+// passed as the startTime of the next request; entries at exactly that time
+// are returned again
+func (h UserFundingHistory) NextStartTime() (int64, bool) {
+	if len(h) < FundingPageLimit {
+		return 0, false
+	}
+	return h[len(h)-1].Time, true
+}
+
+// Info represents the Info API client. Its asset lookups are safe for
+// concurrent use, including while metadata is refreshed.
 type Info struct {
 	*API
-	wsManager           *WebSocketManager
-	coinToAsset         map[string]int
-	nameToCoins         map[string]string
-	assetToSzDecimals   map[int]int
+	wsManager       *WebSocketManager
+	wsOptions       []WSOptions // Passed on to the WebSocket manager
+	explorer        *API
+	perpDexs        []string
+	perpDexToOffset map[string]int
+	assetsMu        sync.RWMutex
+	assets          *assetMaps
+	refreshMu       sync.Mutex
+	autoRefreshMeta atomic.Bool
+	autoRefreshMu   sync.Mutex
+	lastAutoRefresh time.Time
+}
+
+// NewInfo creates a new Info client instance. WebSocket options, if given,
+// configure how its WebSocket manager connects.
+func NewInfo(baseURL string, skipWS bool, meta *Meta, spotMeta *SpotMeta, perpDexs []string, timeout time.Duration, wsOpts ...WSOptions) (*Info, error) {
+	opts := make([]ClientOption, len(wsOpts))
+	for i, wsOpt := range wsOpts {
+		opts[i] = WithWSOptions(wsOpt)
+	}
+	return NewInfoOpts(baseURL, skipWS, meta, spotMeta, perpDexs, timeout, opts...)
 }
 
-// NewInfo creates a new Info client instance
-func NewInfo(baseURL string, skipWS bool, meta *Meta, spotMeta *SpotMeta, perpDexs []string, timeout time.Duration) (*Info, error) {
+// NewInfoOpts is like NewInfo but configured with client options, such as
+// WithHTTPClient or WithTransport, which apply to every /info request and
+// to the explorer.
+func NewInfoOpts(baseURL string, skipWS bool, meta *Meta, spotMeta *SpotMeta, perpDexs []string, timeout time.Duration, opts ...ClientOption) (*Info, error) {
 	if baseURL == "" {
 		baseURL = utils.MainnetAPIURL
 	}
+	options := newClientOptions(opts)
 	
-	api := NewAPI(baseURL, timeout)
+	api := options.newAPI(baseURL, timeout)
 	info := &Info{
-		API:               api,
-		coinToAsset:       make(map[string]int),
-		nameToCoins:       make(map[string]string),
-		assetToSzDecimals: make(map[int]int),
+		API:       api,
+		explorer:  api.withBaseURL(explorerURL(baseURL)),
+		wsOptions: options.wsOptions,
 	}
 	
 	// Initialize WebSocket manager if not skipped
 	if !skipWS {
-		info.wsManager = NewWebSocketManager(baseURL)
+		info.wsManager = NewWebSocketManager(baseURL, options.wsOptions...)
 		if err := info.wsManager.Start(); err != nil {
 			return nil, fmt.Errorf("failed to start WebSocket manager: %w", err)
 		}
@@ -101,24 +578,6 @@ func NewInfo(baseURL string, skipWS bool, meta *Meta, spotMeta *SpotMeta, perpDe
 		}
 	}
 	
-	// Process spot assets (start at 10000)
-	for _, spotInfo := range spotMeta.Universe {
-		asset := spotInfo.Index + 10000
-		info.coinToAsset[spotInfo.Name] = asset
-		info.nameToCoins[spotInfo.Name] = spotInfo.Name
-		
-		baseToken := spotInfo.Tokens[0]
-		quoteToken := spotInfo.Tokens[1]
-		baseInfo := spotMeta.Tokens[baseToken]
-		quoteInfo := spotMeta.Tokens[quoteToken]
-		info.assetToSzDecimals[asset] = baseInfo.SzDecimals
-		
-		name := fmt.Sprintf("%s/%s", baseInfo.Name, quoteInfo.Name)
-		if _, exists := info.nameToCoins[name]; !exists {
-			info.nameToCoins[name] = spotInfo.Name
-		}
-	}
-	
 	// Process perp dexs
 	perpDexToOffset := map[string]int{"": 0}
 	if perpDexs == nil {
@@ -140,44 +599,84 @@ func NewInfo(baseURL string, skipWS bool, meta *Meta, spotMeta *SpotMeta, perpDe
 			}
 		}
 	}
+	info.perpDexs = perpDexs
+	info.perpDexToOffset = perpDexToOffset
 	
+	perpMetas := make(map[string]Meta, len(perpDexs))
 	for _, perpDex := range perpDexs {
-		offset := perpDexToOffset[perpDex]
 		if perpDex == "" && meta != nil {
-			info.setPerpMeta(*meta, offset)
+			perpMetas[perpDex] = *meta
 		} else {
 			freshMeta, err := info.Meta(perpDex)
 			if err != nil {
 				return nil, fmt.Errorf("failed to get meta for dex %s: %w", perpDex, err)
 			}
-			info.setPerpMeta(*freshMeta, offset)
+			perpMetas[perpDex] = *freshMeta
 		}
 	}
 	
+	info.assets = newAssetMaps(spotMeta, perpMetas, perpDexs, perpDexToOffset)
 	return info, nil
 }
 
-// setPerpMeta sets perp metadata with offset
-func (i *Info) setPerpMeta(meta Meta, offset int) {
-	for asset, assetInfo := range meta.Universe {
-		assetID := asset + offset
-		i.coinToAsset[assetInfo.Name] = assetID
-		i.nameToCoins[assetInfo.Name] = assetInfo.Name
-		i.assetToSzDecimals[assetID] = assetInfo.SzDecimals
+// ConnectWebSocket starts the WebSocket connection of a client created with
+// skipWS, or restarts it after DisconnectWebSocket. A non-empty wsURL is
+// connected to instead of the endpoint derived from the base URL.
+func (i *Info) ConnectWebSocket(wsURL string) error {
+	if i.wsManager == nil {
+		i.wsManager = NewWebSocketManager(i.baseURL, i.wsOptions...)
+		i.wsManager.SetLogger(i.logger)
+	}
+	if wsURL != "" {
+		i.wsManager.SetURL(wsURL)
+	}
+	if err := i.wsManager.Start(); err != nil {
+		return fmt.Errorf("failed to start WebSocket manager: %w", err)
+	}
+	return nil
+}
+
+// SetLogger sets the logger of the client and its WebSocket manager,
+// DefaultLogger by default. A nil logger discards everything.
+func (i *Info) SetLogger(logger *slog.Logger) {
+	i.API.SetLogger(logger)
+	i.explorer.SetLogger(logger)
+	if i.wsManager != nil {
+		i.wsManager.SetLogger(logger)
 	}
 }
 
-// DisconnectWebSocket disconnects the WebSocket connection
+// WebSocketManager returns the client's WebSocket manager, or nil when it was
+// created with skipWS and never connected
+func (i *Info) WebSocketManager() *WebSocketManager {
+	return i.wsManager
+}
+
+// DisconnectWebSocket closes the WebSocket connection gracefully, giving
+// callbacks up to DefaultCloseTimeout to handle the messages queued for them.
+// See WebSocketManager.Close.
 func (i *Info) DisconnectWebSocket() error {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultCloseTimeout)
+	defer cancel()
+	return i.DisconnectWebSocketCtx(ctx)
+}
+
+// DisconnectWebSocketCtx is like DisconnectWebSocket but waits for callbacks
+// until ctx is done instead
+func (i *Info) DisconnectWebSocketCtx(ctx context.Context) error {
 	if i.wsManager == nil {
 		return fmt.Errorf("cannot disconnect WebSocket since skip_ws was used")
 	}
-	i.wsManager.Stop()
-	return nil
+	return i.wsManager.Close(ctx)
 }
 
 // UserState retrieves trading details about a user
 func (i *Info) UserState(address string, dex string) (interface{}, error) {
+	return i.UserStateCtx(context.Background(), address, dex)
+}
+
+// UserStateCtx is like UserState but carries ctx to the request
+func (i *Info) UserStateCtx(ctx context.Context, address string, dex string) (interface{}, error) {
 	if dex == "" {
 		dex = ""
 	}
@@ -186,20 +685,103 @@ func (i *Info) UserState(address string, dex string) (interface{}, error) {
 		"user": address,
 		"dex":  dex,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
+}
+
+// ClearinghouseState retrieves a user's perp positions and margin on a perp
+// dex, "" being the default one
+func (i *Info) ClearinghouseState(address string, dex string) (*ClearinghouseState, error) {
+	return i.ClearinghouseStateCtx(context.Background(), address, dex)
+}
+
+// ClearinghouseStateCtx is like ClearinghouseState but carries ctx to the request
+func (i *Info) ClearinghouseStateCtx(ctx context.Context, address string, dex string) (*ClearinghouseState, error) {
+	payload := map[string]interface{}{
+		"type": "clearinghouseState",
+		"user": address,
+		"dex":  dex,
+	}
+	var state ClearinghouseState
+	if err := i.PostInto(ctx, "/info", payload, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
 }
 
 // SpotUserState retrieves spot trading details about a user
 func (i *Info) SpotUserState(address string) (interface{}, error) {
+	return i.SpotUserStateCtx(context.Background(), address)
+}
+
+// SpotUserStateCtx is like SpotUserState but carries ctx to the request
+func (i *Info) SpotUserStateCtx(ctx context.Context, address string) (interface{}, error) {
+	payload := map[string]interface{}{
+		"type": "spotClearinghouseState",
+		"user": address,
+	}
+	return i.PostWithContext(ctx, "/info", payload)
+}
+
+// SpotClearinghouseState retrieves a user's spot balances
+func (i *Info) SpotClearinghouseState(address string) (*SpotClearinghouseState, error) {
+	return i.SpotClearinghouseStateCtx(context.Background(), address)
+}
+
+// SpotClearinghouseStateCtx is like SpotClearinghouseState but carries ctx to the request
+func (i *Info) SpotClearinghouseStateCtx(ctx context.Context, address string) (*SpotClearinghouseState, error) {
 	payload := map[string]interface{}{
 		"type": "spotClearinghouseState",
 		"user": address,
 	}
-	return i.Post("/info", payload)
+	var state SpotClearinghouseState
+	if err := i.PostInto(ctx, "/info", payload, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SpotBalanceTotal returns a balance's total as a float, rounded to the
+// token's wei decimals from the spot metadata
+func (i *Info) SpotBalanceTotal(balance SpotBalance) (float64, error) {
+	token, err := i.spotToken(balance.Token)
+	if err != nil {
+		return 0, err
+	}
+	total, err := strconv.ParseFloat(balance.Total, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s balance %q: %w", balance.Coin, balance.Total, err)
+	}
+	multiplier := math.Pow(10, float64(token.WeiDecimals))
+	return math.Round(total*multiplier) / multiplier, nil
+}
+
+// SpotToWei converts an amount of a spot token, looked up by name in the spot
+// metadata, to wei; see utils.SpotToWei
+func (i *Info) SpotToWei(token string, amount string) (*big.Int, error) {
+	tokenInfo, err := i.spotTokenByName(token)
+	if err != nil {
+		return nil, err
+	}
+	return utils.SpotToWei(amount, tokenInfo.WeiDecimals)
+}
+
+// WeiToSpot converts an amount of wei of a spot token, looked up by name in
+// the spot metadata, to a decimal token amount; see utils.WeiToSpot
+func (i *Info) WeiToSpot(token string, wei *big.Int) (string, error) {
+	tokenInfo, err := i.spotTokenByName(token)
+	if err != nil {
+		return "", err
+	}
+	return utils.WeiToSpot(wei, tokenInfo.WeiDecimals), nil
 }
 
 // OpenOrders retrieves a user's open orders
 func (i *Info) OpenOrders(address string, dex string) (interface{}, error) {
+	return i.OpenOrdersCtx(context.Background(), address, dex)
+}
+
+// OpenOrdersCtx is like OpenOrders but carries ctx to the request
+func (i *Info) OpenOrdersCtx(ctx context.Context, address string, dex string) (interface{}, error) {
 	if dex == "" {
 		dex = ""
 	}
@@ -208,11 +790,36 @@ func (i *Info) OpenOrders(address string, dex string) (interface{}, error) {
 		"user": address,
 		"dex":  dex,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
+}
+
+// UserOpenOrders retrieves a user's open orders on a perp dex, "" being the
+// default one, as typed structs
+func (i *Info) UserOpenOrders(address string, dex string) ([]utils.OrderInfo, error) {
+	return i.UserOpenOrdersCtx(context.Background(), address, dex)
+}
+
+// UserOpenOrdersCtx is like UserOpenOrders but carries ctx to the request
+func (i *Info) UserOpenOrdersCtx(ctx context.Context, address string, dex string) ([]utils.OrderInfo, error) {
+	payload := map[string]interface{}{
+		"type": "openOrders",
+		"user": address,
+		"dex":  dex,
+	}
+	var orders []utils.OrderInfo
+	if err := i.PostInto(ctx, "/info", payload, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
 
 // FrontendOpenOrders retrieves a user's open orders with additional frontend info
 func (i *Info) FrontendOpenOrders(address string, dex string) (interface{}, error) {
+	return i.FrontendOpenOrdersCtx(context.Background(), address, dex)
+}
+
+// FrontendOpenOrdersCtx is like FrontendOpenOrders but carries ctx to the request
+func (i *Info) FrontendOpenOrdersCtx(ctx context.Context, address string, dex string) (interface{}, error) {
 	if dex == "" {
 		dex = ""
 	}
@@ -221,11 +828,16 @@ func (i *Info) FrontendOpenOrders(address string, dex string) (interface{}, erro
 		"user": address,
 		"dex":  dex,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // AllMids retrieves all mids for all actively traded coins
 func (i *Info) AllMids(dex string) (interface{}, error) {
+	return i.AllMidsCtx(context.Background(), dex)
+}
+
+// AllMidsCtx is like AllMids but carries ctx to the request
+func (i *Info) AllMidsCtx(ctx context.Context, dex string) (interface{}, error) {
 	if dex == "" {
 		dex = ""
 	}
@@ -233,20 +845,57 @@ func (i *Info) AllMids(dex string) (interface{}, error) {
 		"type": "allMids",
 		"dex":  dex,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
+}
+
+// FillsOptions are optional parameters of fill queries
+type FillsOptions struct {
+	// AggregateByTime merges the partial fills of an order that happened at
+	// the same time into one fill, which has no tid
+	AggregateByTime bool
+}
+
+// apply adds the options that are set to a request payload
+func (o FillsOptions) apply(payload map[string]interface{}) {
+	if o.AggregateByTime {
+		payload["aggregateByTime"] = true
+	}
+}
+
+// fillsOptions returns the options passed to a fill query, if any
+func fillsOptions(opts []FillsOptions) FillsOptions {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return FillsOptions{}
 }
 
 // UserFills retrieves a given user's fills
-func (i *Info) UserFills(address string) (interface{}, error) {
+func (i *Info) UserFills(address string, opts ...FillsOptions) ([]utils.Fill, error) {
+	return i.UserFillsCtx(context.Background(), address, opts...)
+}
+
+// UserFillsCtx is like UserFills but carries ctx to the request
+func (i *Info) UserFillsCtx(ctx context.Context, address string, opts ...FillsOptions) ([]utils.Fill, error) {
 	payload := map[string]interface{}{
 		"type": "userFills",
 		"user": address,
 	}
-	return i.Post("/info", payload)
+	fillsOptions(opts).apply(payload)
+	var fills []utils.Fill
+	if err := i.PostInto(ctx, "/info", payload, &fills); err != nil {
+		return nil, err
+	}
+	return fills, nil
 }
 
 // UserFillsByTime retrieves a given user's fills by time
-func (i *Info) UserFillsByTime(address string, startTime int64, endTime *int64) (interface{}, error) {
+func (i *Info) UserFillsByTime(address string, startTime int64, endTime *int64, opts ...FillsOptions) ([]utils.Fill, error) {
+	return i.UserFillsByTimeCtx(context.Background(), address, startTime, endTime, opts...)
+}
+
+// UserFillsByTimeCtx is like UserFillsByTime but carries ctx to the request
+func (i *Info) UserFillsByTimeCtx(ctx context.Context, address string, startTime int64, endTime *int64, opts ...FillsOptions) ([]utils.Fill, error) {
 	payload := map[string]interface{}{
 		"type":      "userFillsByTime",
 		"user":      address,
@@ -255,11 +904,40 @@ func (i *Info) UserFillsByTime(address string, startTime int64, endTime *int64)
 	if endTime != nil {
 		payload["endTime"] = *endTime
 	}
-	return i.Post("/info", payload)
+	fillsOptions(opts).apply(payload)
+	var fills []utils.Fill
+	if err := i.PostInto(ctx, "/info", payload, &fills); err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// HistoricalOrders retrieves a user's most recent orders with their final or
+// current status, including filled, canceled and rejected ones
+func (i *Info) HistoricalOrders(address string) ([]utils.OrderStatusEntry, error) {
+	return i.HistoricalOrdersCtx(context.Background(), address)
+}
+
+// HistoricalOrdersCtx is like HistoricalOrders but carries ctx to the request
+func (i *Info) HistoricalOrdersCtx(ctx context.Context, address string) ([]utils.OrderStatusEntry, error) {
+	payload := map[string]interface{}{
+		"type": "historicalOrders",
+		"user": address,
+	}
+	var orders []utils.OrderStatusEntry
+	if err := i.PostInto(ctx, "/info", payload, &orders); err != nil {
+		return nil, err
+	}
+	return orders, nil
 }
 
 // Meta retrieves exchange perp metadata
 func (i *Info) Meta(dex string) (*Meta, error) {
+	return i.MetaCtx(context.Background(), dex)
+}
+
+// MetaCtx is like Meta but carries ctx to the request
+func (i *Info) MetaCtx(ctx context.Context, dex string) (*Meta, error) {
 	if dex == "" {
 		dex = ""
 	}
@@ -267,28 +945,9 @@ func (i *Info) Meta(dex string) (*Meta, error) {
 		"type": "meta",
 		"dex":  dex,
 	}
-	result, err := i.Post("/info", payload)
-	if err != nil {
-		return nil, err
-	}
-	
-	// Convert interface{} to Meta struct
 	var meta Meta
-	if resultMap, ok := result.(map[string]interface{}); ok {
-		if universe, ok := resultMap["universe"].([]interface{}); ok {
-			for _, assetInterface := range universe {
-				if assetMap, ok := assetInterface.(map[string]interface{}); ok {
-					assetInfo := AssetInfo{}
-					if name, ok := assetMap["name"].(string); ok {
-						assetInfo.Name = name
-					}
-					if szDecimals, ok := assetMap["szDecimals"].(float64); ok {
-						assetInfo.SzDecimals = int(szDecimals)
-					}
-					meta.Universe = append(meta.Universe, assetInfo)
-				}
-			}
-		}
+	if err := i.PostInto(ctx, "/info", payload, &meta); err != nil {
+		return nil, err
 	}
 	
 	return &meta, nil
@@ -296,26 +955,41 @@ func (i *Info) Meta(dex string) (*Meta, error) {
 
 // MetaAndAssetCtxs retrieves exchange MetaAndAssetCtxs
 func (i *Info) MetaAndAssetCtxs() (interface{}, error) {
+	return i.MetaAndAssetCtxsCtx(context.Background())
+}
+
+// MetaAndAssetCtxsCtx is like MetaAndAssetCtxs but carries ctx to the request
+func (i *Info) MetaAndAssetCtxsCtx(ctx context.Context) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "metaAndAssetCtxs",
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // PerpDexs retrieves perp dexs
 func (i *Info) PerpDexs() (interface{}, error) {
+	return i.PerpDexsCtx(context.Background())
+}
+
+// PerpDexsCtx is like PerpDexs but carries ctx to the request
+func (i *Info) PerpDexsCtx(ctx context.Context) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "perpDexs",
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // SpotMeta retrieves exchange spot metadata
 func (i *Info) SpotMeta() (*SpotMeta, error) {
+	return i.SpotMetaCtx(context.Background())
+}
+
+// SpotMetaCtx is like SpotMeta but carries ctx to the request
+func (i *Info) SpotMetaCtx(ctx context.Context) (*SpotMeta, error) {
 	payload := map[string]interface{}{
 		"type": "spotMeta",
 	}
-	result, err := i.Post("/info", payload)
+	result, err := i.PostWithContext(ctx, "/info", payload)
 	if err != nil {
 		return nil, err
 	}
@@ -373,8 +1047,13 @@ func (i *Info) SpotMeta() (*SpotMeta, error) {
 					if isCanonical, ok := tokenMap["isCanonical"].(bool); ok {
 						tokenInfo.IsCanonical = isCanonical
 					}
+					// evmContract is either a bare address or {"address": ..., "evm_extra_wei_decimals": ...}
 					if evmContract, ok := tokenMap["evmContract"].(string); ok {
 						tokenInfo.EvmContract = &evmContract
+					} else if evmContractMap, ok := tokenMap["evmContract"].(map[string]interface{}); ok {
+						if address, ok := evmContractMap["address"].(string); ok {
+							tokenInfo.EvmContract = &address
+						}
 					}
 					if fullName, ok := tokenMap["fullName"].(string); ok {
 						tokenInfo.FullName = &fullName
@@ -390,15 +1069,25 @@ func (i *Info) SpotMeta() (*SpotMeta, error) {
 
 // SpotMetaAndAssetCtxs retrieves exchange spot asset contexts
 func (i *Info) SpotMetaAndAssetCtxs() (interface{}, error) {
+	return i.SpotMetaAndAssetCtxsCtx(context.Background())
+}
+
+// SpotMetaAndAssetCtxsCtx is like SpotMetaAndAssetCtxs but carries ctx to the request
+func (i *Info) SpotMetaAndAssetCtxsCtx(ctx context.Context) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "spotMetaAndAssetCtxs",
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // FundingHistory retrieves funding history for a given coin
-func (i *Info) FundingHistory(name string, startTime int64, endTime *int64) (interface{}, error) {
-	coin, exists := i.nameToCoins[name]
+func (i *Info) FundingHistory(name string, startTime int64, endTime *int64) (FundingHistory, error) {
+	return i.FundingHistoryCtx(context.Background(), name, startTime, endTime)
+}
+
+// FundingHistoryCtx is like FundingHistory but carries ctx to the request
+func (i *Info) FundingHistoryCtx(ctx context.Context, name string, startTime int64, endTime *int64) (FundingHistory, error) {
+	coin, _, exists := i.coinForName(name)
 	if !exists {
 		return nil, fmt.Errorf("coin not found for name: %s", name)
 	}
@@ -411,11 +1100,20 @@ func (i *Info) FundingHistory(name string, startTime int64, endTime *int64) (int
 	if endTime != nil {
 		payload["endTime"] = *endTime
 	}
-	return i.Post("/info", payload)
+	var history FundingHistory
+	if err := i.PostInto(ctx, "/info", payload, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
 // UserFundingHistory retrieves a user's funding history
-func (i *Info) UserFundingHistory(user string, startTime int64, endTime *int64) (interface{}, error) {
+func (i *Info) UserFundingHistory(user string, startTime int64, endTime *int64) (UserFundingHistory, error) {
+	return i.UserFundingHistoryCtx(context.Background(), user, startTime, endTime)
+}
+
+// UserFundingHistoryCtx is like UserFundingHistory but carries ctx to the request
+func (i *Info) UserFundingHistoryCtx(ctx context.Context, user string, startTime int64, endTime *int64) (UserFundingHistory, error) {
 	payload := map[string]interface{}{
 		"type":      "userFunding",
 		"user":      user,
@@ -424,28 +1122,325 @@ func (i *Info) UserFundingHistory(user string, startTime int64, endTime *int64)
 	if endTime != nil {
 		payload["endTime"] = *endTime
 	}
-	return i.Post("/info", payload)
+	var history UserFundingHistory
+	if err := i.PostInto(ctx, "/info", payload, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
-// L2Snapshot retrieves L2 snapshot for a given coin
-func (i *Info) L2Snapshot(name string) (interface{}, error) {
-	coin, exists := i.nameToCoins[name]
-	if !exists {
-		return nil, fmt.Errorf("coin not found for name: %s", name)
-	}
-	
+// TwapHistory retrieves a user's TWAP orders, both running and completed
+func (i *Info) TwapHistory(user string) ([]TwapHistoryEntry, error) {
+	return i.TwapHistoryCtx(context.Background(), user)
+}
+
+// TwapHistoryCtx is like TwapHistory but carries ctx to the request
+func (i *Info) TwapHistoryCtx(ctx context.Context, user string) ([]TwapHistoryEntry, error) {
 	payload := map[string]interface{}{
-		"type": "l2Book",
-		"coin": coin,
+		"type": "twapHistory",
+		"user": user,
 	}
-	return i.Post("/info", payload)
+	var history []TwapHistoryEntry
+	if err := i.PostInto(ctx, "/info", payload, &history); err != nil {
+		return nil, fmt.Errorf("failed to fetch twap history: %w", err)
+	}
+	return history, nil
 }
 
-// CandlesSnapshot retrieves candles snapshot for a given coin
-func (i *Info) CandlesSnapshot(name string, interval string, startTime int64, endTime int64) (interface{}, error) {
-	coin, exists := i.nameToCoins[name]
-	if !exists {
-		return nil, fmt.Errorf("coin not found for name: %s", name)
+// ActiveTwaps retrieves a user's running TWAP orders; their TwapID can be
+// passed to Exchange.TwapCancel
+func (i *Info) ActiveTwaps(user string) ([]TwapHistoryEntry, error) {
+	return i.ActiveTwapsCtx(context.Background(), user)
+}
+
+// ActiveTwapsCtx is like ActiveTwaps but carries ctx to the request
+func (i *Info) ActiveTwapsCtx(ctx context.Context, user string) ([]TwapHistoryEntry, error) {
+	history, err := i.TwapHistoryCtx(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	var active []TwapHistoryEntry
+	for _, entry := range history {
+		if entry.Status.Status == "activated" && entry.TwapID != nil {
+			active = append(active, entry)
+		}
+	}
+	return active, nil
+}
+
+// UserTwapSliceFills retrieves the fills of a user's TWAP order slices, most
+// recent first
+func (i *Info) UserTwapSliceFills(user string) ([]TwapSliceFill, error) {
+	return i.UserTwapSliceFillsCtx(context.Background(), user)
+}
+
+// UserTwapSliceFillsCtx is like UserTwapSliceFills but carries ctx to the request
+func (i *Info) UserTwapSliceFillsCtx(ctx context.Context, user string) ([]TwapSliceFill, error) {
+	payload := map[string]interface{}{
+		"type": "userTwapSliceFills",
+		"user": user,
+	}
+	var fills []TwapSliceFill
+	if err := i.PostInto(ctx, "/info", payload, &fills); err != nil {
+		return nil, err
+	}
+	return fills, nil
+}
+
+// VaultDetails retrieves a vault's details. If user is set, FollowerState
+// holds that user's position in the vault.
+func (i *Info) VaultDetails(vaultAddress string, user *string) (*VaultDetails, error) {
+	return i.VaultDetailsCtx(context.Background(), vaultAddress, user)
+}
+
+// VaultDetailsCtx is like VaultDetails but carries ctx to the request
+func (i *Info) VaultDetailsCtx(ctx context.Context, vaultAddress string, user *string) (*VaultDetails, error) {
+	payload := map[string]interface{}{
+		"type":         "vaultDetails",
+		"vaultAddress": vaultAddress,
+	}
+	if user != nil {
+		payload["user"] = *user
+	}
+	var details VaultDetails
+	if err := i.PostInto(ctx, "/info", payload, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// UserVaultEquities retrieves a user's equity in every vault they deposited into
+func (i *Info) UserVaultEquities(user string) ([]VaultEquity, error) {
+	return i.UserVaultEquitiesCtx(context.Background(), user)
+}
+
+// UserVaultEquitiesCtx is like UserVaultEquities but carries ctx to the request
+func (i *Info) UserVaultEquitiesCtx(ctx context.Context, user string) ([]VaultEquity, error) {
+	payload := map[string]interface{}{
+		"type": "userVaultEquities",
+		"user": user,
+	}
+	var equities []VaultEquity
+	if err := i.PostInto(ctx, "/info", payload, &equities); err != nil {
+		return nil, err
+	}
+	return equities, nil
+}
+
+// UserRole retrieves whether an address is a user, agent, vault or sub-account
+func (i *Info) UserRole(user string) (*UserRole, error) {
+	return i.UserRoleCtx(context.Background(), user)
+}
+
+// UserRoleCtx is like UserRole but carries ctx to the request
+func (i *Info) UserRoleCtx(ctx context.Context, user string) (*UserRole, error) {
+	payload := map[string]interface{}{
+		"type": "userRole",
+		"user": user,
+	}
+	var role UserRole
+	if err := i.PostInto(ctx, "/info", payload, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Portfolio retrieves a user's account value and pnl history by period
+func (i *Info) Portfolio(user string) (Portfolio, error) {
+	return i.PortfolioCtx(context.Background(), user)
+}
+
+// PortfolioCtx is like Portfolio but carries ctx to the request
+func (i *Info) PortfolioCtx(ctx context.Context, user string) (Portfolio, error) {
+	payload := map[string]interface{}{
+		"type": "portfolio",
+		"user": user,
+	}
+	var portfolio Portfolio
+	if err := i.PostInto(ctx, "/info", payload, &portfolio); err != nil {
+		return nil, err
+	}
+	return portfolio, nil
+}
+
+// UserRateLimit retrieves a user's cumulative volume and address-based
+// request budget. Pass Remaining to RateLimiter.SetAddressBudget to throttle
+// exchange requests before the address is cut off.
+func (i *Info) UserRateLimit(user string) (*UserRateLimit, error) {
+	return i.UserRateLimitCtx(context.Background(), user)
+}
+
+// UserRateLimitCtx is like UserRateLimit but carries ctx to the request
+func (i *Info) UserRateLimitCtx(ctx context.Context, user string) (*UserRateLimit, error) {
+	payload := map[string]interface{}{
+		"type": "userRateLimit",
+		"user": user,
+	}
+	var limit UserRateLimit
+	if err := i.PostInto(ctx, "/info", payload, &limit); err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// MaxBuilderFee retrieves the highest fee, in tenths of basis points, a user
+// approved builder to charge
+func (i *Info) MaxBuilderFee(user string, builder string) (int, error) {
+	return i.MaxBuilderFeeCtx(context.Background(), user, builder)
+}
+
+// MaxBuilderFeeCtx is like MaxBuilderFee but carries ctx to the request
+func (i *Info) MaxBuilderFeeCtx(ctx context.Context, user string, builder string) (int, error) {
+	payload := map[string]interface{}{
+		"type":    "maxBuilderFee",
+		"user":    user,
+		"builder": builder,
+	}
+	var fee int
+	if err := i.PostInto(ctx, "/info", payload, &fee); err != nil {
+		return 0, err
+	}
+	return fee, nil
+}
+
+// ExtraAgents retrieves the agent wallets a user approved and their expiry
+func (i *Info) ExtraAgents(user string) ([]ExtraAgent, error) {
+	return i.ExtraAgentsCtx(context.Background(), user)
+}
+
+// ExtraAgentsCtx is like ExtraAgents but carries ctx to the request
+func (i *Info) ExtraAgentsCtx(ctx context.Context, user string) ([]ExtraAgent, error) {
+	payload := map[string]interface{}{
+		"type": "extraAgents",
+		"user": user,
+	}
+	var agents []ExtraAgent
+	if err := i.PostInto(ctx, "/info", payload, &agents); err != nil {
+		return nil, err
+	}
+	return agents, nil
+}
+
+// PreTransferCheck retrieves whether user, the destination of a transfer
+// from source, exists and what sending to it costs
+func (i *Info) PreTransferCheck(user string, source string) (*PreTransferCheck, error) {
+	return i.PreTransferCheckCtx(context.Background(), user, source)
+}
+
+// PreTransferCheckCtx is like PreTransferCheck but carries ctx to the request
+func (i *Info) PreTransferCheckCtx(ctx context.Context, user string, source string) (*PreTransferCheck, error) {
+	payload := map[string]interface{}{
+		"type":   "preTransferCheck",
+		"user":   user,
+		"source": source,
+	}
+	var check PreTransferCheck
+	if err := i.PostInto(ctx, "/info", payload, &check); err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// PerpsAtOpenInterestCap retrieves the perps that reached their open interest
+// cap and only accept orders reducing it
+func (i *Info) PerpsAtOpenInterestCap() ([]string, error) {
+	return i.PerpsAtOpenInterestCapCtx(context.Background())
+}
+
+// PerpsAtOpenInterestCapCtx is like PerpsAtOpenInterestCap but carries ctx to the request
+func (i *Info) PerpsAtOpenInterestCapCtx(ctx context.Context) ([]string, error) {
+	payload := map[string]interface{}{
+		"type": "perpsAtOpenInterestCap",
+	}
+	var coins []string
+	if err := i.PostInto(ctx, "/info", payload, &coins); err != nil {
+		return nil, err
+	}
+	return coins, nil
+}
+
+// PerpDexLimits retrieves the limits of a builder-deployed perp dex. It
+// returns nil for the default dex, which has none.
+func (i *Info) PerpDexLimits(dex string) (*PerpDexLimits, error) {
+	return i.PerpDexLimitsCtx(context.Background(), dex)
+}
+
+// PerpDexLimitsCtx is like PerpDexLimits but carries ctx to the request
+func (i *Info) PerpDexLimitsCtx(ctx context.Context, dex string) (*PerpDexLimits, error) {
+	payload := map[string]interface{}{
+		"type": "perpDexLimits",
+		"dex":  dex,
+	}
+	var limits *PerpDexLimits
+	if err := i.PostInto(ctx, "/info", payload, &limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// TokenDetails retrieves the details of a spot token, given either its 16
+// byte tokenId or its name in the spot metadata
+func (i *Info) TokenDetails(tokenID string) (*TokenDetails, error) {
+	return i.TokenDetailsCtx(context.Background(), tokenID)
+}
+
+// TokenDetailsCtx is like TokenDetails but carries ctx to the request
+func (i *Info) TokenDetailsCtx(ctx context.Context, tokenID string) (*TokenDetails, error) {
+	if !isTokenID(tokenID) {
+		token, err := i.spotTokenByName(tokenID)
+		if err != nil {
+			return nil, err
+		}
+		tokenID = token.TokenID
+	}
+
+	payload := map[string]interface{}{
+		"type":    "tokenDetails",
+		"tokenId": tokenID,
+	}
+	var details TokenDetails
+	if err := i.PostInto(ctx, "/info", payload, &details); err != nil {
+		return nil, err
+	}
+	return &details, nil
+}
+
+// L2Snapshot retrieves L2 snapshot for a given coin
+func (i *Info) L2Snapshot(name string) (*utils.L2BookData, error) {
+	return i.L2SnapshotCtx(context.Background(), name)
+}
+
+// L2SnapshotCtx is like L2Snapshot but carries ctx to the request
+func (i *Info) L2SnapshotCtx(ctx context.Context, name string) (*utils.L2BookData, error) {
+	coin, _, exists := i.coinForName(name)
+	if !exists {
+		return nil, fmt.Errorf("coin not found for name: %s", name)
+	}
+	
+	payload := map[string]interface{}{
+		"type": "l2Book",
+		"coin": coin,
+	}
+	var snapshot utils.L2BookData
+	if err := i.PostInto(ctx, "/info", payload, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// CandlesSnapshot retrieves candles snapshot for a given coin
+func (i *Info) CandlesSnapshot(name string, interval string, startTime int64, endTime int64) ([]utils.Candle, error) {
+	return i.CandlesSnapshotCtx(context.Background(), name, interval, startTime, endTime)
+}
+
+// CandlesSnapshotCtx is like CandlesSnapshot but carries ctx to the request
+func (i *Info) CandlesSnapshotCtx(ctx context.Context, name string, interval string, startTime int64, endTime int64) ([]utils.Candle, error) {
+	coin, _, exists := i.coinForName(name)
+	if !exists {
+		return nil, fmt.Errorf("coin not found for name: %s", name)
+	}
+	if !utils.ValidCandleInterval(interval) {
+		return nil, fmt.Errorf("unsupported candle interval %q: expected one of %s", interval, strings.Join(utils.CandleIntervals, ", "))
 	}
 	
 	req := map[string]interface{}{
@@ -459,134 +1454,567 @@ func (i *Info) CandlesSnapshot(name string, interval string, startTime int64, en
 		"type": "candleSnapshot",
 		"req":  req,
 	}
-	return i.Post("/info", payload)
+	var candles []utils.Candle
+	if err := i.PostInto(ctx, "/info", payload, &candles); err != nil {
+		return nil, err
+	}
+	return candles, nil
 }
 
 // UserFees retrieves the volume of trading activity associated with a user
 func (i *Info) UserFees(address string) (interface{}, error) {
+	return i.UserFeesCtx(context.Background(), address)
+}
+
+// UserFeesCtx is like UserFees but carries ctx to the request
+func (i *Info) UserFeesCtx(ctx context.Context, address string) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "userFees",
 		"user": address,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // UserStakingSummary retrieves the staking summary associated with a user
-func (i *Info) UserStakingSummary(address string) (interface{}, error) {
+func (i *Info) UserStakingSummary(address string) (*DelegatorSummary, error) {
+	return i.UserStakingSummaryCtx(context.Background(), address)
+}
+
+// UserStakingSummaryCtx is like UserStakingSummary but carries ctx to the request
+func (i *Info) UserStakingSummaryCtx(ctx context.Context, address string) (*DelegatorSummary, error) {
 	payload := map[string]interface{}{
 		"type": "delegatorSummary",
 		"user": address,
 	}
-	return i.Post("/info", payload)
+	var summary DelegatorSummary
+	if err := i.PostInto(ctx, "/info", payload, &summary); err != nil {
+		return nil, err
+	}
+	return &summary, nil
 }
 
 // UserStakingDelegations retrieves the user's staking delegations
-func (i *Info) UserStakingDelegations(address string) (interface{}, error) {
+func (i *Info) UserStakingDelegations(address string) ([]Delegation, error) {
+	return i.UserStakingDelegationsCtx(context.Background(), address)
+}
+
+// UserStakingDelegationsCtx is like UserStakingDelegations but carries ctx to the request
+func (i *Info) UserStakingDelegationsCtx(ctx context.Context, address string) ([]Delegation, error) {
 	payload := map[string]interface{}{
 		"type": "delegations",
 		"user": address,
 	}
-	return i.Post("/info", payload)
+	var delegations []Delegation
+	if err := i.PostInto(ctx, "/info", payload, &delegations); err != nil {
+		return nil, err
+	}
+	return delegations, nil
 }
 
 // UserStakingRewards retrieves the historic staking rewards associated with a user
-func (i *Info) UserStakingRewards(address string) (interface{}, error) {
+func (i *Info) UserStakingRewards(address string) ([]DelegatorReward, error) {
+	return i.UserStakingRewardsCtx(context.Background(), address)
+}
+
+// UserStakingRewardsCtx is like UserStakingRewards but carries ctx to the request
+func (i *Info) UserStakingRewardsCtx(ctx context.Context, address string) ([]DelegatorReward, error) {
 	payload := map[string]interface{}{
 		"type": "delegatorRewards",
 		"user": address,
 	}
-	return i.Post("/info", payload)
+	var rewards []DelegatorReward
+	if err := i.PostInto(ctx, "/info", payload, &rewards); err != nil {
+		return nil, err
+	}
+	return rewards, nil
+}
+
+// DelegatorHistory retrieves a user's delegations, undelegations, deposits
+// to and withdrawals from staking between startTime and endTime, in
+// milliseconds. The API returns the whole history; it is filtered here. A nil
+// endTime means now.
+func (i *Info) DelegatorHistory(user string, startTime int64, endTime *int64) ([]DelegatorEvent, error) {
+	return i.DelegatorHistoryCtx(context.Background(), user, startTime, endTime)
+}
+
+// DelegatorHistoryCtx is like DelegatorHistory but carries ctx to the request
+func (i *Info) DelegatorHistoryCtx(ctx context.Context, user string, startTime int64, endTime *int64) ([]DelegatorEvent, error) {
+	payload := map[string]interface{}{
+		"type": "delegatorHistory",
+		"user": user,
+	}
+	var history []DelegatorEvent
+	if err := i.PostInto(ctx, "/info", payload, &history); err != nil {
+		return nil, err
+	}
+
+	events := history[:0]
+	for _, event := range history {
+		if event.Time >= startTime && (endTime == nil || event.Time <= *endTime) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// ValidatorSummaries retrieves every validator with its stake, commission,
+// jail status and predicted APR
+func (i *Info) ValidatorSummaries() ([]ValidatorSummary, error) {
+	return i.ValidatorSummariesCtx(context.Background())
+}
+
+// ValidatorSummariesCtx is like ValidatorSummaries but carries ctx to the request
+func (i *Info) ValidatorSummariesCtx(ctx context.Context) ([]ValidatorSummary, error) {
+	payload := map[string]interface{}{
+		"type": "validatorSummaries",
+	}
+	var validators []ValidatorSummary
+	if err := i.PostInto(ctx, "/info", payload, &validators); err != nil {
+		return nil, err
+	}
+	return validators, nil
 }
 
 // QueryOrderByOID queries order by order ID
 func (i *Info) QueryOrderByOID(user string, oid int) (interface{}, error) {
+	return i.QueryOrderByOIDCtx(context.Background(), user, oid)
+}
+
+// QueryOrderByOIDCtx is like QueryOrderByOID but carries ctx to the request
+func (i *Info) QueryOrderByOIDCtx(ctx context.Context, user string, oid int) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "orderStatus",
 		"user": user,
 		"oid":  oid,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // QueryOrderByCloid queries order by client order ID
 func (i *Info) QueryOrderByCloid(user string, cloid string) (interface{}, error) {
+	return i.QueryOrderByCloidCtx(context.Background(), user, cloid)
+}
+
+// QueryOrderByCloidCtx is like QueryOrderByCloid but carries ctx to the request
+func (i *Info) QueryOrderByCloidCtx(ctx context.Context, user string, cloid string) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "orderStatus",
 		"user": user,
 		"oid":  cloid,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
+}
+
+// OrderStatus retrieves the status of a user's order by oid, an int, or by
+// cloid, a string, as a typed entry. It returns nil when the exchange does
+// not know the order.
+func (i *Info) OrderStatus(user string, oid interface{}) (*utils.OrderStatusEntry, error) {
+	return i.OrderStatusCtx(context.Background(), user, oid)
+}
+
+// OrderStatusCtx is like OrderStatus but carries ctx to the request
+func (i *Info) OrderStatusCtx(ctx context.Context, user string, oid interface{}) (*utils.OrderStatusEntry, error) {
+	payload := map[string]interface{}{
+		"type": "orderStatus",
+		"user": user,
+		"oid":  oid,
+	}
+	var response struct {
+		Status string                  `json:"status"`
+		Order  *utils.OrderStatusEntry `json:"order"`
+	}
+	if err := i.PostInto(ctx, "/info", payload, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "order" {
+		return nil, nil
+	}
+	return response.Order, nil
 }
 
 // QueryReferralState queries referral state
 func (i *Info) QueryReferralState(user string) (interface{}, error) {
+	return i.QueryReferralStateCtx(context.Background(), user)
+}
+
+// QueryReferralStateCtx is like QueryReferralState but carries ctx to the request
+func (i *Info) QueryReferralStateCtx(ctx context.Context, user string) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "referral",
 		"user": user,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // QuerySubAccounts queries sub accounts
 func (i *Info) QuerySubAccounts(user string) (interface{}, error) {
+	return i.QuerySubAccountsCtx(context.Background(), user)
+}
+
+// QuerySubAccountsCtx is like QuerySubAccounts but carries ctx to the request
+func (i *Info) QuerySubAccountsCtx(ctx context.Context, user string) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "subAccounts",
 		"user": user,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // QueryUserToMultiSigSigners queries user to multi-sig signers
 func (i *Info) QueryUserToMultiSigSigners(multiSigUser string) (interface{}, error) {
+	return i.QueryUserToMultiSigSignersCtx(context.Background(), multiSigUser)
+}
+
+// QueryUserToMultiSigSignersCtx is like QueryUserToMultiSigSigners but carries ctx to the request
+func (i *Info) QueryUserToMultiSigSignersCtx(ctx context.Context, multiSigUser string) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "userToMultiSigSigners",
 		"user": multiSigUser,
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
+}
+
+// SpotDeployState retrieves the spot deploy auction state and the tokens a
+// deployer has in progress
+func (i *Info) SpotDeployState(user string) (interface{}, error) {
+	return i.SpotDeployStateCtx(context.Background(), user)
+}
+
+// SpotDeployStateCtx is like SpotDeployState but carries ctx to the request
+func (i *Info) SpotDeployStateCtx(ctx context.Context, user string) (interface{}, error) {
+	payload := map[string]interface{}{
+		"type": "spotDeployState",
+		"user": user,
+	}
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
 // QueryPerpDeployAuctionStatus queries perp deploy auction status
 func (i *Info) QueryPerpDeployAuctionStatus() (interface{}, error) {
+	return i.QueryPerpDeployAuctionStatusCtx(context.Background())
+}
+
+// QueryPerpDeployAuctionStatusCtx is like QueryPerpDeployAuctionStatus but carries ctx to the request
+func (i *Info) QueryPerpDeployAuctionStatusCtx(ctx context.Context) (interface{}, error) {
 	payload := map[string]interface{}{
 		"type": "perpDeployAuctionStatus",
 	}
-	return i.Post("/info", payload)
+	return i.PostWithContext(ctx, "/info", payload)
 }
 
-// remapCoinSubscription remaps coin in subscription
-func (i *Info) remapCoinSubscription(subscription *Subscription) {
-	if subscription.Type == L2Book || subscription.Type == Trades || subscription.Type == Candle ||
-		subscription.Type == BBO || subscription.Type == ActiveAssetCtx {
-		if coin, exists := i.nameToCoins[subscription.Coin]; exists {
+// remapCoinSubscription converts subscription to its wire form with the coin
+// remapped to the name the server expects
+func (i *Info) remapCoinSubscription(s utils.Subscription) utils.WsSubscription {
+	subscription := s.Wire()
+	if subscription.Type == utils.SubTypeL2Book || subscription.Type == utils.SubTypeTrades || subscription.Type == utils.SubTypeCandle ||
+		subscription.Type == utils.SubTypeBbo || subscription.Type == utils.SubTypeActiveAssetCtx {
+		if coin, _, exists := i.coinForName(subscription.Coin); exists {
 			subscription.Coin = coin
 		}
 	}
+	return subscription
+}
+
+// Subscribe subscribes to a WebSocket channel and waits up to
+// SubscriptionAckTimeout for the server to acknowledge it. subscription is a
+// utils.WsSubscription or one of the typed subscriptions in utils. A rejected
+// subscription returns a *SubscriptionError.
+func (i *Info) Subscribe(subscription utils.Subscription, callback func(WsMsg)) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SubscriptionAckTimeout)
+	defer cancel()
+	return i.SubscribeCtx(ctx, subscription, callback)
 }
 
-// Subscribe subscribes to a WebSocket channel
-func (i *Info) Subscribe(subscription Subscription, callback func(WsMsg)) (int, error) {
-	i.remapCoinSubscription(&subscription)
+// SubscribeCtx is like Subscribe but waits for the acknowledgement until ctx
+// is done instead
+func (i *Info) SubscribeCtx(ctx context.Context, subscription utils.Subscription, callback func(WsMsg)) (int, error) {
 	if i.wsManager == nil {
 		return 0, fmt.Errorf("cannot subscribe since skip_ws was used")
 	}
-	return i.wsManager.Subscribe(subscription, callback), nil
+	return i.wsManager.SubscribeCtx(ctx, i.remapCoinSubscription(subscription), callback)
 }
 
-// Unsubscribe unsubscribes from a WebSocket channel
-func (i *Info) Unsubscribe(subscription Subscription, subscriptionID int) (bool, error) {
-	i.remapCoinSubscription(&subscription)
+// SubscribeUserEvents subscribes to the userEvents channel of user and
+// delivers its fills, funding payments, liquidations and system cancels
+// decoded. Like Subscribe it waits up to SubscriptionAckTimeout for the
+// acknowledgement.
+func (i *Info) SubscribeUserEvents(user string, callback func(utils.UserEventsData)) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SubscriptionAckTimeout)
+	defer cancel()
+	return i.SubscribeUserEventsCtx(ctx, user, callback)
+}
+
+// SubscribeUserEventsCtx is like SubscribeUserEvents but waits for the
+// acknowledgement until ctx is done instead
+func (i *Info) SubscribeUserEventsCtx(ctx context.Context, user string, callback func(utils.UserEventsData)) (int, error) {
+	return i.SubscribeCtx(ctx, utils.UserEventsSubscription{User: user}, func(msg WsMsg) {
+		var data utils.UserEventsData
+		if err := decodeResult(msg.Data, &data); err != nil {
+			i.logger.Warn("Failed to decode user events", "err", err)
+			return
+		}
+		callback(data)
+	})
+}
+
+// SubscribeOrderUpdates subscribes to the orderUpdates channel of user and
+// delivers the status changes decoded. Like Subscribe it waits up to
+// SubscriptionAckTimeout for the acknowledgement.
+func (i *Info) SubscribeOrderUpdates(user string, callback func([]utils.OrderUpdate)) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SubscriptionAckTimeout)
+	defer cancel()
+	return i.SubscribeOrderUpdatesCtx(ctx, user, callback)
+}
+
+// SubscribeOrderUpdatesCtx is like SubscribeOrderUpdates but waits for the
+// acknowledgement until ctx is done instead
+func (i *Info) SubscribeOrderUpdatesCtx(ctx context.Context, user string, callback func([]utils.OrderUpdate)) (int, error) {
+	return i.SubscribeCtx(ctx, utils.OrderUpdatesSubscription{User: user}, func(msg WsMsg) {
+		updates, err := decodeOrderUpdates(msg.Data)
+		if err != nil {
+			i.logger.Warn("Failed to decode order updates", "err", err)
+			return
+		}
+		callback(updates)
+	})
+}
+
+// SubscribeBBO subscribes to the best bid and offer of name, a perp or spot
+// coin in any form NameToAsset accepts. Like Subscribe it waits up to
+// SubscriptionAckTimeout for the acknowledgement.
+func (i *Info) SubscribeBBO(name string, callback func(utils.BboData)) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SubscriptionAckTimeout)
+	defer cancel()
+	return i.SubscribeBBOCtx(ctx, name, callback)
+}
+
+// SubscribeBBOCtx is like SubscribeBBO but waits for the acknowledgement
+// until ctx is done instead
+func (i *Info) SubscribeBBOCtx(ctx context.Context, name string, callback func(utils.BboData)) (int, error) {
+	if _, _, exists := i.coinForName(name); !exists {
+		return 0, fmt.Errorf("coin not found for name: %s", name)
+	}
+	return i.SubscribeCtx(ctx, utils.BboSubscription{Coin: name}, func(msg WsMsg) {
+		var data utils.BboData
+		if err := decodeResult(msg.Data, &data); err != nil {
+			i.logger.Warn("Failed to decode bbo", "err", err)
+			return
+		}
+		callback(data)
+	})
+}
+
+// SubscribeActiveAssetCtx subscribes to the asset context of name, a perp or
+// spot coin in any form NameToAsset accepts. The server sends perp contexts
+// to onPerp and spot contexts to onSpot; either may be nil for a coin that
+// never receives it. Like Subscribe it waits up to SubscriptionAckTimeout for
+// the acknowledgement.
+func (i *Info) SubscribeActiveAssetCtx(name string, onPerp func(utils.ActiveAssetCtx), onSpot func(utils.ActiveSpotAssetCtx)) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), SubscriptionAckTimeout)
+	defer cancel()
+	return i.SubscribeActiveAssetCtxCtx(ctx, name, onPerp, onSpot)
+}
+
+// SubscribeActiveAssetCtxCtx is like SubscribeActiveAssetCtx but waits for
+// the acknowledgement until ctx is done instead
+func (i *Info) SubscribeActiveAssetCtxCtx(ctx context.Context, name string, onPerp func(utils.ActiveAssetCtx), onSpot func(utils.ActiveSpotAssetCtx)) (int, error) {
+	if _, _, exists := i.coinForName(name); !exists {
+		return 0, fmt.Errorf("coin not found for name: %s", name)
+	}
+	return i.SubscribeCtx(ctx, utils.ActiveAssetCtxSubscription{Coin: name}, func(msg WsMsg) {
+		switch msg.Channel {
+		case "activeSpotAssetCtx":
+			var data utils.ActiveSpotAssetCtx
+			if err := decodeResult(msg.Data, &data); err != nil {
+				i.logger.Warn("Failed to decode spot asset context", "err", err)
+				return
+			}
+			if onSpot != nil {
+				onSpot(data)
+			}
+		default:
+			var data utils.ActiveAssetCtx
+			if err := decodeResult(msg.Data, &data); err != nil {
+				i.logger.Warn("Failed to decode asset context", "err", err)
+				return
+			}
+			if onPerp != nil {
+				onPerp(data)
+			}
+		}
+	})
+}
+
+// decodeOrderUpdates decodes the data of an orderUpdates message, which is
+// usually an array of updates but may be a single one
+func decodeOrderUpdates(data interface{}) ([]utils.OrderUpdate, error) {
+	if update, ok := data.(map[string]interface{}); ok {
+		var single utils.OrderUpdate
+		if err := decodeResult(update, &single); err != nil {
+			return nil, err
+		}
+		return []utils.OrderUpdate{single}, nil
+	}
+	var updates []utils.OrderUpdate
+	if err := decodeResult(data, &updates); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// OnReconnect sets a callback run when the WebSocket connection has been
+// re-established and its subscriptions re-sent
+func (i *Info) OnReconnect(callback func()) error {
+	if i.wsManager == nil {
+		return fmt.Errorf("cannot watch reconnects since skip_ws was used")
+	}
+	i.wsManager.OnReconnect(callback)
+	return nil
+}
+
+// OnDisconnect sets a callback run when the WebSocket connection drops, with
+// the error that dropped it
+func (i *Info) OnDisconnect(callback func(error)) error {
+	if i.wsManager == nil {
+		return fmt.Errorf("cannot watch disconnects since skip_ws was used")
+	}
+	i.wsManager.OnDisconnect(callback)
+	return nil
+}
+
+// Unsubscribe unsubscribes from a WebSocket channel. See
+// WebSocketManager.Unsubscribe for the errors it returns.
+func (i *Info) Unsubscribe(subscription utils.Subscription, subscriptionID int) (bool, error) {
 	if i.wsManager == nil {
 		return false, fmt.Errorf("cannot unsubscribe since skip_ws was used")
 	}
-	return i.wsManager.Unsubscribe(subscription, subscriptionID), nil
+	return i.wsManager.Unsubscribe(i.remapCoinSubscription(subscription), subscriptionID)
 }
 
-// NameToAsset converts name to asset ID
+// NameToAsset converts name to asset ID. Spot pairs are accepted by coin,
+// "@index" or BASE/QUOTE name.
 func (i *Info) NameToAsset(name string) (int, error) {
-	if coin, exists := i.nameToCoins[name]; exists {
-		if asset, exists := i.coinToAsset[coin]; exists {
+	if coin, assets, exists := i.coinForName(name); exists {
+		if asset, exists := assets.coinToAsset[coin]; exists {
 			return asset, nil
 		}
 	}
 	return 0, fmt.Errorf("asset not found for name: %s", name)
 }
+
+// AssetToName converts an asset ID back to the name the API uses for it, such
+// as "BTC", "PURR/USDC", "@1" or, on a builder-deployed perp dex, "dex:COIN".
+// The name is accepted by every method taking one.
+func (i *Info) AssetToName(asset int) (string, error) {
+	if name, exists := i.currentAssets().assetToName[asset]; exists {
+		return name, nil
+	}
+	return "", fmt.Errorf("name not found for asset: %d", asset)
+}
+
+// SzDecimals returns the number of decimals sizes of an asset are rounded to
+func (i *Info) SzDecimals(name string) (int, error) {
+	if coin, assets, exists := i.coinForName(name); exists {
+		if asset, exists := assets.coinToAsset[coin]; exists {
+			return assets.assetToSzDecimals[asset], nil
+		}
+	}
+	return 0, fmt.Errorf("asset not found for name: %s", name)
+}
+
+// IsSpotAsset reports whether an asset ID is a spot pair. Spot assets are
+// numbered from 10000 and builder-deployed perp dexs from 110000.
+func (i *Info) IsSpotAsset(asset int) bool {
+	return asset >= 10000 && asset < 110000
+}
+
+// AssetMeta returns the metadata of a perp asset, such as its max leverage
+func (i *Info) AssetMeta(name string) (*AssetInfo, error) {
+	if coin, assets, exists := i.coinForName(name); exists {
+		if assetInfo, exists := assets.perpAssets[coin]; exists {
+			return &assetInfo, nil
+		}
+	}
+	return nil, fmt.Errorf("perp asset not found for name: %s", name)
+}
+
+// TokenByEvmContract finds the spot token linked to an EVM contract address.
+// The address is matched case-insensitively.
+func (i *Info) TokenByEvmContract(address string) (*SpotTokenInfo, error) {
+	tokenIndex, exists := i.currentAssets().evmContractToToken[strings.ToLower(address)]
+	if !exists {
+		return nil, fmt.Errorf("no spot token linked to EVM contract: %s", address)
+	}
+	return i.spotToken(tokenIndex)
+}
+
+// PairsForToken returns the spot pairs that trade the given token as base or quote
+func (i *Info) PairsForToken(tokenIndex int) ([]SpotAssetInfo, error) {
+	if _, err := i.spotToken(tokenIndex); err != nil {
+		return nil, err
+	}
+	
+	var pairs []SpotAssetInfo
+	for _, spotInfo := range i.currentAssets().spotMeta.Universe {
+		if spotInfo.Tokens[0] == tokenIndex || spotInfo.Tokens[1] == tokenIndex {
+			pairs = append(pairs, spotInfo)
+		}
+	}
+	return pairs, nil
+}
+
+// spotToken looks up spot token metadata by token index
+func (i *Info) spotToken(tokenIndex int) (*SpotTokenInfo, error) {
+	if spotMeta := i.currentAssets().spotMeta; spotMeta != nil {
+		for idx := range spotMeta.Tokens {
+			if spotMeta.Tokens[idx].Index == tokenIndex {
+				return &spotMeta.Tokens[idx], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("spot token not found for index: %d", tokenIndex)
+}
+
+// spotTokenByName looks up spot token metadata by token name
+func (i *Info) spotTokenByName(name string) (*SpotTokenInfo, error) {
+	if spotMeta := i.currentAssets().spotMeta; spotMeta != nil {
+		for idx := range spotMeta.Tokens {
+			if spotMeta.Tokens[idx].Name == name {
+				return &spotMeta.Tokens[idx], nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("spot token not found: %s", name)
+}
+
+// isTokenID reports whether s is a 16 byte hex token id
+func isTokenID(s string) bool {
+	if len(s) != 34 || !strings.HasPrefix(s, "0x") {
+		return false
+	}
+	for _, c := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// SimulateMarketOrder fetches a fresh L2 snapshot and estimates how a market order
+// of size sz would fill against it
+func (i *Info) SimulateMarketOrder(ctx context.Context, name string, isBuy bool, sz float64) (*FillEstimate, error) {
+	snapshot, err := i.L2SnapshotCtx(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return NewOrderBook(*snapshot).SimulateMarketOrder(isBuy, sz)
+}
+
+// decodeResult converts a generic JSON response into a typed value
+func decodeResult(result interface{}, out interface{}) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,32 @@
+// Package hyperliquid - Logging
+package hyperliquid
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// DefaultLogger returns the logger clients log with unless SetLogger
+// replaces it: warnings and errors, such as reconnects and failed
+// subscriptions, as text on stderr. Debug and info entries, among them every
+// WebSocket message received, need a logger enabling those levels.
+func DefaultLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+}
+
+// discardHandler is a slog.Handler dropping every entry
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// orDiscard returns logger, or a logger discarding everything when it is nil
+func orDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return slog.New(discardHandler{})
+	}
+	return logger
+}
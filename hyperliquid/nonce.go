@@ -0,0 +1,46 @@
+// Package hyperliquid - Nonce management
+package hyperliquid
+
+import (
+	"sync"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// NonceManager hands out strictly increasing millisecond nonces, so actions
+// signed concurrently from one wallet never share a nonce
+type NonceManager struct {
+	mu   sync.Mutex
+	last int64
+	now  func() int64
+}
+
+// NewNonceManager creates a NonceManager based on the current time
+func NewNonceManager() *NonceManager {
+	return &NonceManager{now: utils.GetTimestampMs}
+}
+
+// Next returns the next nonce: the current time in milliseconds, or one more
+// than the previous nonce if the clock has not moved past it
+func (m *NonceManager) Next() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nonce := m.now()
+	if nonce <= m.last {
+		nonce = m.last + 1
+	}
+	m.last = nonce
+	return nonce
+}
+
+// SetMinNonce makes every following nonce at least nonce, for recovering after
+// the exchange rejects a nonce as too old or already used
+func (m *NonceManager) SetMinNonce(nonce int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if nonce-1 > m.last {
+		m.last = nonce - 1
+	}
+}
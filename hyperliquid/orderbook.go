@@ -0,0 +1,379 @@
+// Package hyperliquid - Order book functionality
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// FillEstimate describes the expected result of sweeping the book with a market order
+type FillEstimate struct {
+	AvgPx       float64 // volume weighted average fill price, 0 if nothing fills
+	WorstPx     float64 // price of the deepest level touched, 0 if nothing fills
+	FilledSz    float64 // size that the visible book can absorb
+	RemainingSz float64 // size left unfilled once the visible book is exhausted
+	Levels      int     // number of price levels consumed
+}
+
+// DefaultBookStaleTimeout is how long a live order book may go without an
+// l2Book update before it resubscribes. The server pushes a snapshot every
+// block the book changes in, so even quiet books update well within it.
+const DefaultBookStaleTimeout = 30 * time.Second
+
+// OrderBook holds an L2 order book for a single coin. All methods are safe
+// for concurrent use.
+//
+// A live order book, created with NewLiveOrderBook, keeps itself up to date
+// from the l2Book subscription once started. Snapshots older than the one
+// applied are dropped, and when no update arrives within the stale timeout
+// the book resubscribes to get a fresh snapshot.
+type OrderBook struct {
+	mu        sync.RWMutex
+	coin      string
+	bids      []utils.L2Level // best (highest) first
+	asks      []utils.L2Level // best (lowest) first
+	time      int64
+	seq       uint64
+	updatedAt time.Time
+	clock     Clock
+
+	// Live books only
+	info         *Info
+	name         string
+	subID        int
+	staleTimeout time.Duration
+	onUpdate     func(*OrderBook)
+	cancel       context.CancelFunc
+}
+
+// NewOrderBook creates an order book from an L2 snapshot
+func NewOrderBook(snapshot utils.L2BookData) *OrderBook {
+	book := &OrderBook{clock: systemClock{}}
+	book.ApplySnapshot(snapshot)
+	return book
+}
+
+// NewLiveOrderBook creates an empty order book for name, filled from the
+// l2Book subscription once started
+func NewLiveOrderBook(info *Info, name string) *OrderBook {
+	return &OrderBook{
+		coin:         name,
+		clock:        systemClock{},
+		info:         info,
+		name:         name,
+		staleTimeout: DefaultBookStaleTimeout,
+	}
+}
+
+// ApplySnapshot replaces the book contents with an L2 snapshot
+func (b *OrderBook) ApplySnapshot(snapshot utils.L2BookData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.applySnapshot(snapshot)
+}
+
+// applySnapshot replaces the book contents. The caller must hold mu.
+func (b *OrderBook) applySnapshot(snapshot utils.L2BookData) {
+	b.coin = snapshot.Coin
+	b.bids = append([]utils.L2Level(nil), snapshot.Levels[0]...)
+	b.asks = append([]utils.L2Level(nil), snapshot.Levels[1]...)
+	b.time = snapshot.Time
+	b.seq++
+	b.updatedAt = b.clock.Now()
+}
+
+// SetStaleTimeout sets how long a live book may go without an update before
+// it resubscribes; zero or less disables the check. It applies from the
+// next Start.
+func (b *OrderBook) SetStaleTimeout(timeout time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.staleTimeout = timeout
+}
+
+// SetClock replaces the time source, for example with a fake clock in tests
+func (b *OrderBook) SetClock(clock Clock) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clock = clock
+}
+
+// OnUpdate sets a callback run after every snapshot a live book applies. It
+// runs on the subscription's dispatch goroutine and may read the book.
+func (b *OrderBook) OnUpdate(callback func(*OrderBook)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onUpdate = callback
+}
+
+// Start subscribes a live book to l2Book updates
+func (b *OrderBook) Start() error {
+	b.mu.Lock()
+	if b.info == nil {
+		b.mu.Unlock()
+		return fmt.Errorf("order book was not created with NewLiveOrderBook")
+	}
+	if b.cancel != nil {
+		b.mu.Unlock()
+		return fmt.Errorf("order book for %s already started", b.name)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	b.updatedAt = b.clock.Now()
+	b.mu.Unlock()
+
+	if err := b.subscribe(); err != nil {
+		b.mu.Lock()
+		b.cancel = nil
+		b.mu.Unlock()
+		cancel()
+		return err
+	}
+	go b.watchStaleness(ctx)
+	return nil
+}
+
+// Stop removes a live book's subscription. The book keeps its last
+// snapshot.
+func (b *OrderBook) Stop() error {
+	b.mu.Lock()
+	cancel, subID := b.cancel, b.subID
+	b.cancel = nil
+	b.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	_, err := b.info.Unsubscribe(utils.L2BookSubscription{Coin: b.name}, subID)
+	return err
+}
+
+// subscribe subscribes to the book's l2Book channel
+func (b *OrderBook) subscribe() error {
+	subID, err := b.info.Subscribe(utils.L2BookSubscription{Coin: b.name}, b.onL2Book)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to l2Book for %s: %w", b.name, err)
+	}
+	b.mu.Lock()
+	b.subID = subID
+	b.mu.Unlock()
+	return nil
+}
+
+// onL2Book applies l2Book snapshots, dropping any older than the current one
+func (b *OrderBook) onL2Book(msg WsMsg) {
+	var snapshot utils.L2BookData
+	if err := decodeResult(msg.Data, &snapshot); err != nil {
+		b.info.logger.Warn("Failed to decode l2Book", "err", err)
+		return
+	}
+
+	b.mu.Lock()
+	if snapshot.Time < b.time {
+		b.mu.Unlock()
+		b.info.logger.Debug("Dropping out of order l2Book snapshot", "coin", snapshot.Coin, "time", snapshot.Time, "current", b.time)
+		return
+	}
+	b.applySnapshot(snapshot)
+	onUpdate := b.onUpdate
+	b.mu.Unlock()
+
+	if onUpdate != nil {
+		onUpdate(b)
+	}
+}
+
+// watchStaleness resubscribes when the book receives no update within the
+// stale timeout, until ctx is done
+func (b *OrderBook) watchStaleness(ctx context.Context) {
+	for {
+		b.mu.RLock()
+		timeout, clock := b.staleTimeout, b.clock
+		wait := timeout - clock.Now().Sub(b.updatedAt)
+		b.mu.RUnlock()
+		if timeout <= 0 {
+			return
+		}
+
+		if wait <= 0 {
+			b.info.logger.Warn("No l2Book update, resubscribing", "coin", b.name, "timeout", timeout)
+			b.resubscribe(ctx)
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// resubscribe replaces the book's subscription, which makes the server send a
+// fresh snapshot. The stale timer restarts whether or not it succeeds.
+func (b *OrderBook) resubscribe(ctx context.Context) {
+	b.mu.Lock()
+	subID := b.subID
+	b.updatedAt = b.clock.Now()
+	b.mu.Unlock()
+
+	if _, err := b.info.Unsubscribe(utils.L2BookSubscription{Coin: b.name}, subID); err != nil {
+		b.info.logger.Warn("Failed to unsubscribe stale l2Book", "coin", b.name, "err", err)
+	}
+	if err := b.subscribe(); err != nil {
+		b.info.logger.Error("Failed to resubscribe", "coin", b.name, "err", err)
+		return
+	}
+	// Stopped while resubscribing
+	if ctx.Err() != nil {
+		b.mu.RLock()
+		subID = b.subID
+		b.mu.RUnlock()
+		_, _ = b.info.Unsubscribe(utils.L2BookSubscription{Coin: b.name}, subID)
+	}
+}
+
+// Coin returns the coin of the book
+func (b *OrderBook) Coin() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.coin
+}
+
+// Time returns the timestamp of the last applied snapshot in milliseconds
+func (b *OrderBook) Time() int64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.time
+}
+
+// Seq returns the number of snapshots applied, so readers can tell whether
+// the book changed since they last looked
+func (b *OrderBook) Seq() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.seq
+}
+
+// UpdatedAt returns the local time the last snapshot was applied
+func (b *OrderBook) UpdatedAt() time.Time {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.updatedAt
+}
+
+// BestBid returns the highest bid, and false if there are no bids
+func (b *OrderBook) BestBid() (utils.L2Level, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.bids) == 0 {
+		return utils.L2Level{}, false
+	}
+	return b.bids[0], true
+}
+
+// BestAsk returns the lowest ask, and false if there are no asks
+func (b *OrderBook) BestAsk() (utils.L2Level, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.asks) == 0 {
+		return utils.L2Level{}, false
+	}
+	return b.asks[0], true
+}
+
+// Mid returns the price halfway between the best bid and ask, and false if
+// either side is empty
+func (b *OrderBook) Mid() (float64, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if len(b.bids) == 0 || len(b.asks) == 0 {
+		return 0, false
+	}
+	bidPx, err := b.bids[0].Price()
+	if err != nil {
+		return 0, false
+	}
+	askPx, err := b.asks[0].Price()
+	if err != nil {
+		return 0, false
+	}
+	return (bidPx + askPx) / 2, true
+}
+
+// Levels returns copies of up to depth levels of each side, best first. A
+// depth of zero or less returns every level.
+func (b *OrderBook) Levels(depth int) (bids, asks []utils.L2Level) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return copyLevels(b.bids, depth), copyLevels(b.asks, depth)
+}
+
+// copyLevels copies up to depth levels, or all of them if depth is not positive
+func copyLevels(levels []utils.L2Level, depth int) []utils.L2Level {
+	if depth > 0 && depth < len(levels) {
+		levels = levels[:depth]
+	}
+	return append([]utils.L2Level(nil), levels...)
+}
+
+// SimulateMarketOrder walks the opposing side of the book to estimate how a
+// market order of size sz would fill. Prices and sizes are accumulated as exact
+// decimals so deep books don't accrue float64 rounding error.
+func (b *OrderBook) SimulateMarketOrder(isBuy bool, sz float64) (*FillEstimate, error) {
+	if sz <= 0 {
+		return nil, fmt.Errorf("order size must be positive: %f", sz)
+	}
+
+	b.mu.RLock()
+	levels := b.bids
+	if isBuy {
+		levels = b.asks
+	}
+	b.mu.RUnlock()
+
+	remaining, _ := new(big.Rat).SetString(strconv.FormatFloat(sz, 'f', -1, 64))
+	filled := new(big.Rat)
+	notional := new(big.Rat)
+	worst := new(big.Rat)
+	estimate := &FillEstimate{}
+
+	for _, level := range levels {
+		if remaining.Sign() == 0 {
+			break
+		}
+
+		px, ok := new(big.Rat).SetString(level.Px)
+		if !ok {
+			return nil, fmt.Errorf("invalid level price: %s", level.Px)
+		}
+		levelSz, ok := new(big.Rat).SetString(level.Sz)
+		if !ok {
+			return nil, fmt.Errorf("invalid level size: %s", level.Sz)
+		}
+
+		take := levelSz
+		if remaining.Cmp(levelSz) < 0 {
+			take = new(big.Rat).Set(remaining)
+		}
+
+		filled.Add(filled, take)
+		remaining.Sub(remaining, take)
+		notional.Add(notional, new(big.Rat).Mul(take, px))
+		worst = px
+		estimate.Levels++
+	}
+
+	estimate.FilledSz, _ = filled.Float64()
+	estimate.RemainingSz, _ = remaining.Float64()
+	if filled.Sign() > 0 {
+		estimate.AvgPx, _ = new(big.Rat).Quo(notional, filled).Float64()
+		estimate.WorstPx, _ = worst.Float64()
+	}
+	return estimate, nil
+}
@@ -0,0 +1,134 @@
+// Package hyperliquid - Pagination of time-bounded history queries
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// FillsPageLimit is the most fills a userFillsByTime request returns
+const FillsPageLimit = 2000
+
+// HistoricalOrdersLimit is the most orders a historicalOrders request
+// returns, the most recent ones
+const HistoricalOrdersLimit = 2000
+
+// paginate walks a time-bounded history one page at a time, handing every
+// page to fn. fetch returns the page starting at a time, oldest first. The
+// next page starts at the time of the last entry, so entries at that time come
+// back again; they are recognised by key and skipped.
+//
+// When a full page shares a single timestamp, starting the next page at it
+// would return the same page forever. The next page then starts a millisecond
+// later, and any entries at that time beyond the page limit are not returned;
+// paginate reports whether that happened.
+func paginate[T any](ctx context.Context, startTime int64, endTime *int64, pageLimit int,
+	fetch func(ctx context.Context, startTime int64) ([]T, error),
+	timeOf func(T) int64, key func(T) string, fn func([]T) error) (bool, error) {
+	seen := make(map[string]bool)
+	truncated := false
+	for {
+		if endTime != nil && startTime > *endTime {
+			return truncated, nil
+		}
+		page, err := fetch(ctx, startTime)
+		if err != nil {
+			return truncated, err
+		}
+
+		fresh := make([]T, 0, len(page))
+		for _, entry := range page {
+			if timeOf(entry) == startTime && seen[key(entry)] {
+				continue
+			}
+			fresh = append(fresh, entry)
+		}
+		if len(fresh) > 0 {
+			if err := fn(fresh); err != nil {
+				return truncated, err
+			}
+		}
+		if len(page) < pageLimit {
+			return truncated, nil
+		}
+
+		last := timeOf(page[len(page)-1])
+		if last <= startTime {
+			truncated = true
+			startTime++
+			seen = make(map[string]bool)
+			continue
+		}
+		startTime = last
+		seen = make(map[string]bool)
+		for _, entry := range page {
+			if timeOf(entry) == last {
+				seen[key(entry)] = true
+			}
+		}
+	}
+}
+
+// UserFillsByTimePaged retrieves all of a user's fills between startTime and
+// endTime, in milliseconds, one page at a time, calling fn with each page in
+// order. A nil endTime means now. It stops at the first error fn returns.
+func (i *Info) UserFillsByTimePaged(address string, startTime int64, endTime *int64, fn func([]utils.Fill) error) error {
+	return i.UserFillsByTimePagedCtx(context.Background(), address, startTime, endTime, fn)
+}
+
+// UserFillsByTimePagedCtx is like UserFillsByTimePaged but carries ctx to the requests
+func (i *Info) UserFillsByTimePagedCtx(ctx context.Context, address string, startTime int64, endTime *int64, fn func([]utils.Fill) error) error {
+	_, err := i.userFillsByTimePaged(ctx, address, startTime, endTime, fn)
+	return err
+}
+
+// userFillsByTimePaged is UserFillsByTimePagedCtx, also reporting whether
+// fills sharing a millisecond were left out because they exceed a page
+func (i *Info) userFillsByTimePaged(ctx context.Context, address string, startTime int64, endTime *int64, fn func([]utils.Fill) error) (bool, error) {
+	fetch := func(ctx context.Context, startTime int64) ([]utils.Fill, error) {
+		return i.UserFillsByTimeCtx(ctx, address, startTime, endTime)
+	}
+	timeOf := func(fill utils.Fill) int64 { return fill.Time }
+	key := func(fill utils.Fill) string { return fmt.Sprintf("%d", fill.Tid) }
+	return paginate(ctx, startTime, endTime, FillsPageLimit, fetch, timeOf, key, fn)
+}
+
+// FundingHistoryPaged retrieves a coin's funding history between startTime
+// and endTime one page at a time, calling fn with each page in order
+func (i *Info) FundingHistoryPaged(name string, startTime int64, endTime *int64, fn func(FundingHistory) error) error {
+	return i.FundingHistoryPagedCtx(context.Background(), name, startTime, endTime, fn)
+}
+
+// FundingHistoryPagedCtx is like FundingHistoryPaged but carries ctx to the requests
+func (i *Info) FundingHistoryPagedCtx(ctx context.Context, name string, startTime int64, endTime *int64, fn func(FundingHistory) error) error {
+	fetch := func(ctx context.Context, startTime int64) ([]FundingHistoryEntry, error) {
+		return i.FundingHistoryCtx(ctx, name, startTime, endTime)
+	}
+	timeOf := func(entry FundingHistoryEntry) int64 { return entry.Time }
+	key := func(entry FundingHistoryEntry) string { return entry.Coin }
+	_, err := paginate(ctx, startTime, endTime, FundingPageLimit, fetch, timeOf, key, func(page []FundingHistoryEntry) error {
+		return fn(page)
+	})
+	return err
+}
+
+// UserFundingHistoryPaged retrieves a user's funding payments between
+// startTime and endTime one page at a time, calling fn with each page in order
+func (i *Info) UserFundingHistoryPaged(user string, startTime int64, endTime *int64, fn func(UserFundingHistory) error) error {
+	return i.UserFundingHistoryPagedCtx(context.Background(), user, startTime, endTime, fn)
+}
+
+// UserFundingHistoryPagedCtx is like UserFundingHistoryPaged but carries ctx to the requests
+func (i *Info) UserFundingHistoryPagedCtx(ctx context.Context, user string, startTime int64, endTime *int64, fn func(UserFundingHistory) error) error {
+	fetch := func(ctx context.Context, startTime int64) ([]UserFundingDelta, error) {
+		return i.UserFundingHistoryCtx(ctx, user, startTime, endTime)
+	}
+	timeOf := func(delta UserFundingDelta) int64 { return delta.Time }
+	key := func(delta UserFundingDelta) string { return delta.Hash + delta.Coin }
+	_, err := paginate(ctx, startTime, endTime, FundingPageLimit, fetch, timeOf, key, func(page []UserFundingDelta) error {
+		return fn(page)
+	})
+	return err
+}
@@ -0,0 +1,239 @@
+// Package hyperliquid - Client-side rate limiting
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// ErrRateLimited is returned by a non-blocking RateLimiter when a request
+// would exceed the remaining budget. It matches utils.ErrRateLimited, as the
+// exchange's own rate limit errors do.
+var ErrRateLimited = fmt.Errorf("%w: request weight exceeds remaining budget", utils.ErrRateLimited)
+
+// DefaultWeightPerMinute is the request weight Hyperliquid allows per IP per minute
+const DefaultWeightPerMinute = 1200
+
+// ThrottledRequestInterval is how often an address that used up its request
+// budget may still send an exchange request
+const ThrottledRequestInterval = 10 * time.Second
+
+// Clock is the time source of a RateLimiter
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// RateLimiter is a token bucket holding a minute's worth of request weight,
+// refilled continuously. Requests take their weight out of the bucket before
+// they are sent, so the client stops short of the server's own limit.
+//
+// It can also track the address-based budget of exchange requests, once set
+// with SetAddressBudget.
+type RateLimiter struct {
+	mu          sync.Mutex
+	capacity    float64
+	tokens      float64
+	perSecond   float64
+	last        time.Time
+	nonBlocking bool
+	clock       Clock
+
+	hasAddressBudget bool
+	addressBudget    int
+	lastThrottled    time.Time
+}
+
+// NewRateLimiter creates a full RateLimiter allowing weightPerMinute. When
+// nonBlocking is set, requests over budget fail with ErrRateLimited instead of
+// waiting for the budget to refill.
+func NewRateLimiter(weightPerMinute int, nonBlocking bool) *RateLimiter {
+	clock := systemClock{}
+	return &RateLimiter{
+		capacity:    float64(weightPerMinute),
+		tokens:      float64(weightPerMinute),
+		perSecond:   float64(weightPerMinute) / 60,
+		last:        clock.Now(),
+		nonBlocking: nonBlocking,
+		clock:       clock,
+	}
+}
+
+// SetClock replaces the time source, for example with a fake clock in tests
+func (l *RateLimiter) SetClock(clock Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = clock
+	l.last = clock.Now()
+}
+
+// Remaining returns the weight that can be spent right now
+func (l *RateLimiter) Remaining() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill()
+	return int(l.tokens)
+}
+
+// Wait takes weight out of the budget, blocking until enough has refilled or
+// ctx is done. In non-blocking mode it returns ErrRateLimited instead.
+func (l *RateLimiter) Wait(ctx context.Context, weight int) error {
+	// A request heavier than the whole bucket could never be sent otherwise
+	need := math.Min(float64(weight), l.capacity)
+	for {
+		l.mu.Lock()
+		l.refill()
+		if l.tokens >= need {
+			l.tokens -= need
+			l.mu.Unlock()
+			return nil
+		}
+		if l.nonBlocking {
+			l.mu.Unlock()
+			return ErrRateLimited
+		}
+		wait := time.Duration((need - l.tokens) / l.perSecond * float64(time.Second))
+		clock := l.clock
+		l.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+	}
+}
+
+// SetAddressBudget sets the number of exchange requests the address may still
+// send, usually UserRateLimit.Remaining. Every order or cancel in an exchange
+// request then counts against it. Once it is used up, exchange requests are
+// spaced ThrottledRequestInterval apart, as the server does, or fail with
+// ErrRateLimited in non-blocking mode.
+func (l *RateLimiter) SetAddressBudget(remaining int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hasAddressBudget = true
+	l.addressBudget = remaining
+}
+
+// AddressRemaining returns the address budget left and whether one was set
+func (l *RateLimiter) AddressRemaining() (int, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.addressBudget, l.hasAddressBudget
+}
+
+// AddAddressBudget adds n requests to the address budget, if one was set,
+// such as those bought with Exchange.ReserveRequestWeight
+func (l *RateLimiter) AddAddressBudget(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hasAddressBudget {
+		l.addressBudget += n
+	}
+}
+
+// WaitAddress takes n requests out of the address budget, if one was set.
+// When it is used up it waits for the next throttled slot or ctx to be done;
+// in non-blocking mode it returns ErrRateLimited instead.
+func (l *RateLimiter) WaitAddress(ctx context.Context, n int) error {
+	l.mu.Lock()
+	if !l.hasAddressBudget {
+		l.mu.Unlock()
+		return nil
+	}
+	if l.addressBudget >= n {
+		l.addressBudget -= n
+		l.mu.Unlock()
+		return nil
+	}
+	if l.nonBlocking {
+		l.mu.Unlock()
+		return ErrRateLimited
+	}
+	clock := l.clock
+	wait := l.lastThrottled.Add(ThrottledRequestInterval).Sub(clock.Now())
+	l.mu.Unlock()
+
+	if wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-clock.After(wait):
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.addressBudget = 0
+	l.lastThrottled = l.clock.Now()
+	return nil
+}
+
+// refill adds the weight accrued since the last refill. Callers hold mu.
+func (l *RateLimiter) refill() {
+	now := l.clock.Now()
+	elapsed := now.Sub(l.last).Seconds()
+	l.last = now
+	if elapsed > 0 {
+		l.tokens = math.Min(l.capacity, l.tokens+elapsed*l.perSecond)
+	}
+}
+
+// RequestWeight returns the documented rate limit weight of a request:
+// 1 + floor(batch length / 40) for exchange actions, and 2, 20 or 60 for info
+// requests depending on their type
+func RequestWeight(urlPath string, payload interface{}) int {
+	request, _ := payload.(map[string]interface{})
+	if urlPath == "/exchange" {
+		return 1 + batchLength(request["action"])/40
+	}
+
+	infoType, _ := request["type"].(string)
+	switch infoType {
+	case "l2Book", "allMids", "clearinghouseState", "orderStatus", "spotClearinghouseState", "exchangeStatus":
+		return 2
+	case "userRole":
+		return 60
+	}
+	return 20
+}
+
+// addressRequests returns how many requests an exchange payload counts for
+// against the address budget: one per order or cancel in a batch
+func addressRequests(payload interface{}) int {
+	request, _ := payload.(map[string]interface{})
+	if n := batchLength(request["action"]); n > 1 {
+		return n
+	}
+	return 1
+}
+
+// batchLength returns the number of orders or cancels in an action
+func batchLength(action interface{}) int {
+	switch a := action.(type) {
+	case utils.OrderAction:
+		return len(a.Orders)
+	case utils.CancelAction:
+		return len(a.Cancels)
+	case utils.BatchModifyAction:
+		return len(a.Modifies)
+	case map[string]interface{}:
+		for _, key := range []string{"orders", "cancels", "modifies"} {
+			if batch, ok := a[key].([]interface{}); ok {
+				return len(batch)
+			}
+		}
+	}
+	return 0
+}
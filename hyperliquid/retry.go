@@ -0,0 +1,133 @@
+// Package hyperliquid - Request retry policy
+package hyperliquid
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how the API client retries failed requests. /info
+// requests are idempotent and are retried on any retryable status code or
+// network error. /exchange posts are only retried when RetryExchange is set,
+// and then only when the action clearly never reached the exchange: a 429
+// response or a failure to connect. Everything else could double-fill.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry; it doubles on every retry
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff delay, unless the server asks for longer with
+	// a Retry-After header
+	MaxDelay time.Duration
+	// Jitter adds a random fraction, between 0 and Jitter, of the delay to it
+	Jitter float64
+	// RetryableStatusCodes are the HTTP status codes worth retrying
+	RetryableStatusCodes []int
+	// RetryExchange enables retrying /exchange posts on pre-execution failures
+	RetryExchange bool
+	// OnRetry, if set, is called before every retry
+	OnRetry func(RetryEvent)
+}
+
+// RetryEvent describes a failed attempt that is about to be retried
+type RetryEvent struct {
+	Path       string
+	Attempt    int
+	StatusCode int // 0 if no response was received
+	Err        error
+	Delay      time.Duration
+}
+
+// DefaultRetryPolicy returns a policy retrying rate limits and server errors
+// up to 4 times with exponential backoff starting at 250ms
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   250 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// shouldRetry reports whether a failed attempt at urlPath may be retried. resp
+// is nil when no response was received.
+func (p *RetryPolicy) shouldRetry(urlPath string, resp *http.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	isExchange := urlPath == "/exchange"
+	if isExchange && !p.RetryExchange {
+		return false
+	}
+
+	if resp == nil {
+		return !isExchange || isDialError(err)
+	}
+	if isExchange {
+		return resp.StatusCode == http.StatusTooManyRequests
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns how long to wait before the retry following attempt. A
+// Retry-After header on resp takes precedence over the backoff.
+func (p *RetryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+			return retryAfter
+		}
+	}
+
+	delay := time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(attempt-1)))
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		delay += time.Duration(rand.Float64() * p.Jitter * float64(delay))
+	}
+	return delay
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// isDialError reports whether err happened while connecting, before any part
+// of the request was sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,342 @@
+// Package hyperliquid - Reconnect-safe user data stream
+package hyperliquid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// UserStreamEventType identifies the kind of a UserStreamEvent
+type UserStreamEventType int
+
+const (
+	UserStreamFill UserStreamEventType = iota
+	UserStreamOrderUpdate
+	UserStreamResynced
+	UserStreamResyncFailed
+)
+
+// UserStreamEvent is a single event delivered by a UserStream
+type UserStreamEvent struct {
+	Seq         uint64 // strictly increasing, starting at 1
+	Type        UserStreamEventType
+	Fill        *utils.Fill
+	OrderUpdate *utils.OrderUpdate
+	Recovered   int   // number of events recovered over REST, set on Resynced events
+	Err         error // set on ResyncFailed events
+}
+
+// ErrUserStreamGap is the error of a ResyncFailed event when more events
+// were missed than the API returns, so some of them were not delivered
+var ErrUserStreamGap = errors.New("missed events exceed what the API returns; some were not delivered")
+
+// userStreamOverlapMs is how far behind the last seen event reconciliation
+// looks, so events sharing a timestamp with the watermark are not lost
+const userStreamOverlapMs = 10_000
+
+// UserStream combines the userFills and orderUpdates subscriptions of a user
+// into a single ordered stream without gaps or duplicates.
+//
+// Every userFills snapshot after the first one means the subscription was
+// re-established, so the stream fetches the fills and order updates it may
+// have missed over REST, delivers them in order and then emits a Resynced
+// event marking the boundary. Fills are de-duplicated by tid and order updates
+// by oid, status and status timestamp.
+//
+// Missed fills are fetched page by page, but historicalOrders only returns
+// the most recent orders. When the missed events may exceed what the API
+// returns, the recovered ones are delivered followed by a ResyncFailed event
+// with ErrUserStreamGap instead of a Resynced event.
+type UserStream struct {
+	mu             sync.Mutex
+	info           *Info
+	user           string
+	handler        func(UserStreamEvent)
+	seq            uint64
+	startTime      int64
+	lastFillTime   int64
+	lastStatusTime int64
+	seenFills      map[int]int64
+	seenOrders     map[string]int64
+	snapshots      int
+	fillsSubID     int
+	ordersSubID    int
+}
+
+// NewUserStream creates a stream for user delivering events at or after
+// startTime (in milliseconds) to handler. The handler is called sequentially
+// and must not call back into the stream.
+func NewUserStream(info *Info, user string, startTime int64, handler func(UserStreamEvent)) *UserStream {
+	return &UserStream{
+		info:           info,
+		user:           user,
+		handler:        handler,
+		startTime:      startTime,
+		lastFillTime:   startTime,
+		lastStatusTime: startTime,
+		seenFills:      make(map[int]int64),
+		seenOrders:     make(map[string]int64),
+	}
+}
+
+// Start subscribes to the user's fills and order updates
+func (s *UserStream) Start() error {
+	fillsSubID, err := s.info.Subscribe(utils.UserFillsSubscription{User: s.user}, s.onUserFills)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to user fills: %w", err)
+	}
+	ordersSubID, err := s.info.Subscribe(utils.OrderUpdatesSubscription{User: s.user}, s.onOrderUpdates)
+	if err != nil {
+		_, _ = s.info.Unsubscribe(utils.UserFillsSubscription{User: s.user}, fillsSubID)
+		return fmt.Errorf("failed to subscribe to order updates: %w", err)
+	}
+
+	s.mu.Lock()
+	s.fillsSubID = fillsSubID
+	s.ordersSubID = ordersSubID
+	s.mu.Unlock()
+	return nil
+}
+
+// Stop removes the stream's subscriptions
+func (s *UserStream) Stop() error {
+	s.mu.Lock()
+	fillsSubID, ordersSubID := s.fillsSubID, s.ordersSubID
+	s.mu.Unlock()
+
+	if _, err := s.info.Unsubscribe(utils.UserFillsSubscription{User: s.user}, fillsSubID); err != nil {
+		return err
+	}
+	_, err := s.info.Unsubscribe(utils.OrderUpdatesSubscription{User: s.user}, ordersSubID)
+	return err
+}
+
+// Resync fetches any fills and order updates missed since the last delivered
+// event and emits a Resynced event. It is called automatically when the fills
+// subscription is re-established, and can be used to retry after a
+// ResyncFailed event, except one with ErrUserStreamGap, whose missing events
+// the API no longer returns.
+func (s *UserStream) Resync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resync(ctx, nil)
+}
+
+// LastFillTime returns the time of the most recent delivered fill
+func (s *UserStream) LastFillTime() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastFillTime
+}
+
+// onUserFills handles userFills messages
+func (s *UserStream) onUserFills(msg WsMsg) {
+	var data utils.UserFillsData
+	if err := decodeResult(msg.Data, &data); err != nil {
+		s.info.logger.Warn("Failed to decode user fills", "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !data.IsSnapshot {
+		s.deliver(s.pendingFills(data.Fills))
+		return
+	}
+
+	s.snapshots++
+	if s.snapshots == 1 {
+		// Initial subscription: catch up from the start time without a marker
+		if _, err := s.reconcile(context.Background(), data.Fills); err != nil {
+			s.emit(UserStreamEvent{Type: UserStreamResyncFailed, Err: err})
+		}
+		return
+	}
+	if err := s.resync(context.Background(), data.Fills); err != nil {
+		s.info.logger.Error("User stream resync failed", "err", err)
+	}
+}
+
+// onOrderUpdates handles orderUpdates messages
+func (s *UserStream) onOrderUpdates(msg WsMsg) {
+	updates, err := decodeOrderUpdates(msg.Data)
+	if err != nil {
+		s.info.logger.Warn("Failed to decode order updates", "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliver(s.pendingOrderUpdates(updates))
+}
+
+// resync reconciles over REST and emits a Resynced or ResyncFailed event.
+// The caller must hold s.mu.
+func (s *UserStream) resync(ctx context.Context, snapshot []utils.Fill) error {
+	recovered, err := s.reconcile(ctx, snapshot)
+	if err != nil {
+		s.emit(UserStreamEvent{Type: UserStreamResyncFailed, Err: err})
+		return err
+	}
+	s.emit(UserStreamEvent{Type: UserStreamResynced, Recovered: recovered})
+	return nil
+}
+
+// reconcile merges the snapshot fills with fills and order updates fetched
+// over REST and delivers the ones not seen yet. It returns ErrUserStreamGap
+// after delivering them when some may be missing. The caller must hold s.mu.
+func (s *UserStream) reconcile(ctx context.Context, snapshot []utils.Fill) (int, error) {
+	var fills []utils.Fill
+	truncated, err := s.info.userFillsByTimePaged(ctx, s.user, s.floor(s.lastFillTime), nil, func(page []utils.Fill) error {
+		fills = append(fills, page...)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch user fills: %w", err)
+	}
+	var gap error
+	if truncated {
+		gap = fmt.Errorf("%w: more than %d fills share a millisecond", ErrUserStreamGap, FillsPageLimit)
+	}
+
+	orders, err := s.info.HistoricalOrdersCtx(ctx, s.user)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch historical orders: %w", err)
+	}
+	updates := make([]utils.OrderUpdate, len(orders))
+	oldest := int64(-1)
+	for i, order := range orders {
+		updates[i] = order.OrderUpdate()
+		if oldest < 0 || updates[i].StatusTimestamp < oldest {
+			oldest = updates[i].StatusTimestamp
+		}
+	}
+	if gap == nil && len(orders) >= HistoricalOrdersLimit && oldest > s.floor(s.lastStatusTime) {
+		gap = fmt.Errorf("%w: historical orders only go back to %d", ErrUserStreamGap, oldest)
+	}
+
+	events := append(s.pendingFills(append(fills, snapshot...)), s.pendingOrderUpdates(updates)...)
+	sort.SliceStable(events, func(a, b int) bool {
+		return eventTime(events[a]) < eventTime(events[b])
+	})
+	s.deliver(events)
+	return len(events), gap
+}
+
+// pendingFills returns the fills not delivered yet, oldest first
+func (s *UserStream) pendingFills(fills []utils.Fill) []UserStreamEvent {
+	floor := s.floor(s.lastFillTime)
+	seen := make(map[int]bool)
+	var events []UserStreamEvent
+	for i := range fills {
+		fill := fills[i]
+		if fill.Time < floor || seen[fill.Tid] {
+			continue
+		}
+		if _, ok := s.seenFills[fill.Tid]; ok {
+			continue
+		}
+		seen[fill.Tid] = true
+		events = append(events, UserStreamEvent{Type: UserStreamFill, Fill: &fill})
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		if events[a].Fill.Time != events[b].Fill.Time {
+			return events[a].Fill.Time < events[b].Fill.Time
+		}
+		return events[a].Fill.Tid < events[b].Fill.Tid
+	})
+	return events
+}
+
+// pendingOrderUpdates returns the order updates not delivered yet, oldest first
+func (s *UserStream) pendingOrderUpdates(updates []utils.OrderUpdate) []UserStreamEvent {
+	floor := s.floor(s.lastStatusTime)
+	seen := make(map[string]bool)
+	var events []UserStreamEvent
+	for i := range updates {
+		update := updates[i]
+		key := orderUpdateKey(update)
+		if update.StatusTimestamp < floor || seen[key] {
+			continue
+		}
+		if _, ok := s.seenOrders[key]; ok {
+			continue
+		}
+		seen[key] = true
+		events = append(events, UserStreamEvent{Type: UserStreamOrderUpdate, OrderUpdate: &update})
+	}
+	sort.SliceStable(events, func(a, b int) bool {
+		return events[a].OrderUpdate.StatusTimestamp < events[b].OrderUpdate.StatusTimestamp
+	})
+	return events
+}
+
+// deliver records events as seen, advances the watermarks and emits them
+func (s *UserStream) deliver(events []UserStreamEvent) {
+	for _, event := range events {
+		switch event.Type {
+		case UserStreamFill:
+			s.seenFills[event.Fill.Tid] = event.Fill.Time
+			if event.Fill.Time > s.lastFillTime {
+				s.lastFillTime = event.Fill.Time
+			}
+		case UserStreamOrderUpdate:
+			s.seenOrders[orderUpdateKey(*event.OrderUpdate)] = event.OrderUpdate.StatusTimestamp
+			if event.OrderUpdate.StatusTimestamp > s.lastStatusTime {
+				s.lastStatusTime = event.OrderUpdate.StatusTimestamp
+			}
+		}
+		s.emit(event)
+	}
+	s.prune()
+}
+
+// emit assigns the next sequence number and calls the handler
+func (s *UserStream) emit(event UserStreamEvent) {
+	s.seq++
+	event.Seq = s.seq
+	s.handler(event)
+}
+
+// prune forgets seen events that are older than the reconciliation window
+func (s *UserStream) prune() {
+	fillFloor := s.floor(s.lastFillTime)
+	for tid, t := range s.seenFills {
+		if t < fillFloor {
+			delete(s.seenFills, tid)
+		}
+	}
+	statusFloor := s.floor(s.lastStatusTime)
+	for key, t := range s.seenOrders {
+		if t < statusFloor {
+			delete(s.seenOrders, key)
+		}
+	}
+}
+
+// floor returns the earliest event time still considered for delivery
+func (s *UserStream) floor(watermark int64) int64 {
+	if floor := watermark - userStreamOverlapMs; floor > s.startTime {
+		return floor
+	}
+	return s.startTime
+}
+
+// orderUpdateKey identifies an order status change
+func orderUpdateKey(update utils.OrderUpdate) string {
+	return fmt.Sprintf("%d:%s:%d", update.Order.Oid, update.Status, update.StatusTimestamp)
+}
+
+// eventTime returns the exchange timestamp of a fill or order update event
+func eventTime(event UserStreamEvent) int64 {
+	if event.Fill != nil {
+		return event.Fill.Time
+	}
+	return event.OrderUpdate.StatusTimestamp
+}
@@ -0,0 +1,351 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Action is implemented by typed actions. Their struct fields carry msgpack
+// tags in the exact order the exchange hashes them, so the action hash is
+// deterministic unlike map based actions.
+type Action interface {
+	ActionType() string
+}
+
+// OrderAction places one or more orders
+type OrderAction struct {
+	Type     string       `json:"type" msgpack:"type"`
+	Orders   []OrderWire  `json:"orders" msgpack:"orders"`
+	Grouping Grouping     `json:"grouping" msgpack:"grouping"`
+	Builder  *BuilderInfo `json:"builder,omitempty" msgpack:"builder,omitempty"`
+}
+
+// ActionType implements Action
+func (a OrderAction) ActionType() string { return a.Type }
+
+// BatchModifyAction modifies one or more resting orders
+type BatchModifyAction struct {
+	Type     string       `json:"type" msgpack:"type"`
+	Modifies []ModifyWire `json:"modifies" msgpack:"modifies"`
+}
+
+// ActionType implements Action
+func (a BatchModifyAction) ActionType() string { return a.Type }
+
+// CancelWire is the wire format of a cancel by order id
+type CancelWire struct {
+	A int `json:"a" msgpack:"a"` // asset
+	O int `json:"o" msgpack:"o"` // oid
+}
+
+// CancelAction cancels one or more orders by order id
+type CancelAction struct {
+	Type    string       `json:"type" msgpack:"type"`
+	Cancels []CancelWire `json:"cancels" msgpack:"cancels"`
+}
+
+// ActionType implements Action
+func (a CancelAction) ActionType() string { return a.Type }
+
+// NoopAction does nothing but use up its nonce
+type NoopAction struct {
+	Type string `json:"type" msgpack:"type"`
+}
+
+// ActionType implements Action
+func (a NoopAction) ActionType() string { return a.Type }
+
+// ReserveRequestWeightAction buys additional exchange requests for the
+// address
+type ReserveRequestWeightAction struct {
+	Type   string `json:"type" msgpack:"type"`
+	Weight int    `json:"weight" msgpack:"weight"`
+}
+
+// ActionType implements Action
+func (a ReserveRequestWeightAction) ActionType() string { return a.Type }
+
+// UpdateLeverageAction sets the leverage and margin mode of an asset
+type UpdateLeverageAction struct {
+	Type     string `json:"type" msgpack:"type"`
+	Asset    int    `json:"asset" msgpack:"asset"`
+	IsCross  bool   `json:"isCross" msgpack:"isCross"`
+	Leverage int    `json:"leverage" msgpack:"leverage"`
+}
+
+// ActionType implements Action
+func (a UpdateLeverageAction) ActionType() string { return a.Type }
+
+// TwapOrderAction places a TWAP order
+type TwapOrderAction struct {
+	Type string   `json:"type" msgpack:"type"`
+	Twap TwapWire `json:"twap" msgpack:"twap"`
+}
+
+// ActionType implements Action
+func (a TwapOrderAction) ActionType() string { return a.Type }
+
+// TwapCancelAction cancels a running TWAP order
+type TwapCancelAction struct {
+	Type string `json:"type" msgpack:"type"`
+	A    int    `json:"a" msgpack:"a"` // asset
+	T    int    `json:"t" msgpack:"t"` // twap id
+}
+
+// ActionType implements Action
+func (a TwapCancelAction) ActionType() string { return a.Type }
+
+// CreateSubAccountAction creates a named sub-account
+type CreateSubAccountAction struct {
+	Type string `json:"type" msgpack:"type"`
+	Name string `json:"name" msgpack:"name"`
+}
+
+// ActionType implements Action
+func (a CreateSubAccountAction) ActionType() string { return a.Type }
+
+// SubAccountTransferAction moves USD to or from a sub-account
+type SubAccountTransferAction struct {
+	Type           string `json:"type" msgpack:"type"`
+	SubAccountUser string `json:"subAccountUser" msgpack:"subAccountUser"`
+	IsDeposit      bool   `json:"isDeposit" msgpack:"isDeposit"`
+	Usd            int64  `json:"usd" msgpack:"usd"`
+}
+
+// ActionType implements Action
+func (a SubAccountTransferAction) ActionType() string { return a.Type }
+
+// SubAccountSpotTransferAction moves a spot token to or from a sub-account
+type SubAccountSpotTransferAction struct {
+	Type           string `json:"type" msgpack:"type"`
+	SubAccountUser string `json:"subAccountUser" msgpack:"subAccountUser"`
+	IsDeposit      bool   `json:"isDeposit" msgpack:"isDeposit"`
+	Token          string `json:"token" msgpack:"token"`
+	Amount         string `json:"amount" msgpack:"amount"`
+}
+
+// ActionType implements Action
+func (a SubAccountSpotTransferAction) ActionType() string { return a.Type }
+
+// VaultTransferAction deposits into or withdraws from a vault
+type VaultTransferAction struct {
+	Type         string `json:"type" msgpack:"type"`
+	VaultAddress string `json:"vaultAddress" msgpack:"vaultAddress"`
+	IsDeposit    bool   `json:"isDeposit" msgpack:"isDeposit"`
+	Usd          int64  `json:"usd" msgpack:"usd"`
+}
+
+// ActionType implements Action
+func (a VaultTransferAction) ActionType() string { return a.Type }
+
+// PerpAssetRequest describes a new asset on a builder-deployed perp dex
+type PerpAssetRequest struct {
+	Coin          string `json:"coin" msgpack:"coin"`
+	SzDecimals    int    `json:"szDecimals" msgpack:"szDecimals"`
+	OraclePx      string `json:"oraclePx" msgpack:"oraclePx"`
+	MarginTableID int    `json:"marginTableId" msgpack:"marginTableId"`
+	OnlyIsolated  bool   `json:"onlyIsolated" msgpack:"onlyIsolated"`
+}
+
+// PerpDexSchemaWire is the wire format of PerpDexSchemaInput
+type PerpDexSchemaWire struct {
+	FullName        string  `json:"fullName" msgpack:"fullName"`
+	CollateralToken int     `json:"collateralToken" msgpack:"collateralToken"`
+	OracleUpdater   *string `json:"oracleUpdater" msgpack:"oracleUpdater"`
+}
+
+// PerpDeployRegisterAsset registers an asset on a perp dex, bidding up to
+// MaxGas in the deploy auction
+type PerpDeployRegisterAsset struct {
+	MaxGas       *int64             `json:"maxGas" msgpack:"maxGas"`
+	AssetRequest PerpAssetRequest   `json:"assetRequest" msgpack:"assetRequest"`
+	Dex          string             `json:"dex" msgpack:"dex"`
+	Schema       *PerpDexSchemaWire `json:"schema" msgpack:"schema"`
+}
+
+// PerpDeploySetOracle updates oracle and mark prices of a perp dex. Prices are
+// [coin, px] pairs sorted by coin.
+type PerpDeploySetOracle struct {
+	Dex       string      `json:"dex" msgpack:"dex"`
+	OraclePxs [][2]string `json:"oraclePxs" msgpack:"oraclePxs"`
+	MarkPxs   [][2]string `json:"markPxs" msgpack:"markPxs"`
+}
+
+// PerpDeployAction is the perpDeploy action; exactly one variant is set
+type PerpDeployAction struct {
+	Type          string                   `json:"type" msgpack:"type"`
+	RegisterAsset *PerpDeployRegisterAsset `json:"registerAsset,omitempty" msgpack:"registerAsset,omitempty"`
+	SetOracle     *PerpDeploySetOracle     `json:"setOracle,omitempty" msgpack:"setOracle,omitempty"`
+}
+
+// ActionType implements Action
+func (a PerpDeployAction) ActionType() string { return a.Type }
+
+// SpotTokenSpec describes a token registered by a spot deployer
+type SpotTokenSpec struct {
+	Name        string `json:"name" msgpack:"name"`
+	SzDecimals  int    `json:"szDecimals" msgpack:"szDecimals"`
+	WeiDecimals int    `json:"weiDecimals" msgpack:"weiDecimals"`
+}
+
+// SpotDeployRegisterToken registers a token, bidding up to MaxGas in the
+// deploy auction
+type SpotDeployRegisterToken struct {
+	Spec     SpotTokenSpec `json:"spec" msgpack:"spec"`
+	MaxGas   int64         `json:"maxGas" msgpack:"maxGas"`
+	FullName string        `json:"fullName" msgpack:"fullName"`
+}
+
+// SpotDeployGenesis sets the maximum supply of a registered token
+type SpotDeployGenesis struct {
+	Token            int    `json:"token" msgpack:"token"`
+	MaxSupply        string `json:"maxSupply" msgpack:"maxSupply"`
+	NoHyperliquidity bool   `json:"noHyperliquidity,omitempty" msgpack:"noHyperliquidity,omitempty"`
+}
+
+// SpotDeployRegisterSpot creates the spot pair for a base and quote token
+type SpotDeployRegisterSpot struct {
+	Tokens [2]int `json:"tokens" msgpack:"tokens"`
+}
+
+// SpotDeploySetDeployerTradingFeeShare sets the deployer's share of trading fees
+type SpotDeploySetDeployerTradingFeeShare struct {
+	Token int    `json:"token" msgpack:"token"`
+	Share string `json:"share" msgpack:"share"`
+}
+
+// SpotDeployAction is the spotDeploy action; exactly one variant is set
+type SpotDeployAction struct {
+	Type                       string                                `json:"type" msgpack:"type"`
+	RegisterToken2             *SpotDeployRegisterToken              `json:"registerToken2,omitempty" msgpack:"registerToken2,omitempty"`
+	Genesis                    *SpotDeployGenesis                    `json:"genesis,omitempty" msgpack:"genesis,omitempty"`
+	RegisterSpot               *SpotDeployRegisterSpot               `json:"registerSpot,omitempty" msgpack:"registerSpot,omitempty"`
+	SetDeployerTradingFeeShare *SpotDeploySetDeployerTradingFeeShare `json:"setDeployerTradingFeeShare,omitempty" msgpack:"setDeployerTradingFeeShare,omitempty"`
+}
+
+// ActionType implements Action
+func (a SpotDeployAction) ActionType() string { return a.Type }
+
+// MultiSigPayload is the inner action a multiSig action executes on behalf
+// of MultiSigUser, submitted by OuterSigner
+type MultiSigPayload struct {
+	MultiSigUser string `json:"multiSigUser" msgpack:"multiSigUser"`
+	OuterSigner  string `json:"outerSigner" msgpack:"outerSigner"`
+	Action       Action `json:"action" msgpack:"action"`
+}
+
+// MultiSigAction submits an action signed by the authorized users of a
+// multi-sig user
+type MultiSigAction struct {
+	Type             string          `json:"type" msgpack:"type"`
+	SignatureChainID string          `json:"signatureChainId" msgpack:"signatureChainId"`
+	Signatures       []Signature     `json:"signatures" msgpack:"signatures"`
+	Payload          MultiSigPayload `json:"payload" msgpack:"payload"`
+}
+
+// ActionType implements Action
+func (a MultiSigAction) ActionType() string { return a.Type }
+
+// multiSigActionWithoutTag is a MultiSigAction as its multiSigActionHash
+// covers it, without the type tag
+type multiSigActionWithoutTag struct {
+	SignatureChainID string          `msgpack:"signatureChainId"`
+	Signatures       []Signature     `msgpack:"signatures"`
+	Payload          MultiSigPayload `msgpack:"payload"`
+}
+
+// UserSignedAction is a user-signed action, such as usdSend or withdraw3,
+// executed inside a multiSig action. Its fields are packed in the order the
+// exchange hashes them: the type tag, signatureChainId and then the fields of
+// its EIP-712 types, starting with hyperliquidChain.
+type UserSignedAction struct {
+	keys   []string
+	values []interface{}
+}
+
+// NewUserSignedAction orders a user-signed action for hashing inside a
+// multiSig action. The action is completed with the chain fields it is
+// signed with, as in SignMultiSigUserSignedActionPayload, and must hold its
+// type and every other field of payloadTypes.
+func NewUserSignedAction(action map[string]interface{}, payloadTypes []apitypes.Type, isMainnet bool) (UserSignedAction, error) {
+	withChain := UserSignedActionWithChain(action, isMainnet, SigningConfig{})
+	keys := []string{"type", "signatureChainId"}
+	for _, field := range payloadTypes {
+		if field.Name != "type" && field.Name != "signatureChainId" {
+			keys = append(keys, field.Name)
+		}
+	}
+	if len(withChain) != len(keys) {
+		return UserSignedAction{}, fmt.Errorf("user-signed action has %d fields, its types declare %d", len(withChain), len(keys))
+	}
+
+	values := make([]interface{}, len(keys))
+	for i, key := range keys {
+		value, ok := withChain[key]
+		if !ok {
+			return UserSignedAction{}, fmt.Errorf("field %s not found in action", key)
+		}
+		switch value.(type) {
+		case string, bool, int, int64, uint64:
+		default:
+			return UserSignedAction{}, fmt.Errorf("field %s has unsupported type %T", key, value)
+		}
+		values[i] = value
+	}
+	if _, ok := values[0].(string); !ok {
+		return UserSignedAction{}, fmt.Errorf("type of the user-signed action must be a string")
+	}
+	return UserSignedAction{keys: keys, values: values}, nil
+}
+
+// ActionType implements Action
+func (a UserSignedAction) ActionType() string {
+	if len(a.values) == 0 {
+		return ""
+	}
+	return a.values[0].(string)
+}
+
+// EncodeMsgpack implements msgpack.CustomEncoder, packing the fields in order
+func (a UserSignedAction) EncodeMsgpack(enc *msgpack.Encoder) error {
+	if err := enc.EncodeMapLen(len(a.keys)); err != nil {
+		return err
+	}
+	for i, key := range a.keys {
+		if err := enc.EncodeString(key); err != nil {
+			return err
+		}
+		if err := enc.Encode(a.values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding the fields in order
+func (a UserSignedAction) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range a.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		encodedValue, err := json.Marshal(a.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(encodedValue)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -4,4 +4,10 @@ const (
 	MainnetAPIURL = "https://api.hyperliquid.xyz"
 	TestnetAPIURL = "https://api.hyperliquid-testnet.xyz"
 	LocalAPIURL   = "http://localhost:3001"
-)
\ No newline at end of file
+)
+
+// RPC hosts serving the /explorer endpoints
+const (
+	MainnetRPCURL = "https://rpc.hyperliquid.xyz"
+	TestnetRPCURL = "https://rpc.hyperliquid-testnet.xyz"
+)
@@ -1,5 +1,84 @@
 package utils
 
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors for the common reasons the exchange rejects a request. The
+// errors the client returns for a rejection match them with errors.Is, while
+// keeping the exchange's own message.
+var (
+	// ErrRateLimited is a request refused for exceeding a rate limit
+	ErrRateLimited = errors.New("rate limited")
+	// ErrInsufficientMargin is an order the account lacks the margin for
+	ErrInsufficientMargin = errors.New("insufficient margin")
+	// ErrInsufficientBalance is a spot order or transfer the account lacks
+	// the balance for
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	// ErrOrderNotFound is a cancel or modify of an order that was never
+	// placed or is already filled or canceled
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrNonceStale is an action whose nonce was already used or is outside
+	// the window the exchange accepts
+	ErrNonceStale = errors.New("stale nonce")
+	// ErrInvalidPrice is an order price off the tick size or too far from
+	// the reference price
+	ErrInvalidPrice = errors.New("invalid price")
+	// ErrInvalidSize is an order size off the size decimals or zero
+	ErrInvalidSize = errors.New("invalid size")
+	// ErrMinOrderValue is an order worth less than the exchange's minimum
+	ErrMinOrderValue = errors.New("order below minimum value")
+	// ErrPostOnlyWouldMatch is a post-only order that would have crossed
+	ErrPostOnlyWouldMatch = errors.New("post-only order would have matched")
+	// ErrNoImmediateMatch is an IOC order that found nothing to match
+	ErrNoImmediateMatch = errors.New("IOC order could not match")
+	// ErrReduceOnly is a reduce-only order that would increase the position
+	ErrReduceOnly = errors.New("reduce-only order would increase position")
+	// ErrUnknownUser is an action signed by a wallet the exchange does not
+	// know, usually an agent that was never approved
+	ErrUnknownUser = errors.New("user or API wallet does not exist")
+)
+
+// messageKinds maps fragments of the exchange's error messages, lowercased,
+// to the sentinel error they report
+var messageKinds = []struct {
+	fragment string
+	err      error
+}{
+	{"too many cumulative requests", ErrRateLimited},
+	{"rate limit", ErrRateLimited},
+	{"insufficient margin", ErrInsufficientMargin},
+	{"insufficient spot balance", ErrInsufficientBalance},
+	{"insufficient balance", ErrInsufficientBalance},
+	{"order was never placed, already canceled, or filled", ErrOrderNotFound},
+	{"cannot modify canceled or filled order", ErrOrderNotFound},
+	{"nonce", ErrNonceStale},
+	{"invalid price", ErrInvalidPrice},
+	{"divisible by tick size", ErrInvalidPrice},
+	{"away from the reference price", ErrInvalidPrice},
+	{"invalid size", ErrInvalidSize},
+	{"minimum value of", ErrMinOrderValue},
+	{"post only order would have immediately matched", ErrPostOnlyWouldMatch},
+	{"could not immediately match", ErrNoImmediateMatch},
+	{"reduce only order would increase position", ErrReduceOnly},
+	{"user or api wallet", ErrUnknownUser},
+}
+
+// ClassifyMessage returns the sentinel error an exchange error message
+// reports, or nil when it is none of them
+func ClassifyMessage(message string) error {
+	message = strings.ToLower(message)
+	for _, kind := range messageKinds {
+		if strings.Contains(message, kind.fragment) {
+			return kind.err
+		}
+	}
+	return nil
+}
 
 type HyperliquidError interface {
 	error
@@ -18,6 +97,39 @@ func (e *ClientError) Error() string {
 	return e.ErrorMessage
 }
 
+// Unwrap returns ErrRateLimited for a 429 response, or else the sentinel
+// error the message reports, if any.
+func (e *ClientError) Unwrap() error {
+	if e.StatusCode == http.StatusTooManyRequests {
+		return ErrRateLimited
+	}
+	return ClassifyMessage(e.ErrorMessage)
+}
+
+// RateLimitError is the error of a 429 response. RetryAfter is how long the
+// server asked to wait with its Retry-After header, or zero if it did not.
+type RateLimitError struct {
+	*ClientError
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface for RateLimitError.
+func (e *RateLimitError) Error() string {
+	message := "rate limited"
+	if e.ErrorMessage != "" {
+		message += ": " + e.ErrorMessage
+	}
+	if e.RetryAfter > 0 {
+		message += fmt.Sprintf(" (retry after %s)", e.RetryAfter)
+	}
+	return message
+}
+
+// Unwrap returns the ClientError of the response.
+func (e *RateLimitError) Unwrap() error {
+	return e.ClientError
+}
+
 type ServerError struct {
 	StatusCode int
 	Message    string
@@ -26,4 +138,63 @@ type ServerError struct {
 // Error implements the error interface for ServerError.
 func (e *ServerError) Error() string {
 	return e.Message
-}
\ No newline at end of file
+}
+
+// ExchangeError is returned when the exchange rejects an action as a whole,
+// answering with status "err" instead of per-order statuses
+type ExchangeError struct {
+	Status  string
+	Message string
+}
+
+// Error implements the error interface for ExchangeError.
+func (e *ExchangeError) Error() string {
+	return "exchange rejected action: " + e.Message
+}
+
+// Unwrap returns the sentinel error the message reports, if any.
+func (e *ExchangeError) Unwrap() error {
+	return ClassifyMessage(e.Message)
+}
+
+// OrderError is a single rejected order of a bulk order or cancel action
+type OrderError struct {
+	Index   int
+	Message string
+}
+
+// Error implements the error interface for OrderError.
+func (e *OrderError) Error() string {
+	return fmt.Sprintf("#%d: %s", e.Index, e.Message)
+}
+
+// Unwrap returns the sentinel error the message reports, if any.
+func (e *OrderError) Unwrap() error {
+	return ClassifyMessage(e.Message)
+}
+
+// PartialFailureError is returned together with the response when some
+// orders of a bulk action were rejected while the rest went through
+type PartialFailureError struct {
+	Total  int
+	Failed []OrderError
+}
+
+// Error implements the error interface for PartialFailureError.
+func (e *PartialFailureError) Error() string {
+	messages := make([]string, len(e.Failed))
+	for i := range e.Failed {
+		messages[i] = e.Failed[i].Error()
+	}
+	return fmt.Sprintf("%d of %d orders rejected: %s", len(e.Failed), e.Total, strings.Join(messages, "; "))
+}
+
+// Unwrap returns the error of every rejected order, so that errors.Is
+// matches a sentinel error when any order was rejected for its reason.
+func (e *PartialFailureError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i := range e.Failed {
+		errs[i] = &e.Failed[i]
+	}
+	return errs
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signer produces EIP-712 signatures for exchange actions. Implementations can
+// keep the key outside the process, for example in a KMS, an HSM or a remote
+// signing service.
+type Signer interface {
+	// SignTypedData signs the EIP-712 digest of data, returning v as 27 or 28
+	SignTypedData(data apitypes.TypedData) (*Signature, error)
+	// Address returns the address of the signing key
+	Address() common.Address
+}
+
+// LocalSigner signs with an in-memory private key
+type LocalSigner struct {
+	privateKey *ecdsa.PrivateKey
+}
+
+// NewLocalSigner creates a Signer backed by privateKey
+func NewLocalSigner(privateKey *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{privateKey: privateKey}
+}
+
+// SignTypedData implements Signer
+func (s *LocalSigner) SignTypedData(data apitypes.TypedData) (*Signature, error) {
+	return SignInner(s.privateKey, data)
+}
+
+// Address implements Signer
+func (s *LocalSigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.privateKey.PublicKey)
+}
+
+// PrivateKey returns the key the signer signs with
+func (s *LocalSigner) PrivateKey() *ecdsa.PrivateKey {
+	return s.privateKey
+}
@@ -1,15 +1,20 @@
 package utils
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math"
 	"math/big"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	ethmath "github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -47,7 +52,7 @@ const (
 
 // LimitOrderType represents a limit order configuration
 type LimitOrderType struct {
-	TIF TIF `json:"tif"`
+	TIF TIF `json:"tif" msgpack:"tif"`
 }
 
 // TriggerOrderType represents a trigger order configuration
@@ -59,9 +64,9 @@ type TriggerOrderType struct {
 
 // TriggerOrderTypeWire represents a trigger order for wire format
 type TriggerOrderTypeWire struct {
-	TriggerPx string `json:"triggerPx"`
-	IsMarket  bool   `json:"isMarket"`
-	TPSL      TPSL   `json:"tpsl"`
+	IsMarket  bool   `json:"isMarket" msgpack:"isMarket"`
+	TriggerPx string `json:"triggerPx" msgpack:"triggerPx"`
+	TPSL      TPSL   `json:"tpsl" msgpack:"tpsl"`
 }
 
 // OrderType represents the type of order (limit or trigger)
@@ -72,8 +77,8 @@ type OrderType struct {
 
 // OrderTypeWire represents the wire format of order type
 type OrderTypeWire struct {
-	Limit   *LimitOrderType       `json:"limit,omitempty"`
-	Trigger *TriggerOrderTypeWire `json:"trigger,omitempty"`
+	Limit   *LimitOrderType       `json:"limit,omitempty" msgpack:"limit,omitempty"`
+	Trigger *TriggerOrderTypeWire `json:"trigger,omitempty" msgpack:"trigger,omitempty"`
 }
 
 // Order represents a simplified order structure
@@ -99,13 +104,23 @@ type OrderRequest struct {
 
 // OrderWire represents the wire format of an order
 type OrderWire struct {
-	A int            `json:"a"`      // asset
-	B bool           `json:"b"`      // is_buy
-	P string         `json:"p"`      // price
-	S string         `json:"s"`      // size
-	R bool           `json:"r"`      // reduce_only
-	T OrderTypeWire  `json:"t"`      // order_type
-	C *string        `json:"c,omitempty"` // cloid
+	A int           `json:"a" msgpack:"a"`                     // asset
+	B bool          `json:"b" msgpack:"b"`                     // is_buy
+	P string        `json:"p" msgpack:"p"`                     // price
+	S string        `json:"s" msgpack:"s"`                     // size
+	R bool          `json:"r" msgpack:"r"`                     // reduce_only
+	T OrderTypeWire `json:"t" msgpack:"t"`                     // order_type
+	C *string       `json:"c,omitempty" msgpack:"c,omitempty"` // cloid
+}
+
+// TwapWire represents the wire format of a TWAP order
+type TwapWire struct {
+	A int    `json:"a" msgpack:"a"` // asset
+	B bool   `json:"b" msgpack:"b"` // is_buy
+	S string `json:"s" msgpack:"s"` // size
+	R bool   `json:"r" msgpack:"r"` // reduce_only
+	M int    `json:"m" msgpack:"m"` // duration in minutes
+	T bool   `json:"t" msgpack:"t"` // randomize
 }
 
 // ModifyRequest represents a request to modify an order
@@ -116,8 +131,8 @@ type ModifyRequest struct {
 
 // ModifyWire represents the wire format of a modify request
 type ModifyWire struct {
-	OID   int       `json:"oid"`
-	Order OrderWire `json:"order"`
+	OID   interface{} `json:"oid" msgpack:"oid"` // int oid or raw cloid string
+	Order OrderWire   `json:"order" msgpack:"order"`
 }
 
 // CancelRequest represents a request to cancel an order
@@ -134,10 +149,13 @@ type CancelByCloidRequest struct {
 
 // ScheduleCancelAction represents a scheduled cancel action
 type ScheduleCancelAction struct {
-	Type string `json:"type"`
-	Time *int64 `json:"time,omitempty"`
+	Type string `json:"type" msgpack:"type"`
+	Time *int64 `json:"time,omitempty" msgpack:"time,omitempty"`
 }
 
+// ActionType implements Action
+func (a ScheduleCancelAction) ActionType() string { return a.Type }
+
 // EIP712 type definitions for various signing operations
 var (
 	USDSendSignTypes = []apitypes.Type{
@@ -180,6 +198,29 @@ var (
 		{Name: "nonce", Type: "uint64"},
 	}
 
+	PerpDexClassTransferSignTypes = []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "dex", Type: "string"},
+		{Name: "token", Type: "string"},
+		{Name: "amount", Type: "string"},
+		{Name: "toPerp", Type: "bool"},
+		{Name: "nonce", Type: "uint64"},
+	}
+
+	ApproveAgentSignTypes = []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "agentAddress", Type: "address"},
+		{Name: "agentName", Type: "string"},
+		{Name: "nonce", Type: "uint64"},
+	}
+
+	ApproveBuilderFeeSignTypes = []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "maxFeeRate", Type: "string"},
+		{Name: "builder", Type: "address"},
+		{Name: "nonce", Type: "uint64"},
+	}
+
 	TokenDelegateTypes = []apitypes.Type{
 		{Name: "hyperliquidChain", Type: "string"},
 		{Name: "validator", Type: "address"},
@@ -188,6 +229,18 @@ var (
 		{Name: "nonce", Type: "uint64"},
 	}
 
+	CDepositSignTypes = []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "wei", Type: "uint64"},
+		{Name: "nonce", Type: "uint64"},
+	}
+
+	CWithdrawSignTypes = []apitypes.Type{
+		{Name: "hyperliquidChain", Type: "string"},
+		{Name: "wei", Type: "uint64"},
+		{Name: "nonce", Type: "uint64"},
+	}
+
 	ConvertToMultiSigUserSignTypes = []apitypes.Type{
 		{Name: "hyperliquidChain", Type: "string"},
 		{Name: "signers", Type: "string"},
@@ -203,9 +256,38 @@ var (
 
 // Signature represents an Ethereum signature
 type Signature struct {
-	R string `json:"r"`
-	S string `json:"s"`
-	V uint8  `json:"v"`
+	R string `json:"r" msgpack:"r"`
+	S string `json:"s" msgpack:"s"`
+	V uint8  `json:"v" msgpack:"v"`
+}
+
+// ToVRS decodes the signature into its recovery id and r, s components
+func (sig Signature) ToVRS() (uint8, [32]byte, [32]byte, error) {
+	var r, s [32]byte
+	rBytes, err := hexutil.Decode(sig.R)
+	if err != nil {
+		return 0, r, s, fmt.Errorf("invalid signature r: %w", err)
+	}
+	sBytes, err := hexutil.Decode(sig.S)
+	if err != nil {
+		return 0, r, s, fmt.Errorf("invalid signature s: %w", err)
+	}
+	if len(rBytes) > 32 || len(sBytes) > 32 {
+		return 0, r, s, fmt.Errorf("signature r and s must be at most 32 bytes")
+	}
+	copy(r[32-len(rBytes):], rBytes)
+	copy(s[32-len(sBytes):], sBytes)
+	return sig.V, r, s, nil
+}
+
+// Bytes returns the 65 byte r || s || v encoding of the signature, with v
+// being 27 or 28
+func (sig Signature) Bytes() ([]byte, error) {
+	v, r, s, err := sig.ToVRS()
+	if err != nil {
+		return nil, err
+	}
+	return append(append(r[:], s[:]...), v), nil
 }
 
 // PhantomAgent represents a phantom agent for L1 actions
@@ -214,30 +296,133 @@ type PhantomAgent struct {
 	ConnectionID string `json:"connectionId"`
 }
 
-// FloatToWire converts a float to wire format string with proper precision
+// WireMaxDecimals is the number of decimals FloatToWire allows, the most any
+// price or size on the exchange has
+const WireMaxDecimals = 8
+
+// wireTolerance is the relative difference between a float and its wire
+// format below which the difference is taken as float64 representation error
+const wireTolerance = 1e-14
+
+// FloatToWire converts a float to wire format string with proper precision,
+// allowing up to WireMaxDecimals decimals; see FloatToWireDecimals
 func FloatToWire(x float64) (string, error) {
-	rounded := fmt.Sprintf("%.8f", x)
-	parsedRounded, err := strconv.ParseFloat(rounded, 64)
+	return FloatToWireDecimals(x, WireMaxDecimals)
+}
+
+// FloatToWireDecimals converts a float to its wire format, the shortest
+// decimal string that parses back to it, and fails if that needs more than
+// maxDecimals decimals. Floats within representation error of a value with
+// maxDecimals decimals, such as 1e6*(0.1+0.2), convert to that value instead.
+func FloatToWireDecimals(x float64, maxDecimals int) (string, error) {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return "", fmt.Errorf("float_to_wire: invalid number %v", x)
+	}
+	value, ok := new(big.Rat).SetString(strconv.FormatFloat(x, 'g', -1, 64))
+	if !ok {
+		return "", fmt.Errorf("float_to_wire: invalid number %v", x)
+	}
+	wire, decimals := ratToWire(value)
+	if decimals <= maxDecimals {
+		return wire, nil
+	}
+
+	if maxDecimals < 0 {
+		maxDecimals = 0
+	}
+	rounded, _ := new(big.Rat).SetString(value.FloatString(maxDecimals))
+	diff, _ := new(big.Rat).Sub(value, rounded).Float64()
+	if math.Abs(diff) > wireTolerance*math.Abs(x) {
+		return "", fmt.Errorf("float_to_wire causes rounding: %s has more than %d decimals", wire, maxDecimals)
+	}
+	wire, _ = ratToWire(rounded)
+	return wire, nil
+}
+
+// WireFromString converts an exact decimal string such as "0.0001230" or
+// "1.5e3" to wire format without going through a float64, so no precision
+// is lost
+func WireFromString(s string) (string, error) {
+	if strings.Contains(s, "/") {
+		return "", fmt.Errorf("invalid decimal: %q", s)
+	}
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return "", fmt.Errorf("invalid decimal: %q", s)
+	}
+	wire, _ := ratToWire(value)
+	return wire, nil
+}
+
+// ratToWire formats a decimal value without trailing zeros or exponent and
+// returns how many decimals it has. The value must be a finite decimal, as
+// any parsed from a decimal string is.
+func ratToWire(value *big.Rat) (string, int) {
+	decimals := 0
+	scaled := new(big.Rat).Set(value)
+	ten := big.NewRat(10, 1)
+	for !scaled.IsInt() {
+		scaled.Mul(scaled, ten)
+		decimals++
+	}
+	wire := value.FloatString(decimals)
+	if wire == "-0" {
+		wire = "0"
+	}
+	return wire, decimals
+}
+
+// Maximum price decimals before subtracting the asset's size decimals
+const (
+	perpMaxDecimals = 6
+	spotMaxDecimals = 8
+)
+
+// RoundPx rounds a price to the nearest one the exchange accepts for an asset
+// with the given size decimals: at most 5 significant figures and at most
+// 6 - szDecimals decimals for perps, 8 - szDecimals for spot. Integer prices
+// are always accepted, so prices of 100000 and above are rounded to integers.
+func RoundPx(px float64, szDecimals int, isSpot bool) float64 {
+	if math.Abs(px) >= 1e5 {
+		return math.Round(px)
+	}
+	sigFigs, err := strconv.ParseFloat(strconv.FormatFloat(px, 'g', 5, 64), 64)
 	if err != nil {
-		return "", err
+		return px
 	}
-	
-	if math.Abs(parsedRounded-x) >= 1e-12 {
-		return "", fmt.Errorf("float_to_wire causes rounding: %f", x)
+	return roundDecimals(sigFigs, PxDecimals(szDecimals, isSpot))
+}
+
+// PxDecimals returns the most decimals a price may have for an asset with the
+// given size decimals: 6 - szDecimals for perps, 8 - szDecimals for spot
+func PxDecimals(szDecimals int, isSpot bool) int {
+	maxDecimals := perpMaxDecimals
+	if isSpot {
+		maxDecimals = spotMaxDecimals
 	}
-	
-	if rounded == "-0.00000000" {
-		rounded = "0.00000000"
+	if maxDecimals < szDecimals {
+		return 0
 	}
-	
-	// Remove trailing zeros and decimal point if not needed
-	trimmed := strings.TrimRight(rounded, "0")
-	trimmed = strings.TrimRight(trimmed, ".")
-	if trimmed == "" {
-		trimmed = "0"
+	return maxDecimals - szDecimals
+}
+
+// RoundSz rounds a size to the nearest one the exchange accepts for an asset
+// with the given size decimals
+func RoundSz(sz float64, szDecimals int) float64 {
+	return roundDecimals(sz, szDecimals)
+}
+
+// roundDecimals rounds x to the given number of decimals, going through its
+// decimal representation so the result is the closest float to it
+func roundDecimals(x float64, decimals int) float64 {
+	if decimals < 0 {
+		decimals = 0
 	}
-	
-	return trimmed, nil
+	rounded, err := strconv.ParseFloat(strconv.FormatFloat(x, 'f', decimals, 64), 64)
+	if err != nil {
+		return x
+	}
+	return rounded
 }
 
 // FloatToIntForHashing converts float to int for hashing with 8 decimal places
@@ -262,6 +447,43 @@ func FloatToInt(x float64, power int) (int64, error) {
 	return int64(rounded), nil
 }
 
+// SpotToWei converts a decimal token amount such as "1.5" to its integer
+// amount of wei for a token with the given wei decimals. The conversion is
+// exact; amounts with more decimals than the token has are rejected.
+func SpotToWei(amount string, weiDecimals int) (*big.Int, error) {
+	if weiDecimals < 0 {
+		return nil, fmt.Errorf("invalid wei decimals: %d", weiDecimals)
+	}
+	if strings.Contains(amount, "/") {
+		return nil, fmt.Errorf("invalid amount: %q", amount)
+	}
+	value, ok := new(big.Rat).SetString(strings.TrimSpace(amount))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount: %q", amount)
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(weiDecimals)), nil)
+	value.Mul(value, new(big.Rat).SetInt(scale))
+	if !value.IsInt() {
+		return nil, fmt.Errorf("amount %s has more than %d decimals", amount, weiDecimals)
+	}
+	return new(big.Int).Set(value.Num()), nil
+}
+
+// WeiToSpot converts an integer amount of wei to the decimal token amount
+// for a token with the given wei decimals, without trailing zeros
+func WeiToSpot(wei *big.Int, weiDecimals int) string {
+	if wei == nil {
+		return "0"
+	}
+	if weiDecimals <= 0 {
+		return wei.String()
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(weiDecimals)), nil)
+	amount := new(big.Rat).SetFrac(wei, scale).FloatString(weiDecimals)
+	amount = strings.TrimRight(amount, "0")
+	return strings.TrimSuffix(amount, ".")
+}
+
 // GetTimestampMs returns current timestamp in milliseconds
 func GetTimestampMs() int64 {
 	return time.Now().UnixMilli()
@@ -269,10 +491,16 @@ func GetTimestampMs() int64 {
 
 // OrderTypeToWire converts OrderType to wire format
 func OrderTypeToWire(orderType OrderType) (OrderTypeWire, error) {
+	return orderTypeToWire(orderType, WireMaxDecimals)
+}
+
+// orderTypeToWire converts OrderType to wire format, allowing pxDecimals
+// decimals in the trigger price
+func orderTypeToWire(orderType OrderType, pxDecimals int) (OrderTypeWire, error) {
 	if orderType.Limit != nil {
 		return OrderTypeWire{Limit: orderType.Limit}, nil
 	} else if orderType.Trigger != nil {
-		triggerPxWire, err := FloatToWire(orderType.Trigger.TriggerPx)
+		triggerPxWire, err := FloatToWireDecimals(orderType.Trigger.TriggerPx, pxDecimals)
 		if err != nil {
 			return OrderTypeWire{}, err
 		}
@@ -287,15 +515,91 @@ func OrderTypeToWire(orderType OrderType) (OrderTypeWire, error) {
 	return OrderTypeWire{}, fmt.Errorf("invalid order type")
 }
 
-// AddressToBytes converts hex address to bytes
+// AddressToBytes converts hex address to bytes, rejecting anything
+// NormalizeAddress rejects
 func AddressToBytes(address string) ([]byte, error) {
-	address = strings.TrimPrefix(address, "0x")
-	return hex.DecodeString(address)
+	normalized, err := NormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(normalized[2:])
+}
+
+// NormalizeAddress checks that address is 20 bytes of hex, with or without
+// the 0x prefix, and returns it lowercase with the prefix, the form actions
+// are hashed with. A mixed-case address must carry a valid EIP-55 checksum,
+// so that a mistyped checksummed address is caught; all-lowercase and
+// all-uppercase addresses carry none.
+func NormalizeAddress(address string) (string, error) {
+	digits := address
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits = digits[2:]
+	}
+	if len(digits) != 2*common.AddressLength {
+		return "", fmt.Errorf("%q has %d hex digits, want %d", address, len(digits), 2*common.AddressLength)
+	}
+	if _, err := hex.DecodeString(digits); err != nil {
+		return "", fmt.Errorf("%q is not hex", address)
+	}
+	lower := strings.ToLower(digits)
+	if digits != lower && digits != strings.ToUpper(digits) && common.HexToAddress(digits).Hex()[2:] != digits {
+		return "", fmt.Errorf("%q fails its EIP-55 checksum", address)
+	}
+	return "0x" + lower, nil
+}
+
+// packAction msgpack-encodes an action the way the exchange does, packing
+// integers into their smallest representation. Actions containing a map are
+// rejected: msgpack packs map keys in random order, so their hash would
+// change from one call to the next.
+func packAction(action interface{}) ([]byte, error) {
+	if err := checkNoMaps(reflect.ValueOf(action), "action"); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+	if err := enc.Encode(action); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// checkNoMaps returns an error naming the first map found in v, at path
+func checkNoMaps(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Map:
+		return fmt.Errorf("%s is a map, which cannot be hashed deterministically; use a struct with msgpack tags", path)
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return checkNoMaps(v.Elem(), path)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if err := checkNoMaps(v.Field(i), path+"."+field.Name); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkNoMaps(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-// ActionHash computes the hash of an action for L1 signing
+// ActionHash computes the hash of an action for L1 signing. The action must
+// be a struct, or a slice of them, whose msgpack tags give the field order
+// the exchange hashes; maps are rejected.
 func ActionHash(action interface{}, vaultAddress *string, nonce uint64, expiresAfter *uint64) ([]byte, error) {
-	data, err := msgpack.Marshal(action)
+	data, err := packAction(action)
 	if err != nil {
 		return nil, err
 	}
@@ -315,7 +619,7 @@ func ActionHash(action interface{}, vaultAddress *string, nonce uint64, expiresA
 		data = append(data, 0x01)
 		vaultBytes, err := AddressToBytes(*vaultAddress)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid vault address: %w", err)
 		}
 		data = append(data, vaultBytes...)
 	}
@@ -349,8 +653,60 @@ func ConstructPhantomAgent(hash []byte, isMainnet bool) PhantomAgent {
 	}
 }
 
+// Default chains and EIP-712 domains actions are signed for, the same on
+// mainnet and testnet as in the Python SDK
+const (
+	DefaultSignatureChainID  = "0x66eee"
+	DefaultL1ChainID         = 1337
+	DefaultL1DomainName      = "Exchange"
+	DefaultUserDomainName    = "HyperliquidSignTransaction"
+	DefaultDomainVersion     = "1"
+	DefaultVerifyingContract = "0x0000000000000000000000000000000000000000"
+)
+
+// SigningConfig sets the chains and EIP-712 domains actions are signed for,
+// for wallets that enforce a chain or local development nodes. Fields left
+// empty keep their defaults.
+type SigningConfig struct {
+	SignatureChainID  string // Chain of user-signed actions, sent as their signatureChainId
+	L1ChainID         int64  // Chain ID of the domain L1 actions are signed in
+	L1DomainName      string // Name of the domain L1 actions are signed in
+	UserDomainName    string // Name of the domain user-signed actions are signed in
+	DomainVersion     string // Version of both domains
+	VerifyingContract string // Verifying contract of both domains
+}
+
+// WithDefaults returns the config with its empty fields set to the defaults
+func (c SigningConfig) WithDefaults() SigningConfig {
+	if c.SignatureChainID == "" {
+		c.SignatureChainID = DefaultSignatureChainID
+	}
+	if c.L1ChainID == 0 {
+		c.L1ChainID = DefaultL1ChainID
+	}
+	if c.L1DomainName == "" {
+		c.L1DomainName = DefaultL1DomainName
+	}
+	if c.UserDomainName == "" {
+		c.UserDomainName = DefaultUserDomainName
+	}
+	if c.DomainVersion == "" {
+		c.DomainVersion = DefaultDomainVersion
+	}
+	if c.VerifyingContract == "" {
+		c.VerifyingContract = DefaultVerifyingContract
+	}
+	return c
+}
+
 // L1Payload creates the EIP712 payload for L1 actions
 func L1Payload(phantomAgent PhantomAgent) apitypes.TypedData {
+	return L1PayloadWithConfig(phantomAgent, SigningConfig{})
+}
+
+// L1PayloadWithConfig is like L1Payload but signs in the domain of config
+func L1PayloadWithConfig(phantomAgent PhantomAgent, config SigningConfig) apitypes.TypedData {
+	config = config.WithDefaults()
 	return apitypes.TypedData{
 		Types: apitypes.Types{
 			"EIP712Domain": []apitypes.Type{
@@ -366,10 +722,10 @@ func L1Payload(phantomAgent PhantomAgent) apitypes.TypedData {
 		},
 		PrimaryType: "Agent",
 		Domain: apitypes.TypedDataDomain{
-			Name:              "Exchange",
-			Version:           "1",
-			ChainId:           (*ethmath.HexOrDecimal256)(big.NewInt(1337)),
-			VerifyingContract: "0x0000000000000000000000000000000000000000",
+			Name:              config.L1DomainName,
+			Version:           config.DomainVersion,
+			ChainId:           (*ethmath.HexOrDecimal256)(big.NewInt(config.L1ChainID)),
+			VerifyingContract: config.VerifyingContract,
 		},
 		Message: apitypes.TypedDataMessage{
 			"source":       phantomAgent.Source,
@@ -380,6 +736,13 @@ func L1Payload(phantomAgent PhantomAgent) apitypes.TypedData {
 
 // UserSignedPayload creates the EIP712 payload for user-signed actions
 func UserSignedPayload(primaryType string, payloadTypes []apitypes.Type, action map[string]interface{}) (apitypes.TypedData, error) {
+	return UserSignedPayloadWithConfig(primaryType, payloadTypes, action, SigningConfig{})
+}
+
+// UserSignedPayloadWithConfig is like UserSignedPayload but signs in the
+// domain of config. The chain ID is still the action's signatureChainId.
+func UserSignedPayloadWithConfig(primaryType string, payloadTypes []apitypes.Type, action map[string]interface{}, config SigningConfig) (apitypes.TypedData, error) {
+	config = config.WithDefaults()
 	chainIDStr, ok := action["signatureChainId"].(string)
 	if !ok {
 		return apitypes.TypedData{}, fmt.Errorf("signatureChainId not found or not string")
@@ -400,100 +763,304 @@ func UserSignedPayload(primaryType string, payloadTypes []apitypes.Type, action
 		primaryType: payloadTypes,
 	}
 	
+	// Only the fields declared in payloadTypes are part of the signed message;
+	// extra action fields such as "type" and "signatureChainId" are rejected by
+	// the EIP712 encoder.
 	message := make(apitypes.TypedDataMessage)
-	for k, v := range action {
-		message[k] = v
+	for _, field := range payloadTypes {
+		value, ok := action[field.Name]
+		if !ok {
+			return apitypes.TypedData{}, fmt.Errorf("field %s not found in action", field.Name)
+		}
+		message[field.Name] = typedDataValue(field.Type, value)
 	}
-	
+
 	return apitypes.TypedData{
 		Types:       types,
 		PrimaryType: primaryType,
 		Domain: apitypes.TypedDataDomain{
-			Name:              "HyperliquidSignTransaction",
-			Version:           "1",
+			Name:              config.UserDomainName,
+			Version:           config.DomainVersion,
 			ChainId:           (*ethmath.HexOrDecimal256)(big.NewInt(chainID)),
-			VerifyingContract: "0x0000000000000000000000000000000000000000",
+			VerifyingContract: config.VerifyingContract,
 		},
 		Message: message,
 	}, nil
 }
 
-// SignInner performs the actual EIP712 signing
-func SignInner(privateKey *ecdsa.PrivateKey, data apitypes.TypedData) (*Signature, error) {
+// typedDataValue converts Go integer values into the *big.Int form expected by
+// the EIP712 encoder for uint/int fields
+func typedDataValue(fieldType string, value interface{}) interface{} {
+	if !strings.HasPrefix(fieldType, "uint") && !strings.HasPrefix(fieldType, "int") {
+		return value
+	}
+	switch v := value.(type) {
+	case int:
+		return big.NewInt(int64(v))
+	case int64:
+		return big.NewInt(v)
+	case uint64:
+		return new(big.Int).SetUint64(v)
+	}
+	return value
+}
+
+// typedDataDigest computes the EIP-712 digest
+// keccak256("\x19\x01" + domainSeparator + structHash)
+func typedDataDigest(data apitypes.TypedData) ([32]byte, error) {
+	var digest [32]byte
 	domainSeparator, err := data.HashStruct("EIP712Domain", data.Domain.Map())
 	if err != nil {
-		return nil, err
+		return digest, err
 	}
 	
 	typedDataHash, err := data.HashStruct(data.PrimaryType, data.Message)
 	if err != nil {
-		return nil, err
+		return digest, err
 	}
 	
-	// EIP712 signing: keccak256("\x19\x01" + domainSeparator + structHash)
 	rawData := append([]byte("\x19\x01"), append(domainSeparator, typedDataHash...)...)
-	hash := crypto.Keccak256Hash(rawData)
-	
-	signature, err := crypto.Sign(hash.Bytes(), privateKey)
+	copy(digest[:], crypto.Keccak256(rawData))
+	return digest, nil
+}
+
+// L1ActionDigest returns the digest to sign for an L1 action, for signing it
+// outside this process. Assemble the result with SignatureFromBytes.
+func L1ActionDigest(action interface{}, vaultAddress *string, nonce uint64, expiresAfter *uint64, isMainnet bool) ([32]byte, error) {
+	data, err := L1ActionTypedData(action, vaultAddress, nonce, expiresAfter, isMainnet)
 	if err != nil {
-		return nil, err
+		return [32]byte{}, err
+	}
+	return typedDataDigest(data)
+}
+
+// UserSignedActionDigest returns the digest to sign for a user-signed action,
+// for signing it outside this process. The action must already carry its
+// signatureChainId and hyperliquidChain fields, as the copy returned by
+// UserSignedActionWithChain does.
+func UserSignedActionDigest(primaryType string, payloadTypes []apitypes.Type, action map[string]interface{}) ([32]byte, error) {
+	data, err := UserSignedPayload(primaryType, payloadTypes, action)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return typedDataDigest(data)
+}
+
+// SignatureFromBytes assembles a Signature from a 65 byte r || s || v
+// signature, with v either 0/1 or 27/28
+func SignatureFromBytes(sig []byte) (*Signature, error) {
+	if len(sig) != 65 {
+		return nil, fmt.Errorf("signature must be 65 bytes, got %d", len(sig))
+	}
+	v := sig[64]
+	if v < 27 {
+		v += 27
+	}
+	if v != 27 && v != 28 {
+		return nil, fmt.Errorf("invalid signature v: %d", sig[64])
 	}
-	
-	r := hexutil.Encode(signature[:32])
-	s := hexutil.Encode(signature[32:64])
-	v := signature[64] + 27
 	
 	return &Signature{
-		R: r,
-		S: s,
+		R: hexutil.Encode(sig[:32]),
+		S: hexutil.Encode(sig[32:64]),
 		V: v,
 	}, nil
 }
 
-// SignL1Action signs an L1 action
-func SignL1Action(privateKey *ecdsa.PrivateKey, action interface{}, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool) (*Signature, error) {
-	hash, err := ActionHash(action, activePool, nonce, expiresAfter)
+// SignInner performs the actual EIP712 signing
+func SignInner(privateKey *ecdsa.PrivateKey, data apitypes.TypedData) (*Signature, error) {
+	hash, err := typedDataDigest(data)
 	if err != nil {
 		return nil, err
 	}
 	
+	signature, err := crypto.Sign(hash[:], privateKey)
+	if err != nil {
+		return nil, err
+	}
+	
+	return SignatureFromBytes(signature)
+}
+
+// L1ActionTypedData builds the phantom agent payload signed for an L1 action
+func L1ActionTypedData(action interface{}, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool) (apitypes.TypedData, error) {
+	return L1ActionTypedDataWithConfig(action, activePool, nonce, expiresAfter, isMainnet, SigningConfig{})
+}
+
+// L1ActionTypedDataWithConfig is like L1ActionTypedData but signs in the
+// domain of config
+func L1ActionTypedDataWithConfig(action interface{}, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool, config SigningConfig) (apitypes.TypedData, error) {
+	hash, err := ActionHash(action, activePool, nonce, expiresAfter)
+	if err != nil {
+		return apitypes.TypedData{}, err
+	}
+	
 	phantomAgent := ConstructPhantomAgent(hash, isMainnet)
-	data := L1Payload(phantomAgent)
+	return L1PayloadWithConfig(phantomAgent, config), nil
+}
+
+// SignL1Action signs an L1 action
+func SignL1Action(privateKey *ecdsa.PrivateKey, action interface{}, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool) (*Signature, error) {
+	return SignL1ActionWithSigner(NewLocalSigner(privateKey), action, activePool, nonce, expiresAfter, isMainnet)
+}
+
+// SignL1ActionWithSigner signs an L1 action with signer
+func SignL1ActionWithSigner(signer Signer, action interface{}, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool) (*Signature, error) {
+	return SignL1ActionWithConfig(signer, action, activePool, nonce, expiresAfter, isMainnet, SigningConfig{})
+}
+
+// SignL1ActionWithConfig signs an L1 action with signer in the domain of
+// config
+func SignL1ActionWithConfig(signer Signer, action interface{}, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool, config SigningConfig) (*Signature, error) {
+	data, err := L1ActionTypedDataWithConfig(action, activePool, nonce, expiresAfter, isMainnet, config)
+	if err != nil {
+		return nil, err
+	}
 	
-	return SignInner(privateKey, data)
+	return signer.SignTypedData(data)
+}
+
+// UserSignedActionTypedData builds the payload signed for a user-signed
+// action, with the signature chain fields of UserSignedActionWithChain
+func UserSignedActionTypedData(action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string, isMainnet bool) (apitypes.TypedData, error) {
+	return UserSignedActionTypedDataWithConfig(action, payloadTypes, primaryType, isMainnet, SigningConfig{})
+}
+
+// UserSignedActionTypedDataWithConfig is like UserSignedActionTypedData but
+// signs for the chain and in the domain of config
+func UserSignedActionTypedDataWithConfig(action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string, isMainnet bool, config SigningConfig) (apitypes.TypedData, error) {
+	return UserSignedPayloadWithConfig(primaryType, payloadTypes, UserSignedActionWithChain(action, isMainnet, config), config)
+}
+
+// UserSignedActionWithChain returns a copy of a user-signed action with the
+// signatureChainId and hyperliquidChain fields it is signed with; the
+// action itself is left unchanged. The copy is the action to post.
+func UserSignedActionWithChain(action map[string]interface{}, isMainnet bool, config SigningConfig) map[string]interface{} {
+	signed := make(map[string]interface{}, len(action)+2)
+	for key, value := range action {
+		signed[key] = value
+	}
+	signed["signatureChainId"] = config.WithDefaults().SignatureChainID
+	if isMainnet {
+		signed["hyperliquidChain"] = "Mainnet"
+	} else {
+		signed["hyperliquidChain"] = "Testnet"
+	}
+	return signed
 }
 
 // SignUserSignedAction signs a user-signed action
 func SignUserSignedAction(privateKey *ecdsa.PrivateKey, action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string, isMainnet bool) (*Signature, error) {
-	// Set signature chain ID and hyperliquid chain
-	action["signatureChainId"] = "0x66eee"
+	return SignUserSignedActionWithSigner(NewLocalSigner(privateKey), action, payloadTypes, primaryType, isMainnet)
+}
+
+// SignUserSignedActionWithSigner signs a user-signed action with signer
+func SignUserSignedActionWithSigner(signer Signer, action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string, isMainnet bool) (*Signature, error) {
+	return SignUserSignedActionWithConfig(signer, action, payloadTypes, primaryType, isMainnet, SigningConfig{})
+}
+
+// SignUserSignedActionWithConfig signs a user-signed action with signer for
+// the chain and in the domain of config
+func SignUserSignedActionWithConfig(signer Signer, action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string, isMainnet bool, config SigningConfig) (*Signature, error) {
+	data, err := UserSignedActionTypedDataWithConfig(action, payloadTypes, primaryType, isMainnet, config)
+	if err != nil {
+		return nil, err
+	}
+	
+	return signer.SignTypedData(data)
+}
+
+// recoverSigner returns the address whose key produced sig over the typed data
+func recoverSigner(data apitypes.TypedData, sig *Signature) (common.Address, error) {
+	hash, err := typedDataDigest(data)
+	if err != nil {
+		return common.Address{}, err
+	}
+	
+	sigBytes, err := sig.Bytes()
+	if err != nil {
+		return common.Address{}, err
+	}
+	if sigBytes[64] != 27 && sigBytes[64] != 28 {
+		return common.Address{}, fmt.Errorf("invalid signature v: %d", sigBytes[64])
+	}
+	sigBytes[64] -= 27
+	
+	publicKey, err := crypto.SigToPub(hash[:], sigBytes)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*publicKey), nil
+}
+
+// RecoverAgentOrUserFromL1Action returns the address that signed an L1 action,
+// which is the agent wallet when the action was signed by an API wallet
+func RecoverAgentOrUserFromL1Action(action interface{}, sig *Signature, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool) (common.Address, error) {
+	return RecoverAgentOrUserFromL1ActionWithConfig(action, sig, activePool, nonce, expiresAfter, isMainnet, SigningConfig{})
+}
+
+// RecoverAgentOrUserFromL1ActionWithConfig is like
+// RecoverAgentOrUserFromL1Action for actions signed in the domain of config
+func RecoverAgentOrUserFromL1ActionWithConfig(action interface{}, sig *Signature, activePool *string, nonce uint64, expiresAfter *uint64, isMainnet bool, config SigningConfig) (common.Address, error) {
+	data, err := L1ActionTypedDataWithConfig(action, activePool, nonce, expiresAfter, isMainnet, config)
+	if err != nil {
+		return common.Address{}, err
+	}
+	
+	return recoverSigner(data, sig)
+}
+
+// RecoverUserFromUserSignedAction returns the address that signed a user-signed
+// action. The action is not modified.
+func RecoverUserFromUserSignedAction(action map[string]interface{}, sig *Signature, payloadTypes []apitypes.Type, primaryType string, isMainnet bool) (common.Address, error) {
+	return RecoverUserFromUserSignedActionWithConfig(action, sig, payloadTypes, primaryType, isMainnet, SigningConfig{})
+}
+
+// RecoverUserFromUserSignedActionWithConfig is like
+// RecoverUserFromUserSignedAction for actions signed in the domain of config.
+// Actions without a signatureChainId are taken as signed for the config's.
+func RecoverUserFromUserSignedActionWithConfig(action map[string]interface{}, sig *Signature, payloadTypes []apitypes.Type, primaryType string, isMainnet bool, config SigningConfig) (common.Address, error) {
+	signed := make(map[string]interface{}, len(action)+2)
+	for key, value := range action {
+		signed[key] = value
+	}
+	if _, ok := signed["signatureChainId"]; !ok {
+		signed["signatureChainId"] = config.WithDefaults().SignatureChainID
+	}
 	if isMainnet {
-		action["hyperliquidChain"] = "Mainnet"
+		signed["hyperliquidChain"] = "Mainnet"
 	} else {
-		action["hyperliquidChain"] = "Testnet"
+		signed["hyperliquidChain"] = "Testnet"
 	}
 	
-	data, err := UserSignedPayload(primaryType, payloadTypes, action)
+	data, err := UserSignedPayloadWithConfig(primaryType, payloadTypes, signed, config)
 	if err != nil {
-		return nil, err
+		return common.Address{}, err
 	}
 	
-	return SignInner(privateKey, data)
+	return recoverSigner(data, sig)
 }
 
 // OrderRequestToOrderWire converts an OrderRequest to wire format
 func OrderRequestToOrderWire(order OrderRequest, asset int) (*OrderWire, error) {
-	limitPxWire, err := FloatToWire(order.LimitPx)
+	return OrderRequestToOrderWireDecimals(order, asset, WireMaxDecimals, WireMaxDecimals)
+}
+
+// OrderRequestToOrderWireDecimals converts an OrderRequest to wire format,
+// allowing at most pxDecimals decimals in its prices and szDecimals in its
+// size, as given by the asset's metadata; see PxDecimals
+func OrderRequestToOrderWireDecimals(order OrderRequest, asset int, pxDecimals, szDecimals int) (*OrderWire, error) {
+	limitPxWire, err := FloatToWireDecimals(order.LimitPx, pxDecimals)
 	if err != nil {
 		return nil, err
 	}
 	
-	szWire, err := FloatToWire(order.Sz)
+	szWire, err := FloatToWireDecimals(order.Sz, szDecimals)
 	if err != nil {
 		return nil, err
 	}
 	
-	orderTypeWire, err := OrderTypeToWire(order.OrderType)
+	orderTypeWire, err := orderTypeToWire(order.OrderType, pxDecimals)
 	if err != nil {
 		return nil, err
 	}
@@ -515,15 +1082,18 @@ func OrderRequestToOrderWire(order OrderRequest, asset int) (*OrderWire, error)
 }
 
 // OrderWiresToOrderAction converts order wires to an order action
-func OrderWiresToOrderAction(orderWires []OrderWire, builder *string) map[string]interface{} {
-	action := map[string]interface{}{
-		"type":     "order",
-		"orders":   orderWires,
-		"grouping": "na",
+func OrderWiresToOrderAction(orderWires []OrderWire, builder *BuilderInfo, grouping Grouping) OrderAction {
+	if grouping == "" {
+		grouping = GroupingNA
+	}
+	action := OrderAction{
+		Type:     "order",
+		Orders:   orderWires,
+		Grouping: grouping,
 	}
 	
 	if builder != nil {
-		action["builder"] = *builder
+		action.Builder = &BuilderInfo{B: strings.ToLower(builder.B), F: builder.F}
 	}
 	
 	return action
@@ -556,34 +1126,177 @@ func SignSendAssetAction(privateKey *ecdsa.PrivateKey, action map[string]interfa
 	return SignUserSignedAction(privateKey, action, SendAssetSignTypes, "HyperliquidTransaction:SendAsset", isMainnet)
 }
 
+// SignPerpDexClassTransferAction signs a perp dex class transfer action
+func SignPerpDexClassTransferAction(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
+	return SignUserSignedAction(privateKey, action, PerpDexClassTransferSignTypes, "HyperliquidTransaction:PerpDexClassTransfer", isMainnet)
+}
+
 // SignConvertToMultiSigUserAction signs a convert to multi-sig user action
 func SignConvertToMultiSigUserAction(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
 	return SignUserSignedAction(privateKey, action, ConvertToMultiSigUserSignTypes, "HyperliquidTransaction:ConvertToMultiSigUser", isMainnet)
 }
 
+// MultiSigSigners are the authorized users of a multi-sig user and how many
+// of their signatures every action needs. They are signed as a JSON string
+// inside the convertToMultiSigUser action, so MarshalJSON always encodes them
+// the same way: addresses lowercased with their 0x prefix and sorted,
+// without whitespace.
+type MultiSigSigners struct {
+	AuthorizedUsers []string
+	Threshold       int
+}
+
+// Validate checks that every authorized user is an address, none is listed
+// twice and the threshold is between 1 and their number
+func (s MultiSigSigners) Validate() error {
+	seen := make(map[string]bool, len(s.AuthorizedUsers))
+	for _, user := range s.AuthorizedUsers {
+		normalized, err := NormalizeAddress(user)
+		if err != nil {
+			return fmt.Errorf("invalid authorized user address: %w", err)
+		}
+		if seen[normalized] {
+			return fmt.Errorf("duplicate authorized user: %s", user)
+		}
+		seen[normalized] = true
+	}
+	if s.Threshold < 1 || s.Threshold > len(s.AuthorizedUsers) {
+		return fmt.Errorf("threshold must be between 1 and %d: %d", len(s.AuthorizedUsers), s.Threshold)
+	}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler with the canonical encoding
+func (s MultiSigSigners) MarshalJSON() ([]byte, error) {
+	users := make([]string, len(s.AuthorizedUsers))
+	for i, user := range s.AuthorizedUsers {
+		normalized, err := NormalizeAddress(user)
+		if err != nil {
+			return nil, fmt.Errorf("invalid authorized user address: %w", err)
+		}
+		users[i] = normalized
+	}
+	sort.Strings(users)
+	return json.Marshal(struct {
+		AuthorizedUsers []string `json:"authorizedUsers"`
+		Threshold       int      `json:"threshold"`
+	}{users, s.Threshold})
+}
+
+// ConvertToMultiSigUserAction builds the convertToMultiSigUser action that
+// makes the signing account a multi-sig user controlled by signers
+func ConvertToMultiSigUserAction(signers MultiSigSigners, nonce int64) (map[string]interface{}, error) {
+	if err := signers.Validate(); err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(signers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signers: %w", err)
+	}
+	return map[string]interface{}{
+		"type":    "convertToMultiSigUser",
+		"signers": string(encoded),
+		"nonce":   nonce,
+	}, nil
+}
+
 // SignAgent signs an agent approval action
 func SignAgent(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
-	agentSignTypes := []apitypes.Type{
-		{Name: "hyperliquidChain", Type: "string"},
-		{Name: "agentAddress", Type: "address"},
-		{Name: "agentName", Type: "string"},
-		{Name: "nonce", Type: "uint64"},
-	}
-	return SignUserSignedAction(privateKey, action, agentSignTypes, "HyperliquidTransaction:ApproveAgent", isMainnet)
+	return SignUserSignedAction(privateKey, action, ApproveAgentSignTypes, "HyperliquidTransaction:ApproveAgent", isMainnet)
 }
 
 // SignApproveBuilderFee signs an approve builder fee action
 func SignApproveBuilderFee(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
-	builderFeeSignTypes := []apitypes.Type{
-		{Name: "hyperliquidChain", Type: "string"},
-		{Name: "maxFeeRate", Type: "string"},
-		{Name: "builder", Type: "address"},
-		{Name: "nonce", Type: "uint64"},
-	}
-	return SignUserSignedAction(privateKey, action, builderFeeSignTypes, "HyperliquidTransaction:ApproveBuilderFee", isMainnet)
+	return SignUserSignedAction(privateKey, action, ApproveBuilderFeeSignTypes, "HyperliquidTransaction:ApproveBuilderFee", isMainnet)
 }
 
 // SignTokenDelegateAction signs a token delegate action
 func SignTokenDelegateAction(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
 	return SignUserSignedAction(privateKey, action, TokenDelegateTypes, "HyperliquidTransaction:TokenDelegate", isMainnet)
 }
+
+// SignCDepositAction signs a staking deposit action
+func SignCDepositAction(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
+	return SignUserSignedAction(privateKey, action, CDepositSignTypes, "HyperliquidTransaction:CDeposit", isMainnet)
+}
+
+// SignCWithdrawAction signs a staking withdrawal action
+func SignCWithdrawAction(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool) (*Signature, error) {
+	return SignUserSignedAction(privateKey, action, CWithdrawSignTypes, "HyperliquidTransaction:CWithdraw", isMainnet)
+}
+
+// SignMultiSigAction signs the envelope of a multiSig action. The action is
+// hashed without its type tag and the outer signer signs that multiSigActionHash.
+func SignMultiSigAction(privateKey *ecdsa.PrivateKey, action MultiSigAction, isMainnet bool, vaultAddress *string, nonce uint64, expiresAfter *uint64) (*Signature, error) {
+	envelope, err := MultiSigEnvelope(action, vaultAddress, nonce, expiresAfter)
+	if err != nil {
+		return nil, err
+	}
+	return SignUserSignedAction(privateKey, envelope, MultiSigEnvelopeSignTypes, "HyperliquidTransaction:SendMultiSig", isMainnet)
+}
+
+// MultiSigEnvelope builds the user-signed envelope the outer signer of a
+// multiSig action signs
+func MultiSigEnvelope(action MultiSigAction, vaultAddress *string, nonce uint64, expiresAfter *uint64) (map[string]interface{}, error) {
+	actionWithoutTag := multiSigActionWithoutTag{
+		SignatureChainID: action.SignatureChainID,
+		Signatures:       action.Signatures,
+		Payload:          action.Payload,
+	}
+	hash, err := ActionHash(actionWithoutTag, vaultAddress, nonce, expiresAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"multiSigActionHash": hexutil.Encode(hash),
+		"nonce":              nonce,
+	}, nil
+}
+
+// SignMultiSigL1ActionPayload produces an authorized user's signature over an
+// L1 action executed on behalf of multiSigUser and submitted by outerSigner
+func SignMultiSigL1ActionPayload(privateKey *ecdsa.PrivateKey, action Action, isMainnet bool, vaultAddress *string, nonce uint64, expiresAfter *uint64, multiSigUser string, outerSigner string) (*Signature, error) {
+	multiSigUser, outerSigner, err := normalizeMultiSigAddresses(multiSigUser, outerSigner)
+	if err != nil {
+		return nil, err
+	}
+	envelope := []interface{}{multiSigUser, outerSigner, action}
+	return SignL1Action(privateKey, envelope, vaultAddress, nonce, expiresAfter, isMainnet)
+}
+
+// normalizeMultiSigAddresses normalizes the multi-sig user and outer signer
+// an authorized user signs
+func normalizeMultiSigAddresses(multiSigUser string, outerSigner string) (string, string, error) {
+	user, err := NormalizeAddress(multiSigUser)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid multi-sig user address: %w", err)
+	}
+	outer, err := NormalizeAddress(outerSigner)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid outer signer address: %w", err)
+	}
+	return user, outer, nil
+}
+
+// SignMultiSigUserSignedActionPayload produces an authorized user's signature
+// over a user-signed action executed on behalf of multiSigUser and submitted by
+// outerSigner. The action is submitted in its NewUserSignedAction form.
+func SignMultiSigUserSignedActionPayload(privateKey *ecdsa.PrivateKey, action map[string]interface{}, isMainnet bool, payloadTypes []apitypes.Type, primaryType string, multiSigUser string, outerSigner string) (*Signature, error) {
+	multiSigUser, outerSigner, err := normalizeMultiSigAddresses(multiSigUser, outerSigner)
+	if err != nil {
+		return nil, err
+	}
+	envelope := make(map[string]interface{}, len(action)+2)
+	for key, value := range action {
+		envelope[key] = value
+	}
+	envelope["payloadMultiSigUser"] = multiSigUser
+	envelope["outerSigner"] = outerSigner
+
+	enrichedTypes := append(append([]apitypes.Type(nil), payloadTypes...),
+		apitypes.Type{Name: "payloadMultiSigUser", Type: "address"},
+		apitypes.Type{Name: "outerSigner", Type: "address"},
+	)
+	return SignUserSignedAction(privateKey, envelope, enrichedTypes, primaryType, isMainnet)
+}
@@ -1,11 +1,24 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 )
 
+// DecimalString is a decimal number the API sends as a string so that it
+// keeps its full precision
+type DecimalString string
+
+// Float parses the number as a float64
+func (d DecimalString) Float() (float64, error) {
+	return strconv.ParseFloat(string(d), 64)
+}
+
 // Side represents trading side (Ask or Bid)
 type Side string
 
@@ -69,36 +82,65 @@ type SpotMetaAndAssetCtxs struct {
 	AssetCtx []SpotAssetCtx
 }
 
-// Subscription types
+// SubscriptionType is the type of a WebSocket subscription
 type SubscriptionType string
 
 const (
-	SubTypeAllMids                        SubscriptionType = "allMids"
-	SubTypeBbo                           SubscriptionType = "bbo"
-	SubTypeL2Book                        SubscriptionType = "l2Book"
-	SubTypeTrades                        SubscriptionType = "trades"
-	SubTypeUserEvents                    SubscriptionType = "userEvents"
-	SubTypeUserFills                     SubscriptionType = "userFills"
-	SubTypeCandle                        SubscriptionType = "candle"
-	SubTypeOrderUpdates                  SubscriptionType = "orderUpdates"
-	SubTypeUserFundings                  SubscriptionType = "userFundings"
-	SubTypeUserNonFundingLedgerUpdates   SubscriptionType = "userNonFundingLedgerUpdates"
-	SubTypeWebData2                      SubscriptionType = "webData2"
-	SubTypeActiveAssetCtx                SubscriptionType = "activeAssetCtx"
-	SubTypeActiveAssetData               SubscriptionType = "activeAssetData"
+	SubTypeAllMids                     SubscriptionType = "allMids"
+	SubTypeBbo                         SubscriptionType = "bbo"
+	SubTypeL2Book                      SubscriptionType = "l2Book"
+	SubTypeTrades                      SubscriptionType = "trades"
+	SubTypeUserEvents                  SubscriptionType = "userEvents"
+	SubTypeUserFills                   SubscriptionType = "userFills"
+	SubTypeCandle                      SubscriptionType = "candle"
+	SubTypeOrderUpdates                SubscriptionType = "orderUpdates"
+	SubTypeUserFundings                SubscriptionType = "userFundings"
+	SubTypeUserNonFundingLedgerUpdates SubscriptionType = "userNonFundingLedgerUpdates"
+	SubTypeWebData2                    SubscriptionType = "webData2"
+	SubTypeActiveAssetCtx              SubscriptionType = "activeAssetCtx"
+	SubTypeActiveAssetData             SubscriptionType = "activeAssetData"
+	SubTypeNotification                SubscriptionType = "notification"
+	SubTypeUserTwapSliceFills          SubscriptionType = "userTwapSliceFills"
+	SubTypeUserTwapHistory             SubscriptionType = "userTwapHistory"
+	// SubTypeUnknown is never sent to the server. Its subscribers receive
+	// every message on a channel the SDK does not know.
+	SubTypeUnknown SubscriptionType = "unknown"
 )
 
-// Base subscription interface
+// Subscription is implemented by the typed subscriptions below and by
+// WsSubscription. Wire converts one into the payload sent to subscribe.
 type Subscription interface {
 	GetType() SubscriptionType
+	Wire() WsSubscription
 }
 
-// AllMidsSubscription for all mids
+// WsSubscription is a subscription as sent on the wire, with the fields
+// the subscription type does not use left empty
+type WsSubscription struct {
+	Type     SubscriptionType `json:"type"`
+	Coin     string           `json:"coin,omitempty"`
+	User     string           `json:"user,omitempty"`
+	Interval string           `json:"interval,omitempty"`
+	Dex      string           `json:"dex,omitempty"` // Builder perp dex of allMids; empty for the default dex
+}
+
+func (s WsSubscription) GetType() SubscriptionType { return s.Type }
+func (s WsSubscription) Wire() WsSubscription      { return s }
+
+// The typed subscriptions below always have the type they are named after;
+// their Type field need not be set.
+
+// AllMidsSubscription for all mids of a perp dex, the default one when Dex
+// is empty
 type AllMidsSubscription struct {
 	Type SubscriptionType `json:"type"`
+	Dex  string           `json:"dex,omitempty"`
 }
 
-func (s AllMidsSubscription) GetType() SubscriptionType { return s.Type }
+func (s AllMidsSubscription) GetType() SubscriptionType { return SubTypeAllMids }
+func (s AllMidsSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeAllMids, Dex: s.Dex}
+}
 
 // BboSubscription for best bid/offer
 type BboSubscription struct {
@@ -106,7 +148,10 @@ type BboSubscription struct {
 	Coin string           `json:"coin"`
 }
 
-func (s BboSubscription) GetType() SubscriptionType { return s.Type }
+func (s BboSubscription) GetType() SubscriptionType { return SubTypeBbo }
+func (s BboSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeBbo, Coin: s.Coin}
+}
 
 // L2BookSubscription for level 2 order book
 type L2BookSubscription struct {
@@ -114,7 +159,10 @@ type L2BookSubscription struct {
 	Coin string           `json:"coin"`
 }
 
-func (s L2BookSubscription) GetType() SubscriptionType { return s.Type }
+func (s L2BookSubscription) GetType() SubscriptionType { return SubTypeL2Book }
+func (s L2BookSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeL2Book, Coin: s.Coin}
+}
 
 // TradesSubscription for trades
 type TradesSubscription struct {
@@ -122,7 +170,10 @@ type TradesSubscription struct {
 	Coin string           `json:"coin"`
 }
 
-func (s TradesSubscription) GetType() SubscriptionType { return s.Type }
+func (s TradesSubscription) GetType() SubscriptionType { return SubTypeTrades }
+func (s TradesSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeTrades, Coin: s.Coin}
+}
 
 // UserEventsSubscription for user events
 type UserEventsSubscription struct {
@@ -130,7 +181,10 @@ type UserEventsSubscription struct {
 	User string           `json:"user"`
 }
 
-func (s UserEventsSubscription) GetType() SubscriptionType { return s.Type }
+func (s UserEventsSubscription) GetType() SubscriptionType { return SubTypeUserEvents }
+func (s UserEventsSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeUserEvents, User: s.User}
+}
 
 // UserFillsSubscription for user fills
 type UserFillsSubscription struct {
@@ -138,7 +192,10 @@ type UserFillsSubscription struct {
 	User string           `json:"user"`
 }
 
-func (s UserFillsSubscription) GetType() SubscriptionType { return s.Type }
+func (s UserFillsSubscription) GetType() SubscriptionType { return SubTypeUserFills }
+func (s UserFillsSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeUserFills, User: s.User}
+}
 
 // CandleSubscription for candles
 type CandleSubscription struct {
@@ -147,7 +204,10 @@ type CandleSubscription struct {
 	Interval string           `json:"interval"`
 }
 
-func (s CandleSubscription) GetType() SubscriptionType { return s.Type }
+func (s CandleSubscription) GetType() SubscriptionType { return SubTypeCandle }
+func (s CandleSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeCandle, Coin: s.Coin, Interval: s.Interval}
+}
 
 // OrderUpdatesSubscription for order updates
 type OrderUpdatesSubscription struct {
@@ -155,7 +215,10 @@ type OrderUpdatesSubscription struct {
 	User string           `json:"user"`
 }
 
-func (s OrderUpdatesSubscription) GetType() SubscriptionType { return s.Type }
+func (s OrderUpdatesSubscription) GetType() SubscriptionType { return SubTypeOrderUpdates }
+func (s OrderUpdatesSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeOrderUpdates, User: s.User}
+}
 
 // UserFundingsSubscription for user fundings
 type UserFundingsSubscription struct {
@@ -163,7 +226,10 @@ type UserFundingsSubscription struct {
 	User string           `json:"user"`
 }
 
-func (s UserFundingsSubscription) GetType() SubscriptionType { return s.Type }
+func (s UserFundingsSubscription) GetType() SubscriptionType { return SubTypeUserFundings }
+func (s UserFundingsSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeUserFundings, User: s.User}
+}
 
 // UserNonFundingLedgerUpdatesSubscription for user non-funding ledger updates
 type UserNonFundingLedgerUpdatesSubscription struct {
@@ -171,7 +237,12 @@ type UserNonFundingLedgerUpdatesSubscription struct {
 	User string           `json:"user"`
 }
 
-func (s UserNonFundingLedgerUpdatesSubscription) GetType() SubscriptionType { return s.Type }
+func (s UserNonFundingLedgerUpdatesSubscription) GetType() SubscriptionType {
+	return SubTypeUserNonFundingLedgerUpdates
+}
+func (s UserNonFundingLedgerUpdatesSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeUserNonFundingLedgerUpdates, User: s.User}
+}
 
 // WebData2Subscription for web data 2
 type WebData2Subscription struct {
@@ -179,7 +250,10 @@ type WebData2Subscription struct {
 	User string           `json:"user"`
 }
 
-func (s WebData2Subscription) GetType() SubscriptionType { return s.Type }
+func (s WebData2Subscription) GetType() SubscriptionType { return SubTypeWebData2 }
+func (s WebData2Subscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeWebData2, User: s.User}
+}
 
 // ActiveAssetCtxSubscription for active asset context
 type ActiveAssetCtxSubscription struct {
@@ -187,7 +261,10 @@ type ActiveAssetCtxSubscription struct {
 	Coin string           `json:"coin"`
 }
 
-func (s ActiveAssetCtxSubscription) GetType() SubscriptionType { return s.Type }
+func (s ActiveAssetCtxSubscription) GetType() SubscriptionType { return SubTypeActiveAssetCtx }
+func (s ActiveAssetCtxSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeActiveAssetCtx, Coin: s.Coin}
+}
 
 // ActiveAssetDataSubscription for active asset data
 type ActiveAssetDataSubscription struct {
@@ -196,13 +273,60 @@ type ActiveAssetDataSubscription struct {
 	Coin string           `json:"coin"`
 }
 
-func (s ActiveAssetDataSubscription) GetType() SubscriptionType { return s.Type }
+func (s ActiveAssetDataSubscription) GetType() SubscriptionType { return SubTypeActiveAssetData }
+func (s ActiveAssetDataSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeActiveAssetData, User: s.User, Coin: s.Coin}
+}
+
+// NotificationSubscription for notifications to a user
+type NotificationSubscription struct {
+	Type SubscriptionType `json:"type"`
+	User string           `json:"user"`
+}
+
+func (s NotificationSubscription) GetType() SubscriptionType { return SubTypeNotification }
+func (s NotificationSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeNotification, User: s.User}
+}
+
+// UserTwapSliceFillsSubscription for the fills of a user's TWAP slices
+type UserTwapSliceFillsSubscription struct {
+	Type SubscriptionType `json:"type"`
+	User string           `json:"user"`
+}
+
+func (s UserTwapSliceFillsSubscription) GetType() SubscriptionType { return SubTypeUserTwapSliceFills }
+func (s UserTwapSliceFillsSubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeUserTwapSliceFills, User: s.User}
+}
+
+// UserTwapHistorySubscription for the history of a user's TWAPs
+type UserTwapHistorySubscription struct {
+	Type SubscriptionType `json:"type"`
+	User string           `json:"user"`
+}
+
+func (s UserTwapHistorySubscription) GetType() SubscriptionType { return SubTypeUserTwapHistory }
+func (s UserTwapHistorySubscription) Wire() WsSubscription {
+	return WsSubscription{Type: SubTypeUserTwapHistory, User: s.User}
+}
+
+// UnknownSubscription receives the messages of channels the SDK does not
+// know, such as channels added to the API after this version. Nothing is
+// sent to the server; subscribe to the channel itself with a WsSubscription.
+type UnknownSubscription struct {
+	Type SubscriptionType `json:"type"`
+}
+
+func (s UnknownSubscription) GetType() SubscriptionType { return SubTypeUnknown }
+func (s UnknownSubscription) Wire() WsSubscription      { return WsSubscription{Type: SubTypeUnknown} }
 
 // WebSocket message data types
 
 // AllMidsData contains mid prices for all assets
 type AllMidsData struct {
-	Mids map[string]string `json:"mids"`
+	Dex  string            `json:"dex,omitempty"`
+	Mids map[string]string `json:"mids"` // Builder dex coins are keyed "dex:COIN"
 }
 
 // AllMidsMsg is the message for all mids
@@ -218,6 +342,16 @@ type L2Level struct {
 	N  int    `json:"n"`  // Number of orders
 }
 
+// Price parses the level price
+func (l L2Level) Price() (float64, error) {
+	return strconv.ParseFloat(l.Px, 64)
+}
+
+// Size parses the level size
+func (l L2Level) Size() (float64, error) {
+	return strconv.ParseFloat(l.Sz, 64)
+}
+
 // L2BookData contains level 2 order book data
 type L2BookData struct {
 	Coin   string      `json:"coin"`
@@ -231,6 +365,47 @@ type L2BookMsg struct {
 	Data    L2BookData `json:"data"`
 }
 
+// CandleIntervals are the candle intervals supported by the API
+var CandleIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "8h", "12h", "1d", "3d", "1w", "1M"}
+
+// ValidCandleInterval reports whether interval is one of CandleIntervals
+func ValidCandleInterval(interval string) bool {
+	for _, supported := range CandleIntervals {
+		if interval == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// Candle is an OHLCV candle
+type Candle struct {
+	OpenTime  int64  `json:"t"` // Open time in milliseconds
+	CloseTime int64  `json:"T"` // Close time in milliseconds
+	Coin      string `json:"s"`
+	Interval  string `json:"i"`
+	Open      string `json:"o"`
+	Close     string `json:"c"`
+	High      string `json:"h"`
+	Low       string `json:"l"`
+	Volume    string `json:"v"` // Volume in base units
+	Trades    int    `json:"n"` // Number of trades
+	// Set on the last update of a candle from Info.SubscribeCandles, once its
+	// close time has passed
+	Closed bool `json:"-"`
+}
+
+// OHLCV parses the candle's prices and volume
+func (c Candle) OHLCV() (open, high, low, close, volume float64, err error) {
+	values := [5]float64{}
+	for i, value := range []string{c.Open, c.High, c.Low, c.Close, c.Volume} {
+		if values[i], err = strconv.ParseFloat(value, 64); err != nil {
+			return 0, 0, 0, 0, 0, fmt.Errorf("invalid candle value %q: %w", value, err)
+		}
+	}
+	return values[0], values[1], values[2], values[3], values[4], nil
+}
+
 // BboData contains best bid/offer data
 type BboData struct {
 	Coin string     `json:"coin"`
@@ -362,13 +537,57 @@ type Fill struct {
 	Oid           int    `json:"oid"`
 	Crossed       bool   `json:"crossed"`
 	Fee           string `json:"fee"`
-	Tid           int    `json:"tid"`
+	Tid           int    `json:"tid"` // 0 on fills aggregated by time
 	FeeToken      string `json:"feeToken"`
+	// Only set on fills fetched over REST
+	Liquidation *FillLiquidation `json:"liquidation,omitempty"`
+	BuilderFee  *string          `json:"builderFee,omitempty"`
+	TwapID      *int64           `json:"twapId,omitempty"`
+}
+
+// FillLiquidation describes the liquidation a fill took part in
+type FillLiquidation struct {
+	LiquidatedUser *string `json:"liquidatedUser,omitempty"`
+	MarkPx         string  `json:"markPx"`
+	Method         string  `json:"method"` // "market" or "backstop"
+}
+
+// UserEventFill is a fill delivered on the userEvents channel
+type UserEventFill = Fill
+
+// UserEventFunding is a funding payment delivered on the userEvents channel
+type UserEventFunding struct {
+	Time        int64  `json:"time"`
+	Coin        string `json:"coin"`
+	Usdc        string `json:"usdc"` // Negative when the user paid
+	Szi         string `json:"szi"`
+	FundingRate string `json:"fundingRate"`
+}
+
+// UserEventLiquidation is a liquidation of the user's account delivered on
+// the userEvents channel
+type UserEventLiquidation struct {
+	Lid                    int64  `json:"lid"`
+	Liquidator             string `json:"liquidator"`
+	LiquidatedUser         string `json:"liquidated_user"`
+	LiquidatedNtlPos       string `json:"liquidated_ntl_pos"`
+	LiquidatedAccountValue string `json:"liquidated_account_value"`
+}
+
+// NonUserCancel is an order cancelled by the system rather than the user,
+// for example for insufficient margin
+type NonUserCancel struct {
+	Coin string `json:"coin"`
+	Oid  int64  `json:"oid"`
 }
 
-// UserEventsData contains user event data
+// UserEventsData contains user event data. Every userEvents message carries
+// one kind of event, so only one field is set.
 type UserEventsData struct {
-	Fills []Fill `json:"fills,omitempty"`
+	Fills          []UserEventFill       `json:"fills,omitempty"`
+	Funding        *UserEventFunding     `json:"funding,omitempty"`
+	Liquidation    *UserEventLiquidation `json:"liquidation,omitempty"`
+	NonUserCancels []NonUserCancel       `json:"nonUserCancel,omitempty"`
 }
 
 // UserEventsMsg is the message for user events
@@ -390,7 +609,56 @@ type UserFillsMsg struct {
 	Data    UserFillsData `json:"data"`
 }
 
-// OtherWsMsg represents other WebSocket messages
+// OrderInfo describes a resting or historical order
+type OrderInfo struct {
+	Coin      string  `json:"coin"`
+	Side      Side    `json:"side"`
+	LimitPx   string  `json:"limitPx"`
+	Sz        string  `json:"sz"`
+	Oid       int     `json:"oid"`
+	Timestamp int64   `json:"timestamp"`
+	OrigSz    string  `json:"origSz"`
+	Cloid     *string `json:"cloid,omitempty"`
+}
+
+// OrderUpdate is an order status change, as delivered by the orderUpdates
+// subscription and the historicalOrders info request
+type OrderUpdate struct {
+	Order           OrderInfo `json:"order"`
+	Status          string    `json:"status"`
+	StatusTimestamp int64     `json:"statusTimestamp"`
+}
+
+// HistoricalOrderInfo is an order with the trigger and time in force details
+// returned by the historicalOrders info request
+type HistoricalOrderInfo struct {
+	OrderInfo
+	OrderType        string  `json:"orderType"`
+	Tif              *string `json:"tif"`
+	ReduceOnly       bool    `json:"reduceOnly"`
+	IsTrigger        bool    `json:"isTrigger"`
+	TriggerPx        string  `json:"triggerPx"`
+	TriggerCondition string  `json:"triggerCondition"`
+	IsPositionTpsl   bool    `json:"isPositionTpsl"`
+}
+
+// OrderStatusEntry is an order with its final or latest status: "open",
+// "filled", "canceled", "triggered", "rejected", "marginCanceled" or one of
+// the other cancel reasons
+type OrderStatusEntry struct {
+	Order           HistoricalOrderInfo `json:"order"`
+	Status          string              `json:"status"`
+	StatusTimestamp int64               `json:"statusTimestamp"`
+}
+
+// OrderUpdate converts the entry to the shape of an orderUpdates message
+func (e OrderStatusEntry) OrderUpdate() OrderUpdate {
+	return OrderUpdate{Order: e.Order.OrderInfo, Status: e.Status, StatusTimestamp: e.StatusTimestamp}
+}
+
+// OtherWsMsg represents other WebSocket messages, and is the generic message
+// subscription callbacks receive, with Data decoded from JSON into maps and
+// slices
 type OtherWsMsg struct {
 	Channel string      `json:"channel"`
 	Data    interface{} `json:"data,omitempty"`
@@ -416,8 +684,235 @@ func (m ActiveAssetDataMsg) GetChannel() string   { return m.Channel }
 
 // BuilderInfo represents builder information
 type BuilderInfo struct {
-	B string `json:"b"` // Public address of the builder
-	F int    `json:"f"` // Fee in tenths of basis points
+	B string `json:"b" msgpack:"b"` // Public address of the builder
+	F int    `json:"f" msgpack:"f"` // Fee in tenths of basis points
+}
+
+// RestingOrder is the status of an order resting on the book
+type RestingOrder struct {
+	Oid   int     `json:"oid"`
+	Cloid *string `json:"cloid,omitempty"`
+}
+
+// FilledOrder is the status of an order that filled immediately
+type FilledOrder struct {
+	Oid     int     `json:"oid"`
+	TotalSz string  `json:"totalSz"`
+	AvgPx   string  `json:"avgPx"`
+	Cloid   *string `json:"cloid,omitempty"`
+}
+
+// OrderStatus is the per-order result of an order action. Exactly one of
+// Resting, Filled, Error or Waiting is set; Waiting holds bare string statuses
+// such as "waitingForTrigger" returned for grouped trigger orders.
+type OrderStatus struct {
+	Resting *RestingOrder `json:"resting,omitempty"`
+	Filled  *FilledOrder  `json:"filled,omitempty"`
+	Error   string        `json:"error,omitempty"`
+	Waiting string        `json:"-"`
+}
+
+// UnmarshalJSON decodes both object and bare string statuses
+func (s *OrderStatus) UnmarshalJSON(data []byte) error {
+	var waiting string
+	if err := json.Unmarshal(data, &waiting); err == nil {
+		*s = OrderStatus{Waiting: waiting}
+		return nil
+	}
+
+	type orderStatus OrderStatus
+	var status orderStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+	*s = OrderStatus(status)
+	return nil
+}
+
+// ExchangeResponse is the envelope of every /exchange response. Raw holds the
+// complete response body for fields that are not modelled.
+type ExchangeResponse struct {
+	Status   string          `json:"status"`
+	Response json.RawMessage `json:"response"`
+	Raw      json.RawMessage `json:"-"`
+}
+
+// OrderResponseData holds the per-order statuses of an order action, in the
+// order the orders were submitted
+type OrderResponseData struct {
+	Statuses []OrderStatus `json:"statuses"`
+}
+
+// OrderResponseBody is the response body of an order action
+type OrderResponseBody struct {
+	Type string            `json:"type"`
+	Data OrderResponseData `json:"data"`
+}
+
+// PriceReference is the price a market order applies its slippage to
+type PriceReference string
+
+const (
+	PriceReferenceGiven PriceReference = "px"  // The price passed by the caller
+	PriceReferenceMid   PriceReference = "mid" // The mid from allMids
+	PriceReferenceBBO   PriceReference = "bbo" // The best opposing level of the L2 book
+)
+
+// OrderResponse is the typed result of an order action
+type OrderResponse struct {
+	Status   string            `json:"status"`
+	Response OrderResponseBody `json:"response"`
+	Raw      json.RawMessage   `json:"-"`
+
+	// Requests are the orders sent, in the order of the statuses, with the
+	// cloids they were sent with
+	Requests []OrderRequest `json:"-"`
+
+	// Cloids are the client order IDs sent with the orders, in order; nil
+	// for orders sent without one
+	Cloids []*string `json:"-"`
+
+	// Set by market orders: the price their slippage was applied to, and
+	// where it came from
+	Reference   PriceReference `json:"-"`
+	ReferencePx float64        `json:"-"`
+}
+
+// OrderResult is the status of one order of an order action together with
+// the request it answers
+type OrderResult struct {
+	Index   int
+	Request OrderRequest // Zero when the response has more statuses than requests
+	Cloid   *string
+	Status  OrderStatus
+}
+
+// ByIndex returns the result of the i-th order sent, and false when the
+// response has no status for it
+func (r *OrderResponse) ByIndex(i int) (OrderResult, bool) {
+	statuses := r.Response.Data.Statuses
+	if i < 0 || i >= len(statuses) {
+		return OrderResult{}, false
+	}
+	result := OrderResult{Index: i, Status: statuses[i]}
+	if i < len(r.Requests) {
+		result.Request = r.Requests[i]
+	}
+	if i < len(r.Cloids) {
+		result.Cloid = r.Cloids[i]
+	}
+	return result, true
+}
+
+// ByCloid returns the result of the order sent with cloid, compared without
+// regard to case, and false when no order was sent with it
+func (r *OrderResponse) ByCloid(cloid string) (OrderResult, bool) {
+	for i, sent := range r.Cloids {
+		if sent != nil && strings.EqualFold(*sent, cloid) {
+			return r.ByIndex(i)
+		}
+	}
+	return OrderResult{}, false
+}
+
+// Results returns the result of every status, in order
+func (r *OrderResponse) Results() []OrderResult {
+	results := make([]OrderResult, len(r.Response.Data.Statuses))
+	for i := range results {
+		results[i], _ = r.ByIndex(i)
+	}
+	return results
+}
+
+// CancelStatus is the per-order result of a cancel action; Error is empty
+// when the order was canceled
+type CancelStatus struct {
+	Success bool   `json:"-"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UnmarshalJSON decodes the bare "success" string and error objects
+func (s *CancelStatus) UnmarshalJSON(data []byte) error {
+	var status string
+	if err := json.Unmarshal(data, &status); err == nil {
+		*s = CancelStatus{Success: status == "success"}
+		return nil
+	}
+
+	var failed struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &failed); err != nil {
+		return err
+	}
+	*s = CancelStatus{Error: failed.Error}
+	return nil
+}
+
+// CancelResponseData holds the per-order statuses of a cancel action
+type CancelResponseData struct {
+	Statuses []CancelStatus `json:"statuses"`
+}
+
+// CancelResponseBody is the response body of a cancel action
+type CancelResponseBody struct {
+	Type string             `json:"type"`
+	Data CancelResponseData `json:"data"`
+}
+
+// CancelResponse is the typed result of a cancel action
+type CancelResponse struct {
+	Status   string             `json:"status"`
+	Response CancelResponseBody `json:"response"`
+	Raw      json.RawMessage    `json:"-"`
+}
+
+// TwapRunning identifies a TWAP order the exchange started
+type TwapRunning struct {
+	TwapID int `json:"twapId"`
+}
+
+// TwapOrderStatus is the result of a TWAP order action; Error is empty when
+// the order is running
+type TwapOrderStatus struct {
+	Running *TwapRunning `json:"running,omitempty"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// TwapOrderResponseData holds the status of a TWAP order action
+type TwapOrderResponseData struct {
+	Status TwapOrderStatus `json:"status"`
+}
+
+// TwapOrderResponseBody is the response body of a TWAP order action
+type TwapOrderResponseBody struct {
+	Type string                `json:"type"`
+	Data TwapOrderResponseData `json:"data"`
+}
+
+// TwapOrderResponse is the typed result of a TWAP order action
+type TwapOrderResponse struct {
+	Status   string                `json:"status"`
+	Response TwapOrderResponseBody `json:"response"`
+	Raw      json.RawMessage       `json:"-"`
+}
+
+// TwapCancelResponseData holds the status of a TWAP cancel action
+type TwapCancelResponseData struct {
+	Status CancelStatus `json:"status"`
+}
+
+// TwapCancelResponseBody is the response body of a TWAP cancel action
+type TwapCancelResponseBody struct {
+	Type string                 `json:"type"`
+	Data TwapCancelResponseData `json:"data"`
+}
+
+// TwapCancelResponse is the typed result of a TWAP cancel action
+type TwapCancelResponse struct {
+	Status   string                 `json:"status"`
+	Response TwapCancelResponseBody `json:"response"`
+	Raw      json.RawMessage        `json:"-"`
 }
 
 // PerpDexSchemaInput represents perpetual DEX schema input
@@ -441,9 +936,31 @@ func NewCloid(rawCloid string) (*Cloid, error) {
 	return c, nil
 }
 
-// NewCloidFromInt creates a new Cloid from an integer
-func NewCloidFromInt(cloid int) *Cloid {
-	return &Cloid{rawCloid: fmt.Sprintf("%#034x", cloid)}
+// maxCloid is the largest cloid, 2^128 - 1
+var maxCloid = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+
+// NewCloidFromInt creates a new Cloid from an integer, zero-padded to 16
+// bytes. Use NewCloidFromBigInt for cloids above 2^64.
+func NewCloidFromInt(cloid uint64) *Cloid {
+	return &Cloid{rawCloid: fmt.Sprintf("0x%032x", cloid)}
+}
+
+// NewCloidFromBigInt creates a new Cloid from an integer between 0 and
+// 2^128 - 1, zero-padded to 16 bytes
+func NewCloidFromBigInt(cloid *big.Int) (*Cloid, error) {
+	if cloid == nil || cloid.Sign() < 0 || cloid.Cmp(maxCloid) > 0 {
+		return nil, fmt.Errorf("cloid %v is outside 0 to 2^128-1", cloid)
+	}
+	return &Cloid{rawCloid: fmt.Sprintf("0x%032x", cloid)}, nil
+}
+
+// RandomCloid creates a Cloid from 16 random bytes
+func RandomCloid() (*Cloid, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, fmt.Errorf("failed to generate cloid: %w", err)
+	}
+	return &Cloid{rawCloid: "0x" + hex.EncodeToString(b[:])}, nil
 }
 
 // NewCloidFromStr creates a new Cloid from a string
@@ -459,6 +976,9 @@ func (c *Cloid) validate() error {
 	if len(c.rawCloid[2:]) != 32 {
 		return fmt.Errorf("cloid is not 16 bytes")
 	}
+	if _, err := hex.DecodeString(c.rawCloid[2:]); err != nil {
+		return fmt.Errorf("cloid is not a hex string")
+	}
 	return nil
 }
 
@@ -472,7 +992,28 @@ func (c *Cloid) ToRaw() string {
 	return c.rawCloid
 }
 
-// ToInt converts the cloid to an integer
+// ToBigInt converts the cloid to an integer
+func (c *Cloid) ToBigInt() (*big.Int, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	value, ok := new(big.Int).SetString(c.rawCloid[2:], 16)
+	if !ok {
+		return nil, fmt.Errorf("cloid is not a hex string")
+	}
+	return value, nil
+}
+
+// ToInt converts the cloid to an integer, failing for cloids above 2^63 - 1.
+//
+// Deprecated: cloids are 128-bit; use ToBigInt.
 func (c *Cloid) ToInt() (int64, error) {
-	return strconv.ParseInt(c.rawCloid, 0, 64)
+	value, err := c.ToBigInt()
+	if err != nil {
+		return 0, err
+	}
+	if !value.IsInt64() {
+		return 0, fmt.Errorf("cloid %s does not fit in an int64", c.rawCloid)
+	}
+	return value.Int64(), nil
 }
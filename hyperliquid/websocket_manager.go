@@ -4,357 +4,1310 @@ package hyperliquid
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
 )
 
-// Subscription types
-type SubscriptionType string
+// SubscriptionType is the type of a WebSocket subscription.
+//
+// Deprecated: use utils.SubscriptionType.
+type SubscriptionType = utils.SubscriptionType
 
+// Deprecated: use the utils.SubType constants.
 const (
-	AllMids                        SubscriptionType = "allMids"
-	L2Book                         SubscriptionType = "l2Book"
-	Trades                         SubscriptionType = "trades"
-	UserEvents                     SubscriptionType = "userEvents"
-	UserFills                      SubscriptionType = "userFills"
-	Candle                         SubscriptionType = "candle"
-	OrderUpdates                   SubscriptionType = "orderUpdates"
-	UserFundings                   SubscriptionType = "userFundings"
-	UserNonFundingLedgerUpdates    SubscriptionType = "userNonFundingLedgerUpdates"
-	WebData2                       SubscriptionType = "webData2"
-	BBO                            SubscriptionType = "bbo"
-	ActiveAssetCtx                 SubscriptionType = "activeAssetCtx"
-	ActiveAssetData                SubscriptionType = "activeAssetData"
+	AllMids                     = utils.SubTypeAllMids
+	L2Book                      = utils.SubTypeL2Book
+	Trades                      = utils.SubTypeTrades
+	UserEvents                  = utils.SubTypeUserEvents
+	UserFills                   = utils.SubTypeUserFills
+	Candle                      = utils.SubTypeCandle
+	OrderUpdates                = utils.SubTypeOrderUpdates
+	UserFundings                = utils.SubTypeUserFundings
+	UserNonFundingLedgerUpdates = utils.SubTypeUserNonFundingLedgerUpdates
+	WebData2                    = utils.SubTypeWebData2
+	BBO                         = utils.SubTypeBbo
+	ActiveAssetCtx              = utils.SubTypeActiveAssetCtx
+	ActiveAssetData             = utils.SubTypeActiveAssetData
 )
 
-// Subscription represents a WebSocket subscription
-type Subscription struct {
-	Type     SubscriptionType `json:"type"`
-	Coin     string           `json:"coin,omitempty"`
-	User     string           `json:"user,omitempty"`
-	Interval string           `json:"interval,omitempty"`
-}
+// Subscription represents a WebSocket subscription as sent on the wire.
+// Typed subscriptions such as utils.L2BookSubscription convert to it with
+// Wire.
+//
+// Deprecated: use utils.WsSubscription.
+type Subscription = utils.WsSubscription
 
-// WsMsg represents a WebSocket message
-type WsMsg struct {
-	Channel string      `json:"channel"`
-	Data    interface{} `json:"data,omitempty"`
-}
+// WsMsg is the generic message delivered to subscription callbacks, the
+// same type as utils.OtherWsMsg
+type WsMsg = utils.OtherWsMsg
 
 // ActiveSubscription represents an active subscription with callback
 type ActiveSubscription struct {
 	Callback       func(WsMsg)
 	SubscriptionID int
+	dispatcher     *dispatcher
+}
+
+// Reconnect backoff defaults: the delay before the first reconnect attempt
+// doubles on every failed attempt up to the max, plus up to 20% jitter
+const (
+	DefaultReconnectBaseDelay = 500 * time.Millisecond
+	DefaultReconnectMaxDelay  = 30 * time.Second
+	reconnectJitter           = 0.2
+)
+
+// DefaultStaleTimeout is how long a connection may go without receiving any
+// message, pongs included, before it is considered dead and replaced. Pings
+// are sent every 50 seconds, so a live connection never goes this long.
+const DefaultStaleTimeout = 60 * time.Second
+
+// Connection defaults used when WSOptions leaves a field unset
+const (
+	DefaultHandshakeTimeout = 45 * time.Second
+	DefaultPingInterval     = 50 * time.Second
+)
+
+// WSOptions configures how a WebSocketManager connects. Zero fields keep the
+// defaults.
+type WSOptions struct {
+	// Dialer opens connections, for example through a proxy or with a
+	// custom TLS config. Defaults to a dialer with DefaultHandshakeTimeout
+	// and no proxy.
+	Dialer *websocket.Dialer
+	// Header is sent with every handshake, for example an Origin header
+	Header http.Header
+	// PingInterval is how often a ping is sent. Defaults to
+	// DefaultPingInterval; keep it below the stale timeout.
+	PingInterval time.Duration
+	// ReadTimeout, if set, fails a read that waits longer for a message
+	ReadTimeout time.Duration
+	// WriteTimeout, if set, fails a write that takes longer
+	WriteTimeout time.Duration
+}
+
+// staleCheckInterval is how often the connection is checked for staleness
+const staleCheckInterval = 5 * time.Second
+
+// ErrConnectionStale is reported to OnDisconnect when a connection is closed
+// for receiving nothing within the stale timeout
+var ErrConnectionStale = errors.New("no WebSocket message received within the stale timeout")
+
+// ErrWebSocketStopped is returned to subscriptions still waiting for an
+// acknowledgement when the manager is stopped
+var ErrWebSocketStopped = errors.New("WebSocket manager stopped")
+
+// ErrNotConnected is returned when unsubscribing from a manager that is not
+// running, or posting without a live connection
+var ErrNotConnected = errors.New("WebSocket not connected")
+
+// ErrDuplicateSubscription is returned when subscribing a second time to a
+// channel that allows only one subscription per connection: userEvents,
+// orderUpdates and notification
+var ErrDuplicateSubscription = errors.New("channel allows only one subscription")
+
+// ErrSubscriptionRemoved is returned to subscriptions still waiting for an
+// acknowledgement when UnsubscribeAll removes them
+var ErrSubscriptionRemoved = errors.New("subscription removed before it was acknowledged")
+
+// DefaultCloseTimeout is how long Info.DisconnectWebSocket waits for
+// callbacks to handle the messages queued for them
+const DefaultCloseTimeout = 5 * time.Second
+
+// closeFrameTimeout bounds sending the close frame
+const closeFrameTimeout = time.Second
+
+// ErrorHandler is called when a callback panics, with the identifier of its
+// subscription, such as "l2Book:btc", or "onReconnect", "onDisconnect" or
+// "onRawMessage", and a *CallbackPanicError
+type ErrorHandler func(identifier string, err error)
+
+// CallbackPanicError describes a panic recovered from a callback
+type CallbackPanicError struct {
+	Value interface{} // The value passed to panic
+	Stack []byte      // The stack of the panicking goroutine
+}
+
+func (e *CallbackPanicError) Error() string {
+	return fmt.Sprintf("callback panicked: %v", e.Value)
 }
 
-// WebSocketManager manages WebSocket connections and subscriptions
+// Identifiers of subscriptions outside the channels the SDK knows: every
+// UnknownSubscription shares one, and subscriptions to other channels are
+// prefixed with their type
+const (
+	unknownIdentifier     = "unknown"
+	otherIdentifierPrefix = "other:"
+)
+
+// SubscriptionAckTimeout is how long Info.Subscribe waits for the server to
+// acknowledge a subscription
+const SubscriptionAckTimeout = 10 * time.Second
+
+// SubscriptionError is returned when the server rejects a subscription, for
+// example for an unknown coin
+type SubscriptionError struct {
+	Subscription utils.WsSubscription
+	Message      string
+}
+
+func (e *SubscriptionError) Error() string {
+	return fmt.Sprintf("subscription to %s rejected: %s", e.Subscription.Type, e.Message)
+}
+
+// WebSocketManager manages WebSocket connections and subscriptions. When the
+// connection drops it reconnects with exponential backoff and re-subscribes
+// to every active subscription, keeping their IDs. Every subscription's
+// callback runs on its own goroutine, in message order, so a slow callback
+// does not hold up the others.
 type WebSocketManager struct {
 	mu                      sync.RWMutex
+	writeMu                 sync.Mutex
 	conn                    *websocket.Conn
 	baseURL                 string
+	wsURL                   string // Overrides the endpoint derived from baseURL
 	subscriptionIDCounter   int
 	wsReady                 bool
+	reconnecting            bool
 	queuedSubscriptions     []queuedSubscription
 	activeSubscriptions     map[string][]ActiveSubscription
-	ctx                     context.Context
+	subscriptions           map[string]utils.WsSubscription
+	pendingAcks             map[string][]chan error
+	postIDCounter           int64
+	pendingPosts            map[int64]chan postResult
+	reconnectBaseDelay      time.Duration
+	reconnectMaxDelay       time.Duration
+	onReconnect             func()
+	onDisconnect            func(error)
+	errorHandler            ErrorHandler
+	onRawMessage            func(json.RawMessage)
+	clock                   Clock
+	staleTimeout            time.Duration
+	lastMessageTime         time.Time
+	closedStale             bool
+	dispatchQueueSize       int
+	overflowPolicy          OverflowPolicy
+	running                 bool
+	closing                 bool // Close sent the close frame; do not reconnect
+	ctx                     context.Context // Of the current run, replaced by every Start
 	cancel                  context.CancelFunc
-	stopCh                  chan struct{}
 	pingTicker              *time.Ticker
+	options                 WSOptions
+	metrics                 wsMetrics
+	loggerPtr               atomic.Pointer[slog.Logger]
 }
 
 type queuedSubscription struct {
-	subscription Subscription
+	subscription utils.WsSubscription
 	active       ActiveSubscription
+	ack          chan error
 }
 
-// NewWebSocketManager creates a new WebSocket manager
-func NewWebSocketManager(baseURL string) *WebSocketManager {
+// NewWebSocketManager creates a new WebSocket manager. Options, if given,
+// configure how it connects.
+func NewWebSocketManager(baseURL string, opts ...WSOptions) *WebSocketManager {
 	ctx, cancel := context.WithCancel(context.Background())
-	return &WebSocketManager{
+	cancel()
+	var options WSOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	if options.Dialer == nil {
+		options.Dialer = &websocket.Dialer{HandshakeTimeout: DefaultHandshakeTimeout}
+	}
+	if options.PingInterval <= 0 {
+		options.PingInterval = DefaultPingInterval
+	}
+	w := &WebSocketManager{
 		baseURL:             baseURL,
 		activeSubscriptions: make(map[string][]ActiveSubscription),
+		subscriptions:       make(map[string]utils.WsSubscription),
+		pendingAcks:         make(map[string][]chan error),
+		pendingPosts:        make(map[int64]chan postResult),
+		reconnectBaseDelay:  DefaultReconnectBaseDelay,
+		reconnectMaxDelay:   DefaultReconnectMaxDelay,
+		clock:               systemClock{},
+		staleTimeout:        DefaultStaleTimeout,
+		dispatchQueueSize:   DefaultDispatchQueueSize,
 		ctx:                 ctx,
 		cancel:              cancel,
-		stopCh:              make(chan struct{}),
+		options:             options,
 	}
+	w.loggerPtr.Store(DefaultLogger())
+	return w
+}
+
+// WebSocketURL returns the WebSocket endpoint of an API base URL: the same
+// host and port with a ws or wss scheme and /ws appended to the path
+func WebSocketURL(baseURL string) (string, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL %q: %w", baseURL, err)
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	case "ws", "wss":
+	default:
+		return "", fmt.Errorf("unsupported base URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("base URL %q has no host", baseURL)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws"
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// SetURL sets the full WebSocket endpoint to connect to instead of the one
+// derived from the base URL, for example to go through a proxy. It applies
+// from the next connection on.
+func (w *WebSocketManager) SetURL(wsURL string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wsURL = wsURL
+}
+
+// SetReconnectBackoff sets the delay before the first reconnect attempt and
+// the most it grows to
+func (w *WebSocketManager) SetReconnectBackoff(baseDelay, maxDelay time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.reconnectBaseDelay = baseDelay
+	w.reconnectMaxDelay = maxDelay
+}
+
+// SetDispatchQueueSize sets how many messages each subscription made from now
+// on can have waiting for its callback
+func (w *WebSocketManager) SetDispatchQueueSize(size int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dispatchQueueSize = size
+}
+
+// SetOverflowPolicy sets what happens to messages for a subscription whose
+// queue is full, for subscriptions made from now on. The default,
+// OverflowBlock, never drops messages but lets a stuck callback stall reads.
+func (w *WebSocketManager) SetOverflowPolicy(policy OverflowPolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.overflowPolicy = policy
+}
+
+// DispatchStats returns the message queue state of a subscription
+func (w *WebSocketManager) DispatchStats(subscriptionID int) (DispatchStats, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, activeSubscriptions := range w.activeSubscriptions {
+		for _, activeSub := range activeSubscriptions {
+			if activeSub.SubscriptionID == subscriptionID {
+				return activeSub.dispatcher.stats(), true
+			}
+		}
+	}
+	return DispatchStats{}, false
+}
+
+// SetStaleTimeout sets how long the connection may go without receiving any
+// message before it is closed and reconnected. Zero disables the check.
+func (w *WebSocketManager) SetStaleTimeout(timeout time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.staleTimeout = timeout
+}
+
+// SetClock replaces the time source of the staleness check, for example with
+// a fake clock in tests. It must be called before Start.
+func (w *WebSocketManager) SetClock(clock Clock) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.clock = clock
+}
+
+// LastMessageTime returns when the last message was received, or when the
+// current connection was made if nothing has been received on it yet
+func (w *WebSocketManager) LastMessageTime() time.Time {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.lastMessageTime
+}
+
+// Healthy reports whether the connection is open and has received a message
+// within the stale timeout
+func (w *WebSocketManager) Healthy() bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.conn == nil || !w.wsReady {
+		return false
+	}
+	return w.staleTimeout <= 0 || w.clock.Now().Sub(w.lastMessageTime) <= w.staleTimeout
+}
+
+// OnReconnect sets a callback run once the connection is re-established and
+// every active subscription has been re-sent. Subscription snapshots follow,
+// so data missed during the gap should be fetched again.
+func (w *WebSocketManager) OnReconnect(callback func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onReconnect = callback
+}
+
+// OnDisconnect sets a callback run with the error that dropped the
+// connection, before reconnecting starts
+func (w *WebSocketManager) OnDisconnect(callback func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onDisconnect = callback
+}
+
+// OnRawMessage sets a callback run with every message as received, before
+// it is decoded, pongs and post responses included. It runs on the read
+// loop, so it must return quickly.
+func (w *WebSocketManager) OnRawMessage(callback func(json.RawMessage)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onRawMessage = callback
 }
 
-// Start starts the WebSocket connection and message handling
+// SetErrorHandler sets a handler called when a callback panics. The panic is
+// recovered and logged either way, and the subscription keeps receiving
+// messages.
+func (w *WebSocketManager) SetErrorHandler(handler ErrorHandler) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.errorHandler = handler
+}
+
+// SetLogger sets the logger of the manager, DefaultLogger by default. A nil
+// logger discards everything. It is safe to call while connected.
+func (w *WebSocketManager) SetLogger(logger *slog.Logger) {
+	w.loggerPtr.Store(orDiscard(logger))
+}
+
+// logger returns the logger of the manager
+func (w *WebSocketManager) logger() *slog.Logger {
+	return w.loggerPtr.Load()
+}
+
+// callSafely runs a callback, recovering a panic so that it does not bring
+// down the goroutine delivering messages to every subscription
+func (w *WebSocketManager) callSafely(identifier string, callback func()) {
+	defer func() {
+		if value := recover(); value != nil {
+			err := &CallbackPanicError{Value: value, Stack: debug.Stack()}
+			w.metrics.callbackPanicked(identifier)
+			w.logger().Error("WebSocket callback panicked", "subscription", identifier, "panic", value, "stack", string(err.Stack))
+			w.mu.RLock()
+			handler := w.errorHandler
+			w.mu.RUnlock()
+			if handler != nil {
+				handler(identifier, err)
+			}
+		}
+	}()
+	callback()
+}
+
+// Start starts the WebSocket connection and message handling. Subscriptions
+// made before Start stay queued if the connection cannot be made. A stopped
+// manager can be started again.
 func (w *WebSocketManager) Start() error {
-	wsURL := "ws" + w.baseURL[len("http"):] + "/ws"
-	
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 45 * time.Second,
+	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		return fmt.Errorf("WebSocket manager already started")
 	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w.mu.Unlock()
 	
-	conn, _, err := dialer.Dial(wsURL, nil)
+	conn, err := w.dial(ctx)
 	if err != nil {
+		cancel()
 		return fmt.Errorf("failed to connect to WebSocket: %w", err)
 	}
 	
 	w.mu.Lock()
+	if w.running {
+		w.mu.Unlock()
+		cancel()
+		conn.Close()
+		return fmt.Errorf("WebSocket manager already started")
+	}
+	w.running = true
+	w.ctx = ctx
+	w.cancel = cancel
 	w.conn = conn
-	w.mu.Unlock()
+	w.lastMessageTime = w.clock.Now()
 	
 	// Start ping sender
-	w.pingTicker = time.NewTicker(50 * time.Second)
-	go w.sendPing()
+	w.pingTicker = time.NewTicker(w.options.PingInterval)
+	go w.sendPing(ctx, w.pingTicker)
+	go w.watchStaleness(ctx)
+	w.mu.Unlock()
 	
 	// Start message handler
-	go w.handleMessages()
+	go w.run(ctx, conn)
 	
 	return nil
 }
 
-// Stop stops the WebSocket connection and all goroutines
-func (w *WebSocketManager) Stop() {
-	w.cancel()
-	close(w.stopCh)
-	
-	if w.pingTicker != nil {
-		w.pingTicker.Stop()
+// dial opens a new connection
+func (w *WebSocketManager) dial(ctx context.Context) (*websocket.Conn, error) {
+	w.mu.RLock()
+	wsURL := w.wsURL
+	w.mu.RUnlock()
+	if wsURL == "" {
+		var err error
+		if wsURL, err = WebSocketURL(w.baseURL); err != nil {
+			return nil, err
+		}
 	}
 	
+	conn, _, err := w.options.Dialer.DialContext(ctx, wsURL, w.options.Header)
+	return conn, err
+}
+
+// Stop stops the WebSocket connection and all goroutines. Subscriptions are
+// dropped, and those waiting for an acknowledgement fail with
+// ErrWebSocketStopped. Stopping a manager that is not running does nothing.
+func (w *WebSocketManager) Stop() {
 	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return
+	}
+	w.running = false
+	w.closing = false
+	w.cancel()
+	w.pingTicker.Stop()
+	
+	// The read deadline wakes a blocked read before the connection closes
 	if w.conn != nil {
+		_ = w.conn.SetReadDeadline(time.Now())
 		w.conn.Close()
 	}
+	w.conn = nil
+	w.wsReady = false
+	w.reconnecting = false
+	w.closedStale = false
+	w.failPosts()
+	
+	var acks []chan error
+	for _, pending := range w.pendingAcks {
+		acks = append(acks, pending...)
+	}
+	for _, queued := range w.queuedSubscriptions {
+		acks = append(acks, queued.ack)
+	}
+	for _, activeSubscriptions := range w.activeSubscriptions {
+		for _, activeSub := range activeSubscriptions {
+			activeSub.dispatcher.stop()
+		}
+	}
+	w.queuedSubscriptions = nil
+	w.activeSubscriptions = make(map[string][]ActiveSubscription)
+	w.subscriptions = make(map[string]utils.WsSubscription)
+	w.pendingAcks = make(map[string][]chan error)
+	w.mu.Unlock()
+	
+	for _, ack := range acks {
+		resolveAck(ack, ErrWebSocketStopped)
+	}
+}
+
+// Close shuts the manager down gracefully. It unsubscribes from every
+// channel, waits until callbacks have handled the messages already queued for
+// them, sends a close frame and then stops like Stop. If ctx is done before
+// the callbacks catch up, the remaining messages are discarded and ctx's
+// error is returned; the manager is stopped either way. A callback must not
+// call Close, as it would wait for itself. Closing a manager that is not
+// running does nothing.
+func (w *WebSocketManager) Close(ctx context.Context) error {
+	w.mu.RLock()
+	running := w.running
+	w.mu.RUnlock()
+	if !running {
+		return nil
+	}
+	defer w.Stop()
+	
+	dispatchers, err := w.unsubscribeAll()
+	for _, d := range dispatchers {
+		if drainErr := d.drain(ctx); drainErr != nil {
+			if err == nil {
+				err = fmt.Errorf("waiting for callbacks: %w", drainErr)
+			}
+			break
+		}
+	}
+	for _, d := range dispatchers {
+		d.stop()
+	}
+	
+	if closeErr := w.sendClose(); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// sendClose sends a close frame on the current connection, if any, so that
+// the server ends the session instead of waiting for the TCP teardown
+func (w *WebSocketManager) sendClose() error {
+	w.mu.Lock()
+	conn := w.conn
+	w.closing = true
 	w.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	if err := conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeFrameTimeout)); err != nil {
+		return fmt.Errorf("failed to send close frame: %w", err)
+	}
+	return nil
+}
+
+// writeJSON sends a message on conn. Writes are serialized as the connection
+// allows one writer at a time. A failed write closes the connection, so the
+// reader sees the error and reconnects.
+func (w *WebSocketManager) writeJSON(conn *websocket.Conn, v interface{}) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	if w.options.WriteTimeout > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(w.options.WriteTimeout))
+	}
+	if err := conn.WriteJSON(v); err != nil {
+		conn.Close()
+		return err
+	}
+	return nil
 }
 
 // sendPing sends periodic ping messages
-func (w *WebSocketManager) sendPing() {
+func (w *WebSocketManager) sendPing(ctx context.Context, ticker *time.Ticker) {
 	for {
 		select {
-		case <-w.ctx.Done():
-			log.Println("WebSocket ping sender stopped")
+		case <-ctx.Done():
+			w.logger().Debug("WebSocket ping sender stopped")
 			return
-		case <-w.pingTicker.C:
+		case <-ticker.C:
 			w.mu.RLock()
 			conn := w.conn
 			w.mu.RUnlock()
 			
 			if conn != nil {
-				log.Println("WebSocket sending ping")
+				w.logger().Debug("WebSocket sending ping")
 				pingMsg := map[string]string{"method": "ping"}
-				if err := conn.WriteJSON(pingMsg); err != nil {
-					log.Printf("Failed to send ping: %v", err)
+				if err := w.writeJSON(conn, pingMsg); err != nil {
+					w.logger().Warn("Failed to send ping", "err", err)
 				}
 			}
 		}
 	}
 }
 
-// handleMessages handles incoming WebSocket messages
-func (w *WebSocketManager) handleMessages() {
+// watchStaleness closes the connection when nothing has been received on it
+// within the stale timeout, so that a half-open connection gets replaced
+func (w *WebSocketManager) watchStaleness(ctx context.Context) {
 	for {
 		select {
-		case <-w.ctx.Done():
+		case <-ctx.Done():
 			return
-		default:
-			w.mu.RLock()
-			conn := w.conn
-			w.mu.RUnlock()
-			
-			if conn == nil {
+		case <-w.clock.After(staleCheckInterval):
+			w.closeIfStale()
+		}
+	}
+}
+
+// closeIfStale closes a connection that went quiet for the stale timeout; the
+// reader then fails and reconnects
+func (w *WebSocketManager) closeIfStale() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil || w.staleTimeout <= 0 {
+		return
+	}
+	if silence := w.clock.Now().Sub(w.lastMessageTime); silence > w.staleTimeout {
+		w.logger().Warn("WebSocket received nothing, reconnecting", "silence", silence)
+		w.closedStale = true
+		w.conn.Close()
+	}
+}
+
+// run handles messages on conn and on every connection replacing it after a
+// disconnect, until ctx, the context of the run, is done
+func (w *WebSocketManager) run(ctx context.Context, conn *websocket.Conn) {
+	for {
+		err := w.handleMessages(conn)
+		w.mu.RLock()
+		closing := w.closing
+		w.mu.RUnlock()
+		if ctx.Err() != nil || closing {
+			return
+		}
+		w.disconnected(ctx, err)
+		
+		conn = w.reconnect(ctx)
+		if conn == nil {
+			return
+		}
+	}
+}
+
+// handleMessages handles incoming WebSocket messages until reading fails
+func (w *WebSocketManager) handleMessages(conn *websocket.Conn) error {
+	for {
+		if w.options.ReadTimeout > 0 {
+			_ = conn.SetReadDeadline(time.Now().Add(w.options.ReadTimeout))
+		}
+		var message json.RawMessage
+		err := conn.ReadJSON(&message)
+		if err != nil {
+			return err
+		}
+		
+		w.mu.Lock()
+		w.lastMessageTime = w.clock.Now()
+		onRawMessage := w.onRawMessage
+		w.mu.Unlock()
+		if onRawMessage != nil {
+			w.callSafely("onRawMessage", func() { onRawMessage(message) })
+		}
+		
+		// Handle string messages
+		var strMsg string
+		if err := json.Unmarshal(message, &strMsg); err == nil {
+			if strMsg == "Websocket connection established." {
+				w.logger().Debug(strMsg)
+				w.onOpen()
 				continue
 			}
-			
-			var message json.RawMessage
-			err := conn.ReadJSON(&message)
-			if err != nil {
-				log.Printf("WebSocket read error: %v", err)
-				return
-			}
-			
-			// Handle string messages
-			var strMsg string
-			if err := json.Unmarshal(message, &strMsg); err == nil {
-				if strMsg == "Websocket connection established." {
-					log.Println(strMsg)
-					w.onOpen()
-					continue
-				}
-			}
-			
-			// Handle JSON messages
-			var wsMsg WsMsg
-			if err := json.Unmarshal(message, &wsMsg); err != nil {
-				log.Printf("Failed to unmarshal WebSocket message: %v", err)
+		}
+		
+		// Post responses are decoded from the raw message, so that large
+		// numbers such as order ids keep their precision
+		var post struct {
+			Channel string          `json:"channel"`
+			Data    json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(message, &post); err == nil {
+			w.metrics.messageReceived(post.Channel, len(message))
+			if post.Channel == "post" {
+				w.onPostResponse(post.Data)
 				continue
 			}
-			
-			w.onMessage(wsMsg)
 		}
+		
+		// Handle JSON messages
+		var wsMsg WsMsg
+		if err := json.Unmarshal(message, &wsMsg); err != nil {
+			w.logger().Warn("Failed to unmarshal WebSocket message", "err", err)
+			continue
+		}
+		
+		w.onMessage(wsMsg)
+	}
+}
+
+// disconnected marks the connection as lost after err
+func (w *WebSocketManager) disconnected(ctx context.Context, err error) {
+	w.logger().Warn("WebSocket read error", "err", err)
+	w.mu.Lock()
+	if ctx.Err() != nil {
+		w.mu.Unlock()
+		return
+	}
+	if w.conn != nil {
+		w.conn.Close()
+	}
+	w.conn = nil
+	w.wsReady = false
+	w.reconnecting = true
+	w.failPosts()
+	if w.closedStale {
+		err = ErrConnectionStale
+		w.closedStale = false
+	}
+	onDisconnect := w.onDisconnect
+	w.mu.Unlock()
+	
+	if onDisconnect != nil {
+		w.callSafely("onDisconnect", func() { onDisconnect(err) })
+	}
+}
+
+// reconnect dials until it succeeds, waiting longer after every failure. It
+// returns nil once the manager is stopped.
+func (w *WebSocketManager) reconnect(ctx context.Context) *websocket.Conn {
+	for attempt := 1; ; attempt++ {
+		delay := w.reconnectDelay(attempt)
+		w.logger().Warn("WebSocket reconnecting", "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		
+		conn, err := w.dial(ctx)
+		if err != nil {
+			w.logger().Warn("WebSocket reconnect failed", "err", err)
+			continue
+		}
+		
+		w.mu.Lock()
+		if ctx.Err() != nil {
+			w.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		w.conn = conn
+		w.lastMessageTime = w.clock.Now()
+		w.mu.Unlock()
+		return conn
 	}
 }
 
-// onOpen handles WebSocket connection open event
+// reconnectDelay returns the backoff before reconnect attempt
+func (w *WebSocketManager) reconnectDelay(attempt int) time.Duration {
+	w.mu.RLock()
+	baseDelay, maxDelay := w.reconnectBaseDelay, w.reconnectMaxDelay
+	w.mu.RUnlock()
+	
+	delay := time.Duration(float64(baseDelay) * math.Pow(2, float64(attempt-1)))
+	if maxDelay > 0 && delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay + time.Duration(rand.Float64()*reconnectJitter*float64(delay))
+}
+
+// onOpen handles WebSocket connection open event. After a reconnect every
+// active subscription is sent again before the queued ones. They are sent
+// without holding w.mu, so a stalled write cannot keep the staleness check
+// from closing the connection.
 func (w *WebSocketManager) onOpen() {
-	log.Println("WebSocket connection opened")
+	w.logger().Info("WebSocket connection opened")
 	w.mu.Lock()
 	w.wsReady = true
+	reconnected := w.reconnecting
+	w.reconnecting = false
+	conn := w.conn
 	
-	// Process queued subscriptions
-	for _, queued := range w.queuedSubscriptions {
-		w.subscribeInternal(queued.subscription, queued.active.Callback, queued.active.SubscriptionID)
+	var resubscriptions []utils.WsSubscription
+	var identifiers []string
+	if reconnected {
+		for identifier, activeSubscriptions := range w.activeSubscriptions {
+			if len(activeSubscriptions) > 0 && identifier != unknownIdentifier {
+				if _, exists := w.pendingAcks[identifier]; !exists {
+					w.pendingAcks[identifier] = nil
+				}
+				resubscriptions = append(resubscriptions, w.subscriptions[identifier])
+				identifiers = append(identifiers, identifier)
+			}
+		}
 	}
-	w.queuedSubscriptions = nil
 	w.mu.Unlock()
+	
+	for i, subscription := range resubscriptions {
+		if err := w.sendSubscriptionOn(conn, "subscribe", subscription); err != nil {
+			w.logger().Error("Failed to re-subscribe", "subscription", identifiers[i], "err", err)
+		}
+	}
+	
+	w.mu.Lock()
+	// Process queued subscriptions. Those not sent because the connection
+	// failed again stay queued for the next one.
+	for len(w.queuedSubscriptions) > 0 {
+		queued := w.queuedSubscriptions[0]
+		err := w.subscribeInternal(queued.subscription, queued.active.Callback, queued.active.SubscriptionID, queued.ack)
+		if err != nil && !errors.Is(err, ErrDuplicateSubscription) {
+			w.logger().Error("Failed to subscribe", "err", err)
+			break
+		}
+		if err != nil {
+			resolveAck(queued.ack, err)
+		}
+		w.queuedSubscriptions = w.queuedSubscriptions[1:]
+	}
+	if len(w.queuedSubscriptions) == 0 {
+		w.queuedSubscriptions = nil
+	}
+	onReconnect := w.onReconnect
+	w.mu.Unlock()
+	
+	if reconnected {
+		w.metrics.reconnected()
+	}
+	if reconnected && onReconnect != nil {
+		w.callSafely("onReconnect", onReconnect)
+	}
 }
 
 // onMessage handles incoming WebSocket messages
 func (w *WebSocketManager) onMessage(wsMsg WsMsg) {
-	log.Printf("Received message: %+v", wsMsg)
+	// Every message is logged only at debug level, and only formatted when
+	// that level is enabled, as busy channels deliver many per second
+	if w.logger().Enabled(context.Background(), slog.LevelDebug) {
+		w.logger().Debug("Received message", "message", fmt.Sprintf("%+v", wsMsg))
+	}
 	
-	identifier := w.wsMsgToIdentifier(wsMsg)
-	if identifier == "pong" {
-		log.Println("WebSocket received pong")
+	switch wsMsg.Channel {
+	case "subscriptionResponse":
+		w.onSubscriptionResponse(wsMsg)
+		return
+	case "error":
+		w.onError(wsMsg)
 		return
 	}
 	
-	if identifier == "" {
-		log.Println("WebSocket not handling empty message")
+	identifier := w.wsMsgToIdentifier(wsMsg)
+	if identifier == "pong" {
+		w.logger().Debug("WebSocket received pong")
+		w.metrics.pongReceived(w.clock.Now())
 		return
 	}
 	
 	w.mu.RLock()
 	activeSubscriptions := w.activeSubscriptions[identifier]
+	if identifier == "" {
+		activeSubscriptions = w.otherSubscriptions(wsMsg.Channel)
+		identifier = wsMsg.Channel
+	}
+	ctx := w.ctx
 	w.mu.RUnlock()
 	
 	if len(activeSubscriptions) == 0 {
-		log.Printf("WebSocket message from unexpected subscription: %s, identifier: %s", wsMsg.Channel, identifier)
+		w.logger().Debug("WebSocket message from unexpected subscription", "channel", wsMsg.Channel, "subscription", identifier)
 	} else {
 		for _, activeSub := range activeSubscriptions {
-			activeSub.Callback(wsMsg)
+			activeSub.dispatcher.enqueue(ctx, wsMsg)
+		}
+	}
+}
+
+// otherSubscriptions returns the subscriptions receiving a message on a
+// channel the SDK does not know: those to the channel itself, made with a
+// WsSubscription of that type, and every UnknownSubscription. The caller
+// must hold mu.
+func (w *WebSocketManager) otherSubscriptions(channel string) []ActiveSubscription {
+	var subscriptions []ActiveSubscription
+	prefix := otherIdentifierPrefix + channel + ":"
+	for identifier, activeSubscriptions := range w.activeSubscriptions {
+		if strings.HasPrefix(identifier, prefix) {
+			subscriptions = append(subscriptions, activeSubscriptions...)
+		}
+	}
+	return append(subscriptions, w.activeSubscriptions[unknownIdentifier]...)
+}
+
+// Subscribe subscribes to a WebSocket channel. subscription is a
+// utils.WsSubscription or one of the typed subscriptions in utils. It does
+// not wait for the server to acknowledge the subscription; see SubscribeCtx.
+// A second subscription to userEvents, orderUpdates or notification returns
+// ErrDuplicateSubscription, and a subscribe request that cannot be sent
+// returns the write error.
+func (w *WebSocketManager) Subscribe(subscription utils.Subscription, callback func(WsMsg)) (int, error) {
+	return w.subscribe(subscription.Wire(), callback, nil)
+}
+
+// SubscribeCtx subscribes to a WebSocket channel and waits until the server
+// acknowledges the subscription. A rejected subscription returns a
+// *SubscriptionError; one not acknowledged before ctx is done is removed.
+func (w *WebSocketManager) SubscribeCtx(ctx context.Context, subscription utils.Subscription, callback func(WsMsg)) (int, error) {
+	ack := make(chan error, 1)
+	subscriptionID, err := w.subscribe(subscription.Wire(), callback, ack)
+	if err != nil {
+		return 0, err
+	}
+	
+	select {
+	case err := <-ack:
+		if err != nil {
+			return 0, err
 		}
+		return subscriptionID, nil
+	case <-ctx.Done():
+		_, _ = w.Unsubscribe(subscription, subscriptionID)
+		return 0, fmt.Errorf("subscription to %s not acknowledged: %w", subscription.GetType(), ctx.Err())
 	}
 }
 
-// Subscribe subscribes to a WebSocket channel
-func (w *WebSocketManager) Subscribe(subscription Subscription, callback func(WsMsg)) int {
+// subscribe registers a subscription, or queues it until connected. ack, if
+// not nil, receives the outcome once the subscription has been sent without
+// error.
+func (w *WebSocketManager) subscribe(subscription utils.WsSubscription, callback func(WsMsg), ack chan error) (int, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	
-	w.subscriptionIDCounter++
-	subscriptionID := w.subscriptionIDCounter
-	
 	if !w.wsReady {
-		log.Println("Enqueueing subscription")
+		identifier := w.subscriptionToIdentifier(subscription)
+		if err := w.checkDuplicate(identifier); err != nil {
+			return 0, err
+		}
+		for _, queued := range w.queuedSubscriptions {
+			if isSingleSubscription(identifier) && w.subscriptionToIdentifier(queued.subscription) == identifier {
+				return 0, fmt.Errorf("%w: %s", ErrDuplicateSubscription, identifier)
+			}
+		}
+		w.subscriptionIDCounter++
+		w.logger().Debug("Enqueueing subscription")
 		w.queuedSubscriptions = append(w.queuedSubscriptions, queuedSubscription{
 			subscription: subscription,
-			active:       ActiveSubscription{Callback: callback, SubscriptionID: subscriptionID},
+			active:       ActiveSubscription{Callback: callback, SubscriptionID: w.subscriptionIDCounter},
+			ack:          ack,
 		})
-	} else {
-		w.subscribeInternal(subscription, callback, subscriptionID)
+		return w.subscriptionIDCounter, nil
 	}
 	
-	return subscriptionID
+	if err := w.subscribeInternal(subscription, callback, w.subscriptionIDCounter+1, ack); err != nil {
+		return 0, err
+	}
+	w.subscriptionIDCounter++
+	return w.subscriptionIDCounter, nil
+}
+
+// isSingleSubscription reports whether a channel allows only one
+// subscription per connection
+func isSingleSubscription(identifier string) bool {
+	return identifier == "userEvents" || identifier == "orderUpdates" || identifier == "notification"
+}
+
+// checkDuplicate returns ErrDuplicateSubscription if identifier is a single
+// subscription channel that is already subscribed. The caller must hold mu.
+func (w *WebSocketManager) checkDuplicate(identifier string) error {
+	if isSingleSubscription(identifier) && len(w.activeSubscriptions[identifier]) != 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateSubscription, identifier)
+	}
+	return nil
 }
 
-// subscribeInternal handles the actual subscription logic
-func (w *WebSocketManager) subscribeInternal(subscription Subscription, callback func(WsMsg), subscriptionID int) {
-	log.Println("Subscribing")
+// subscribeInternal handles the actual subscription logic. A channel that is
+// already subscribed is not subscribed to again; the callback is added and
+// acknowledged along with it. Nothing is registered when it returns an
+// error, and ack is left for the caller to resolve.
+func (w *WebSocketManager) subscribeInternal(subscription utils.WsSubscription, callback func(WsMsg), subscriptionID int, ack chan error) error {
+	w.logger().Debug("Subscribing")
 	identifier := w.subscriptionToIdentifier(subscription)
-	
-	// Check for single subscription constraints
-	if identifier == "userEvents" || identifier == "orderUpdates" {
-		if len(w.activeSubscriptions[identifier]) != 0 {
-			log.Printf("Cannot subscribe to %s multiple times", identifier)
-			return
+	alreadySubscribed := len(w.activeSubscriptions[identifier]) != 0
+	if err := w.checkDuplicate(identifier); err != nil {
+		return err
+	}
+	if !alreadySubscribed {
+		if err := w.sendSubscription("subscribe", subscription); err != nil {
+			return err
 		}
 	}
-	
 	w.activeSubscriptions[identifier] = append(w.activeSubscriptions[identifier], ActiveSubscription{
 		Callback:       callback,
 		SubscriptionID: subscriptionID,
+		dispatcher:     newDispatcher(w.ctx, identifier, w.guardCallback(identifier, callback), w.dispatchQueueSize, w.overflowPolicy, &w.metrics),
 	})
+	w.subscriptions[identifier] = subscription
+	if subscription.Type == utils.SubTypeUnknown {
+		// Nothing was sent, so nothing will be acknowledged
+		resolveAck(ack, nil)
+		return nil
+	}
 	
-	subMsg := map[string]interface{}{
-		"method":      "subscribe",
-		"subscription": subscription,
+	_, inFlight := w.pendingAcks[identifier]
+	if alreadySubscribed && !inFlight {
+		resolveAck(ack, nil)
+		return nil
+	}
+	if ack != nil {
+		w.pendingAcks[identifier] = append(w.pendingAcks[identifier], ack)
+	} else if !inFlight {
+		w.pendingAcks[identifier] = nil
+	}
+	return nil
+}
+
+// guardCallback wraps a subscription callback so that a panic in it is
+// recovered and reported with the subscription's identifier
+func (w *WebSocketManager) guardCallback(identifier string, callback func(WsMsg)) func(WsMsg) {
+	return func(msg WsMsg) {
+		w.callSafely(identifier, func() { callback(msg) })
+	}
+}
+
+// resolveAck delivers the outcome of a subscription to a waiting caller
+func resolveAck(ack chan error, err error) {
+	if ack != nil {
+		ack <- err
+	}
+}
+
+// onSubscriptionResponse acknowledges the subscription a subscriptionResponse
+// message confirms
+func (w *WebSocketManager) onSubscriptionResponse(wsMsg WsMsg) {
+	var response struct {
+		Method       string       `json:"method"`
+		Subscription utils.WsSubscription `json:"subscription"`
+	}
+	if err := decodeResult(wsMsg.Data, &response); err != nil {
+		w.logger().Warn("Failed to decode subscription response", "err", err)
+		return
+	}
+	if response.Method != "subscribe" {
+		return
 	}
 	
-	if w.conn != nil {
-		if err := w.conn.WriteJSON(subMsg); err != nil {
-			log.Printf("Failed to send subscription: %v", err)
+	identifier := w.subscriptionToIdentifier(response.Subscription)
+	w.mu.Lock()
+	acks := w.pendingAcks[identifier]
+	delete(w.pendingAcks, identifier)
+	w.mu.Unlock()
+	
+	for _, ack := range acks {
+		resolveAck(ack, nil)
+	}
+}
+
+// onError fails the subscription an error message refers to. The server
+// quotes the subscription in the message; when it cannot be parsed, the only
+// pending subscription, if there is just one, is assumed.
+func (w *WebSocketManager) onError(wsMsg WsMsg) {
+	message, _ := wsMsg.Data.(string)
+	w.logger().Error("WebSocket error", "message", message)
+	
+	w.mu.Lock()
+	identifier := ""
+	if start := strings.Index(message, "{"); start >= 0 {
+		var subscription utils.WsSubscription
+		if err := json.Unmarshal([]byte(message[start:]), &subscription); err == nil {
+			identifier = w.subscriptionToIdentifier(subscription)
+		}
+	}
+	if identifier == "" && len(w.pendingAcks) == 1 {
+		for pendingIdentifier := range w.pendingAcks {
+			identifier = pendingIdentifier
 		}
 	}
+	if _, pending := w.pendingAcks[identifier]; !pending {
+		w.mu.Unlock()
+		return
+	}
+	
+	subscriptionErr := &SubscriptionError{Subscription: w.subscriptions[identifier], Message: message}
+	acks := w.pendingAcks[identifier]
+	for _, activeSub := range w.activeSubscriptions[identifier] {
+		activeSub.dispatcher.stop()
+	}
+	delete(w.pendingAcks, identifier)
+	delete(w.activeSubscriptions, identifier)
+	delete(w.subscriptions, identifier)
+	w.mu.Unlock()
+	
+	for _, ack := range acks {
+		resolveAck(ack, subscriptionErr)
+	}
+}
+
+// sendSubscription sends a subscribe or unsubscribe request, if connected.
+// The caller must hold mu.
+func (w *WebSocketManager) sendSubscription(method string, subscription utils.WsSubscription) error {
+	return w.sendSubscriptionOn(w.conn, method, subscription)
+}
+
+// sendSubscriptionOn is like sendSubscription but writes to conn, so that
+// it can be called without holding w.mu
+func (w *WebSocketManager) sendSubscriptionOn(conn *websocket.Conn, method string, subscription utils.WsSubscription) error {
+	if conn == nil || subscription.Type == utils.SubTypeUnknown {
+		return nil
+	}
+	msg := map[string]interface{}{
+		"method":       method,
+		"subscription": subscription,
+	}
+	if err := w.writeJSON(conn, msg); err != nil {
+		return fmt.Errorf("failed to send %s for %s: %w", method, subscription.Type, err)
+	}
+	return nil
 }
 
-// Unsubscribe unsubscribes from a WebSocket channel
-func (w *WebSocketManager) Unsubscribe(subscription Subscription, subscriptionID int) bool {
+// Unsubscribe unsubscribes from a WebSocket channel. It reports whether
+// subscriptionID was subscribed, and returns ErrNotConnected when the manager
+// is not running. Subscriptions still queued can be removed without a
+// connection. The subscription is removed even when the unsubscribe request
+// cannot be sent, in which case the write error is returned.
+func (w *WebSocketManager) Unsubscribe(subscription utils.Subscription, subscriptionID int) (bool, error) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 	
-	if !w.wsReady {
-		log.Println("Cannot unsubscribe before WebSocket connected")
-		return false
+	// Subscriptions made while disconnected are still queued
+	for i, queued := range w.queuedSubscriptions {
+		if queued.active.SubscriptionID == subscriptionID {
+			w.queuedSubscriptions = append(w.queuedSubscriptions[:i:i], w.queuedSubscriptions[i+1:]...)
+			return true, nil
+		}
+	}
+	if !w.running {
+		return false, ErrNotConnected
 	}
 	
-	identifier := w.subscriptionToIdentifier(subscription)
+	wire := subscription.Wire()
+	identifier := w.subscriptionToIdentifier(wire)
 	activeSubscriptions := w.activeSubscriptions[identifier]
 	
 	newActiveSubscriptions := make([]ActiveSubscription, 0)
 	for _, sub := range activeSubscriptions {
 		if sub.SubscriptionID != subscriptionID {
 			newActiveSubscriptions = append(newActiveSubscriptions, sub)
+		} else {
+			sub.dispatcher.stop()
 		}
 	}
 	
+	removed := len(activeSubscriptions) != len(newActiveSubscriptions)
+	w.activeSubscriptions[identifier] = newActiveSubscriptions
 	if len(newActiveSubscriptions) == 0 {
-		unsubMsg := map[string]interface{}{
-			"method":      "unsubscribe",
-			"subscription": subscription,
-		}
-		if w.conn != nil {
-			if err := w.conn.WriteJSON(unsubMsg); err != nil {
-				log.Printf("Failed to send unsubscription: %v", err)
+		delete(w.subscriptions, identifier)
+		delete(w.pendingAcks, identifier)
+		if removed && w.wsReady {
+			if err := w.sendSubscription("unsubscribe", wire); err != nil {
+				return true, err
 			}
 		}
 	}
+	return removed, nil
+}
+
+// UnsubscribeAll removes every subscription, queued ones included, and sends
+// an unsubscribe request for every channel subscribed on the server.
+// Callbacks receive no further messages, and subscriptions still waiting for
+// an acknowledgement fail with ErrSubscriptionRemoved. The subscriptions are
+// removed even when a request cannot be sent, in which case the first write
+// error is returned.
+func (w *WebSocketManager) UnsubscribeAll() error {
+	dispatchers, err := w.unsubscribeAll()
+	for _, d := range dispatchers {
+		d.stop()
+	}
+	return err
+}
+
+// unsubscribeAll removes every subscription and returns the dispatchers of
+// the active ones, still running, for the caller to stop or drain
+func (w *WebSocketManager) unsubscribeAll() ([]*dispatcher, error) {
+	w.mu.Lock()
+	var acks []chan error
+	for _, queued := range w.queuedSubscriptions {
+		acks = append(acks, queued.ack)
+	}
+	for _, pending := range w.pendingAcks {
+		acks = append(acks, pending...)
+	}
 	
-	w.activeSubscriptions[identifier] = newActiveSubscriptions
-	return len(activeSubscriptions) != len(newActiveSubscriptions)
+	var dispatchers []*dispatcher
+	var err error
+	for identifier, activeSubscriptions := range w.activeSubscriptions {
+		for _, activeSub := range activeSubscriptions {
+			dispatchers = append(dispatchers, activeSub.dispatcher)
+		}
+		if len(activeSubscriptions) > 0 && w.wsReady && err == nil {
+			err = w.sendSubscription("unsubscribe", w.subscriptions[identifier])
+		}
+	}
+	w.queuedSubscriptions = nil
+	w.activeSubscriptions = make(map[string][]ActiveSubscription)
+	w.subscriptions = make(map[string]utils.WsSubscription)
+	w.pendingAcks = make(map[string][]chan error)
+	w.mu.Unlock()
+	
+	for _, ack := range acks {
+		resolveAck(ack, ErrSubscriptionRemoved)
+	}
+	return dispatchers, err
 }
 
 // subscriptionToIdentifier converts a subscription to an identifier string
-func (w *WebSocketManager) subscriptionToIdentifier(subscription Subscription) string {
+func (w *WebSocketManager) subscriptionToIdentifier(subscription utils.WsSubscription) string {
 	switch subscription.Type {
-	case AllMids:
-		return "allMids"
-	case L2Book:
+	case utils.SubTypeAllMids:
+		return allMidsIdentifier(subscription.Dex)
+	case utils.SubTypeL2Book:
 		return fmt.Sprintf("l2Book:%s", strings.ToLower(subscription.Coin))
-	case Trades:
+	case utils.SubTypeTrades:
 		return fmt.Sprintf("trades:%s", strings.ToLower(subscription.Coin))
-	case UserEvents:
+	case utils.SubTypeUserEvents:
 		return "userEvents"
-	case UserFills:
+	case utils.SubTypeUserFills:
 		return fmt.Sprintf("userFills:%s", strings.ToLower(subscription.User))
-	case Candle:
+	case utils.SubTypeCandle:
 		return fmt.Sprintf("candle:%s,%s", strings.ToLower(subscription.Coin), subscription.Interval)
-	case OrderUpdates:
+	case utils.SubTypeOrderUpdates:
 		return "orderUpdates"
-	case UserFundings:
+	case utils.SubTypeUserFundings:
 		return fmt.Sprintf("userFundings:%s", strings.ToLower(subscription.User))
-	case UserNonFundingLedgerUpdates:
+	case utils.SubTypeUserNonFundingLedgerUpdates:
 		return fmt.Sprintf("userNonFundingLedgerUpdates:%s", strings.ToLower(subscription.User))
-	case WebData2:
+	case utils.SubTypeWebData2:
 		return fmt.Sprintf("webData2:%s", strings.ToLower(subscription.User))
-	case BBO:
+	case utils.SubTypeBbo:
 		return fmt.Sprintf("bbo:%s", strings.ToLower(subscription.Coin))
-	case ActiveAssetCtx:
+	case utils.SubTypeActiveAssetCtx:
 		return fmt.Sprintf("activeAssetCtx:%s", strings.ToLower(subscription.Coin))
-	case ActiveAssetData:
+	case utils.SubTypeActiveAssetData:
 		return fmt.Sprintf("activeAssetData:%s,%s", strings.ToLower(subscription.Coin), strings.ToLower(subscription.User))
+	case utils.SubTypeNotification:
+		return "notification"
+	case utils.SubTypeUserTwapSliceFills:
+		return fmt.Sprintf("userTwapSliceFills:%s", strings.ToLower(subscription.User))
+	case utils.SubTypeUserTwapHistory:
+		return fmt.Sprintf("userTwapHistory:%s", strings.ToLower(subscription.User))
+	case utils.SubTypeUnknown:
+		return unknownIdentifier
 	default:
+		// A channel the SDK does not know receives the messages of the
+		// channel named like its type
+		return fmt.Sprintf("%s%s:%s,%s,%s,%s", otherIdentifierPrefix, subscription.Type,
+			strings.ToLower(subscription.Coin), strings.ToLower(subscription.User), subscription.Interval, strings.ToLower(subscription.Dex))
+	}
+}
+
+// allMidsIdentifier returns the identifier of the allMids channel of a perp
+// dex, namespaced unless it is the default dex
+func allMidsIdentifier(dex string) string {
+	if dex == "" {
+		return "allMids"
+	}
+	return fmt.Sprintf("allMids:%s", strings.ToLower(dex))
+}
+
+// allMidsDex returns the perp dex of an allMids message: its dex field if
+// present, otherwise the prefix of its "dex:COIN" keys. Default dex and spot
+// coins have no such prefix.
+func allMidsDex(data interface{}) string {
+	fields, ok := data.(map[string]interface{})
+	if !ok {
 		return ""
 	}
+	if dex, ok := fields["dex"].(string); ok {
+		return dex
+	}
+	mids, _ := fields["mids"].(map[string]interface{})
+	for coin := range mids {
+		return perpDexOf(coin)
+	}
+	return ""
 }
 
 // wsMsgToIdentifier converts a WebSocket message to an identifier string
@@ -363,7 +1316,7 @@ func (w *WebSocketManager) wsMsgToIdentifier(wsMsg WsMsg) string {
 	case "pong":
 		return "pong"
 	case "allMids":
-		return "allMids"
+		return allMidsIdentifier(allMidsDex(wsMsg.Data))
 	case "l2Book":
 		if data, ok := wsMsg.Data.(map[string]interface{}); ok {
 			if coin, ok := data["coin"].(string); ok {
@@ -426,6 +1379,14 @@ func (w *WebSocketManager) wsMsgToIdentifier(wsMsg WsMsg) string {
 				return fmt.Sprintf("activeAssetCtx:%s", strings.ToLower(coin))
 			}
 		}
+	case "notification":
+		return "notification"
+	case "userTwapSliceFills", "userTwapHistory":
+		if data, ok := wsMsg.Data.(map[string]interface{}); ok {
+			if user, ok := data["user"].(string); ok {
+				return fmt.Sprintf("%s:%s", wsMsg.Channel, strings.ToLower(user))
+			}
+		}
 	case "activeAssetData":
 		if data, ok := wsMsg.Data.(map[string]interface{}); ok {
 			if coin, ok := data["coin"].(string); ok {
@@ -0,0 +1,177 @@
+// Package hyperliquid - Per-subscription WebSocket message dispatch
+package hyperliquid
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDispatchQueueSize is how many messages a subscription can have
+// waiting for its callback
+const DefaultDispatchQueueSize = 1024
+
+// OverflowPolicy decides what happens to a message for a subscription whose
+// queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes the read loop wait for room, holding up every
+	// subscription until the slow callback catches up
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropOldest discards the oldest waiting message, keeping the
+	// most recent data
+	OverflowDropOldest
+	// OverflowDropNewest discards the incoming message
+	OverflowDropNewest
+)
+
+// DispatchStats describes the message queue of a subscription
+type DispatchStats struct {
+	Queued    int    // Messages waiting for the callback
+	Delivered uint64 // Messages the callback has returned from
+	Dropped   uint64 // Messages discarded because the queue was full
+}
+
+// dispatcher runs a subscription's callback on its own goroutine, in the
+// order messages arrived, so that a slow callback only delays itself
+type dispatcher struct {
+	identifier string
+	callback   func(WsMsg)
+	policy     OverflowPolicy
+	metrics    *wsMetrics
+	queue      chan queuedMsg
+	done       chan struct{}
+	draining   chan struct{}
+	finished   chan struct{}
+	delivered  atomic.Uint64
+	dropped    atomic.Uint64
+}
+
+// queuedMsg is a message waiting for the callback and when it was queued
+type queuedMsg struct {
+	msg      WsMsg
+	queuedAt time.Time
+}
+
+// newDispatcher starts a dispatcher for the subscription identifier that
+// runs until stop is called or ctx is done. Dispatch latency and dropped
+// messages are reported to metrics.
+func newDispatcher(ctx context.Context, identifier string, callback func(WsMsg), queueSize int, policy OverflowPolicy, metrics *wsMetrics) *dispatcher {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	d := &dispatcher{
+		identifier: identifier,
+		callback:   callback,
+		policy:     policy,
+		metrics:    metrics,
+		queue:      make(chan queuedMsg, queueSize),
+		done:       make(chan struct{}),
+		draining:   make(chan struct{}),
+		finished:   make(chan struct{}),
+	}
+	go d.run(ctx)
+	return d
+}
+
+// run calls the callback with every queued message. Once draining, it
+// returns as soon as the queue is empty.
+func (d *dispatcher) run(ctx context.Context) {
+	defer close(d.finished)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.done:
+			return
+		case queued := <-d.queue:
+			d.deliver(queued)
+		case <-d.draining:
+			for {
+				select {
+				case queued := <-d.queue:
+					d.deliver(queued)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver calls the callback with a queued message
+func (d *dispatcher) deliver(queued queuedMsg) {
+	d.metrics.messageDispatched(d.identifier, time.Since(queued.queuedAt))
+	d.callback(queued.msg)
+	d.delivered.Add(1)
+}
+
+// drop counts a message discarded by the overflow policy
+func (d *dispatcher) drop() {
+	d.dropped.Add(1)
+	d.metrics.messageDropped(d.identifier)
+}
+
+// enqueue hands a message to the callback, applying the overflow policy when
+// the queue is full
+func (d *dispatcher) enqueue(ctx context.Context, msg WsMsg) {
+	queued := queuedMsg{msg: msg, queuedAt: time.Now()}
+	select {
+	case d.queue <- queued:
+		return
+	default:
+	}
+
+	switch d.policy {
+	case OverflowDropNewest:
+		d.drop()
+	case OverflowDropOldest:
+		for {
+			select {
+			case d.queue <- queued:
+				return
+			default:
+			}
+			select {
+			case <-d.queue:
+				d.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case d.queue <- queued:
+		case <-d.done:
+		case <-d.finished:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// stop ends the dispatcher, discarding any queued messages
+func (d *dispatcher) stop() {
+	close(d.done)
+}
+
+// drain waits until the callback has returned from every queued message,
+// then ends the dispatcher. Messages enqueued meanwhile may be discarded. It
+// returns ctx's error if ctx is done first, leaving the dispatcher draining.
+func (d *dispatcher) drain(ctx context.Context) error {
+	close(d.draining)
+	select {
+	case <-d.finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stats returns the state of the queue
+func (d *dispatcher) stats() DispatchStats {
+	return DispatchStats{
+		Queued:    len(d.queue),
+		Delivered: d.delivered.Load(),
+		Dropped:   d.dropped.Load(),
+	}
+}
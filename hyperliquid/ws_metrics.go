@@ -0,0 +1,151 @@
+// Package hyperliquid - WebSocket counters and observability hooks
+package hyperliquid
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WSStats counts what a WebSocketManager has received and done since it was
+// created
+type WSStats struct {
+	MessagesReceived map[string]uint64 // By channel, such as "l2Book", "pong" or "post"
+	BytesReceived    uint64
+	Reconnects       uint64
+	LastPongAt       time.Time // Zero until the first pong
+	CallbackPanics   uint64
+	QueueDrops       uint64 // Messages discarded by an overflow policy
+}
+
+// WSObserver is notified of WebSocket events, for example to export them as
+// metrics. Its methods are called on the goroutine the event happens on, the
+// read loop included, so they must return quickly. Embed NopWSObserver to
+// implement only some of them.
+type WSObserver interface {
+	// MessageReceived is called for every message read, with its channel
+	// and size in bytes
+	MessageReceived(channel string, bytes int)
+	// MessageDispatched is called before a subscription's callback runs,
+	// with how long the message waited in its queue
+	MessageDispatched(identifier string, latency time.Duration)
+	// MessageDropped is called when a subscription's full queue discards a
+	// message
+	MessageDropped(identifier string)
+	// CallbackPanicked is called when a callback panics
+	CallbackPanicked(identifier string)
+	// Reconnected is called once a dropped connection is re-established
+	Reconnected()
+	// PongReceived is called for every pong
+	PongReceived()
+}
+
+// NopWSObserver implements WSObserver doing nothing
+type NopWSObserver struct{}
+
+func (NopWSObserver) MessageReceived(string, int)             {}
+func (NopWSObserver) MessageDispatched(string, time.Duration) {}
+func (NopWSObserver) MessageDropped(string)                   {}
+func (NopWSObserver) CallbackPanicked(string)                 {}
+func (NopWSObserver) Reconnected()                            {}
+func (NopWSObserver) PongReceived()                           {}
+
+// wsMetrics holds the counters behind WSStats. They are atomics so that the
+// read loop and dispatchers never wait on a lock to update them.
+type wsMetrics struct {
+	channels   sync.Map // Channel to *atomic.Uint64
+	bytes      atomic.Uint64
+	reconnects atomic.Uint64
+	lastPong   atomic.Int64 // Unix nanoseconds
+	panics     atomic.Uint64
+	drops      atomic.Uint64
+	observer   atomic.Pointer[WSObserver]
+}
+
+// observe returns the observer, or nil
+func (m *wsMetrics) observe() WSObserver {
+	if observer := m.observer.Load(); observer != nil {
+		return *observer
+	}
+	return nil
+}
+
+func (m *wsMetrics) messageReceived(channel string, size int) {
+	counter, ok := m.channels.Load(channel)
+	if !ok {
+		counter, _ = m.channels.LoadOrStore(channel, new(atomic.Uint64))
+	}
+	counter.(*atomic.Uint64).Add(1)
+	m.bytes.Add(uint64(size))
+	if observer := m.observe(); observer != nil {
+		observer.MessageReceived(channel, size)
+	}
+}
+
+func (m *wsMetrics) messageDispatched(identifier string, latency time.Duration) {
+	if observer := m.observe(); observer != nil {
+		observer.MessageDispatched(identifier, latency)
+	}
+}
+
+func (m *wsMetrics) messageDropped(identifier string) {
+	m.drops.Add(1)
+	if observer := m.observe(); observer != nil {
+		observer.MessageDropped(identifier)
+	}
+}
+
+func (m *wsMetrics) callbackPanicked(identifier string) {
+	m.panics.Add(1)
+	if observer := m.observe(); observer != nil {
+		observer.CallbackPanicked(identifier)
+	}
+}
+
+func (m *wsMetrics) reconnected() {
+	m.reconnects.Add(1)
+	if observer := m.observe(); observer != nil {
+		observer.Reconnected()
+	}
+}
+
+func (m *wsMetrics) pongReceived(at time.Time) {
+	m.lastPong.Store(at.UnixNano())
+	if observer := m.observe(); observer != nil {
+		observer.PongReceived()
+	}
+}
+
+// snapshot returns the current counters
+func (m *wsMetrics) snapshot() WSStats {
+	stats := WSStats{
+		MessagesReceived: make(map[string]uint64),
+		BytesReceived:    m.bytes.Load(),
+		Reconnects:       m.reconnects.Load(),
+		CallbackPanics:   m.panics.Load(),
+		QueueDrops:       m.drops.Load(),
+	}
+	m.channels.Range(func(channel, counter interface{}) bool {
+		stats.MessagesReceived[channel.(string)] = counter.(*atomic.Uint64).Load()
+		return true
+	})
+	if lastPong := m.lastPong.Load(); lastPong != 0 {
+		stats.LastPongAt = time.Unix(0, lastPong)
+	}
+	return stats
+}
+
+// Stats returns the manager's counters
+func (w *WebSocketManager) Stats() WSStats {
+	return w.metrics.snapshot()
+}
+
+// SetObserver sets an observer notified of every event counted in Stats, or
+// removes it when nil
+func (w *WebSocketManager) SetObserver(observer WSObserver) {
+	if observer == nil {
+		w.metrics.observer.Store(nil)
+		return
+	}
+	w.metrics.observer.Store(&observer)
+}
@@ -0,0 +1,141 @@
+// Package hyperliquid - Info requests and actions posted over the WebSocket
+package hyperliquid
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// PostRequestTimeout is the longest PostRequest waits for a response
+const PostRequestTimeout = 10 * time.Second
+
+// Post request types
+const (
+	PostInfo   = "info"
+	PostAction = "action"
+)
+
+// ErrPostInterrupted is returned by PostRequest when the connection drops or
+// the manager stops before the response arrives. An action may still have
+// been executed.
+var ErrPostInterrupted = errors.New("WebSocket connection lost before the post response arrived")
+
+// PostError is returned by PostRequest when the server answers a post with
+// an error
+type PostError struct {
+	Message string
+}
+
+func (e *PostError) Error() string {
+	return fmt.Sprintf("post request failed: %s", e.Message)
+}
+
+// Unwrap returns the utils sentinel error the message reports, if any
+func (e *PostError) Unwrap() error {
+	return utils.ClassifyMessage(e.Message)
+}
+
+// postResult is the outcome of a post request
+type postResult struct {
+	payload json.RawMessage
+	err     error
+}
+
+// postResponse is the data of a post channel message
+type postResponse struct {
+	ID       int64 `json:"id"`
+	Response struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	} `json:"response"`
+}
+
+// PostRequest sends an info request or a signed action, by requestType
+// PostInfo or PostAction, over the WebSocket and returns the payload of the
+// response: for info requests the {"type", "data"} object, for actions the
+// same response /exchange returns. It waits at most PostRequestTimeout.
+func (w *WebSocketManager) PostRequest(ctx context.Context, requestType string, payload map[string]interface{}) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, PostRequestTimeout)
+	defer cancel()
+
+	result := make(chan postResult, 1)
+	w.mu.Lock()
+	conn := w.conn
+	if conn == nil || !w.wsReady {
+		w.mu.Unlock()
+		return nil, fmt.Errorf("cannot post %s request: %w", requestType, ErrNotConnected)
+	}
+	w.postIDCounter++
+	id := w.postIDCounter
+	w.pendingPosts[id] = result
+	w.mu.Unlock()
+
+	msg := map[string]interface{}{
+		"method": "post",
+		"id":     id,
+		"request": map[string]interface{}{
+			"type":    requestType,
+			"payload": payload,
+		},
+	}
+	if err := w.writeJSON(conn, msg); err != nil {
+		w.forgetPost(id)
+		return nil, fmt.Errorf("failed to send %s request: %w", requestType, err)
+	}
+
+	select {
+	case res := <-result:
+		return res.payload, res.err
+	case <-ctx.Done():
+		w.forgetPost(id)
+		return nil, fmt.Errorf("%s request %d got no response: %w", requestType, id, ctx.Err())
+	}
+}
+
+// forgetPost stops waiting for the response to a post
+func (w *WebSocketManager) forgetPost(id int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.pendingPosts, id)
+}
+
+// onPostResponse hands a post response to the request waiting for it
+func (w *WebSocketManager) onPostResponse(data json.RawMessage) {
+	var response postResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		w.logger().Warn("Failed to decode post response", "err", err)
+		return
+	}
+
+	w.mu.Lock()
+	result, exists := w.pendingPosts[response.ID]
+	delete(w.pendingPosts, response.ID)
+	w.mu.Unlock()
+	if !exists {
+		w.logger().Warn("Post response for unknown request", "id", response.ID)
+		return
+	}
+
+	if response.Response.Type == "error" {
+		var message string
+		if err := json.Unmarshal(response.Response.Payload, &message); err != nil {
+			message = string(response.Response.Payload)
+		}
+		result <- postResult{err: &PostError{Message: message}}
+		return
+	}
+	result <- postResult{payload: response.Response.Payload}
+}
+
+// failPosts fails every post waiting for a response. The caller must hold mu.
+func (w *WebSocketManager) failPosts() {
+	for id, result := range w.pendingPosts {
+		result <- postResult{err: ErrPostInterrupted}
+		delete(w.pendingPosts, id)
+	}
+}
@@ -0,0 +1,309 @@
+// Package hyperliquid - Subscriptions spread across several WebSocket connections
+package hyperliquid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+)
+
+// ShardedWebSocketManager spreads subscriptions across several WebSocket
+// connections, for subscription counts above the per-connection limit. It
+// has the subscription methods of WebSocketManager. Every channel stays on
+// the connection it was first subscribed on until it is unsubscribed, so
+// reconnects do not move it; user-specific channels are all kept on the
+// first connection, and the others go to the connection with the fewest
+// channels. Every connection reconnects on its own.
+type ShardedWebSocketManager struct {
+	mu                    sync.Mutex
+	shards                []*WebSocketManager
+	channels              map[string]shardChannel
+	subscriptions         map[int]shardSubscription
+	subscriptionIDCounter int
+}
+
+// shardChannel is the connection a channel is subscribed on, and how many
+// subscriptions use it
+type shardChannel struct {
+	shard int
+	refs  int
+}
+
+// shardSubscription is a subscription made on one of the connections
+type shardSubscription struct {
+	shard          int
+	identifier     string
+	subscriptionID int // On the connection's manager
+}
+
+// ConnectionStats describes one connection of a ShardedWebSocketManager
+type ConnectionStats struct {
+	Connection      int       // Index of the connection
+	Channels        int       // Channels subscribed on it
+	Subscriptions   int       // Subscriptions, several of which may share a channel
+	Healthy         bool      // See WebSocketManager.Healthy
+	LastMessageTime time.Time // See WebSocketManager.LastMessageTime
+	Stats           WSStats   // See WebSocketManager.Stats
+}
+
+// NewShardedWebSocketManager creates a manager using the given number of
+// connections, at least one. Options, if given, configure every connection.
+func NewShardedWebSocketManager(baseURL string, connections int, opts ...WSOptions) *ShardedWebSocketManager {
+	if connections < 1 {
+		connections = 1
+	}
+	s := &ShardedWebSocketManager{
+		channels:      make(map[string]shardChannel),
+		subscriptions: make(map[int]shardSubscription),
+	}
+	for i := 0; i < connections; i++ {
+		s.shards = append(s.shards, NewWebSocketManager(baseURL, opts...))
+	}
+	return s
+}
+
+// SetURL sets the full WebSocket endpoint every connection connects to; see
+// WebSocketManager.SetURL
+func (s *ShardedWebSocketManager) SetURL(wsURL string) {
+	for _, shard := range s.shards {
+		shard.SetURL(wsURL)
+	}
+}
+
+// SetLogger sets the logger of every connection; see
+// WebSocketManager.SetLogger
+func (s *ShardedWebSocketManager) SetLogger(logger *slog.Logger) {
+	for _, shard := range s.shards {
+		shard.SetLogger(logger)
+	}
+}
+
+// SetReconnectBackoff sets the reconnect backoff of every connection; see
+// WebSocketManager.SetReconnectBackoff
+func (s *ShardedWebSocketManager) SetReconnectBackoff(baseDelay, maxDelay time.Duration) {
+	for _, shard := range s.shards {
+		shard.SetReconnectBackoff(baseDelay, maxDelay)
+	}
+}
+
+// SetErrorHandler sets the handler for panicking callbacks on every
+// connection; see WebSocketManager.SetErrorHandler
+func (s *ShardedWebSocketManager) SetErrorHandler(handler ErrorHandler) {
+	for _, shard := range s.shards {
+		shard.SetErrorHandler(handler)
+	}
+}
+
+// SetObserver sets an observer notified of the events of every connection;
+// see WebSocketManager.SetObserver
+func (s *ShardedWebSocketManager) SetObserver(observer WSObserver) {
+	for _, shard := range s.shards {
+		shard.SetObserver(observer)
+	}
+}
+
+// OnReconnect sets a callback run with the index of a connection once it is
+// re-established and its subscriptions have been re-sent
+func (s *ShardedWebSocketManager) OnReconnect(callback func(connection int)) {
+	for i, shard := range s.shards {
+		i := i
+		shard.OnReconnect(func() { callback(i) })
+	}
+}
+
+// OnDisconnect sets a callback run with the index of a connection and the
+// error that dropped it
+func (s *ShardedWebSocketManager) OnDisconnect(callback func(connection int, err error)) {
+	for i, shard := range s.shards {
+		i := i
+		shard.OnDisconnect(func(err error) { callback(i, err) })
+	}
+}
+
+// Start connects every connection. If one cannot be made, those already
+// started are stopped again and the error is returned.
+func (s *ShardedWebSocketManager) Start() error {
+	for i, shard := range s.shards {
+		if err := shard.Start(); err != nil {
+			for _, started := range s.shards[:i] {
+				started.Stop()
+			}
+			return fmt.Errorf("connection %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Stop stops every connection and drops all subscriptions; see
+// WebSocketManager.Stop
+func (s *ShardedWebSocketManager) Stop() {
+	for _, shard := range s.shards {
+		shard.Stop()
+	}
+	s.forgetAll()
+}
+
+// Close shuts every connection down gracefully and returns the first error;
+// see WebSocketManager.Close
+func (s *ShardedWebSocketManager) Close(ctx context.Context) error {
+	var firstErr error
+	for _, shard := range s.shards {
+		if err := shard.Close(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.forgetAll()
+	return firstErr
+}
+
+// Subscribe subscribes to a WebSocket channel on the connection it belongs
+// to; see WebSocketManager.Subscribe
+func (s *ShardedWebSocketManager) Subscribe(subscription utils.Subscription, callback func(WsMsg)) (int, error) {
+	return s.subscribe(subscription, func(shard *WebSocketManager) (int, error) {
+		return shard.Subscribe(subscription, callback)
+	})
+}
+
+// SubscribeCtx is like Subscribe but waits for the acknowledgement; see
+// WebSocketManager.SubscribeCtx
+func (s *ShardedWebSocketManager) SubscribeCtx(ctx context.Context, subscription utils.Subscription, callback func(WsMsg)) (int, error) {
+	return s.subscribe(subscription, func(shard *WebSocketManager) (int, error) {
+		return shard.SubscribeCtx(ctx, subscription, callback)
+	})
+}
+
+// subscribe picks the connection for subscription, subscribes on it and
+// returns an ID unique across connections
+func (s *ShardedWebSocketManager) subscribe(subscription utils.Subscription, subscribe func(*WebSocketManager) (int, error)) (int, error) {
+	wire := subscription.Wire()
+	identifier := s.shards[0].subscriptionToIdentifier(wire)
+
+	s.mu.Lock()
+	channel, ok := s.channels[identifier]
+	if !ok {
+		channel.shard = s.pickShard(wire)
+	}
+	channel.refs++
+	s.channels[identifier] = channel
+	s.mu.Unlock()
+
+	subscriptionID, err := subscribe(s.shards[channel.shard])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.release(identifier)
+		return 0, err
+	}
+	s.subscriptionIDCounter++
+	s.subscriptions[s.subscriptionIDCounter] = shardSubscription{
+		shard:          channel.shard,
+		identifier:     identifier,
+		subscriptionID: subscriptionID,
+	}
+	return s.subscriptionIDCounter, nil
+}
+
+// pickShard returns the connection for a channel not subscribed yet: the
+// first for user-specific channels, otherwise the one with the fewest
+// channels. The caller must hold mu.
+func (s *ShardedWebSocketManager) pickShard(subscription utils.WsSubscription) int {
+	if subscription.User != "" {
+		return 0
+	}
+	counts := make([]int, len(s.shards))
+	for _, channel := range s.channels {
+		counts[channel.shard]++
+	}
+	best := 0
+	for i, count := range counts {
+		if count < counts[best] {
+			best = i
+		}
+	}
+	return best
+}
+
+// release drops a subscription's reference to its channel, freeing the
+// channel's connection once no subscription uses it. The caller must hold
+// mu.
+func (s *ShardedWebSocketManager) release(identifier string) {
+	channel := s.channels[identifier]
+	channel.refs--
+	if channel.refs <= 0 {
+		delete(s.channels, identifier)
+		return
+	}
+	s.channels[identifier] = channel
+}
+
+// Unsubscribe unsubscribes from a WebSocket channel; see
+// WebSocketManager.Unsubscribe
+func (s *ShardedWebSocketManager) Unsubscribe(subscription utils.Subscription, subscriptionID int) (bool, error) {
+	s.mu.Lock()
+	sub, ok := s.subscriptions[subscriptionID]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+
+	removed, err := s.shards[sub.shard].Unsubscribe(subscription, sub.subscriptionID)
+	if removed {
+		s.mu.Lock()
+		if _, ok := s.subscriptions[subscriptionID]; ok {
+			delete(s.subscriptions, subscriptionID)
+			s.release(sub.identifier)
+		}
+		s.mu.Unlock()
+	}
+	return removed, err
+}
+
+// UnsubscribeAll removes every subscription on every connection and returns
+// the first error; see WebSocketManager.UnsubscribeAll
+func (s *ShardedWebSocketManager) UnsubscribeAll() error {
+	var firstErr error
+	for _, shard := range s.shards {
+		if err := shard.UnsubscribeAll(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.forgetAll()
+	return firstErr
+}
+
+// forgetAll drops the channel assignments and subscription IDs once the
+// connections have dropped their subscriptions
+func (s *ShardedWebSocketManager) forgetAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels = make(map[string]shardChannel)
+	s.subscriptions = make(map[int]shardSubscription)
+}
+
+// ConnectionStats returns the state of every connection
+func (s *ShardedWebSocketManager) ConnectionStats() []ConnectionStats {
+	stats := make([]ConnectionStats, len(s.shards))
+	for i, shard := range s.shards {
+		stats[i] = ConnectionStats{
+			Connection:      i,
+			Healthy:         shard.Healthy(),
+			LastMessageTime: shard.LastMessageTime(),
+			Stats:           shard.Stats(),
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, channel := range s.channels {
+		stats[channel.shard].Channels++
+	}
+	for _, sub := range s.subscriptions {
+		stats[sub.shard].Subscriptions++
+	}
+	return stats
+}
@@ -0,0 +1,512 @@
+// Package tests - API client tests
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyServer fails the first len(failures) requests with the given status
+// codes and answers every later request with {"status": "ok"}
+type flakyServer struct {
+	*httptest.Server
+	mu         sync.Mutex
+	failures   []int
+	retryAfter string
+	requests   int
+}
+
+func newFlakyServer(t *testing.T, failures ...int) *flakyServer {
+	t.Helper()
+	fs := &flakyServer{failures: failures}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		fs.requests++
+		if fs.requests <= len(fs.failures) {
+			if fs.retryAfter != "" {
+				w.Header().Set("Retry-After", fs.retryAfter)
+			}
+			http.Error(w, "try again", fs.failures[fs.requests-1])
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *flakyServer) requestCount() int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.requests
+}
+
+func fastRetryPolicy() *hyperliquid.RetryPolicy {
+	policy := hyperliquid.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.Jitter = 0
+	return policy
+}
+
+func TestRetryInfoRequest(t *testing.T) {
+	fs := newFlakyServer(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	policy := fastRetryPolicy()
+	var events []hyperliquid.RetryEvent
+	policy.OnRetry = func(event hyperliquid.RetryEvent) {
+		events = append(events, event)
+	}
+	api.SetRetryPolicy(policy)
+
+	result, err := api.Post("/info", map[string]interface{}{"type": "allMids"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"status": "ok"}, result)
+	require.Equal(t, 3, fs.requestCount())
+
+	require.Len(t, events, 2)
+	require.Equal(t, "/info", events[0].Path)
+	require.Equal(t, 1, events[0].Attempt)
+	require.Equal(t, http.StatusInternalServerError, events[0].StatusCode)
+	require.Equal(t, time.Millisecond, events[0].Delay)
+	require.Equal(t, http.StatusTooManyRequests, events[1].StatusCode)
+	require.Equal(t, 2*time.Millisecond, events[1].Delay)
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	fs := newFlakyServer(t, 503, 503, 503, 503, 503)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	api.SetRetryPolicy(fastRetryPolicy())
+
+	_, err := api.Post("/info", nil)
+	var serverErr *utils.ServerError
+	require.ErrorAs(t, err, &serverErr)
+	require.Equal(t, http.StatusServiceUnavailable, serverErr.StatusCode)
+	require.Equal(t, 4, fs.requestCount())
+}
+
+func TestRetryDisabledByDefault(t *testing.T) {
+	fs := newFlakyServer(t, http.StatusInternalServerError)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+
+	_, err := api.Post("/info", nil)
+	require.Error(t, err)
+	require.Equal(t, 1, fs.requestCount())
+}
+
+func TestRetryCancelledDuringBackoff(t *testing.T) {
+	fs := newFlakyServer(t, http.StatusInternalServerError)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	policy := fastRetryPolicy()
+	policy.BaseDelay = time.Hour
+	policy.OnRetry = func(hyperliquid.RetryEvent) {
+		cancel()
+	}
+	api.SetRetryPolicy(policy)
+
+	_, err := api.PostWithContext(ctx, "/info", nil)
+	require.ErrorIs(t, err, context.Canceled)
+	var serverErr *utils.ServerError
+	require.ErrorAs(t, err, &serverErr)
+	assert.Equal(t, http.StatusInternalServerError, serverErr.StatusCode)
+	assert.Equal(t, 1, fs.requestCount())
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	fs := newFlakyServer(t, http.StatusTooManyRequests)
+	fs.retryAfter = "0"
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	policy := fastRetryPolicy()
+	policy.BaseDelay = time.Hour
+	var delay time.Duration
+	policy.OnRetry = func(event hyperliquid.RetryEvent) {
+		delay = event.Delay
+	}
+	api.SetRetryPolicy(policy)
+
+	_, err := api.Post("/info", nil)
+	require.NoError(t, err)
+	require.Equal(t, time.Duration(0), delay)
+}
+
+func TestRateLimitError(t *testing.T) {
+	fs := newFlakyServer(t, http.StatusTooManyRequests, http.StatusTooManyRequests)
+	fs.retryAfter = "7"
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+
+	_, err := api.Post("/info", nil)
+	var rateLimitErr *utils.RateLimitError
+	require.ErrorAs(t, err, &rateLimitErr)
+	require.Equal(t, 7*time.Second, rateLimitErr.RetryAfter)
+	require.ErrorIs(t, err, utils.ErrRateLimited)
+	var clientErr *utils.ClientError
+	require.ErrorAs(t, err, &clientErr)
+	require.Equal(t, http.StatusTooManyRequests, clientErr.StatusCode)
+	require.Equal(t, "rate limited: try again\n (retry after 7s)", err.Error())
+
+	fs.retryAfter = ""
+	_, err = api.Post("/info", nil)
+	require.ErrorAs(t, err, &rateLimitErr)
+	require.Zero(t, rateLimitErr.RetryAfter)
+
+	// Other client errors are not rate limits
+	fs = newFlakyServer(t, http.StatusBadRequest)
+	_, err = hyperliquid.NewAPI(fs.URL, 5*time.Second).Post("/info", nil)
+	require.ErrorAs(t, err, &clientErr)
+	require.False(t, errors.As(err, &rateLimitErr))
+	require.NotErrorIs(t, err, utils.ErrRateLimited)
+}
+
+func TestRequestHooks(t *testing.T) {
+	var received []string
+	var traces []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		traces = append(traces, r.Header.Get("X-Trace-Id"))
+		if r.URL.Path == "/missing" {
+			http.Error(w, `{"msg": "not found"}`, http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "ok"}`))
+	}))
+	t.Cleanup(server.Close)
+
+	type response struct {
+		status  int
+		body    string
+		elapsed time.Duration
+		err     error
+	}
+	var responses []response
+	api := hyperliquid.NewAPI(server.URL, 5*time.Second).
+		WithRequestHook(func(req *http.Request) {
+			req.Header.Set("X-Trace-Id", "trace-1")
+		}).
+		WithRequestHook(func(req *http.Request) {
+			// Hooks see the payload but cannot change what is sent
+			body, err := io.ReadAll(req.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"type": "meta"}`, string(body))
+			req.Body = io.NopCloser(strings.NewReader("corrupted"))
+		}).
+		WithResponseHook(func(resp *http.Response, body []byte, elapsed time.Duration, err error) {
+			responses = append(responses, response{resp.StatusCode, string(body), elapsed, err})
+			copy(body, "corrupted")
+		})
+
+	var result map[string]string
+	require.NoError(t, api.PostInto(context.Background(), "/info", map[string]string{"type": "meta"}, &result))
+	assert.Equal(t, map[string]string{"status": "ok"}, result)
+	assert.Equal(t, []string{`{"type":"meta"}`}, received)
+	assert.Equal(t, []string{"trace-1"}, traces)
+	require.Len(t, responses, 1)
+	assert.Equal(t, http.StatusOK, responses[0].status)
+	assert.Equal(t, `{"status": "ok"}`, responses[0].body)
+	assert.Positive(t, responses[0].elapsed)
+	assert.NoError(t, responses[0].err)
+
+	// Failed requests reach the response hooks with their error
+	_, err := api.Post("/missing", map[string]string{"type": "meta"})
+	require.Error(t, err)
+	require.Len(t, responses, 2)
+	assert.Equal(t, http.StatusNotFound, responses[1].status)
+	assert.Equal(t, err, responses[1].err)
+}
+
+func TestRequestTimer(t *testing.T) {
+	fs := newFlakyServer(t, http.StatusInternalServerError, http.StatusBadGateway)
+	timer := hyperliquid.NewRequestTimer()
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second).WithResponseHook(timer.Hook)
+	api.SetRetryPolicy(fastRetryPolicy())
+
+	_, err := api.Post("/info", nil)
+	require.NoError(t, err)
+	stats := timer.Stats()["/info"]
+	assert.Equal(t, 3, stats.Requests)
+	assert.Equal(t, 2, stats.Errors)
+	assert.Positive(t, stats.MaxLatency)
+	assert.GreaterOrEqual(t, stats.TotalLatency, stats.MaxLatency)
+	assert.Equal(t, stats.TotalLatency/3, stats.AverageLatency())
+
+	// Requests that get no response are recorded under their path too
+	fs.Close()
+	_, err = hyperliquid.NewAPI(fs.URL, 5*time.Second).WithResponseHook(timer.Hook).Post("/exchange", nil)
+	require.Error(t, err)
+	stats = timer.Stats()["/exchange"]
+	assert.Equal(t, 1, stats.Requests)
+	assert.Equal(t, 1, stats.Errors)
+}
+
+// recordingTransport records the path and User-Agent of every request before
+// passing it on to http.DefaultTransport
+type recordingTransport struct {
+	mu         sync.Mutex
+	paths      []string
+	userAgents []string
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.mu.Lock()
+	rt.paths = append(rt.paths, req.URL.Path)
+	rt.userAgents = append(rt.userAgents, req.Header.Get("User-Agent"))
+	rt.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestCustomTransport(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+	fs.infoResponses["meta"] = loadCassette(t, "meta.json")
+	signer := &fakeSigner{address: common.HexToAddress("0x0000000000000000000000000000000000000009")}
+
+	transport := &recordingTransport{}
+	exchange, err := hyperliquid.NewExchangeOpts(signer, fs.URL, nil, nil, nil, nil, nil, 5*time.Second, hyperliquid.WithTransport(transport))
+	require.NoError(t, err)
+	_, err = exchange.Noop()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/info", "/info", "/exchange"}, transport.paths, "spot and perp metadata, then the action")
+	for _, userAgent := range transport.userAgents {
+		assert.Equal(t, "hyperliquid-go/"+hyperliquid.Version, userAgent)
+	}
+
+	// An exchange sharing an info sends through the info's transport
+	withInfo, err := hyperliquid.NewExchangeWithInfo(signer, exchange.Info(), nil, nil)
+	require.NoError(t, err)
+	_, err = withInfo.Noop()
+	require.NoError(t, err)
+	assert.Equal(t, "/exchange", transport.paths[len(transport.paths)-1])
+
+	// A transport applies on top of a given client, which is left unchanged
+	client := &http.Client{}
+	other := &recordingTransport{}
+	info, err := hyperliquid.NewInfoOpts(fs.URL, true, nil, nil, nil, 5*time.Second,
+		hyperliquid.WithHTTPClient(client), hyperliquid.WithTransport(other))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/info", "/info"}, other.paths)
+	_, err = info.Meta("")
+	require.NoError(t, err)
+	assert.Len(t, other.paths, 3)
+	assert.Nil(t, client.Transport)
+	assert.Zero(t, client.Timeout)
+}
+
+func TestRetryExchange(t *testing.T) {
+	// Exchange posts are never retried unless explicitly enabled
+	fs := newFlakyServer(t, http.StatusTooManyRequests)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	api.SetRetryPolicy(fastRetryPolicy())
+	_, err := api.Post("/exchange", nil)
+	require.Error(t, err)
+	require.Equal(t, 1, fs.requestCount())
+
+	// Once enabled, a rate limit is retried since the action was not executed
+	fs = newFlakyServer(t, http.StatusTooManyRequests)
+	api = hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	policy := fastRetryPolicy()
+	policy.RetryExchange = true
+	api.SetRetryPolicy(policy)
+	_, err = api.Post("/exchange", nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, fs.requestCount())
+
+	// but a server error is not, as the action may have gone through
+	fs = newFlakyServer(t, http.StatusInternalServerError)
+	api = hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	api.SetRetryPolicy(policy)
+	_, err = api.Post("/exchange", nil)
+	require.Error(t, err)
+	require.Equal(t, 1, fs.requestCount())
+}
+
+// fakeClock only moves when a rate limiter waits on it or the test advances it
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newFakeClockLimiter(weightPerMinute int, nonBlocking bool) (*hyperliquid.RateLimiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	limiter := hyperliquid.NewRateLimiter(weightPerMinute, nonBlocking)
+	limiter.SetClock(clock)
+	return limiter, clock
+}
+
+func TestRequestWeight(t *testing.T) {
+	require.Equal(t, 2, hyperliquid.RequestWeight("/info", map[string]interface{}{"type": "l2Book"}))
+	require.Equal(t, 2, hyperliquid.RequestWeight("/info", map[string]interface{}{"type": "clearinghouseState"}))
+	require.Equal(t, 60, hyperliquid.RequestWeight("/info", map[string]interface{}{"type": "userRole"}))
+	require.Equal(t, 20, hyperliquid.RequestWeight("/info", map[string]interface{}{"type": "meta"}))
+
+	orders := make([]utils.OrderWire, 85)
+	require.Equal(t, 3, hyperliquid.RequestWeight("/exchange", map[string]interface{}{
+		"action": utils.OrderAction{Type: "order", Orders: orders},
+	}))
+	require.Equal(t, 1, hyperliquid.RequestWeight("/exchange", map[string]interface{}{
+		"action": utils.CancelAction{Type: "cancel", Cancels: make([]utils.CancelWire, 39)},
+	}))
+	require.Equal(t, 2, hyperliquid.RequestWeight("/exchange", map[string]interface{}{
+		"action": map[string]interface{}{"type": "cancelByCloid", "cancels": make([]interface{}, 40)},
+	}))
+	require.Equal(t, 2, hyperliquid.RequestWeight("/exchange", map[string]interface{}{
+		"action": utils.BatchModifyAction{Type: "batchModify", Modifies: make([]utils.ModifyWire, 40)},
+	}))
+	require.Equal(t, 1, hyperliquid.RequestWeight("/exchange", map[string]interface{}{
+		"action": utils.UpdateLeverageAction{Type: "updateLeverage"},
+	}))
+}
+
+func TestRateLimiterNonBlocking(t *testing.T) {
+	limiter, clock := newFakeClockLimiter(120, true)
+	ctx := context.Background()
+	require.Equal(t, 120, limiter.Remaining())
+
+	for i := 0; i < 6; i++ {
+		require.NoError(t, limiter.Wait(ctx, 20))
+	}
+	require.Equal(t, 0, limiter.Remaining())
+	require.ErrorIs(t, limiter.Wait(ctx, 2), hyperliquid.ErrRateLimited)
+	require.ErrorIs(t, limiter.Wait(ctx, 2), utils.ErrRateLimited)
+
+	// 120 per minute refills 2 per second
+	clock.Advance(time.Second)
+	require.Equal(t, 2, limiter.Remaining())
+	require.NoError(t, limiter.Wait(ctx, 2))
+	require.ErrorIs(t, limiter.Wait(ctx, 2), hyperliquid.ErrRateLimited)
+
+	// The budget never grows past a minute's worth
+	clock.Advance(10 * time.Minute)
+	require.Equal(t, 120, limiter.Remaining())
+}
+
+func TestRateLimiterBlocking(t *testing.T) {
+	limiter, clock := newFakeClockLimiter(120, false)
+	ctx := context.Background()
+
+	require.NoError(t, limiter.Wait(ctx, 110))
+	require.Empty(t, clock.sleeps)
+
+	// 10 left, 20 needed: waits 5s for the missing 10
+	require.NoError(t, limiter.Wait(ctx, 20))
+	require.Equal(t, []time.Duration{5 * time.Second}, clock.sleeps)
+	require.Equal(t, 0, limiter.Remaining())
+
+	// Weights above the capacity wait for a full bucket instead of forever
+	require.NoError(t, limiter.Wait(ctx, 500))
+	require.Equal(t, 60*time.Second, clock.sleeps[1])
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	limiter2 := hyperliquid.NewRateLimiter(60, false)
+	require.NoError(t, limiter2.Wait(ctx, 60))
+	require.ErrorIs(t, limiter2.Wait(cancelled, 60), context.Canceled)
+}
+
+func TestAPIRateLimiter(t *testing.T) {
+	fs := newFlakyServer(t)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	limiter, _ := newFakeClockLimiter(50, true)
+	api.SetRateLimiter(limiter)
+
+	for i := 0; i < 2; i++ {
+		_, err := api.Post("/info", map[string]interface{}{"type": "meta"})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 10, limiter.Remaining())
+
+	// Refused before reaching the server
+	_, err := api.Post("/info", map[string]interface{}{"type": "meta"})
+	require.ErrorIs(t, err, hyperliquid.ErrRateLimited)
+	require.Equal(t, 2, fs.requestCount())
+
+	_, err = api.Post("/info", map[string]interface{}{"type": "allMids"})
+	require.NoError(t, err)
+	require.Equal(t, 8, limiter.Remaining())
+}
+
+func TestRateLimiterAddressBudget(t *testing.T) {
+	ctx := context.Background()
+	limiter, clock := newFakeClockLimiter(hyperliquid.DefaultWeightPerMinute, false)
+
+	// Without a budget nothing is tracked
+	require.NoError(t, limiter.WaitAddress(ctx, 5))
+	_, ok := limiter.AddressRemaining()
+	require.False(t, ok)
+
+	limiter.SetAddressBudget(hyperliquid.UserRateLimit{NRequestsCap: 10000, NRequestsUsed: 9997}.Remaining())
+	require.NoError(t, limiter.WaitAddress(ctx, 3))
+	remaining, ok := limiter.AddressRemaining()
+	require.True(t, ok)
+	require.Equal(t, 0, remaining)
+
+	// Used up: one request per 10 seconds
+	require.NoError(t, limiter.WaitAddress(ctx, 1))
+	require.Empty(t, clock.sleeps)
+	require.NoError(t, limiter.WaitAddress(ctx, 1))
+	require.Equal(t, []time.Duration{hyperliquid.ThrottledRequestInterval}, clock.sleeps)
+
+	nonBlocking, _ := newFakeClockLimiter(hyperliquid.DefaultWeightPerMinute, true)
+	nonBlocking.SetAddressBudget(0)
+	require.ErrorIs(t, nonBlocking.WaitAddress(ctx, 1), hyperliquid.ErrRateLimited)
+}
+
+func TestAPIAddressBudget(t *testing.T) {
+	fs := newFlakyServer(t)
+	api := hyperliquid.NewAPI(fs.URL, 5*time.Second)
+	limiter, _ := newFakeClockLimiter(hyperliquid.DefaultWeightPerMinute, true)
+	limiter.SetAddressBudget(2)
+	api.SetRateLimiter(limiter)
+
+	// Info requests do not count against the address budget
+	_, err := api.Post("/info", map[string]interface{}{"type": "allMids"})
+	require.NoError(t, err)
+
+	// A batch counts once per order
+	_, err = api.Post("/exchange", map[string]interface{}{
+		"action": utils.OrderAction{Type: "order", Orders: make([]utils.OrderWire, 2)},
+	})
+	require.NoError(t, err)
+	_, err = api.Post("/exchange", map[string]interface{}{
+		"action": utils.UpdateLeverageAction{Type: "updateLeverage"},
+	})
+	require.ErrorIs(t, err, hyperliquid.ErrRateLimited)
+	require.Equal(t, 2, fs.requestCount())
+}
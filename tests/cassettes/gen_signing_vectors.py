@@ -0,0 +1,174 @@
+"""Adds signing vectors computed by the Hyperliquid Python SDK to signing_vectors.json.
+
+Run from this directory with the SDK installed (pip install hyperliquid-python-sdk):
+
+    python gen_signing_vectors.py
+
+Vectors are merged by name: one already in the file is replaced, a new one is
+appended to its section. Every value is computed by the SDK's own signing
+functions, never by this package.
+"""
+
+import copy
+import json
+import os
+
+import eth_account
+from hyperliquid.utils.signing import (
+    action_hash,
+    sign_agent,
+    sign_l1_action,
+    sign_spot_transfer_action,
+    sign_usd_class_transfer_action,
+    sign_usd_transfer_action,
+    sign_withdraw_from_bridge_action,
+)
+
+PATH = os.path.join(os.path.dirname(os.path.abspath(__file__)), "signing_vectors.json")
+
+VAULT = "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+NONCE = 1700000000000
+EXPIRES_AFTER = 1700000060000
+TIME = 1687816341423
+DESTINATION = "0x5e9ee1089755c3435139848e47e6635505d5a13a"
+
+ORDER = {
+    "type": "order",
+    "orders": [{"a": 1, "b": True, "p": "100", "s": "100", "r": False, "t": {"limit": {"tif": "Gtc"}}}],
+    "grouping": "na",
+}
+PRODUCTION_ORDER = {
+    "type": "order",
+    "orders": [{"a": 4, "b": True, "p": "1670.1", "s": "0.0147", "r": False, "t": {"limit": {"tif": "Ioc"}}}],
+    "grouping": "na",
+}
+CANCEL = {"type": "cancel", "cancels": [{"a": 1, "o": 123}]}
+CANCEL_BY_CLOID = {"type": "cancelByCloid", "cancels": [{"asset": 1, "cloid": "0x00000000000000000000000000000001"}]}
+
+# name, action, vault address, nonce, expires after
+L1_ACTIONS = [
+    ("Cancel", CANCEL, None, NONCE, None),
+    ("Cancel by cloid", CANCEL_BY_CLOID, None, NONCE, None),
+    ("Cancel with vault and expiresAfter", CANCEL, VAULT, NONCE, EXPIRES_AFTER),
+    ("Limit order with expiresAfter", ORDER, None, NONCE, EXPIRES_AFTER),
+    ("Limit order with vault and expiresAfter", ORDER, VAULT, NONCE, EXPIRES_AFTER),
+]
+
+ACTION_HASHES = [
+    ("Production order with expiresAfter", PRODUCTION_ORDER, None, 1677777606040, 1677777666040),
+    ("Production order with vault and expiresAfter", PRODUCTION_ORDER, VAULT, 1677777606040, 1677777666040),
+]
+
+# name, primary type, SDK signing function, action
+USER_SIGNED_ACTIONS = [
+    (
+        "USD send",
+        "HyperliquidTransaction:UsdSend",
+        sign_usd_transfer_action,
+        {"type": "usdSend", "destination": DESTINATION, "amount": "1", "time": TIME},
+    ),
+    (
+        "Withdraw",
+        "HyperliquidTransaction:Withdraw",
+        sign_withdraw_from_bridge_action,
+        {"type": "withdraw3", "destination": DESTINATION, "amount": "1", "time": TIME},
+    ),
+    (
+        "Spot send",
+        "HyperliquidTransaction:SpotSend",
+        sign_spot_transfer_action,
+        {
+            "type": "spotSend",
+            "destination": DESTINATION,
+            "token": "PURR:0xc4bf3f870c0e9465323c0b6ed28096c2",
+            "amount": "0.1",
+            "time": TIME,
+        },
+    ),
+    (
+        "Approve agent",
+        "HyperliquidTransaction:ApproveAgent",
+        sign_agent,
+        {"type": "approveAgent", "agentAddress": DESTINATION, "agentName": "bot", "nonce": TIME},
+    ),
+    (
+        "USD class transfer",
+        "HyperliquidTransaction:UsdClassTransfer",
+        sign_usd_class_transfer_action,
+        {"type": "usdClassTransfer", "amount": "1", "toPerp": True, "nonce": TIME},
+    ),
+]
+
+
+def signature(signed):
+    # The SDK drops leading zeros of r and s; the fixture keeps all 32 bytes
+    return {
+        "r": "0x" + signed["r"][2:].rjust(64, "0"),
+        "s": "0x" + signed["s"][2:].rjust(64, "0"),
+        "v": signed["v"],
+    }
+
+
+def l1_vector(wallet, name, action, vault_address, nonce, expires_after, signed=True):
+    vector = {"name": name, "source": "python-sdk", "action": action}
+    if vault_address is not None:
+        vector["vaultAddress"] = vault_address
+    vector["nonce"] = nonce
+    if expires_after is not None:
+        vector["expiresAfter"] = expires_after
+    vector["actionHash"] = "0x" + action_hash(action, vault_address, nonce, expires_after).hex()
+    if signed:
+        for network, is_mainnet in (("mainnet", True), ("testnet", False)):
+            vector[network] = signature(sign_l1_action(wallet, action, vault_address, nonce, expires_after, is_mainnet))
+    return vector
+
+
+def user_signed_vector(wallet, name, primary_type, sign, action):
+    vector = {"name": name, "source": "python-sdk", "primaryType": primary_type, "action": action}
+    for network, is_mainnet in (("mainnet", True), ("testnet", False)):
+        # The SDK adds the chain fields to the action it signs
+        vector[network] = signature(sign(wallet, copy.deepcopy(action), is_mainnet))
+    return vector
+
+
+def merge(section, vectors):
+    for vector in vectors:
+        for i, existing in enumerate(section):
+            if existing["name"] == vector["name"]:
+                section[i] = vector
+                break
+        else:
+            section.append(vector)
+
+
+def dump(fixture):
+    # One line per field, as the fixture is written by hand
+    lines = ["{", '  "privateKey": %s,' % json.dumps(fixture["privateKey"])]
+    sections = ["l1Actions", "actionHashes", "userSignedActions"]
+    for s, key in enumerate(sections):
+        lines.append('  "%s": [' % key)
+        for v, vector in enumerate(fixture[key]):
+            lines.append("    {")
+            fields = ['      "%s": %s' % (field, json.dumps(value)) for field, value in vector.items()]
+            lines.append(",\n".join(fields))
+            lines.append("    }" + ("," if v < len(fixture[key]) - 1 else ""))
+        lines.append("  ]" + ("," if s < len(sections) - 1 else ""))
+    lines.append("}")
+    return "\n".join(lines) + "\n"
+
+
+def main():
+    with open(PATH) as f:
+        fixture = json.load(f)
+    wallet = eth_account.Account.from_key(fixture["privateKey"])
+
+    merge(fixture["l1Actions"], [l1_vector(wallet, *args) for args in L1_ACTIONS])
+    merge(fixture["actionHashes"], [l1_vector(wallet, *args, signed=False) for args in ACTION_HASHES])
+    merge(fixture["userSignedActions"], [user_signed_vector(wallet, *args) for args in USER_SIGNED_ACTIONS])
+
+    with open(PATH, "w") as f:
+        f.write(dump(fixture))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,206 @@
+// Package tests - Config loading tests
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/config"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	fileSecretKey = "0x0101010101010101010101010101010101010101010101010101010101010101"
+	envSecretKey  = "0x0202020202020202020202020202020202020202020202020202020202020202"
+)
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoadFromFileProfiles(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"network": "mainnet",
+		"secret_key": "`+fileSecretKey+`",
+		"profiles": {
+			"mainnet": {"vault_address": "0x1719884eb866cb12b2287399b15f7db5e7d775ea"},
+			"testnet": {"base_url": "http://localhost:9999"}
+		}
+	}`)
+
+	cfg, err := config.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, config.NetworkMainnet, cfg.Network)
+	assert.Equal(t, utils.MainnetAPIURL, cfg.APIURL())
+	assert.Equal(t, "0x1719884eb866cb12b2287399b15f7db5e7d775ea", cfg.VaultAddress)
+
+	t.Setenv(config.EnvNetwork, "testnet")
+	cfg, err = config.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, config.NetworkTestnet, cfg.Network)
+	assert.Equal(t, "http://localhost:9999", cfg.APIURL())
+	assert.Empty(t, cfg.VaultAddress)
+}
+
+func TestEnvironmentTakesPrecedence(t *testing.T) {
+	path := writeConfigFile(t, `{
+		"secret_key": "`+fileSecretKey+`",
+		"account_address": "0x0000000000000000000000000000000000000001",
+		"multi_sig": {"authorized_users": [{"comment": "signer 1", "secret_key": "`+fileSecretKey+`"}]}
+	}`)
+
+	t.Setenv(config.EnvSecretKey, envSecretKey)
+	t.Setenv(config.EnvAccountAddress, "0x0000000000000000000000000000000000000002")
+	t.Setenv(config.EnvMultiSigSecrets, envSecretKey+","+fileSecretKey)
+
+	cfg, err := config.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, envSecretKey, cfg.SecretKey)
+
+	address, err := cfg.Address()
+	require.NoError(t, err)
+	assert.Equal(t, "0x0000000000000000000000000000000000000002", address)
+
+	wallets, err := cfg.MultiSigWallets()
+	require.NoError(t, err)
+	require.Len(t, wallets, 2)
+	envKey, err := crypto.HexToECDSA(envSecretKey[2:])
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(envKey.PublicKey), crypto.PubkeyToAddress(wallets[0].PublicKey))
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv(config.EnvSecretKey, envSecretKey)
+
+	cfg, err := config.LoadFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, config.NetworkTestnet, cfg.Network)
+	assert.Equal(t, utils.TestnetAPIURL, cfg.APIURL())
+
+	envKey, err := crypto.HexToECDSA(envSecretKey[2:])
+	require.NoError(t, err)
+	address, err := cfg.Address()
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(envKey.PublicKey).Hex(), address)
+}
+
+func TestConfigValidationErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"Missing secret key", `{}`},
+		{"Invalid secret key", `{"secret_key": "0x1234"}`},
+		{"Unknown network", `{"network": "devnet", "secret_key": "` + fileSecretKey + `"}`},
+		{"Invalid account address", `{"secret_key": "` + fileSecretKey + `", "account_address": "0x1234"}`},
+		{"Invalid vault address", `{"secret_key": "` + fileSecretKey + `", "vault_address": "vault"}`},
+		{"Vault address checksum", `{"secret_key": "` + fileSecretKey + `", "vault_address": "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"}`},
+		{"Multi-sig address mismatch", `{"secret_key": "` + fileSecretKey + `", "multi_sig": {"authorized_users": [
+			{"secret_key": "` + fileSecretKey + `", "account_address": "0x0000000000000000000000000000000000000001"}]}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := config.LoadFromFile(writeConfigFile(t, tt.contents))
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := config.LoadFromFile(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestBuildClientSharesInfo(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["meta"] = loadCassette(t, "meta.json")
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+
+	client, err := config.BuildClient(&config.Config{Network: config.NetworkLocal, BaseURL: fs.URL, SecretKey: fileSecretKey, SkipWS: true})
+	require.NoError(t, err)
+	assert.Same(t, client.Info, client.Exchange.Info())
+
+	// The metadata is fetched once, for both clients
+	var types []string
+	for _, request := range fs.infoRequests {
+		types = append(types, request["type"].(string))
+	}
+	assert.ElementsMatch(t, []string{"spotMeta", "meta"}, types)
+}
+
+// Test vectors of the Web3 Secret Storage Definition, encrypting
+// keystoreSecretKey with the password "testpassword"
+const (
+	keystoreSecretKey = "0x7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+	pbkdf2Keystore    = `{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+			"ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+			"kdf": "pbkdf2",
+			"kdfparams": {"c": 262144, "dklen": 32, "prf": "hmac-sha256", "salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},
+			"mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`
+	scryptKeystore = `{
+		"crypto": {
+			"cipher": "aes-128-ctr",
+			"cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"},
+			"ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+			"kdf": "scrypt",
+			"kdfparams": {"dklen": 32, "n": 262144, "p": 8, "r": 1, "salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},
+			"mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+		},
+		"id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+		"version": 3
+	}`
+)
+
+func writeKeystore(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "keystore.json")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func TestLoadKeystore(t *testing.T) {
+	for _, contents := range []string{pbkdf2Keystore, scryptKeystore} {
+		secretKey, err := config.LoadKeystore(writeKeystore(t, contents), "testpassword")
+		require.NoError(t, err)
+		assert.Equal(t, keystoreSecretKey, secretKey)
+	}
+
+	_, err := config.LoadKeystore(writeKeystore(t, pbkdf2Keystore), "wrong")
+	assert.ErrorContains(t, err, "wrong password")
+	_, err = config.LoadKeystore(filepath.Join(t.TempDir(), "missing.json"), "testpassword")
+	assert.Error(t, err)
+}
+
+func TestLoadFromFileKeystore(t *testing.T) {
+	keystorePath := writeKeystore(t, pbkdf2Keystore)
+	path := writeConfigFile(t, `{"network": "mainnet", "keystore_path": "`+keystorePath+`"}`)
+
+	t.Setenv(config.EnvKeystorePassword, "testpassword")
+	cfg, err := config.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, keystoreSecretKey, cfg.SecretKey)
+
+	// A secret key takes precedence over the keystore
+	t.Setenv(config.EnvSecretKey, envSecretKey)
+	cfg, err = config.LoadFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, envSecretKey, cfg.SecretKey)
+
+	t.Setenv(config.EnvSecretKey, "")
+	t.Setenv(config.EnvKeystorePassword, "wrong")
+	_, err = config.LoadFromFile(path)
+	assert.ErrorContains(t, err, "wrong password")
+}
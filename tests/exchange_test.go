@@ -0,0 +1,2779 @@
+// Package tests - Exchange functionality tests
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/gorilla/websocket"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer serves recorded /info and /explorer responses, records every
+// /info and /exchange payload and hands accepted /ws connections to the test
+type fakeServer struct {
+	*httptest.Server
+	mu               sync.Mutex
+	infoResponses    map[string]string
+	exchangeResponse string
+	// exchangeReply returns the response to an /exchange payload, empty for
+	// exchangeResponse; it is called holding mu
+	exchangeReply    func(payload map[string]interface{}) string
+	exchangeRequests []map[string]interface{}
+	// infoReply returns the response to an /info payload, empty for
+	// infoResponses; it is called holding mu
+	infoReply       func(payload map[string]interface{}) string
+	infoRequests    []map[string]interface{}
+	delay           time.Duration
+	wsConns         chan *websocket.Conn
+	wsSubscriptions chan map[string]interface{}
+	// wsUnsubscriptions receives unsubscribe requests, dropping those that
+	// do not fit, and wsCloses the close frames of connections
+	wsUnsubscriptions chan map[string]interface{}
+	wsCloses          chan *websocket.CloseError
+	wsConn            *websocket.Conn // The most recent connection
+	// wsReply returns the reply to a subscribe request, nil for none; by
+	// default the subscription is acknowledged
+	wsReply func(subscription map[string]interface{}) interface{}
+	// wsPostReply returns the response to a post request, nil for none; by
+	// default it is answered like the same request over HTTP
+	wsPostReply func(request map[string]interface{}) interface{}
+}
+
+func newFakeServer(t testing.TB) *fakeServer {
+	t.Helper()
+	fs := &fakeServer{
+		infoResponses:     make(map[string]string),
+		exchangeResponse:  loadCassette(t, "exchange_ok.json"),
+		wsConns:           make(chan *websocket.Conn, 1),
+		wsSubscriptions:   make(chan map[string]interface{}, 16),
+		wsUnsubscriptions: make(chan map[string]interface{}, 16),
+		wsCloses:          make(chan *websocket.CloseError, 1),
+	}
+	fs.Server = httptest.NewServer(http.HandlerFunc(fs.handle))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+func (fs *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/ws" {
+		fs.handleWs(w, r)
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	var payload map[string]interface{}
+	_ = json.Unmarshal(body, &payload)
+
+	fs.mu.Lock()
+	delay := fs.delay
+	fs.mu.Unlock()
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/exchange":
+		fs.exchangeRequests = append(fs.exchangeRequests, payload)
+		response := fs.exchangeResponse
+		if fs.exchangeReply != nil {
+			if reply := fs.exchangeReply(payload); reply != "" {
+				response = reply
+			}
+		}
+		_, _ = w.Write([]byte(response))
+	case "/info", "/explorer":
+		fs.infoRequests = append(fs.infoRequests, payload)
+		infoType, _ := payload["type"].(string)
+		response, ok := fs.infoResponses[infoType]
+		if fs.infoReply != nil {
+			if reply := fs.infoReply(payload); reply != "" {
+				response, ok = reply, true
+			}
+		}
+		if !ok {
+			http.Error(w, `{"msg":"unexpected info request"}`, http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// handleWs accepts a websocket connection, publishes it on wsConns and
+// forwards every subscribe request to wsSubscriptions after replying to it,
+// and every unsubscribe request to wsUnsubscriptions
+func (fs *fakeServer) handleWs(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON("Websocket connection established."); err != nil {
+		return
+	}
+	fs.mu.Lock()
+	fs.wsConn = conn
+	fs.mu.Unlock()
+	fs.wsConns <- conn
+
+	for {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			var closeErr *websocket.CloseError
+			if errors.As(err, &closeErr) {
+				select {
+				case fs.wsCloses <- closeErr:
+				default:
+				}
+			}
+			return
+		}
+		if msg["method"] == "unsubscribe" {
+			select {
+			case fs.wsUnsubscriptions <- msg["subscription"].(map[string]interface{}):
+			default:
+			}
+		}
+		if msg["method"] == "subscribe" {
+			subscription := msg["subscription"].(map[string]interface{})
+			fs.mu.Lock()
+			wsReply := fs.wsReply
+			fs.mu.Unlock()
+			reply := interface{}(map[string]interface{}{
+				"channel": "subscriptionResponse",
+				"data":    map[string]interface{}{"method": "subscribe", "subscription": subscription},
+			})
+			if wsReply != nil {
+				reply = wsReply(subscription)
+			}
+			if reply != nil {
+				if err := conn.WriteJSON(reply); err != nil {
+					return
+				}
+			}
+			fs.wsSubscriptions <- subscription
+		}
+		if msg["method"] == "post" {
+			request := msg["request"].(map[string]interface{})
+			fs.mu.Lock()
+			wsPostReply := fs.wsPostReply
+			fs.mu.Unlock()
+			response := fs.postResponse(request)
+			if wsPostReply != nil {
+				response = wsPostReply(request)
+			}
+			if response == nil {
+				continue
+			}
+			reply := map[string]interface{}{
+				"channel": "post",
+				"data":    map[string]interface{}{"id": msg["id"], "response": response},
+			}
+			if err := conn.WriteJSON(reply); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// postResponse answers a WebSocket post request the way the HTTP endpoints
+// would, recording it alongside HTTP requests
+func (fs *fakeServer) postResponse(request map[string]interface{}) interface{} {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	payload, _ := request["payload"].(map[string]interface{})
+	switch request["type"] {
+	case "action":
+		fs.exchangeRequests = append(fs.exchangeRequests, payload)
+		return map[string]interface{}{"type": "action", "payload": json.RawMessage(fs.exchangeResponse)}
+	case "info":
+		fs.infoRequests = append(fs.infoRequests, payload)
+		infoType, _ := payload["type"].(string)
+		response, ok := fs.infoResponses[infoType]
+		if fs.infoReply != nil {
+			if reply := fs.infoReply(payload); reply != "" {
+				response, ok = reply, true
+			}
+		}
+		if !ok {
+			return map[string]interface{}{"type": "error", "payload": "unexpected info request"}
+		}
+		return map[string]interface{}{
+			"type":    "info",
+			"payload": map[string]interface{}{"type": infoType, "data": json.RawMessage(response)},
+		}
+	}
+	return map[string]interface{}{"type": "error", "payload": "unknown request type"}
+}
+
+// lastWsConn returns the most recent WebSocket connection
+func (fs *fakeServer) lastWsConn(t *testing.T) *websocket.Conn {
+	t.Helper()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	require.NotNil(t, fs.wsConn, "no WebSocket connection made")
+	return fs.wsConn
+}
+
+// setInfoResponse replaces the response served for an info request type
+func (fs *fakeServer) setInfoResponse(infoType string, response string) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.infoResponses[infoType] = response
+}
+
+// lastExchangeRequest returns the most recent payload posted to /exchange
+func (fs *fakeServer) lastExchangeRequest(t *testing.T) map[string]interface{} {
+	t.Helper()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	require.NotEmpty(t, fs.exchangeRequests, "no /exchange request recorded")
+	return fs.exchangeRequests[len(fs.exchangeRequests)-1]
+}
+
+// lastInfoRequest returns the most recent payload posted to /info
+func (fs *fakeServer) lastInfoRequest(t *testing.T) map[string]interface{} {
+	t.Helper()
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	require.NotEmpty(t, fs.infoRequests, "no /info request recorded")
+	return fs.infoRequests[len(fs.infoRequests)-1]
+}
+
+// loadCassette reads a recorded response from the cassettes directory
+func loadCassette(t testing.TB, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("cassettes", name))
+	require.NoError(t, err)
+	return string(data)
+}
+
+// newTestExchange creates an Exchange against the fake server using recorded metadata
+func newTestExchange(t *testing.T, fs *fakeServer, vaultAddress *string) (*hyperliquid.Exchange, *ecdsa.PrivateKey) {
+	t.Helper()
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	var spotMeta hyperliquid.SpotMeta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "spot_meta.json")), &spotMeta))
+
+	exchange, err := hyperliquid.NewExchange(privateKey, fs.URL, &meta, vaultAddress, nil, &spotMeta, nil, 5*time.Second)
+	require.NoError(t, err)
+	return exchange, privateKey
+}
+
+// fakeSigner stands in for a remote signing service: it records every payload
+// it is asked to sign and answers with a canned signature, holding no key
+type fakeSigner struct {
+	address  common.Address
+	requests []apitypes.TypedData
+}
+
+func (s *fakeSigner) SignTypedData(data apitypes.TypedData) (*utils.Signature, error) {
+	s.requests = append(s.requests, data)
+	return &utils.Signature{R: "0x" + strings.Repeat("11", 32), S: "0x" + strings.Repeat("22", 32), V: 27}, nil
+}
+
+func (s *fakeSigner) Address() common.Address {
+	return s.address
+}
+
+func TestExchangeWithSigner(t *testing.T) {
+	fs := newFakeServer(t)
+	signer := &fakeSigner{address: common.HexToAddress("0x0000000000000000000000000000000000000009")}
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	var spotMeta hyperliquid.SpotMeta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "spot_meta.json")), &spotMeta))
+	exchange, err := hyperliquid.NewExchangeWithSigner(signer, fs.URL, &meta, nil, nil, &spotMeta, nil, 5*time.Second)
+	require.NoError(t, err)
+
+	_, err = exchange.Cancel("BTC", 123)
+	require.NoError(t, err)
+	require.Len(t, signer.requests, 1)
+	require.Equal(t, "Agent", signer.requests[0].PrimaryType)
+	require.Equal(t, "b", signer.requests[0].Message["source"])
+	require.Equal(t, &utils.Signature{R: "0x" + strings.Repeat("11", 32), S: "0x" + strings.Repeat("22", 32), V: 27}, postedSignature(t, fs.lastExchangeRequest(t)))
+
+	_, err = exchange.UsdTransfer(1, "0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Len(t, signer.requests, 2)
+	require.Equal(t, "HyperliquidTransaction:UsdSend", signer.requests[1].PrimaryType)
+
+	// The outer signer of a multi-sig action is the signer's address
+	_, err = exchange.MultiSig("0x0000000000000000000000000000000000000005", utils.NoopAction{Type: "noop"}, []utils.Signature{{}}, 1, nil)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)["action"].(map[string]interface{})["payload"].(map[string]interface{})
+	require.Equal(t, "0x0000000000000000000000000000000000000009", payload["outerSigner"])
+	require.Equal(t, "HyperliquidTransaction:SendMultiSig", signer.requests[2].PrimaryType)
+}
+
+func TestExchangeAccessors(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	wallet := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	assert.Equal(t, privateKey, exchange.GetPrivateKey())
+	assert.Equal(t, wallet, exchange.GetSigner().Address().Hex())
+	assert.Equal(t, wallet, exchange.GetWalletAddress())
+	assert.Equal(t, wallet, exchange.GetAccountAddress(), "no account address given")
+	assert.Nil(t, exchange.GetVaultAddress())
+
+	// Orders carry the vault address once it is set, and stop carrying it
+	// once it is cleared
+	vault := "0x0000000000000000000000000000000000000002"
+	require.NoError(t, exchange.SetVaultAddress(&vault))
+	assert.Equal(t, &vault, exchange.GetVaultAddress())
+	_, err := exchange.Cancel("BTC", 1)
+	require.NoError(t, err)
+	assert.Equal(t, vault, fs.lastExchangeRequest(t)["vaultAddress"])
+
+	require.NoError(t, exchange.SetVaultAddress(nil))
+	_, err = exchange.Cancel("BTC", 1)
+	require.NoError(t, err)
+	assert.NotContains(t, fs.lastExchangeRequest(t), "vaultAddress")
+
+	// Concurrent changes are safe
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = exchange.SetVaultAddress(&vault)
+			_ = exchange.GetVaultAddress()
+		}()
+	}
+	wg.Wait()
+
+	account := "0x0000000000000000000000000000000000000003"
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	signer := &fakeSigner{address: common.HexToAddress("0x0000000000000000000000000000000000000009")}
+	agent, err := hyperliquid.NewExchangeWithSigner(signer, fs.URL, &meta, nil, &account, &hyperliquid.SpotMeta{}, nil, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, account, agent.GetAccountAddress())
+	assert.Equal(t, signer.address.Hex(), agent.GetWalletAddress())
+	assert.Nil(t, agent.GetPrivateKey(), "the key is not held in process")
+}
+
+func TestExchangeWithInfo(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfo(t, fs)
+	signer := &fakeSigner{address: common.HexToAddress("0x0000000000000000000000000000000000000009")}
+
+	_, err := hyperliquid.NewExchangeWithInfo(signer, nil, nil, nil)
+	assert.Error(t, err)
+	_, err = hyperliquid.NewExchangeWithInfo(nil, info, nil, nil)
+	assert.Error(t, err)
+
+	requests := len(fs.infoRequests)
+	exchange, err := hyperliquid.NewExchangeWithInfo(signer, info, nil, nil)
+	require.NoError(t, err)
+	assert.Same(t, info, exchange.Info())
+	assert.Equal(t, requests, len(fs.infoRequests), "no metadata fetched")
+	assert.Equal(t, fs.URL, exchange.GetBaseURL())
+
+	// A refresh through the shared info makes new listings tradable
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	_, err = exchange.Order("NEWCOIN", true, 1, 10, orderType, false, nil, nil)
+	require.Error(t, err)
+	fs.setInfoResponse("meta", loadCassette(t, "meta_new_listing.json"))
+	require.NoError(t, info.RefreshMeta())
+	_, err = exchange.Order("NEWCOIN", true, 1, 10, orderType, false, nil, nil)
+	require.NoError(t, err)
+	order := fs.lastExchangeRequest(t)["action"].(map[string]interface{})["orders"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(3), order["a"])
+}
+
+func TestEffectiveAddress(t *testing.T) {
+	vault := "0x0000000000000000000000000000000000000002"
+	account := "0x0000000000000000000000000000000000000003"
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	wallet := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+
+	tests := []struct {
+		name    string
+		vault   *string
+		account *string
+		user    string
+	}{
+		{"vault over account", &vault, &account, vault},
+		{"account over wallet", nil, &account, account},
+		{"wallet", nil, nil, wallet},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			fs.setInfoResponse("clearinghouseState", loadCassette(t, "clearinghouse_state.json"))
+			fs.setInfoResponse("allMids", `{"BTC": "50000", "ETH": "3000"}`)
+			fs.setInfoResponse("openOrders", `[]`)
+			exchange, err := hyperliquid.NewExchangeWithInfo(utils.NewLocalSigner(privateKey), newTestInfo(t, fs), tt.vault, tt.account)
+			require.NoError(t, err)
+
+			// Every query for the account's state asks about the same user
+			_, err = exchange.MarketClose("BTC", nil, nil, 0.05, nil, nil)
+			require.NoError(t, err)
+			_, err = exchange.CancelAllOrders(nil)
+			require.NoError(t, err)
+			queried := map[string]interface{}{}
+			for _, request := range fs.infoRequests {
+				if user, ok := request["user"]; ok {
+					queried[request["type"].(string)] = user
+				}
+			}
+			assert.Equal(t, map[string]interface{}{"clearinghouseState": tt.user, "openOrders": tt.user}, queried)
+		})
+	}
+}
+
+func TestValidateAgent(t *testing.T) {
+	fs := newFakeServer(t)
+	account := "0x0000000000000000000000000000000000000003"
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	agent := strings.ToLower(crypto.PubkeyToAddress(privateKey.PublicKey).Hex())
+	exchange, err := hyperliquid.NewExchangeWithInfo(utils.NewLocalSigner(privateKey), newTestInfo(t, fs), nil, &account)
+	require.NoError(t, err)
+
+	validUntil := time.Now().Add(time.Hour).UnixMilli()
+	fs.setInfoResponse("extraAgents", fmt.Sprintf(`[{"address": %q, "name": "bot", "validUntil": %d}]`, agent, validUntil))
+	approved, err := exchange.ValidateAgent()
+	require.NoError(t, err)
+	require.NotNil(t, approved)
+	assert.Equal(t, "bot", approved.Name)
+	assert.Equal(t, validUntil, approved.ValidUntil)
+	assert.Equal(t, account, fs.lastInfoRequest(t)["user"])
+
+	expired := time.Now().Add(-time.Hour).UnixMilli()
+	fs.setInfoResponse("extraAgents", fmt.Sprintf(`[{"address": %q, "name": "bot", "validUntil": %d}]`, agent, expired))
+	_, err = exchange.ValidateAgent()
+	var agentErr *hyperliquid.InvalidAgentError
+	require.ErrorAs(t, err, &agentErr)
+	assert.Equal(t, expired, agentErr.ValidUntil)
+	assert.ErrorContains(t, err, "expired")
+
+	fs.setInfoResponse("extraAgents", loadCassette(t, "extra_agents.json"))
+	_, err = exchange.ValidateAgent()
+	require.ErrorAs(t, err, &agentErr)
+	assert.Zero(t, agentErr.ValidUntil)
+	assert.ErrorContains(t, err, "not approved")
+
+	// Signing with the account's own key needs no agent
+	requests := len(fs.infoRequests)
+	own, _ := newTestExchange(t, fs, nil)
+	approved, err = own.ValidateAgent()
+	require.NoError(t, err)
+	assert.Nil(t, approved)
+	assert.Len(t, fs.infoRequests, requests)
+}
+
+func TestExpiresAfter(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	wallet := crypto.PubkeyToAddress(privateKey.PublicKey)
+
+	// A fixed time that has passed fails before anything is sent
+	past := time.Now().Add(-time.Minute).UnixMilli()
+	exchange.SetExpiresAfter(&past)
+	_, err := exchange.Cancel("BTC", 1)
+	assert.ErrorContains(t, err, "is not after the action's nonce")
+	_, err = exchange.UpdateLeverage(10, "BTC", true)
+	assert.Error(t, err)
+	assert.Empty(t, fs.exchangeRequests)
+
+	// A duration is counted from every action's own nonce, and the payload
+	// carries the time that was signed
+	exchange.SetExpiresAfterDuration(time.Minute)
+	for i := 0; i < 2; i++ {
+		_, err = exchange.Cancel("BTC", 1)
+		require.NoError(t, err)
+		request := fs.lastExchangeRequest(t)
+		nonce := uint64(request["nonce"].(float64))
+		expiresAfter := uint64(request["expiresAfter"].(float64))
+		assert.Equal(t, nonce+60000, expiresAfter)
+
+		action := utils.CancelAction{Type: "cancel", Cancels: []utils.CancelWire{{A: 0, O: 1}}}
+		signer, err := utils.RecoverAgentOrUserFromL1Action(action, postedSignature(t, request), nil, nonce, &expiresAfter, false)
+		require.NoError(t, err)
+		assert.Equal(t, wallet, signer)
+	}
+
+	// Changing it while actions are sent is safe
+	future := time.Now().Add(time.Hour).UnixMilli()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			exchange.SetExpiresAfter(&future)
+			exchange.SetExpiresAfterDuration(time.Minute)
+		}()
+		go func() {
+			defer wg.Done()
+			_, err := exchange.Cancel("BTC", 1)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	exchange.SetExpiresAfterDuration(0)
+	_, err = exchange.Cancel("BTC", 1)
+	require.NoError(t, err)
+	assert.NotContains(t, fs.lastExchangeRequest(t), "expiresAfter")
+}
+
+func TestPostSignedAction(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	// The action is built and digested here, signed by a key the exchange
+	// never sees, and then submitted
+	offlineKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	action := utils.CancelAction{Type: "cancel", Cancels: []utils.CancelWire{{A: 0, O: 123}}}
+	nonce := utils.GetTimestampMs()
+	digest, err := utils.L1ActionDigest(action, nil, uint64(nonce), nil, false)
+	require.NoError(t, err)
+	raw, err := crypto.Sign(digest[:], offlineKey)
+	require.NoError(t, err)
+	signature, err := utils.SignatureFromBytes(raw)
+	require.NoError(t, err)
+
+	_, err = exchange.PostSignedAction(action, signature, nonce)
+	require.NoError(t, err)
+
+	payload := fs.lastExchangeRequest(t)
+	require.Equal(t, float64(nonce), payload["nonce"])
+	require.Equal(t, map[string]interface{}{
+		"type":    "cancel",
+		"cancels": []interface{}{map[string]interface{}{"a": float64(0), "o": float64(123)}},
+	}, payload["action"])
+	signer, err := utils.RecoverAgentOrUserFromL1Action(action, postedSignature(t, payload), nil, uint64(nonce), nil, false)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(offlineKey.PublicKey), signer)
+}
+
+func TestNoop(t *testing.T) {
+	fs := newFakeServer(t)
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchange, privateKey := newTestExchange(t, fs, &vault)
+
+	response, err := exchange.Noop()
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response.Status)
+	payload := fs.lastExchangeRequest(t)
+	assert.Equal(t, map[string]interface{}{"type": "noop"}, payload["action"])
+	assert.Equal(t, vault, payload["vaultAddress"])
+	nonce := uint64(payload["nonce"].(float64))
+	signer, err := utils.RecoverAgentOrUserFromL1Action(utils.NoopAction{Type: "noop"}, postedSignature(t, payload), &vault, nonce, nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+}
+
+func TestSetSigningConfig(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	address := crypto.PubkeyToAddress(privateKey.PublicKey)
+	config := utils.SigningConfig{SignatureChainID: "0xa4b1", L1ChainID: 31337}
+	exchange.SetSigningConfig(config)
+
+	_, err := exchange.UsdTransfer(1, "0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	action := payload["action"].(map[string]interface{})
+	assert.Equal(t, "0xa4b1", action["signatureChainId"])
+	action["time"] = int64(action["time"].(float64))
+	assert.Equal(t, address, recoverUserSignedSigner(t, action, utils.USDSendSignTypes, "HyperliquidTransaction:UsdSend", postedSignature(t, payload)))
+
+	_, err = exchange.Noop()
+	require.NoError(t, err)
+	payload = fs.lastExchangeRequest(t)
+	nonce := uint64(payload["nonce"].(float64))
+	signer, err := utils.RecoverAgentOrUserFromL1ActionWithConfig(utils.NoopAction{Type: "noop"}, postedSignature(t, payload), nil, nonce, nil, false, config)
+	require.NoError(t, err)
+	assert.Equal(t, address, signer)
+	signer, err = utils.RecoverAgentOrUserFromL1Action(utils.NoopAction{Type: "noop"}, postedSignature(t, payload), nil, nonce, nil, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, address, signer, "signed in the default domain")
+}
+
+func TestInvalidateNoncesThrough(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	stuck := utils.GetTimestampMs() + 60000
+
+	sent, err := exchange.InvalidateNoncesThrough(stuck)
+	require.NoError(t, err)
+	assert.Equal(t, 100, sent)
+	require.Len(t, fs.exchangeRequests, 100)
+	seen := map[float64]bool{}
+	for _, request := range fs.exchangeRequests {
+		nonce := request["nonce"].(float64)
+		assert.Greater(t, nonce, float64(stuck))
+		assert.False(t, seen[nonce])
+		seen[nonce] = true
+	}
+	assert.Greater(t, exchange.NonceManager().Next(), stuck+100)
+
+	// Stops at the first rejected noop
+	fs.exchangeRequests = nil
+	fs.exchangeResponse = loadCassette(t, "exchange_err.json")
+	sent, err = exchange.InvalidateNoncesThrough(stuck)
+	assert.Error(t, err)
+	assert.Zero(t, sent)
+	assert.Len(t, fs.exchangeRequests, 1)
+}
+
+func TestReserveRequestWeight(t *testing.T) {
+	fs := newFakeServer(t)
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchange, privateKey := newTestExchange(t, fs, &vault)
+	limiter := hyperliquid.NewRateLimiter(hyperliquid.DefaultWeightPerMinute, true)
+	limiter.SetAddressBudget(5)
+	exchange.SetRateLimiter(limiter)
+
+	_, err := exchange.ReserveRequestWeight(1000)
+	require.ErrorIs(t, err, hyperliquid.ErrWeightPurchaseDisabled)
+	require.Empty(t, fs.exchangeRequests)
+
+	exchange.SetWeightPurchase(true)
+	_, err = exchange.ReserveRequestWeight(0)
+	require.Error(t, err)
+	_, err = exchange.ReserveRequestWeight(1000)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	assert.Equal(t, map[string]interface{}{"type": "reserveRequestWeight", "weight": float64(1000)}, payload["action"])
+	assert.NotContains(t, payload, "vaultAddress", "weight is bought for the signing user")
+	action := utils.ReserveRequestWeightAction{Type: "reserveRequestWeight", Weight: 1000}
+	signer, err := utils.RecoverAgentOrUserFromL1Action(action, postedSignature(t, payload), nil, uint64(payload["nonce"].(float64)), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+
+	// The purchase itself counts as a request
+	remaining, _ := limiter.AddressRemaining()
+	assert.Equal(t, 1004, remaining)
+}
+
+func TestAutoReserve(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	limiter := hyperliquid.NewRateLimiter(hyperliquid.DefaultWeightPerMinute, true)
+	limiter.SetAddressBudget(3)
+	exchange.SetRateLimiter(limiter)
+	exchange.SetAutoReserve(5, 100)
+
+	actionTypes := func() []string {
+		var types []string
+		for _, request := range fs.exchangeRequests {
+			types = append(types, request["action"].(map[string]interface{})["type"].(string))
+		}
+		return types
+	}
+
+	// Nothing is bought without opting in
+	_, err := exchange.UpdateLeverage(10, "BTC", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"updateLeverage"}, actionTypes())
+
+	exchange.SetWeightPurchase(true)
+	_, err = exchange.UpdateLeverage(10, "BTC", true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"updateLeverage", "reserveRequestWeight", "updateLeverage"}, actionTypes())
+	remaining, _ := limiter.AddressRemaining()
+	assert.Equal(t, 100, remaining)
+
+	// Above the threshold nothing more is bought
+	_, err = exchange.UpdateLeverage(10, "BTC", true)
+	require.NoError(t, err)
+	assert.Len(t, fs.exchangeRequests, 4)
+
+	// A failed purchase does not hold the action back
+	limiter.SetAddressBudget(2)
+	fs.exchangeResponse = loadCassette(t, "exchange_err.json")
+	_, err = exchange.UpdateLeverage(10, "BTC", true)
+	require.Error(t, err)
+	assert.Equal(t, []string{"reserveRequestWeight", "updateLeverage"}, actionTypes()[4:])
+}
+
+func TestExchangePayloadShape(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	_, err := exchange.Cancel("BTC", 123)
+	require.NoError(t, err)
+
+	payload := fs.lastExchangeRequest(t)
+	require.ElementsMatch(t, []string{"action", "nonce", "signature"}, mapKeys(payload))
+	require.IsType(t, float64(0), payload["nonce"])
+
+	signature, ok := payload["signature"].(map[string]interface{})
+	require.True(t, ok, "signature must be posted as an object, got %T", payload["signature"])
+	require.ElementsMatch(t, []string{"r", "s", "v"}, mapKeys(signature))
+	require.Regexp(t, "^0x[0-9a-f]{64}$", signature["r"])
+	require.Regexp(t, "^0x[0-9a-f]{64}$", signature["s"])
+	require.Contains(t, []float64{27, 28}, signature["v"])
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestTransferAddressNormalization(t *testing.T) {
+	checksummed := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	lower := "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	_, err := exchange.UsdTransfer(1, checksummed)
+	require.NoError(t, err)
+	assert.Equal(t, lower, fs.lastExchangeRequest(t)["action"].(map[string]interface{})["destination"])
+	_, err = exchange.SendAsset(checksummed, "", "spot", "USDC", "1", checksummed)
+	require.NoError(t, err)
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	assert.Equal(t, lower, action["destination"])
+	assert.Equal(t, lower, action["fromSubAccount"])
+
+	requests := len(fs.exchangeRequests)
+	_, err = exchange.UsdTransfer(1, lower[:40])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid destination address")
+	_, err = exchange.SendAsset(lower, "", "spot", "USDC", "1", "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid fromSubAccount address")
+	assert.Len(t, fs.exchangeRequests, requests)
+
+	// Every other address that is hashed or signed goes through the same check
+	badChecksum := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"
+	_, err = exchange.VaultUsdTransfer(checksummed, true, 1000000)
+	require.NoError(t, err)
+	assert.Equal(t, lower, fs.lastExchangeRequest(t)["action"].(map[string]interface{})["vaultAddress"])
+	_, err = exchange.ApproveBuilderFee(checksummed, "0.001%")
+	require.NoError(t, err)
+	assert.Equal(t, lower, fs.lastExchangeRequest(t)["action"].(map[string]interface{})["builder"])
+	_, err = exchange.UsdClassTransfer(1, true, &checksummed)
+	require.NoError(t, err)
+	assert.Equal(t, "1.000000 subaccount:"+lower, fs.lastExchangeRequest(t)["action"].(map[string]interface{})["amount"])
+
+	requests = len(fs.exchangeRequests)
+	_, err = exchange.VaultUsdTransfer(badChecksum, true, 1000000)
+	assert.ErrorContains(t, err, "invalid vault address")
+	_, err = exchange.ApproveBuilderFee(badChecksum, "0.001%")
+	assert.ErrorContains(t, err, "invalid builder address")
+	_, err = exchange.UsdClassTransfer(1, true, &badChecksum)
+	assert.ErrorContains(t, err, "invalid sub-account address")
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	_, err = exchange.Order("BTC", true, 1, 10, orderType, false, nil, &hyperliquid.BuilderInfo{B: badChecksum, F: 10})
+	assert.ErrorContains(t, err, "invalid builder address")
+	assert.Len(t, fs.exchangeRequests, requests)
+
+	// Vault addresses are posted in the form they are hashed with
+	require.NoError(t, exchange.SetVaultAddress(&checksummed))
+	assert.Equal(t, lower, *exchange.GetVaultAddress())
+	_, err = exchange.Cancel("BTC", 1)
+	require.NoError(t, err)
+	assert.Equal(t, lower, fs.lastExchangeRequest(t)["vaultAddress"])
+
+	// An invalid vault address is rejected and the current one kept
+	invalid := lower + "00"
+	err = exchange.SetVaultAddress(&invalid)
+	assert.ErrorContains(t, err, "invalid vault address")
+	assert.ErrorContains(t, exchange.SetVaultAddress(&badChecksum), "invalid vault address")
+	assert.Equal(t, lower, *exchange.GetVaultAddress())
+	_, err = exchange.Cancel("BTC", 1)
+	require.NoError(t, err)
+	assert.Equal(t, lower, fs.lastExchangeRequest(t)["vaultAddress"])
+
+	_, err = hyperliquid.NewExchangeWithInfo(utils.NewLocalSigner(privateKey), exchange.Info(), &invalid, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid vault address")
+}
+
+func TestSendAsset(t *testing.T) {
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	destination := "0x0000000000000000000000000000000000000001"
+
+	tests := []struct {
+		name           string
+		sourceDex      string
+		destinationDex string
+		token          string
+		fromSubAccount string
+	}{
+		{"Perp to spot", "", "spot", "USDC", ""},
+		{"Spot to perp from sub-account", "spot", "", "USDC", vault},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			exchange, _ := newTestExchange(t, fs, &vault)
+
+			_, err := exchange.SendAsset(destination, tt.sourceDex, tt.destinationDex, tt.token, "1.5", tt.fromSubAccount)
+			require.NoError(t, err)
+
+			payload := fs.lastExchangeRequest(t)
+			action := payload["action"].(map[string]interface{})
+			require.Equal(t, "sendAsset", action["type"])
+			require.Equal(t, destination, action["destination"])
+			require.Equal(t, tt.sourceDex, action["sourceDex"])
+			require.Equal(t, tt.destinationDex, action["destinationDex"])
+			require.Equal(t, tt.token, action["token"])
+			require.Equal(t, "1.5", action["amount"])
+			require.Equal(t, tt.fromSubAccount, action["fromSubAccount"])
+			require.Equal(t, "Testnet", action["hyperliquidChain"])
+			require.Equal(t, payload["nonce"], action["nonce"])
+			require.NotContains(t, payload, "vaultAddress")
+			require.NotEmpty(t, payload["signature"])
+		})
+	}
+}
+
+func TestValidateDestination(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("preTransferCheck", `{"fee": "1.0", "isSanctioned": false, "userExists": false, "userHasSentTx": false}`)
+	exchange, _ := newTestExchange(t, fs, nil)
+	destination := "0x0000000000000000000000000000000000000002"
+
+	// Off by default
+	_, err := exchange.UsdTransfer(5, destination)
+	require.NoError(t, err)
+	require.Len(t, fs.exchangeRequests, 1)
+
+	exchange.SetValidateDestination(true)
+	_, err = exchange.UsdTransfer(5, destination)
+	var destErr *hyperliquid.InvalidDestinationError
+	require.ErrorAs(t, err, &destErr)
+	require.Equal(t, destination, destErr.Destination)
+	_, err = exchange.SendAsset(destination, "", "spot", "USDC", "1.5", "")
+	require.ErrorAs(t, err, &destErr)
+	require.Len(t, fs.exchangeRequests, 1)
+
+	fs.setInfoResponse("preTransferCheck", `{"fee": "0.0", "isSanctioned": false, "userExists": true, "userHasSentTx": true}`)
+	_, err = exchange.UsdTransfer(5, destination)
+	require.NoError(t, err)
+	require.Len(t, fs.exchangeRequests, 2)
+}
+
+func TestSubAccountActions(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = loadCassette(t, "create_sub_account.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+	subAccount := "0x1d9470d4b963f552e6f671a81619d395877bf409"
+
+	result, err := exchange.CreateSubAccount("example")
+	require.NoError(t, err)
+	response := result.(map[string]interface{})["response"].(map[string]interface{})
+	require.Equal(t, subAccount, response["data"])
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "createSubAccount", "name": "example"}, action)
+
+	_, err = exchange.SubAccountTransfer(subAccount, true, 1000000)
+	require.NoError(t, err)
+	action = fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"type":           "subAccountTransfer",
+		"subAccountUser": subAccount,
+		"isDeposit":      true,
+		"usd":            float64(1000000),
+	}, action)
+
+	_, err = exchange.SubAccountSpotTransfer(subAccount, false, "PURR:0xc1fb593aeffbeb02f85e0308e9956a90", 1.25)
+	require.NoError(t, err)
+	action = fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"type":           "subAccountSpotTransfer",
+		"subAccountUser": subAccount,
+		"isDeposit":      false,
+		"token":          "PURR:0xc1fb593aeffbeb02f85e0308e9956a90",
+		"amount":         "1.25",
+	}, action)
+
+	_, err = exchange.SubAccountTransfer("0x1234", true, 1000000)
+	require.Error(t, err)
+	_, err = exchange.SubAccountSpotTransfer("not-an-address", true, "USDC", 1)
+	require.Error(t, err)
+}
+
+func TestSubAccountAddressNormalization(t *testing.T) {
+	checksummed := "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"
+	lower := strings.ToLower(checksummed)
+	nonce := utils.GetTimestampMs() + 60000
+
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	transfers := map[string]func(e *hyperliquid.Exchange, subAccount string) error{
+		"subAccountTransfer": func(e *hyperliquid.Exchange, subAccount string) error {
+			_, err := e.SubAccountTransfer(subAccount, true, 1000000)
+			return err
+		},
+		"subAccountSpotTransfer": func(e *hyperliquid.Exchange, subAccount string) error {
+			_, err := e.SubAccountSpotTransfer(subAccount, false, "USDC", 1)
+			return err
+		},
+	}
+	for name, transfer := range transfers {
+		t.Run(name, func(t *testing.T) {
+			// Mixed-case and lowercase input sign the same action
+			var posted []map[string]interface{}
+			for _, subAccount := range []string{checksummed, lower} {
+				sender, err := hyperliquid.NewExchangeWithInfo(utils.NewLocalSigner(privateKey), exchange.Info(), nil, nil)
+				require.NoError(t, err)
+				sender.NonceManager().SetMinNonce(nonce)
+				require.NoError(t, transfer(sender, subAccount))
+				posted = append(posted, fs.lastExchangeRequest(t))
+			}
+			assert.Equal(t, float64(nonce), posted[0]["nonce"])
+			assert.Equal(t, posted[0]["signature"], posted[1]["signature"])
+			assert.Equal(t, lower, posted[0]["action"].(map[string]interface{})["subAccountUser"])
+
+			err := transfer(exchange, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid sub-account address")
+		})
+	}
+}
+
+func TestVaultUsdTransfer(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	_, err := exchange.VaultUsdTransferAmount("0xA15099A30BBF2E68942D6F4C43D70D04FAEAB0A0", true, 12.5)
+	require.NoError(t, err)
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"type":         "vaultTransfer",
+		"vaultAddress": "0xa15099a30bbf2e68942d6f4c43d70d04faeab0a0",
+		"isDeposit":    true,
+		"usd":          float64(12500000),
+	}, action)
+
+	_, err = exchange.VaultUsdTransfer("0xa15099", false, 1000000)
+	require.Error(t, err)
+	_, err = exchange.VaultUsdTransferAmount("0xa15099a30bbf2e68942d6f4c43d70d04faeab0a0", false, 0.0000001)
+	require.Error(t, err)
+}
+
+// postedSignature decodes the {r, s, v} signature object of a posted payload
+func postedSignature(t *testing.T, payload map[string]interface{}) *utils.Signature {
+	t.Helper()
+	raw, ok := payload["signature"].(map[string]interface{})
+	require.True(t, ok, "signature must be posted as an object, got %T", payload["signature"])
+	require.Len(t, raw, 3)
+	return &utils.Signature{
+		R: raw["r"].(string),
+		S: raw["s"].(string),
+		V: uint8(raw["v"].(float64)),
+	}
+}
+
+// recoverUserSignedSigner recovers the address that signed a posted user-signed action
+func recoverUserSignedSigner(t *testing.T, action map[string]interface{}, payloadTypes []apitypes.Type, primaryType string, signature *utils.Signature) common.Address {
+	t.Helper()
+	signer, err := utils.RecoverUserFromUserSignedAction(action, signature, payloadTypes, primaryType, action["hyperliquidChain"] == "Mainnet")
+	require.NoError(t, err)
+	return signer
+}
+
+func TestApproveAgent(t *testing.T) {
+	name := "bot"
+
+	for _, agentName := range []*string{&name, nil} {
+		fs := newFakeServer(t)
+		exchange, privateKey := newTestExchange(t, fs, nil)
+
+		_, agentKeyHex, err := exchange.ApproveAgent(agentName)
+		require.NoError(t, err)
+		agentKey, err := crypto.HexToECDSA(strings.TrimPrefix(agentKeyHex, "0x"))
+		require.NoError(t, err)
+
+		payload := fs.lastExchangeRequest(t)
+		action := payload["action"].(map[string]interface{})
+		require.Equal(t, "approveAgent", action["type"])
+		require.Equal(t, strings.ToLower(crypto.PubkeyToAddress(agentKey.PublicKey).Hex()), action["agentAddress"])
+		if agentName == nil {
+			require.NotContains(t, action, "agentName")
+			action["agentName"] = ""
+		} else {
+			require.Equal(t, name, action["agentName"])
+		}
+
+		action["nonce"] = int64(action["nonce"].(float64))
+		signer := recoverUserSignedSigner(t, action, utils.ApproveAgentSignTypes, "HyperliquidTransaction:ApproveAgent", postedSignature(t, payload))
+		require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+	}
+}
+
+func TestApproveBuilderFee(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	builder := "0x8C967E73E7B15087C42A10D344CFF4C96D877F1D"
+
+	_, err := exchange.ApproveBuilderFee(builder, "0.001%")
+	require.NoError(t, err)
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, "approveBuilderFee", action["type"])
+	require.Equal(t, "0.001%", action["maxFeeRate"])
+	require.Equal(t, strings.ToLower(builder), action["builder"])
+
+	for _, rate := range []string{"0.001", "1%%", "abc%", "-0.1%", ""} {
+		_, err = exchange.ApproveBuilderFee(builder, rate)
+		require.Error(t, err, rate)
+	}
+	_, err = exchange.ApproveBuilderFee("0x8c967e", "0.001%")
+	require.Error(t, err)
+}
+
+func TestBuilderFeeCheck(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("maxBuilderFee", "10")
+	exchange, _ := newTestExchange(t, fs, nil)
+	exchange.SetBuilderFeeCheck(true)
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	builder := "0x8C967E73E7B15087C42A10D344CFF4C96D877F1D"
+
+	_, err := exchange.Order("BTC", true, 0.01, 100000, orderType, false, nil, &hyperliquid.BuilderInfo{B: builder, F: 10})
+	require.NoError(t, err)
+	require.Len(t, fs.exchangeRequests, 1)
+
+	_, err = exchange.Order("BTC", true, 0.01, 100000, orderType, false, nil, &hyperliquid.BuilderInfo{B: builder, F: 11})
+	var feeErr *hyperliquid.BuilderFeeExceededError
+	require.ErrorAs(t, err, &feeErr)
+	require.Equal(t, 11, feeErr.Fee)
+	require.Equal(t, 10, feeErr.MaxFee)
+	require.Len(t, fs.exchangeRequests, 1)
+}
+
+func TestBulkOrdersWithGrouping(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	trigger := func(tpsl utils.TPSL, px float64) utils.OrderType {
+		return utils.OrderType{Trigger: &utils.TriggerOrderType{TriggerPx: px, IsMarket: true, TPSL: tpsl}}
+	}
+	limit := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	entry := utils.OrderRequest{Coin: "BTC", IsBuy: true, Sz: 0.01, LimitPx: 100000, OrderType: limit}
+	tp := utils.OrderRequest{Coin: "BTC", IsBuy: false, Sz: 0.01, LimitPx: 110000, OrderType: trigger(utils.TPSLTp, 110000), ReduceOnly: true}
+	sl := utils.OrderRequest{Coin: "BTC", IsBuy: false, Sz: 0.01, LimitPx: 90000, OrderType: trigger(utils.TPSLSl, 90000), ReduceOnly: true}
+
+	_, err := exchange.BulkOrdersWithGrouping([]utils.OrderRequest{entry, tp, sl}, nil, utils.GroupingNormalTpsl)
+	require.NoError(t, err)
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, "normalTpsl", action["grouping"])
+	require.Len(t, action["orders"], 3)
+
+	_, err = exchange.BulkOrders([]utils.OrderRequest{entry}, nil)
+	require.NoError(t, err)
+	action = fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, "na", action["grouping"])
+
+	_, err = exchange.BulkOrdersWithGrouping([]utils.OrderRequest{tp, sl}, nil, utils.GroupingPositionTpsl)
+	require.NoError(t, err)
+	action = fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, "positionTpsl", action["grouping"])
+
+	notReduceOnly := sl
+	notReduceOnly.ReduceOnly = false
+	sameSide := tp
+	sameSide.IsBuy = true
+
+	invalid := []struct {
+		name     string
+		orders   []utils.OrderRequest
+		grouping utils.Grouping
+	}{
+		{"positionTpsl with non-reduce-only trigger", []utils.OrderRequest{tp, notReduceOnly}, utils.GroupingPositionTpsl},
+		{"positionTpsl with entry order", []utils.OrderRequest{entry, tp}, utils.GroupingPositionTpsl},
+		{"normalTpsl without entry", []utils.OrderRequest{tp, sl}, utils.GroupingNormalTpsl},
+		{"normalTpsl without triggers", []utils.OrderRequest{entry}, utils.GroupingNormalTpsl},
+		{"normalTpsl trigger on entry side", []utils.OrderRequest{entry, sameSide}, utils.GroupingNormalTpsl},
+		{"normalTpsl with two take profits", []utils.OrderRequest{entry, tp, tp}, utils.GroupingNormalTpsl},
+		{"unknown grouping", []utils.OrderRequest{entry}, utils.Grouping("bracket")},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := len(fs.exchangeRequests)
+			_, err := exchange.BulkOrdersWithGrouping(tt.orders, nil, tt.grouping)
+			require.Error(t, err)
+			require.Len(t, fs.exchangeRequests, requests)
+		})
+	}
+}
+
+func TestOrderWithTpSl(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = loadCassette(t, "order_tpsl.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	tp, sl := 110000.1234, 89999.96
+	statuses, err := exchange.OrderWithTpSl("BTC", true, 0.01, 100000.04, &tp, &sl, nil)
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	require.NotNil(t, statuses[0].Resting)
+	require.Equal(t, 77738308, statuses[0].Resting.Oid)
+	require.Equal(t, "waitingForTrigger", statuses[1].Waiting)
+	require.Equal(t, "waitingForTrigger", statuses[2].Waiting)
+
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, "normalTpsl", action["grouping"])
+	orders := action["orders"].([]interface{})
+	require.Len(t, orders, 3)
+
+	entry := orders[0].(map[string]interface{})
+	require.Equal(t, true, entry["b"])
+	require.Equal(t, "100000", entry["p"])
+	require.Equal(t, "0.01", entry["s"])
+	require.Equal(t, false, entry["r"])
+
+	for i, expected := range []struct {
+		tpsl string
+		px   string
+	}{{"tp", "110000"}, {"sl", "90000"}} {
+		leg := orders[i+1].(map[string]interface{})
+		require.Equal(t, false, leg["b"])
+		require.Equal(t, "0.01", leg["s"])
+		require.Equal(t, true, leg["r"])
+		require.Equal(t, expected.px, leg["p"])
+		trigger := leg["t"].(map[string]interface{})["trigger"].(map[string]interface{})
+		require.Equal(t, expected.tpsl, trigger["tpsl"])
+		require.Equal(t, expected.px, trigger["triggerPx"])
+		require.Equal(t, true, trigger["isMarket"])
+	}
+
+	_, err = exchange.OrderWithTpSl("BTC", true, 0.01, 100000, nil, nil, nil)
+	require.Error(t, err)
+}
+
+func TestTwapOrder(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	fs.exchangeReply = func(payload map[string]interface{}) string {
+		switch payload["action"].(map[string]interface{})["type"] {
+		case "twapOrder":
+			return `{"status":"ok","response":{"type":"twapOrder","data":{"status":{"running":{"twapId":3156}}}}}`
+		case "twapCancel":
+			return `{"status":"ok","response":{"type":"twapCancel","data":{"status":{"error":"TWAP was never placed, already canceled, or filled."}}}}`
+		}
+		return ""
+	}
+
+	response, err := exchange.TwapOrder("ETH", false, 2.0, true, 30, true)
+	require.NoError(t, err)
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"type": "twapOrder",
+		"twap": map[string]interface{}{"a": float64(1), "b": false, "s": "2", "r": true, "m": float64(30), "t": true},
+	}, action)
+	require.NotNil(t, response.Response.Data.Status.Running)
+	assert.Equal(t, 3156, response.Response.Data.Status.Running.TwapID)
+	assert.Empty(t, response.Response.Data.Status.Error)
+	assert.NotEmpty(t, response.Raw)
+
+	cancel, err := exchange.TwapCancel("ETH", 3156)
+	require.NoError(t, err)
+	action = fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{"type": "twapCancel", "a": float64(1), "t": float64(3156)}, action)
+	assert.False(t, cancel.Response.Data.Status.Success)
+	assert.Equal(t, "TWAP was never placed, already canceled, or filled.", cancel.Response.Data.Status.Error)
+
+	fs.exchangeReply = nil
+	fs.exchangeResponse = `{"status":"ok","response":{"type":"twapCancel","data":{"status":"success"}}}`
+	cancel, err = exchange.TwapCancel("ETH", 3156)
+	require.NoError(t, err)
+	assert.True(t, cancel.Response.Data.Status.Success)
+
+	_, err = exchange.TwapOrder("ETH", true, 1, false, 0, false)
+	require.Error(t, err)
+	_, err = exchange.TwapCancel("UNKNOWN", 1)
+	require.Error(t, err)
+}
+
+func TestStakingActions(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	signer := crypto.PubkeyToAddress(privateKey.PublicKey)
+	validator := common.HexToAddress("0x5ac99df645f3414876c816caa18b2d234024b487").Hex()
+
+	_, err := exchange.TokenDelegate(validator, 100000000, false)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	action := payload["action"].(map[string]interface{})
+	require.Equal(t, "tokenDelegate", action["type"])
+	require.Equal(t, strings.ToLower(validator), action["validator"])
+	require.Equal(t, float64(100000000), action["wei"])
+	require.Equal(t, false, action["isUndelegate"])
+	action["wei"] = uint64(action["wei"].(float64))
+	action["nonce"] = int64(action["nonce"].(float64))
+	require.Equal(t, signer, recoverUserSignedSigner(t, action, utils.TokenDelegateTypes, "HyperliquidTransaction:TokenDelegate", postedSignature(t, payload)))
+
+	for _, tt := range []struct {
+		actionType  string
+		primaryType string
+		payloadType []apitypes.Type
+		submit      func(uint64) (interface{}, error)
+	}{
+		{"cDeposit", "HyperliquidTransaction:CDeposit", utils.CDepositSignTypes, exchange.CDeposit},
+		{"cWithdraw", "HyperliquidTransaction:CWithdraw", utils.CWithdrawSignTypes, exchange.CWithdraw},
+	} {
+		_, err := tt.submit(250000000)
+		require.NoError(t, err)
+		payload := fs.lastExchangeRequest(t)
+		action := payload["action"].(map[string]interface{})
+		require.Equal(t, tt.actionType, action["type"])
+		require.Equal(t, float64(250000000), action["wei"])
+		require.Equal(t, payload["nonce"], action["nonce"])
+		action["wei"] = uint64(action["wei"].(float64))
+		action["nonce"] = int64(action["nonce"].(float64))
+		require.Equal(t, signer, recoverUserSignedSigner(t, action, tt.payloadType, tt.primaryType, postedSignature(t, payload)))
+
+		_, err = tt.submit(0)
+		require.Error(t, err)
+	}
+
+	// Flip the case of one letter to break the EIP-55 checksum
+	badChecksum := []byte(validator)
+	for i := 2; i < len(badChecksum); i++ {
+		if c := badChecksum[i]; c >= 'a' && c <= 'f' {
+			badChecksum[i] = c - 'a' + 'A'
+			break
+		}
+	}
+	_, err = exchange.TokenDelegate(string(badChecksum), 1, true)
+	require.Error(t, err)
+	_, err = exchange.TokenDelegate("0x5ac99df645f3414876c816caa18b2d23", 1, true)
+	require.Error(t, err)
+	_, err = exchange.TokenDelegate(strings.ToUpper(validator[2:]), 1, true)
+	require.NoError(t, err)
+}
+
+func TestConvertToMultiSigUser(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	userB := "0xBBBB000000000000000000000000000000000002"
+	userA := "0xaaaa000000000000000000000000000000000001"
+
+	_, err := exchange.ConvertToMultiSigUser([]string{userB, userA}, 2)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	action := payload["action"].(map[string]interface{})
+	require.Equal(t, "convertToMultiSigUser", action["type"])
+	require.Equal(t, `{"authorizedUsers":["0xaaaa000000000000000000000000000000000001","0xbbbb000000000000000000000000000000000002"],"threshold":2}`, action["signers"])
+
+	action["nonce"] = int64(action["nonce"].(float64))
+	signer := recoverUserSignedSigner(t, action, utils.ConvertToMultiSigUserSignTypes, "HyperliquidTransaction:ConvertToMultiSigUser", postedSignature(t, payload))
+	require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+
+	_, err = exchange.ConvertToMultiSigUser([]string{userA, userB}, 3)
+	require.Error(t, err)
+	_, err = exchange.ConvertToMultiSigUser([]string{userA, "0x1234"}, 1)
+	require.Error(t, err)
+	_, err = exchange.ConvertToMultiSigUser([]string{userA, strings.ToUpper(userA[2:])}, 1)
+	require.Error(t, err)
+	assert.Len(t, fs.exchangeRequests, 1)
+}
+
+func TestMultiSig(t *testing.T) {
+	fs := newFakeServer(t)
+	// The multi-sig user trades for its own vault, not the exchange's
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchangeVault := "0x0000000000000000000000000000000000000002"
+	exchange, outerKey := newTestExchange(t, fs, &exchangeVault)
+	outerSigner := crypto.PubkeyToAddress(outerKey.PublicKey)
+	multiSigUser := "0x0000000000000000000000000000000000000005"
+	nonce := utils.GetTimestampMs()
+
+	innerAction := utils.CreateSubAccountAction{Type: "createSubAccount", Name: "multi"}
+	var signatures []utils.Signature
+	var authorizedUsers []common.Address
+	for i := 0; i < 2; i++ {
+		authorizedKey, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		signature, err := utils.SignMultiSigL1ActionPayload(authorizedKey, innerAction, false, &vault, uint64(nonce), nil, multiSigUser, outerSigner.Hex())
+		require.NoError(t, err)
+		signatures = append(signatures, *signature)
+		authorizedUsers = append(authorizedUsers, crypto.PubkeyToAddress(authorizedKey.PublicKey))
+	}
+
+	_, err := exchange.MultiSig(multiSigUser, innerAction, signatures, nonce, &vault)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	require.Equal(t, float64(nonce), payload["nonce"])
+	require.Equal(t, vault, payload["vaultAddress"])
+
+	action := payload["action"].(map[string]interface{})
+	require.Equal(t, "multiSig", action["type"])
+	require.Equal(t, "0x66eee", action["signatureChainId"])
+	posted := action["signatures"].([]interface{})
+	require.Len(t, posted, 2)
+	require.Equal(t, signatures[0].R, posted[0].(map[string]interface{})["r"])
+	require.Equal(t, map[string]interface{}{
+		"multiSigUser": multiSigUser,
+		"outerSigner":  strings.ToLower(outerSigner.Hex()),
+		"action":       map[string]interface{}{"type": "createSubAccount", "name": "multi"},
+	}, action["payload"])
+
+	// The hash is the same on every call, and the posted signature is the
+	// outer signer's over it with the given vault address
+	expected := utils.MultiSigAction{
+		Type:             "multiSig",
+		SignatureChainID: "0x66eee",
+		Signatures:       signatures,
+		Payload: utils.MultiSigPayload{
+			MultiSigUser: multiSigUser,
+			OuterSigner:  strings.ToLower(outerSigner.Hex()),
+			Action:       innerAction,
+		},
+	}
+	envelope, err := utils.MultiSigEnvelope(expected, &vault, uint64(nonce), nil)
+	require.NoError(t, err)
+	for i := 0; i < 50; i++ {
+		again, err := utils.MultiSigEnvelope(expected, &vault, uint64(nonce), nil)
+		require.NoError(t, err)
+		require.Equal(t, envelope["multiSigActionHash"], again["multiSigActionHash"])
+	}
+	signer, err := utils.RecoverUserFromUserSignedAction(envelope, postedSignature(t, payload), utils.MultiSigEnvelopeSignTypes, "HyperliquidTransaction:SendMultiSig", false)
+	require.NoError(t, err)
+	assert.Equal(t, outerSigner, signer)
+
+	// Every authorized user signed the inner action for this outer signer
+	for i, signature := range signatures {
+		signature := signature
+		inner := []interface{}{multiSigUser, strings.ToLower(outerSigner.Hex()), innerAction}
+		signer, err := utils.RecoverAgentOrUserFromL1Action(inner, &signature, &vault, uint64(nonce), nil, false)
+		require.NoError(t, err)
+		assert.Equal(t, authorizedUsers[i], signer)
+	}
+
+	// Without a vault address none is hashed or posted
+	_, err = exchange.MultiSig(multiSigUser, innerAction, signatures, nonce, nil)
+	require.NoError(t, err)
+	payload = fs.lastExchangeRequest(t)
+	assert.NotContains(t, payload, "vaultAddress")
+	envelope, err = utils.MultiSigEnvelope(expected, nil, uint64(nonce), nil)
+	require.NoError(t, err)
+	signer, err = utils.RecoverUserFromUserSignedAction(envelope, postedSignature(t, payload), utils.MultiSigEnvelopeSignTypes, "HyperliquidTransaction:SendMultiSig", false)
+	require.NoError(t, err)
+	assert.Equal(t, outerSigner, signer)
+
+	_, err = exchange.MultiSig(multiSigUser, innerAction, nil, nonce, &vault)
+	require.Error(t, err)
+	_, err = exchange.MultiSig("0x05", innerAction, signatures, nonce, &vault)
+	require.Error(t, err)
+	_, err = exchange.MultiSig(multiSigUser, nil, signatures, nonce, &vault)
+	require.Error(t, err)
+	invalid := vault + "00"
+	_, err = exchange.MultiSig(multiSigUser, innerAction, signatures, nonce, &invalid)
+	assert.ErrorContains(t, err, "invalid vault address")
+}
+
+func TestMultiSigUserSignedAction(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, outerKey := newTestExchange(t, fs, nil)
+	outerSigner := strings.ToLower(crypto.PubkeyToAddress(outerKey.PublicKey).Hex())
+	multiSigUser := "0x0000000000000000000000000000000000000005"
+	nonce := utils.GetTimestampMs()
+
+	usdSend := map[string]interface{}{
+		"type":        "usdSend",
+		"destination": "0x0000000000000000000000000000000000000007",
+		"amount":      "10",
+		"time":        nonce,
+	}
+	var signatures []utils.Signature
+	var authorizedUsers []common.Address
+	for i := 0; i < 2; i++ {
+		authorizedKey, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		signature, err := utils.SignMultiSigUserSignedActionPayload(authorizedKey, usdSend, false, utils.USDSendSignTypes, "HyperliquidTransaction:UsdSend", multiSigUser, outerSigner)
+		require.NoError(t, err)
+		signatures = append(signatures, *signature)
+		authorizedUsers = append(authorizedUsers, crypto.PubkeyToAddress(authorizedKey.PublicKey))
+	}
+
+	innerAction, err := utils.NewUserSignedAction(usdSend, utils.USDSendSignTypes, false)
+	require.NoError(t, err)
+	assert.Equal(t, "usdSend", innerAction.ActionType())
+
+	// Packed in the exchange's field order, like a struct with msgpack tags
+	ordered := struct {
+		Type             string `msgpack:"type"`
+		SignatureChainID string `msgpack:"signatureChainId"`
+		HyperliquidChain string `msgpack:"hyperliquidChain"`
+		Destination      string `msgpack:"destination"`
+		Amount           string `msgpack:"amount"`
+		Time             int64  `msgpack:"time"`
+	}{"usdSend", "0x66eee", "Testnet", "0x0000000000000000000000000000000000000007", "10", nonce}
+	expectedHash, err := utils.ActionHash(ordered, nil, uint64(nonce), nil)
+	require.NoError(t, err)
+	hash, err := utils.ActionHash(innerAction, nil, uint64(nonce), nil)
+	require.NoError(t, err)
+	assert.Equal(t, expectedHash, hash)
+
+	_, err = exchange.MultiSig(multiSigUser, innerAction, signatures, nonce, nil)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	action := payload["action"].(map[string]interface{})
+	require.Equal(t, "multiSig", action["type"])
+	posted := action["payload"].(map[string]interface{})["action"].(map[string]interface{})
+	require.Equal(t, map[string]interface{}{
+		"type":             "usdSend",
+		"signatureChainId": "0x66eee",
+		"hyperliquidChain": "Testnet",
+		"destination":      "0x0000000000000000000000000000000000000007",
+		"amount":           "10",
+		"time":             float64(nonce),
+	}, posted)
+
+	// The posted signature is the outer signer's over the multiSig action
+	envelope, err := utils.MultiSigEnvelope(utils.MultiSigAction{
+		Type:             "multiSig",
+		SignatureChainID: "0x66eee",
+		Signatures:       signatures,
+		Payload: utils.MultiSigPayload{
+			MultiSigUser: multiSigUser,
+			OuterSigner:  outerSigner,
+			Action:       innerAction,
+		},
+	}, nil, uint64(nonce), nil)
+	require.NoError(t, err)
+	signer, err := utils.RecoverUserFromUserSignedAction(envelope, postedSignature(t, payload), utils.MultiSigEnvelopeSignTypes, "HyperliquidTransaction:SendMultiSig", false)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(outerKey.PublicKey), signer)
+
+	// Every authorized user signed the posted inner action for this outer signer
+	enriched := map[string]interface{}{
+		"destination":         posted["destination"],
+		"amount":              posted["amount"],
+		"time":                int64(posted["time"].(float64)),
+		"payloadMultiSigUser": multiSigUser,
+		"outerSigner":         outerSigner,
+	}
+	enrichedTypes := append(append([]apitypes.Type(nil), utils.USDSendSignTypes...),
+		apitypes.Type{Name: "payloadMultiSigUser", Type: "address"},
+		apitypes.Type{Name: "outerSigner", Type: "address"},
+	)
+	for i, signature := range signatures {
+		signature := signature
+		assert.Equal(t, authorizedUsers[i], recoverUserSignedSigner(t, enriched, enrichedTypes, "HyperliquidTransaction:UsdSend", &signature))
+	}
+
+	// Every field must be declared by the types, and every type present
+	_, err = utils.NewUserSignedAction(map[string]interface{}{"type": "usdSend", "destination": "0x0000000000000000000000000000000000000007", "amount": "10"}, utils.USDSendSignTypes, false)
+	require.Error(t, err)
+	withExtra := map[string]interface{}{"extra": "x"}
+	for key, value := range usdSend {
+		withExtra[key] = value
+	}
+	_, err = utils.NewUserSignedAction(withExtra, utils.USDSendSignTypes, false)
+	require.Error(t, err)
+	withExtra["time"] = map[string]interface{}{}
+	delete(withExtra, "extra")
+	_, err = utils.NewUserSignedAction(withExtra, utils.USDSendSignTypes, false)
+	assert.Error(t, err)
+}
+
+func TestPerpDeployActions(t *testing.T) {
+	fs := newFakeServer(t)
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchange, _ := newTestExchange(t, fs, &vault)
+	maxGas := int64(1000000)
+
+	_, err := exchange.PerpDeployRegisterAsset("test", &maxGas, "test:ABC", 2, "10.0", 10, false)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	require.NotContains(t, payload, "vaultAddress")
+	require.Equal(t, map[string]interface{}{
+		"type": "perpDeploy",
+		"registerAsset": map[string]interface{}{
+			"maxGas": float64(1000000),
+			"assetRequest": map[string]interface{}{
+				"coin": "test:ABC", "szDecimals": float64(2), "oraclePx": "10.0", "marginTableId": float64(10), "onlyIsolated": false,
+			},
+			"dex":    "test",
+			"schema": nil,
+		},
+	}, payload["action"])
+
+	_, err = exchange.PerpDeploySetOracle("test", map[string]string{"test:XYZ": "2.0", "test:ABC": "10.0"}, map[string]string{"test:ABC": "10.1"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"type": "perpDeploy",
+		"setOracle": map[string]interface{}{
+			"dex":       "test",
+			"oraclePxs": []interface{}{[]interface{}{"test:ABC", "10.0"}, []interface{}{"test:XYZ", "2.0"}},
+			"markPxs":   []interface{}{[]interface{}{"test:ABC", "10.1"}},
+		},
+	}, fs.lastExchangeRequest(t)["action"])
+}
+
+func TestSpotDeployActions(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	tests := []struct {
+		name     string
+		submit   func() (interface{}, error)
+		expected map[string]interface{}
+	}{
+		{
+			"Register token",
+			func() (interface{}, error) {
+				return exchange.SpotDeployRegisterToken("TEST0", 2, 8, 1000000000000, "Test token")
+			},
+			map[string]interface{}{"registerToken2": map[string]interface{}{
+				"spec":     map[string]interface{}{"name": "TEST0", "szDecimals": float64(2), "weiDecimals": float64(8)},
+				"maxGas":   float64(1000000000000),
+				"fullName": "Test token",
+			}},
+		},
+		{
+			"Genesis",
+			func() (interface{}, error) { return exchange.SpotDeployGenesis(1, "100000000000000", true) },
+			map[string]interface{}{"genesis": map[string]interface{}{"token": float64(1), "maxSupply": "100000000000000", "noHyperliquidity": true}},
+		},
+		{
+			"Genesis with Hyperliquidity",
+			func() (interface{}, error) { return exchange.SpotDeployGenesis(1, "100000000000000", false) },
+			map[string]interface{}{"genesis": map[string]interface{}{"token": float64(1), "maxSupply": "100000000000000"}},
+		},
+		{
+			"Register spot",
+			func() (interface{}, error) { return exchange.SpotDeployRegisterSpot(1, 0) },
+			map[string]interface{}{"registerSpot": map[string]interface{}{"tokens": []interface{}{float64(1), float64(0)}}},
+		},
+		{
+			"Set deployer trading fee share",
+			func() (interface{}, error) { return exchange.SpotDeploySetDeployerTradingFeeShare(1, "100%") },
+			map[string]interface{}{"setDeployerTradingFeeShare": map[string]interface{}{"token": float64(1), "share": "100%"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.submit()
+			require.NoError(t, err)
+			tt.expected["type"] = "spotDeploy"
+			require.Equal(t, tt.expected, fs.lastExchangeRequest(t)["action"])
+		})
+	}
+
+	_, err := exchange.SpotDeploySetDeployerTradingFeeShare(1, "1.0")
+	require.Error(t, err)
+}
+
+func TestPerpDexClassTransfer(t *testing.T) {
+	fs := newFakeServer(t)
+	vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+	exchange, privateKey := newTestExchange(t, fs, &vault)
+
+	_, err := exchange.PerpDexClassTransfer("test", "USDC", 10.5, true)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	require.NotContains(t, payload, "vaultAddress")
+	action := payload["action"].(map[string]interface{})
+	require.Equal(t, "PerpDexClassTransfer", action["type"])
+	require.Equal(t, "test", action["dex"])
+	require.Equal(t, "USDC", action["token"])
+	require.Equal(t, "10.5", action["amount"])
+	require.Equal(t, true, action["toPerp"])
+	require.Equal(t, "Testnet", action["hyperliquidChain"])
+	require.Equal(t, payload["nonce"], action["nonce"])
+
+	action["nonce"] = int64(action["nonce"].(float64))
+	signer := recoverUserSignedSigner(t, action, utils.PerpDexClassTransferSignTypes, "HyperliquidTransaction:PerpDexClassTransfer", postedSignature(t, payload))
+	require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+}
+
+func TestUsdClassTransfer(t *testing.T) {
+	subAccount := "0x1D9470D4B963F552E6F671A81619D395877BF409"
+	tests := []struct {
+		name       string
+		subAccount *string
+		amount     string
+	}{
+		{"plain", nil, "12.500000"},
+		{"sub-account", &subAccount, "12.500000 subaccount:0x1d9470d4b963f552e6f671a81619d395877bf409"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			vault := "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+			exchange, privateKey := newTestExchange(t, fs, &vault)
+
+			_, err := exchange.UsdClassTransfer(12.5, true, tt.subAccount)
+			require.NoError(t, err)
+			payload := fs.lastExchangeRequest(t)
+			require.NotContains(t, payload, "vaultAddress")
+			action := payload["action"].(map[string]interface{})
+			require.Equal(t, "usdClassTransfer", action["type"])
+			require.Equal(t, tt.amount, action["amount"])
+			require.Equal(t, true, action["toPerp"])
+			require.Equal(t, payload["nonce"], action["nonce"])
+
+			// The signature must cover the posted action exactly
+			action["nonce"] = int64(action["nonce"].(float64))
+			signer := recoverUserSignedSigner(t, action, utils.USDClassTransferSignTypes, "HyperliquidTransaction:UsdClassTransfer", postedSignature(t, payload))
+			require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+		})
+	}
+
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	invalid := "0x1d9470"
+	_, err := exchange.UsdClassTransfer(1, false, &invalid)
+	require.Error(t, err)
+	require.Empty(t, fs.exchangeRequests)
+}
+
+// TestUserSignedPostedActionMatchesSigned checks that every user-signed
+// action is posted with exactly the fields it was signed with
+func TestUserSignedPostedActionMatchesSigned(t *testing.T) {
+	destination := "0x5e9ee1089755c3435139848e47e6635505d5a13a"
+	tests := []struct {
+		name         string
+		send         func(exchange *hyperliquid.Exchange) error
+		payloadTypes []apitypes.Type
+		primaryType  string
+	}{
+		{"usdSend", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.UsdTransfer(1, destination)
+			return err
+		}, utils.USDSendSignTypes, "HyperliquidTransaction:UsdSend"},
+		{"usdClassTransfer", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.UsdClassTransfer(1, true, nil)
+			return err
+		}, utils.USDClassTransferSignTypes, "HyperliquidTransaction:UsdClassTransfer"},
+		{"perpDexClassTransfer", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.PerpDexClassTransfer("test", "USDC", 1, true)
+			return err
+		}, utils.PerpDexClassTransferSignTypes, "HyperliquidTransaction:PerpDexClassTransfer"},
+		{"sendAsset", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.SendAsset(destination, "", "spot", "USDC", "1", "")
+			return err
+		}, utils.SendAssetSignTypes, "HyperliquidTransaction:SendAsset"},
+		{"tokenDelegate", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.TokenDelegate(destination, 100, false)
+			return err
+		}, utils.TokenDelegateTypes, "HyperliquidTransaction:TokenDelegate"},
+		{"cDeposit", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.CDeposit(100)
+			return err
+		}, utils.CDepositSignTypes, "HyperliquidTransaction:CDeposit"},
+		{"approveBuilderFee", func(exchange *hyperliquid.Exchange) error {
+			_, err := exchange.ApproveBuilderFee(destination, "0.001%")
+			return err
+		}, utils.ApproveBuilderFeeSignTypes, "HyperliquidTransaction:ApproveBuilderFee"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			exchange, privateKey := newTestExchange(t, fs, nil)
+			require.NoError(t, tt.send(exchange))
+
+			payload := fs.lastExchangeRequest(t)
+			action := payload["action"].(map[string]interface{})
+			fields := []string{"type", "signatureChainId"}
+			for _, field := range tt.payloadTypes {
+				fields = append(fields, field.Name)
+				if field.Type == "uint64" {
+					action[field.Name] = int64(action[field.Name].(float64))
+				}
+			}
+			posted := make([]string, 0, len(action))
+			for field := range action {
+				posted = append(posted, field)
+			}
+			require.ElementsMatch(t, fields, posted)
+			require.Equal(t, utils.DefaultSignatureChainID, action["signatureChainId"])
+
+			signer := recoverUserSignedSigner(t, action, tt.payloadTypes, tt.primaryType, postedSignature(t, payload))
+			require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+		})
+	}
+}
+
+func TestNonceManagerConcurrent(t *testing.T) {
+	nonces := hyperliquid.NewNonceManager()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	seen := make(map[int64]bool)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first := nonces.Next()
+			second := nonces.Next()
+			assert.Greater(t, second, first)
+
+			mu.Lock()
+			defer mu.Unlock()
+			seen[first] = true
+			seen[second] = true
+		}()
+	}
+	wg.Wait()
+	require.Len(t, seen, 200)
+
+	// Nonces keep increasing after the burst and respect a raised minimum
+	last := nonces.Next()
+	minNonce := last + 1_000_000
+	nonces.SetMinNonce(minNonce)
+	require.Equal(t, minNonce, nonces.Next())
+	nonces.SetMinNonce(last)
+	require.Equal(t, minNonce+1, nonces.Next())
+}
+
+func TestConcurrentOrdersUseUniqueNonces(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(oid int) {
+			defer wg.Done()
+			_, err := exchange.Cancel("BTC", oid)
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	require.Len(t, fs.exchangeRequests, 100)
+	seen := make(map[float64]bool)
+	for _, payload := range fs.exchangeRequests {
+		seen[payload["nonce"].(float64)] = true
+	}
+	require.Len(t, seen, 100)
+}
+
+func TestOrderResponse(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = loadCassette(t, "order_statuses.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	response, err := exchange.Order("BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	var partialErr *utils.PartialFailureError
+	require.ErrorAs(t, err, &partialErr)
+	require.Equal(t, 3, partialErr.Total)
+	require.Equal(t, []utils.OrderError{{Index: 2, Message: "Order must have minimum value of $10."}}, partialErr.Failed)
+	require.Equal(t, "1 of 3 orders rejected: #2: Order must have minimum value of $10.", err.Error())
+	require.ErrorIs(t, err, utils.ErrMinOrderValue)
+	require.NotErrorIs(t, err, utils.ErrInsufficientMargin)
+
+	require.NotNil(t, response)
+	require.Equal(t, "ok", response.Status)
+	require.Equal(t, "order", response.Response.Type)
+	require.JSONEq(t, loadCassette(t, "order_statuses.json"), string(response.Raw))
+
+	statuses := response.Response.Data.Statuses
+	require.Len(t, statuses, 3)
+	cloid := "0x00000000000000000000000000000001"
+	require.Equal(t, &utils.RestingOrder{Oid: 77738308, Cloid: &cloid}, statuses[0].Resting)
+	require.Equal(t, &utils.FilledOrder{Oid: 77747314, TotalSz: "0.02", AvgPx: "1891.4"}, statuses[1].Filled)
+	require.Equal(t, "Order must have minimum value of $10.", statuses[2].Error)
+}
+
+func TestModifyOrder(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	cloid := "0x00000000000000000000000000000001"
+
+	_, err := exchange.ModifyOrder(12345, "BTC", true, 0.01, 50000, orderType, false, &cloid)
+	require.NoError(t, err)
+	payload := fs.lastExchangeRequest(t)
+	require.Equal(t, map[string]interface{}{
+		"type": "batchModify",
+		"modifies": []interface{}{map[string]interface{}{
+			"oid": float64(12345),
+			"order": map[string]interface{}{
+				"a": float64(0), "b": true, "p": "50000", "s": "0.01", "r": false,
+				"t": map[string]interface{}{"limit": map[string]interface{}{"tif": "Gtc"}},
+				"c": cloid,
+			},
+		}},
+	}, payload["action"])
+
+	action := utils.BatchModifyAction{Type: "batchModify", Modifies: []utils.ModifyWire{{
+		OID:   12345,
+		Order: utils.OrderWire{A: 0, B: true, P: "50000", S: "0.01", T: utils.OrderTypeWire{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}, C: &cloid},
+	}}}
+	signer, err := utils.RecoverAgentOrUserFromL1Action(action, postedSignature(t, payload), nil, uint64(payload["nonce"].(float64)), nil, false)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+
+	// Orders are modified by cloid too
+	_, err = exchange.ModifyOrder(cloid, "BTC", true, 0.01, 50000, orderType, false, nil)
+	require.NoError(t, err)
+	modify := fs.lastExchangeRequest(t)["action"].(map[string]interface{})["modifies"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, cloid, modify["oid"])
+
+	requests := len(fs.exchangeRequests)
+	_, err = exchange.ModifyOrder("0x01", "BTC", true, 0.01, 50000, orderType, false, nil)
+	assert.Error(t, err)
+	_, err = exchange.ModifyOrder(1.5, "BTC", true, 0.01, 50000, orderType, false, nil)
+	assert.Error(t, err)
+	_, err = exchange.ModifyOrder(12345, "BTC", true, 0.01, 50000.5, orderType, false, nil)
+	assert.ErrorAs(t, err, new(*hyperliquid.InvalidOrderError))
+	assert.Len(t, fs.exchangeRequests, requests)
+}
+
+func TestReprice(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("orderStatus", loadCassette(t, "order_status.json"))
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	_, err := exchange.Reprice("ETH", 77738308, 1990.47, 0)
+	require.NoError(t, err)
+	assert.Equal(t, float64(77738308), fs.lastInfoRequest(t)["oid"])
+	modify := fs.lastExchangeRequest(t)["action"].(map[string]interface{})["modifies"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, float64(77738308), modify["oid"])
+	assert.Equal(t, map[string]interface{}{
+		"a": float64(1), "b": true, "p": "1990.5", "s": "0.5", "r": false,
+		"t": map[string]interface{}{"limit": map[string]interface{}{"tif": "Alo"}},
+		"c": "0x00000000000000000000000000000001",
+	}, modify["order"], "side, size, time in force and cloid are kept")
+
+	// Trigger orders keep their trigger
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "order_status.json")), &entry))
+	order := entry["order"].(map[string]interface{})["order"].(map[string]interface{})
+	order["side"] = "A"
+	order["isTrigger"] = true
+	order["orderType"] = "Take Profit Market"
+	order["triggerPx"] = "2100.0"
+	order["reduceOnly"] = true
+	fs.setInfoResponse("orderStatus", mustJSON(t, entry))
+	_, err = exchange.Reprice("ETH", "0x00000000000000000000000000000001", 2090, 0.25)
+	require.NoError(t, err)
+	assert.Equal(t, "0x00000000000000000000000000000001", fs.lastInfoRequest(t)["oid"])
+	modify = fs.lastExchangeRequest(t)["action"].(map[string]interface{})["modifies"].([]interface{})[0].(map[string]interface{})
+	wire := modify["order"].(map[string]interface{})
+	assert.Equal(t, false, wire["b"])
+	assert.Equal(t, true, wire["r"])
+	assert.Equal(t, "0.25", wire["s"])
+	assert.Equal(t, map[string]interface{}{"trigger": map[string]interface{}{"isMarket": true, "triggerPx": "2100", "tpsl": "tp"}}, wire["t"])
+
+	// An order filled before the lookup or before the modify is gone
+	requests := len(fs.exchangeRequests)
+	fs.setInfoResponse("orderStatus", `{"status": "unknownOid"}`)
+	_, err = exchange.Reprice("ETH", 77738308, 1990, 0)
+	assert.ErrorIs(t, err, hyperliquid.ErrOrderGone)
+	entry["order"].(map[string]interface{})["status"] = "filled"
+	fs.setInfoResponse("orderStatus", mustJSON(t, entry))
+	_, err = exchange.Reprice("ETH", 77738308, 1990, 0)
+	assert.ErrorIs(t, err, hyperliquid.ErrOrderGone)
+	assert.Len(t, fs.exchangeRequests, requests)
+
+	fs.setInfoResponse("orderStatus", loadCassette(t, "order_status.json"))
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "order", "data": {"statuses": [{"error": "Cannot modify canceled or filled order"}]}}}`
+	response, err := exchange.Reprice("ETH", 77738308, 1990, 0)
+	assert.ErrorIs(t, err, hyperliquid.ErrOrderGone)
+	require.NotNil(t, response)
+
+	_, err = exchange.Reprice("BTC", 77738308, 1990, 0)
+	assert.ErrorContains(t, err, "is on ETH")
+}
+
+func TestCancelResponse(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = loadCassette(t, "cancel_statuses.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	response, err := exchange.BulkCancel([]utils.CancelRequest{{Coin: "BTC", OID: 1}, {Coin: "BTC", OID: 2}})
+	var partialErr *utils.PartialFailureError
+	require.ErrorAs(t, err, &partialErr)
+	require.Equal(t, []utils.OrderError{{Index: 1, Message: "Order was never placed, already canceled, or filled."}}, partialErr.Failed)
+	require.ErrorIs(t, err, utils.ErrOrderNotFound)
+	require.NotNil(t, response)
+	require.Equal(t, []utils.CancelStatus{
+		{Success: true},
+		{Error: "Order was never placed, already canceled, or filled."},
+	}, response.Response.Data.Statuses)
+}
+
+func TestExchangeErrorResponse(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = loadCassette(t, "exchange_err.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	_, err := exchange.Order("BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	var exchangeErr *utils.ExchangeError
+	require.ErrorAs(t, err, &exchangeErr)
+	require.Equal(t, "err", exchangeErr.Status)
+	require.Equal(t, "User or API Wallet 0x0000000000000000000000000000000000000001 does not exist.", exchangeErr.Message)
+	require.ErrorIs(t, err, utils.ErrUnknownUser)
+
+	_, err = exchange.Cancel("BTC", 1)
+	require.ErrorAs(t, err, &exchangeErr)
+
+	_, err = exchange.UpdateLeverage(10, "BTC", true)
+	require.ErrorAs(t, err, &exchangeErr)
+	require.Equal(t, "User or API Wallet 0x0000000000000000000000000000000000000001 does not exist.", exchangeErr.Message)
+}
+
+// errorKinds are the sentinel errors of the kinds in error_messages.json
+var errorKinds = map[string]error{
+	"rateLimited":         utils.ErrRateLimited,
+	"insufficientMargin":  utils.ErrInsufficientMargin,
+	"insufficientBalance": utils.ErrInsufficientBalance,
+	"orderNotFound":       utils.ErrOrderNotFound,
+	"nonceStale":          utils.ErrNonceStale,
+	"invalidPrice":        utils.ErrInvalidPrice,
+	"invalidSize":         utils.ErrInvalidSize,
+	"minOrderValue":       utils.ErrMinOrderValue,
+	"postOnlyWouldMatch":  utils.ErrPostOnlyWouldMatch,
+	"noImmediateMatch":    utils.ErrNoImmediateMatch,
+	"reduceOnly":          utils.ErrReduceOnly,
+	"unknownUser":         utils.ErrUnknownUser,
+}
+
+func TestClassifyMessage(t *testing.T) {
+	var corpus []struct {
+		Message string `json:"message"`
+		Kind    string `json:"kind"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "error_messages.json")), &corpus))
+
+	for _, tt := range corpus {
+		t.Run(tt.Message, func(t *testing.T) {
+			expected := errorKinds[tt.Kind]
+			require.Equal(t, expected, utils.ClassifyMessage(tt.Message))
+
+			// Every error carrying an exchange message matches its kind and
+			// keeps the message
+			errs := []error{
+				&utils.ExchangeError{Status: "err", Message: tt.Message},
+				&utils.ClientError{StatusCode: 400, ErrorMessage: tt.Message},
+				&utils.PartialFailureError{Total: 2, Failed: []utils.OrderError{{Index: 1, Message: tt.Message}}},
+				&hyperliquid.PostError{Message: tt.Message},
+			}
+			for _, err := range errs {
+				assert.Contains(t, err.Error(), tt.Message)
+				for _, kind := range errorKinds {
+					assert.Equal(t, kind == expected, errors.Is(err, kind), "%T %v", err, kind)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderResponseSuccess(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = loadCassette(t, "order_tpsl.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	response, err := exchange.BulkOrders([]utils.OrderRequest{
+		{Coin: "BTC", IsBuy: true, Sz: 0.01, LimitPx: 100000, OrderType: orderType},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, response.Response.Data.Statuses, 3)
+
+	fs.exchangeResponse = loadCassette(t, "exchange_ok.json")
+	_, err = exchange.UpdateLeverage(10, "BTC", true)
+	require.NoError(t, err)
+}
+
+func TestValidateOrder(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	limit := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+
+	// BTC has 5 size decimals, ETH 4, PURR 0 and HFUN (@1) 2
+	tests := []struct {
+		name    string
+		order   utils.OrderRequest
+		field   string
+		nearest float64
+	}{
+		{"Valid perp", utils.OrderRequest{Coin: "BTC", Sz: 0.00001, LimitPx: 50000}, "", 0},
+		{"Perp integer price", utils.OrderRequest{Coin: "BTC", Sz: 0.01, LimitPx: 123456}, "", 0},
+		{"Perp price decimals", utils.OrderRequest{Coin: "ETH", Sz: 0.1, LimitPx: 3.14159}, "limitPx", 3.14},
+		{"Perp significant figures", utils.OrderRequest{Coin: "BTC", Sz: 0.1, LimitPx: 50000.6}, "limitPx", 50001},
+		{"Perp size decimals", utils.OrderRequest{Coin: "ETH", Sz: 0.123456, LimitPx: 3000}, "sz", 0.1235},
+		{"Perp trigger price", utils.OrderRequest{Coin: "BTC", Sz: 0.1, LimitPx: 50000,
+			OrderType: utils.OrderType{Trigger: &utils.TriggerOrderType{TriggerPx: 49999.9, IsMarket: true, TPSL: utils.TPSLSl}}}, "triggerPx", 50000},
+		{"Valid spot", utils.OrderRequest{Coin: "PURR/USDC", Sz: 10, LimitPx: 0.12345}, "", 0},
+		{"Spot price decimals", utils.OrderRequest{Coin: "@1", Sz: 1, LimitPx: 0.0000123456}, "limitPx", 0.000012},
+		{"Spot significant figures", utils.OrderRequest{Coin: "PURR/USDC", Sz: 10, LimitPx: 0.123456}, "limitPx", 0.12346},
+		{"Spot size decimals", utils.OrderRequest{Coin: "PURR/USDC", Sz: 10.6, LimitPx: 0.1}, "sz", 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.order.OrderType.Trigger == nil {
+				tt.order.OrderType = limit
+			}
+			err := exchange.ValidateOrder(tt.order)
+			if tt.field == "" {
+				require.NoError(t, err)
+				return
+			}
+			var invalid *hyperliquid.InvalidOrderError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.order.Coin, invalid.Coin)
+			assert.Equal(t, tt.field, invalid.Field)
+			assert.Equal(t, tt.nearest, invalid.Nearest)
+			assert.Contains(t, err.Error(), tt.field)
+		})
+	}
+
+	assert.Error(t, exchange.ValidateOrder(utils.OrderRequest{Coin: "NOPE", Sz: 1, LimitPx: 1}))
+
+	// Orders are validated before they are signed, unless disabled
+	_, err := exchange.Order("ETH", true, 0.123456, 3000, limit, false, nil, nil)
+	var invalid *hyperliquid.InvalidOrderError
+	require.ErrorAs(t, err, &invalid)
+	assert.Empty(t, fs.exchangeRequests)
+
+	exchange.SetOrderValidation(false)
+	_, err = exchange.Order("ETH", true, 0.1235, 3000.05, limit, false, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "3000.05", fs.lastExchangeRequest(t)["action"].(map[string]interface{})["orders"].([]interface{})[0].(map[string]interface{})["p"])
+}
+
+func TestMarketOrderSlippagePrice(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("allMids", `{"BTC": "123456", "ETH": "3000.5", "kPEPE": "0.0123456", "PURR/USDC": "0.123456", "@1": "12.3456"}`)
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	meta := hyperliquid.Meta{Universe: []hyperliquid.AssetInfo{
+		{Name: "BTC", SzDecimals: 5, MaxLeverage: 40},
+		{Name: "ETH", SzDecimals: 4, MaxLeverage: 25},
+		{Name: "kPEPE", SzDecimals: 0, MaxLeverage: 10},
+	}}
+	var spotMeta hyperliquid.SpotMeta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "spot_meta.json")), &spotMeta))
+	exchange, err := hyperliquid.NewExchange(privateKey, fs.URL, &meta, nil, nil, &spotMeta, nil, 5*time.Second)
+	require.NoError(t, err)
+
+	// Prices keep 5 significant figures and at most 6 (perps) or 8 (spot)
+	// decimals less the size decimals; integer prices are always valid
+	tests := []struct {
+		name  string
+		isBuy bool
+		sz    float64
+		px    string
+	}{
+		{"BTC", true, 0.001, "129629"},
+		{"BTC", false, 0.001, "117283"},
+		{"ETH", true, 0.1, "3150.5"},
+		{"kPEPE", true, 1000, "0.012963"},
+		{"kPEPE", false, 1000, "0.011728"},
+		{"PURR/USDC", true, 100, "0.12963"},
+		{"@1", false, 1, "11.728"},
+	}
+	for _, tt := range tests {
+		_, err := exchange.MarketOpen(tt.name, tt.isBuy, tt.sz, nil, 0.05, nil, nil)
+		require.NoError(t, err, tt.name)
+		action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+		order := action["orders"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, tt.px, order["p"], tt.name)
+	}
+}
+
+func TestMarketOrderReference(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("allMids", `{"BTC": "50000"}`)
+	fs.setInfoResponse("l2Book", `{"coin": "BTC", "time": 1, "levels": [[{"px": "49000", "sz": "1", "n": 1}], [{"px": "51000", "sz": "1", "n": 1}]]}`)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	lastPx := func() string {
+		action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+		return action["orders"].([]interface{})[0].(map[string]interface{})["p"].(string)
+	}
+
+	// The mid by default
+	response, err := exchange.MarketOpen("BTC", true, 0.01, nil, 0.05, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, utils.PriceReferenceMid, response.Reference)
+	assert.Equal(t, 50000.0, response.ReferencePx)
+	assert.Equal(t, "52500", lastPx())
+	assert.Equal(t, "allMids", fs.lastInfoRequest(t)["type"])
+
+	// The best opposing level once the default is changed
+	exchange.SetMarketReference(utils.PriceReferenceBBO)
+	response, err = exchange.MarketOpen("BTC", true, 0.01, nil, 0.05, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, utils.PriceReferenceBBO, response.Reference)
+	assert.Equal(t, 51000.0, response.ReferencePx)
+	assert.Equal(t, "53550", lastPx())
+	assert.Equal(t, "l2Book", fs.lastInfoRequest(t)["type"])
+
+	response, err = exchange.MarketOpen("BTC", false, 0.01, nil, 0.05, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 49000.0, response.ReferencePx)
+	assert.Equal(t, "46550", lastPx())
+
+	// Options override the default, and a given price overrides both
+	response, err = exchange.MarketOpen("BTC", true, 0.01, nil, 0.05, nil, nil, hyperliquid.MarketOptions{Reference: utils.PriceReferenceMid})
+	require.NoError(t, err)
+	assert.Equal(t, utils.PriceReferenceMid, response.Reference)
+	assert.Equal(t, "52500", lastPx())
+
+	px := 40000.0
+	response, err = exchange.MarketOpen("BTC", true, 0.01, &px, 0.05, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, utils.PriceReferenceGiven, response.Reference)
+	assert.Equal(t, "42000", lastPx())
+
+	// An empty side of the book falls back to the mid
+	fs.setInfoResponse("l2Book", `{"coin": "BTC", "time": 2, "levels": [[{"px": "49000", "sz": "1", "n": 1}], []]}`)
+	response, err = exchange.MarketOpen("BTC", true, 0.01, nil, 0.05, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, utils.PriceReferenceMid, response.Reference)
+	assert.Equal(t, "52500", lastPx())
+}
+
+func TestClearinghouseState(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("clearinghouseState", loadCassette(t, "clearinghouse_state.json"))
+	info := newTestInfo(t, fs)
+
+	state, err := info.ClearinghouseState("0x1", "xyz")
+	require.NoError(t, err)
+	assert.Equal(t, "xyz", fs.lastInfoRequest(t)["dex"])
+	assert.Equal(t, utils.DecimalString("13109.482328"), state.MarginSummary.AccountValue)
+	require.Len(t, state.AssetPositions, 3)
+	btc := state.AssetPositions[0].Position
+	assert.Equal(t, "BTC", btc.Coin)
+	assert.Nil(t, btc.LiquidationPx)
+	eth := state.AssetPositions[1].Position
+	assert.Equal(t, utils.DecimalString("-1.2345"), eth.Szi)
+	assert.Equal(t, "isolated", eth.Leverage.Type)
+	require.NotNil(t, eth.LiquidationPx)
+	assert.Equal(t, utils.DecimalString("3250.4"), *eth.LiquidationPx)
+}
+
+func TestMarketClosePercent(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("clearinghouseState", loadCassette(t, "clearinghouse_state.json"))
+	fs.setInfoResponse("allMids", `{"BTC": "50000", "ETH": "3000"}`)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	lastOrder := func() map[string]interface{} {
+		action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+		return action["orders"].([]interface{})[0].(map[string]interface{})
+	}
+
+	// BTC has 5 size decimals and ETH 4
+	tests := []struct {
+		coin    string
+		percent float64
+		sz      string
+	}{
+		{"BTC", 0, "0.01234"},
+		{"BTC", 50, "0.00617"},
+		{"BTC", 33.3, "0.00411"},
+		{"ETH", 25, "0.3086"},
+		{"ETH", 99.999, "1.2345"}, // The remainder rounds to zero
+		{"ETH", 100, "1.2345"},
+	}
+	for _, tt := range tests {
+		_, err := exchange.MarketClose(tt.coin, nil, nil, 0.05, nil, nil, hyperliquid.MarketOptions{ClosePercent: tt.percent})
+		require.NoError(t, err, tt.coin)
+		order := lastOrder()
+		assert.Equal(t, tt.sz, order["s"], "%s %g%%", tt.coin, tt.percent)
+		assert.Equal(t, tt.coin == "ETH", order["b"], "shorts are closed by buying")
+		assert.Equal(t, true, order["r"])
+	}
+
+	requests := len(fs.exchangeRequests)
+	_, err := exchange.MarketClose("BTC", nil, nil, 0.05, nil, nil, hyperliquid.MarketOptions{ClosePercent: 0.01})
+	assert.ErrorContains(t, err, "rounds to zero")
+	_, err = exchange.MarketClose("BTC", nil, nil, 0.05, nil, nil, hyperliquid.MarketOptions{ClosePercent: 150})
+	assert.Error(t, err)
+	_, err = exchange.MarketClose("SOL", nil, nil, 0.05, nil, nil)
+	assert.ErrorContains(t, err, "position not found")
+	assert.Len(t, fs.exchangeRequests, requests)
+}
+
+func TestCloseAllPositions(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("clearinghouseState", loadCassette(t, "clearinghouse_state.json"))
+	fs.setInfoResponse("allMids", `{"BTC": "50000", "ETH": "3000"}`)
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "order", "data": {"statuses": [{"filled": {"totalSz": "0.01234", "avgPx": "49990", "oid": 1}}, {"error": "Reduce only order would increase position."}]}}}`
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	closed, err := exchange.CloseAllPositions(0.05)
+	var partial *utils.PartialFailureError
+	require.ErrorAs(t, err, &partial)
+	require.Len(t, closed, 2, "the empty ATOM position is skipped")
+	assert.Equal(t, "BTC", closed[0].Coin)
+	assert.False(t, closed[0].IsBuy)
+	assert.Equal(t, 0.01234, closed[0].Sz)
+	assert.Equal(t, 47500.0, closed[0].LimitPx)
+	require.NotNil(t, closed[0].Status.Filled)
+	assert.Equal(t, "ETH", closed[1].Coin)
+	assert.True(t, closed[1].IsBuy)
+	assert.Equal(t, 3150.0, closed[1].LimitPx)
+	assert.Equal(t, "Reduce only order would increase position.", closed[1].Status.Error)
+
+	require.Len(t, fs.exchangeRequests, 1, "one bulk order")
+	orders := fs.lastExchangeRequest(t)["action"].(map[string]interface{})["orders"].([]interface{})
+	require.Len(t, orders, 2)
+	for _, order := range orders {
+		order := order.(map[string]interface{})
+		assert.Equal(t, true, order["r"])
+		assert.Equal(t, map[string]interface{}{"limit": map[string]interface{}{"tif": "Ioc"}}, order["t"])
+	}
+
+	fs.setInfoResponse("clearinghouseState", `{"assetPositions": []}`)
+	closed, err = exchange.CloseAllPositions(0.05)
+	require.NoError(t, err)
+	assert.Empty(t, closed)
+	assert.Len(t, fs.exchangeRequests, 1)
+}
+
+func TestCancelAllOrders(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("openOrders", `[
+		{"coin": "BTC", "side": "B", "limitPx": "29792.0", "sz": "0.01", "oid": 91490942, "timestamp": 1681247412573, "origSz": "0.01"},
+		{"coin": "@1", "side": "A", "limitPx": "12.5", "sz": "2.0", "oid": 91490943, "timestamp": 1681247412574, "origSz": "2.0"},
+		{"coin": "BTC", "side": "A", "limitPx": "31000.0", "sz": "0.02", "oid": 91490944, "timestamp": 1681247412575, "origSz": "0.02", "cloid": "0x00000000000000000000000000000001"}
+	]`)
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "cancel", "data": {"statuses": ["success", {"error": "Order was never placed, already canceled, or filled. asset=0"}, {"error": "Insufficient margin."}]}}}`
+	vault := "0x0000000000000000000000000000000000000002"
+	exchange, _ := newTestExchange(t, fs, &vault)
+
+	result, err := exchange.CancelAllOrders(nil)
+	assert.Equal(t, vault, fs.lastInfoRequest(t)["user"], "orders of the vault")
+	var partial *utils.PartialFailureError
+	require.ErrorAs(t, err, &partial)
+	assert.Equal(t, []utils.OrderError{{Index: 2, Message: "Insufficient margin."}}, partial.Failed)
+	require.Len(t, result.Orders, 3)
+	assert.Equal(t, []utils.CancelStatus{{Success: true}, {Success: true}, {Error: "Insufficient margin."}}, result.Statuses, "filled orders count as cancelled")
+
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"a": float64(0), "o": float64(91490942)},
+		map[string]interface{}{"a": float64(10001), "o": float64(91490943)},
+		map[string]interface{}{"a": float64(0), "o": float64(91490944)},
+	}, action["cancels"])
+
+	// Only the orders on one coin
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "cancel", "data": {"statuses": ["success", "success"]}}}`
+	coin := "BTC"
+	result, err = exchange.CancelAllOrders(&coin)
+	require.NoError(t, err)
+	require.Len(t, result.Orders, 2)
+	action = fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	assert.Len(t, action["cancels"], 2)
+
+	// Nothing to cancel sends nothing
+	requests := len(fs.exchangeRequests)
+	fs.setInfoResponse("openOrders", `[]`)
+	result, err = exchange.CancelAllOrders(nil)
+	require.NoError(t, err)
+	assert.Empty(t, result.Orders)
+	assert.Len(t, fs.exchangeRequests, requests)
+}
+
+func TestCancelAllOrdersBatches(t *testing.T) {
+	fs := newFakeServer(t)
+	var orders []map[string]interface{}
+	for oid := 1; oid <= 41; oid++ {
+		orders = append(orders, map[string]interface{}{"coin": "ETH", "side": "B", "limitPx": "1000.0", "sz": "1.0", "oid": oid, "timestamp": 1, "origSz": "1.0"})
+	}
+	fs.setInfoResponse("openOrders", mustJSON(t, orders))
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "cancel", "data": {"statuses": ["success"]}}}`
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	cancelCounts := func() []int {
+		t.Helper()
+		fs.mu.Lock()
+		defer fs.mu.Unlock()
+		var counts []int
+		for _, request := range fs.exchangeRequests {
+			counts = append(counts, len(request["action"].(map[string]interface{})["cancels"].([]interface{})))
+		}
+		fs.exchangeRequests = nil
+		return counts
+	}
+
+	_, err := exchange.CancelAllOrders(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{40, 1}, cancelCounts())
+
+	// The batch size of the exchange applies, and 0 sends a single action
+	exchange.SetBatchSize(15)
+	_, err = exchange.CancelAllOrders(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{15, 15, 11}, cancelCounts())
+
+	exchange.SetBatchSize(0)
+	_, err = exchange.CancelAllOrders(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []int{41}, cancelCounts())
+}
+
+func TestBulkOrdersBatches(t *testing.T) {
+	fs := newFakeServer(t)
+	// Every order rests with its price as oid, except the one priced 10095
+	fs.exchangeReply = func(payload map[string]interface{}) string {
+		action := payload["action"].(map[string]interface{})
+		var statuses []interface{}
+		for _, order := range action["orders"].([]interface{}) {
+			oid, _ := strconv.Atoi(order.(map[string]interface{})["p"].(string))
+			if oid == 10095 {
+				statuses = append(statuses, map[string]interface{}{"error": "Insufficient margin to place order."})
+				continue
+			}
+			statuses = append(statuses, map[string]interface{}{"resting": map[string]interface{}{"oid": oid}})
+		}
+		return mustJSON(t, map[string]interface{}{"status": "ok", "response": map[string]interface{}{"type": "order", "data": map[string]interface{}{"statuses": statuses}}})
+	}
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	orders := make([]utils.OrderRequest, 150)
+	for i := range orders {
+		orders[i] = utils.OrderRequest{Coin: "BTC", IsBuy: true, Sz: 0.001, LimitPx: float64(10000 + i), OrderType: orderType}
+	}
+
+	for _, concurrency := range []int{1, 3} {
+		fs.mu.Lock()
+		fs.exchangeRequests = nil
+		fs.mu.Unlock()
+		exchange.SetBatchConcurrency(concurrency)
+
+		response, err := exchange.BulkOrders(orders, nil)
+		var partial *utils.PartialFailureError
+		require.ErrorAs(t, err, &partial)
+		assert.Equal(t, []utils.OrderError{{Index: 95, Message: "Insufficient margin to place order."}}, partial.Failed)
+		assert.Equal(t, 150, partial.Total)
+
+		statuses := response.Response.Data.Statuses
+		require.Len(t, statuses, 150)
+		for i, status := range statuses {
+			if i != 95 {
+				require.NotNil(t, status.Resting, i)
+				assert.Equal(t, 10000+i, status.Resting.Oid, "statuses keep input order")
+			}
+		}
+
+		var sizes []int
+		nonces := map[interface{}]bool{}
+		for _, request := range fs.exchangeRequests {
+			sizes = append(sizes, len(request["action"].(map[string]interface{})["orders"].([]interface{})))
+			nonces[request["nonce"]] = true
+		}
+		sort.Ints(sizes)
+		assert.Equal(t, []int{30, 40, 40, 40}, sizes)
+		assert.Len(t, nonces, 4, "every batch has its own nonce")
+	}
+
+	// A batch failing outright stops the ones after it
+	exchange.SetBatchConcurrency(1)
+	reply := fs.exchangeReply
+	fs.exchangeReply = func(payload map[string]interface{}) string {
+		if len(fs.exchangeRequests) == 2 {
+			return `{"status": "err", "response": "Too many requests."}`
+		}
+		return reply(payload)
+	}
+	fs.exchangeRequests = nil
+	response, err := exchange.BulkOrders(orders, nil)
+	var exchangeErr *utils.ExchangeError
+	require.ErrorAs(t, err, &exchangeErr)
+	assert.ErrorContains(t, err, "batch 2 of 4")
+	assert.Len(t, fs.exchangeRequests, 2)
+	statuses := response.Response.Data.Statuses
+	require.Len(t, statuses, 150)
+	assert.Equal(t, 10039, statuses[39].Resting.Oid)
+	assert.Contains(t, statuses[40].Error, "Too many requests.")
+	assert.Contains(t, statuses[149].Error, "not sent")
+
+	// Without batching every order goes in one action
+	exchange.SetBatchSize(0)
+	fs.exchangeReply = reply
+	fs.exchangeRequests = nil
+	_, err = exchange.BulkOrders(orders, nil)
+	require.ErrorAs(t, err, new(*utils.PartialFailureError))
+	require.Len(t, fs.exchangeRequests, 1)
+	assert.Len(t, fs.lastExchangeRequest(t)["action"].(map[string]interface{})["orders"], 150)
+}
+
+func TestBulkCancelBatches(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeReply = func(payload map[string]interface{}) string {
+		cancels := payload["action"].(map[string]interface{})["cancels"].([]interface{})
+		statuses := make([]interface{}, len(cancels))
+		for i := range statuses {
+			statuses[i] = "success"
+		}
+		return mustJSON(t, map[string]interface{}{"status": "ok", "response": map[string]interface{}{"type": "cancel", "data": map[string]interface{}{"statuses": statuses}}})
+	}
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	cancels := make([]utils.CancelRequest, 150)
+	for i := range cancels {
+		cancels[i] = utils.CancelRequest{Coin: "ETH", OID: i + 1}
+	}
+	response, err := exchange.BulkCancel(cancels)
+	require.NoError(t, err)
+	assert.Len(t, response.Response.Data.Statuses, 150)
+	require.Len(t, fs.exchangeRequests, 4)
+	var oids []float64
+	for _, request := range fs.exchangeRequests {
+		for _, cancel := range request["action"].(map[string]interface{})["cancels"].([]interface{}) {
+			oids = append(oids, cancel.(map[string]interface{})["o"].(float64))
+		}
+	}
+	assert.Len(t, oids, 150)
+	assert.Equal(t, float64(1), oids[0])
+	assert.Equal(t, float64(150), oids[149])
+
+	var raws []json.RawMessage
+	require.NoError(t, json.Unmarshal(response.Raw, &raws))
+	assert.Len(t, raws, 4, "the raw response of every batch")
+}
+
+func TestOrderResponseCorrelation(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "order", "data": {"statuses": [
+		{"resting": {"oid": 1, "cloid": "0x00000000000000000000000000000001"}},
+		{"filled": {"totalSz": "0.1", "avgPx": "3000", "oid": 2}},
+		{"error": "Order must have minimum value of $10."}]}}}`
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	first := "0x00000000000000000000000000000001"
+	third := "0x000000000000000000000000000000AB"
+	response, err := exchange.BulkOrders([]utils.OrderRequest{
+		{Coin: "BTC", IsBuy: true, Sz: 0.01, LimitPx: 50000, OrderType: orderType, Cloid: &first},
+		{Coin: "ETH", IsBuy: false, Sz: 0.1, LimitPx: 3000, OrderType: orderType},
+		{Coin: "ATOM", IsBuy: true, Sz: 0.1, LimitPx: 5, OrderType: orderType, Cloid: &third},
+	}, nil)
+	require.ErrorAs(t, err, new(*utils.PartialFailureError))
+
+	result, ok := response.ByCloid(first)
+	require.True(t, ok)
+	assert.Equal(t, 0, result.Index)
+	assert.Equal(t, "BTC", result.Request.Coin)
+	assert.Equal(t, 1, result.Status.Resting.Oid)
+
+	result, ok = response.ByIndex(1)
+	require.True(t, ok)
+	assert.Equal(t, "ETH", result.Request.Coin)
+	assert.Nil(t, result.Cloid)
+	assert.Equal(t, 2, result.Status.Filled.Oid)
+
+	result, ok = response.ByCloid("0x000000000000000000000000000000ab")
+	require.True(t, ok, "cloids match regardless of case")
+	assert.Equal(t, "ATOM", result.Request.Coin)
+	assert.Equal(t, "Order must have minimum value of $10.", result.Status.Error)
+
+	_, ok = response.ByIndex(3)
+	assert.False(t, ok)
+	_, ok = response.ByCloid("0x00000000000000000000000000000002")
+	assert.False(t, ok)
+	assert.Len(t, response.Results(), 3)
+
+	// Correlation survives batches sent concurrently
+	fs.exchangeReply = func(payload map[string]interface{}) string {
+		var statuses []interface{}
+		for _, order := range payload["action"].(map[string]interface{})["orders"].([]interface{}) {
+			oid, _ := strconv.Atoi(order.(map[string]interface{})["p"].(string))
+			statuses = append(statuses, map[string]interface{}{"resting": map[string]interface{}{"oid": oid}})
+		}
+		return mustJSON(t, map[string]interface{}{"status": "ok", "response": map[string]interface{}{"type": "order", "data": map[string]interface{}{"statuses": statuses}}})
+	}
+	fs.delay = 10 * time.Millisecond
+	exchange.SetAutoCloid(true)
+	exchange.SetBatchSize(2)
+	exchange.SetBatchConcurrency(3)
+	orders := make([]utils.OrderRequest, 5)
+	for i := range orders {
+		orders[i] = utils.OrderRequest{Coin: "BTC", IsBuy: true, Sz: 0.001, LimitPx: float64(10000 + i), OrderType: orderType}
+	}
+	response, err = exchange.BulkOrders(orders, nil)
+	require.NoError(t, err)
+	for i, result := range response.Results() {
+		require.NotNil(t, result.Cloid)
+		assert.Equal(t, result.Cloid, result.Request.Cloid)
+		assert.Equal(t, float64(10000+i), result.Request.LimitPx)
+		assert.Equal(t, 10000+i, result.Status.Resting.Oid)
+
+		byCloid, ok := response.ByCloid(*result.Cloid)
+		require.True(t, ok)
+		assert.Equal(t, i, byCloid.Index)
+	}
+}
+
+func TestCloids(t *testing.T) {
+	first, err := utils.RandomCloid()
+	require.NoError(t, err)
+	second, err := utils.RandomCloid()
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ToRaw(), second.ToRaw())
+	_, err = utils.NewCloid(first.ToRaw())
+	require.NoError(t, err)
+
+	for _, malformed := range []string{"1", "0x1", "0x" + strings.Repeat("zz", 16), strings.Repeat("00", 17)} {
+		_, err := utils.NewCloid(malformed)
+		assert.Error(t, err, malformed)
+	}
+
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	lastCloid := func() interface{} {
+		action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+		return action["orders"].([]interface{})[0].(map[string]interface{})["c"]
+	}
+
+	// Malformed cloids are rejected before signing
+	malformed := "order-1"
+	_, err = exchange.Order("BTC", true, 0.01, 50000, orderType, false, &malformed, nil)
+	assert.ErrorContains(t, err, `invalid cloid "order-1"`)
+	assert.Empty(t, fs.exchangeRequests)
+
+	response, err := exchange.Order("BTC", true, 0.01, 50000, orderType, false, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*string{nil}, response.Cloids)
+	assert.Nil(t, lastCloid())
+
+	// Orders without a cloid get a random one once enabled
+	exchange.SetAutoCloid(true)
+	response, err = exchange.Order("BTC", true, 0.01, 50000, orderType, false, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, response.Cloids, 1)
+	require.NotNil(t, response.Cloids[0])
+	_, err = utils.NewCloid(*response.Cloids[0])
+	require.NoError(t, err)
+	assert.Equal(t, *response.Cloids[0], lastCloid())
+
+	given := "0x00000000000000000000000000000001"
+	response, err = exchange.Order("BTC", true, 0.01, 50000, orderType, false, &given, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []*string{&given}, response.Cloids)
+	assert.Equal(t, given, lastCloid())
+}
+
+func TestCloidIntegers(t *testing.T) {
+	assert.Equal(t, "0x00000000000000000000000000000001", utils.NewCloidFromInt(1).ToRaw())
+	assert.Equal(t, "0x0000000000000000ffffffffffffffff", utils.NewCloidFromInt(math.MaxUint64).ToRaw())
+
+	maxCloid := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
+	edges := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(math.MaxInt64),
+		new(big.Int).Lsh(big.NewInt(1), 63),
+		new(big.Int).SetUint64(math.MaxUint64),
+		new(big.Int).Lsh(big.NewInt(1), 64),
+		new(big.Int).Lsh(big.NewInt(1), 127),
+		maxCloid,
+	}
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		// Random bit lengths so small and large cloids are both covered
+		bits := rng.Intn(129)
+		edges = append(edges, new(big.Int).Rand(rng, new(big.Int).Lsh(big.NewInt(1), uint(bits))))
+	}
+
+	for _, value := range edges {
+		cloid, err := utils.NewCloidFromBigInt(value)
+		require.NoError(t, err, value)
+		require.Len(t, cloid.ToRaw(), 34, value)
+		_, err = utils.NewCloid(cloid.ToRaw())
+		require.NoError(t, err, value)
+
+		roundTrip, err := cloid.ToBigInt()
+		require.NoError(t, err)
+		require.Equal(t, 0, value.Cmp(roundTrip), "%s round-tripped to %s", value, roundTrip)
+
+		if value.IsUint64() {
+			assert.Equal(t, cloid.ToRaw(), utils.NewCloidFromInt(value.Uint64()).ToRaw())
+		}
+		small, err := cloid.ToInt()
+		if value.IsInt64() {
+			require.NoError(t, err)
+			assert.Equal(t, value.Int64(), small)
+		} else {
+			assert.Error(t, err, value)
+		}
+	}
+
+	for _, outOfRange := range []*big.Int{nil, big.NewInt(-1), new(big.Int).Lsh(big.NewInt(1), 128)} {
+		_, err := utils.NewCloidFromBigInt(outOfRange)
+		assert.Error(t, err, outOfRange)
+	}
+}
+
+func TestSpotOrderByIndex(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+
+	for name, asset := range map[string]float64{"@1": 10001, "@0": 10000, "PURR/USDC": 10000} {
+		_, err := exchange.Order(name, true, 10, 0.5, orderType, false, nil, nil)
+		require.NoError(t, err, name)
+		action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+		order := action["orders"].([]interface{})[0].(map[string]interface{})
+		require.Equal(t, asset, order["a"], name)
+	}
+}
+
+func TestBuilderPerpDexOffsets(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("spotMeta", loadCassette(t, "spot_meta.json"))
+	fs.setInfoResponse("perpDexs", `[null, {"name": "abc"}, {"name": "xyz"}]`)
+	// Every builder dex lists BTC and ETH without a dex prefix
+	fs.setInfoResponse("meta", loadCassette(t, "meta_builder_dex.json"))
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	info, err := hyperliquid.NewInfo(fs.URL, true, &meta, nil, []string{"", "abc", "xyz"}, 5*time.Second)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		asset int
+	}{
+		{"BTC", 0},
+		{"ETH", 1},
+		{"abc:BTC", 110000},
+		{"abc:ETH", 110001},
+		{"xyz:BTC", 120000},
+		{"xyz:ETH", 120001},
+	}
+	for _, tt := range tests {
+		asset, err := info.NameToAsset(tt.name)
+		require.NoError(t, err, tt.name)
+		require.Equal(t, tt.asset, asset, tt.name)
+	}
+	szDecimals, err := info.SzDecimals("xyz:BTC")
+	require.NoError(t, err)
+	require.Equal(t, 4, szDecimals)
+}
+
+func TestBuilderPerpDexOrder(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("perpDexs", `[null, {"name": "abc"}, {"name": "xyz"}]`)
+	fs.setInfoResponse("meta", loadCassette(t, "meta_builder_dex.json"))
+	fs.setInfoResponse("allMids", `{"xyz:BTC": "50000.0", "xyz:ETH": "3000.0"}`)
+	privateKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	var spotMeta hyperliquid.SpotMeta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "spot_meta.json")), &spotMeta))
+	exchange, err := hyperliquid.NewExchange(privateKey, fs.URL, &meta, nil, nil, &spotMeta, []string{"", "abc", "xyz"}, 5*time.Second)
+	require.NoError(t, err)
+
+	_, err = exchange.MarketOpen("xyz:BTC", true, 0.01, nil, 0.01, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, "xyz", fs.lastInfoRequest(t)["dex"], "mid comes from the coin's dex")
+	action := fs.lastExchangeRequest(t)["action"].(map[string]interface{})
+	order := action["orders"].([]interface{})[0].(map[string]interface{})
+	require.Equal(t, float64(120000), order["a"])
+	require.Equal(t, "50500", order["p"])
+}
+
+func TestUpdateLeverageLimits(t *testing.T) {
+	fs := newFakeServer(t)
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	_, err := exchange.UpdateLeverage(41, "BTC", true)
+	require.ErrorContains(t, err, "max leverage of 40")
+	_, err = exchange.UpdateLeverage(3, "ATOM", true)
+	require.ErrorContains(t, err, "only supports isolated margin")
+	require.Empty(t, fs.exchangeRequests)
+
+	_, err = exchange.UpdateLeverage(40, "BTC", true)
+	require.NoError(t, err)
+	_, err = exchange.UpdateLeverage(5, "ATOM", false)
+	require.NoError(t, err)
+	require.Len(t, fs.exchangeRequests, 2)
+}
+
+func TestCancelledContextAbortsRequest(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("clearinghouseState", `{"assetPositions": []}`)
+	exchange, _ := newTestExchange(t, fs, nil)
+	info := newTestInfo(t, fs)
+	fs.delay = 5 * time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	_, err := exchange.OrderCtx(ctx, "BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.Less(t, time.Since(start), fs.delay)
+
+	_, err = info.UserStateCtx(ctx, "0x0000000000000000000000000000000000000001", "")
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLargeOidRoundTrips(t *testing.T) {
+	// 2^53 + 1 is the first integer a float64 cannot represent
+	const oid = 9007199254740993
+	fs := newFakeServer(t)
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "order", "data": {"statuses": [{"resting": {"oid": 9007199254740993}}, {"filled": {"oid": 9007199254740993, "totalSz": "0.01", "avgPx": "100000.0"}}]}}}`
+	exchange, _ := newTestExchange(t, fs, nil)
+
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+	response, err := exchange.Order("BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, oid, response.Response.Data.Statuses[0].Resting.Oid)
+	require.Equal(t, oid, response.Response.Data.Statuses[1].Filled.Oid)
+	require.Contains(t, string(response.Raw), "9007199254740993")
+
+	fs.setInfoResponse("orderStatus", `{"status": "order", "order": {"order": {"oid": 9007199254740993}}}`)
+	var status struct {
+		Order struct {
+			Order struct {
+				Oid int64 `json:"oid"`
+			} `json:"order"`
+		} `json:"order"`
+	}
+	require.NoError(t, exchange.PostInto(context.Background(), "/info", map[string]interface{}{"type": "orderStatus"}, &status))
+	require.Equal(t, int64(oid), status.Order.Order.Oid)
+}
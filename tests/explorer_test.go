@@ -0,0 +1,70 @@
+// Package tests - Explorer query tests
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTxHash = "0x1b6c5a2b0c3e4b7d8e9f00112233445566778899aabbccddeeff001122334455"
+
+func TestBlockDetails(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["blockDetails"] = loadCassette(t, "block_details.json")
+	info := newTestInfo(t, fs)
+
+	block, err := info.BlockDetails(385720411)
+	require.NoError(t, err)
+	assert.Equal(t, float64(385720411), fs.lastInfoRequest(t)["height"])
+	assert.Equal(t, int64(385720411), block.Height)
+	assert.Equal(t, 2, block.NumTxs)
+	require.Len(t, block.Txs, 2)
+	assert.Equal(t, "order", block.Txs[0].ActionType())
+	assert.Nil(t, block.Txs[0].Error)
+	assert.Equal(t, "cancel", block.Txs[1].ActionType())
+	require.NotNil(t, block.Txs[1].Error)
+}
+
+func TestTxAndUserDetails(t *testing.T) {
+	fs := newFakeServer(t)
+	tx := `{"action": {"type": "usdSend", "destination": "0x0000000000000000000000000000000000000002", "amount": "5.0"}, "block": 385720412, "error": null, "hash": "` + testTxHash + `", "time": 1741886631000, "user": "0x0000000000000000000000000000000000000001"}`
+	fs.infoResponses["txDetails"] = `{"type": "txDetails", "tx": ` + tx + `}`
+	fs.infoResponses["userDetails"] = `{"type": "userDetails", "txs": [` + tx + `]}`
+	info := newTestInfo(t, fs)
+
+	got, err := info.TxDetails(testTxHash)
+	require.NoError(t, err)
+	assert.Equal(t, testTxHash, fs.lastInfoRequest(t)["hash"])
+	assert.Equal(t, "usdSend", got.ActionType())
+	assert.Equal(t, int64(385720412), got.Block)
+	assert.JSONEq(t, `{"type": "usdSend", "destination": "0x0000000000000000000000000000000000000002", "amount": "5.0"}`, string(got.Action))
+
+	txs, err := info.UserDetails("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, *got, txs[0])
+}
+
+func TestWaitForTx(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["txDetails"] = `{"type": "txDetails", "tx": null}`
+	info := newTestInfo(t, fs)
+
+	_, err := info.TxDetails(testTxHash)
+	require.ErrorIs(t, err, hyperliquid.ErrTxNotFound)
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		fs.setInfoResponse("txDetails", `{"type": "txDetails", "tx": {"action": {"type": "order"}, "block": 385720413, "error": null, "hash": "`+testTxHash+`", "time": 1741886632000, "user": "0x0000000000000000000000000000000000000001"}}`)
+	})
+	tx, err := info.WaitForTx(testTxHash, 5*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, int64(385720413), tx.Block)
+
+	fs.setInfoResponse("txDetails", `{"type": "txDetails", "tx": null}`)
+	_, err = info.WaitForTx(testTxHash, 50*time.Millisecond)
+	require.Error(t, err)
+}
@@ -1,2 +1,830 @@
 // Package tests - Info functionality tests
 package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestInfo creates an Info client against the fake server, fetching spot
+// metadata from the spotMeta cassette
+func newTestInfo(t *testing.T, fs *fakeServer) *hyperliquid.Info {
+	t.Helper()
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	if _, ok := fs.infoResponses["spotMeta"]; !ok {
+		fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+	}
+
+	info, err := hyperliquid.NewInfo(fs.URL, true, &meta, nil, nil, 5*time.Second)
+	require.NoError(t, err)
+	return info
+}
+
+// newTestInfoWithWS is like newTestInfo but connects the WebSocket
+func newTestInfoWithWS(t *testing.T, fs *fakeServer) *hyperliquid.Info {
+	t.Helper()
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	if _, ok := fs.infoResponses["spotMeta"]; !ok {
+		fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+	}
+
+	info, err := hyperliquid.NewInfo(fs.URL, false, &meta, nil, nil, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = info.DisconnectWebSocket() })
+	return info
+}
+
+func TestTokenByEvmContract(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta_evm.json")
+	info := newTestInfo(t, fs)
+
+	token, err := info.TokenByEvmContract("0xA320D9F65EC992EFF38622C63627856382DB726C")
+	require.NoError(t, err)
+	assert.Equal(t, "HFUN", token.Name)
+	assert.Equal(t, 2, token.Index)
+
+	_, err = info.TokenByEvmContract("0x0000000000000000000000000000000000000000")
+	assert.Error(t, err)
+
+	pairs, err := info.PairsForToken(token.Index)
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "@1", pairs[0].Name)
+	assert.Equal(t, "@2", pairs[1].Name)
+
+	// Tokens without a linked contract are still tradable through their pairs
+	pairs, err = info.PairsForToken(1)
+	require.NoError(t, err)
+	require.Len(t, pairs, 2)
+	assert.Equal(t, "PURR/USDC", pairs[0].Name)
+
+	_, err = info.PairsForToken(42)
+	assert.Error(t, err)
+}
+
+func TestActiveTwaps(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["twapHistory"] = loadCassette(t, "twap_history.json")
+	info := newTestInfo(t, fs)
+
+	history, err := info.TwapHistory("0x1719884eb866cb12b2287399b15f7db5e7d775ea")
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+
+	active, err := info.ActiveTwaps("0x1719884eb866cb12b2287399b15f7db5e7d775ea")
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, 3156, *active[0].TwapID)
+	assert.Equal(t, "ETH", active[0].State.Coin)
+	assert.Equal(t, 30, active[0].State.Minutes)
+}
+
+func TestUserTwapSliceFills(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userTwapSliceFills"] = loadCassette(t, "user_twap_slice_fills.json")
+	info := newTestInfo(t, fs)
+
+	fills, err := info.UserTwapSliceFills("0x1719884eb866cb12b2287399b15f7db5e7d775ea")
+	require.NoError(t, err)
+	require.Len(t, fills, 2)
+	assert.Equal(t, 3156, fills[0].TwapID)
+	assert.Equal(t, "ETH", fills[0].Fill.Coin)
+	assert.Equal(t, "0.0666", fills[0].Fill.Sz)
+	assert.Equal(t, utils.SideAsk, fills[0].Fill.Side)
+	assert.Equal(t, 33160789, fills[0].Fill.Oid)
+	assert.Equal(t, int64(1754451000000), fills[1].Fill.Time)
+}
+
+func TestVaultDetails(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["vaultDetails"] = loadCassette(t, "vault_details.json")
+	info := newTestInfo(t, fs)
+
+	user := "0x0000000000000000000000000000000000000001"
+	details, err := info.VaultDetails("0xdfc24b077bc1425ad1dea75bcb6f8158e10df303", &user)
+	require.NoError(t, err)
+	assert.Equal(t, "Test Vault", details.Name)
+	assert.Equal(t, "0x677d831aef5328190852e24f13c46cac05f984e7", details.Leader)
+	assert.InDelta(t, 0.3639, details.Apr, 1e-4)
+	assert.True(t, details.AllowDeposits)
+	require.Len(t, details.Followers, 2)
+	require.NotNil(t, details.FollowerState)
+	assert.Equal(t, user, details.FollowerState.User)
+	assert.Equal(t, "1250.5", details.FollowerState.VaultEquity)
+	assert.Equal(t, int64(1734445200000), details.FollowerState.LockupUntil)
+	require.Contains(t, details.Portfolio, "day")
+	assert.Equal(t, utils.DecimalString("742500.08"), details.Portfolio["day"].AccountValueHistory[0].Value)
+}
+
+func TestUserVaultEquities(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userVaultEquities"] = loadCassette(t, "user_vault_equities.json")
+	info := newTestInfo(t, fs)
+
+	equities, err := info.UserVaultEquities("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Len(t, equities, 2)
+	assert.Equal(t, hyperliquid.VaultEquity{
+		VaultAddress:         "0xdfc24b077bc1425ad1dea75bcb6f8158e10df303",
+		Equity:               "1250.5",
+		LockedUntilTimestamp: 1734445200000,
+	}, equities[0])
+}
+
+func TestUserRole(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userRole"] = loadCassette(t, "user_role.json")
+	info := newTestInfo(t, fs)
+
+	role, err := info.UserRole("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	assert.Equal(t, hyperliquid.RoleSubAccount, role.Role)
+	require.NotNil(t, role.Data)
+	assert.Equal(t, "0x1719884eb866cb12b2287399b15f7db5e7d775ea", role.Data.Master)
+}
+
+func TestPortfolio(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["portfolio"] = loadCassette(t, "portfolio.json")
+	info := newTestInfo(t, fs)
+
+	portfolio, err := info.Portfolio("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Len(t, portfolio, 4)
+	day := portfolio["day"]
+	require.Len(t, day.AccountValueHistory, 2)
+	assert.Equal(t, hyperliquid.PortfolioPoint{Time: 1741895270493, Value: "1024.5"}, day.AccountValueHistory[1])
+	assert.Equal(t, utils.DecimalString("24.5"), day.PnlHistory[1].Value)
+	assert.Equal(t, utils.DecimalString("82000.0"), portfolio["allTime"].Vlm)
+}
+
+func TestUserRateLimit(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userRateLimit"] = loadCassette(t, "user_rate_limit.json")
+	info := newTestInfo(t, fs)
+
+	limit, err := info.UserRateLimit("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	assert.Equal(t, utils.DecimalString("2854574.593578"), limit.CumVlm)
+	assert.Equal(t, 2890, limit.NRequestsUsed)
+	assert.Equal(t, 2861684, limit.Remaining())
+}
+
+func TestMaxBuilderFee(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["maxBuilderFee"] = "10"
+	info := newTestInfo(t, fs)
+
+	fee, err := info.MaxBuilderFee("0x0000000000000000000000000000000000000001", "0x8c967e73e7b15087c42a10d344cff4c96d877f1d")
+	require.NoError(t, err)
+	assert.Equal(t, 10, fee)
+}
+
+func TestExtraAgents(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["extraAgents"] = loadCassette(t, "extra_agents.json")
+	info := newTestInfo(t, fs)
+
+	agents, err := info.ExtraAgents("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Len(t, agents, 2)
+	assert.Equal(t, hyperliquid.ExtraAgent{
+		Address:    "0x5e9ee1089755c3435139848e47e6635505d5a13a",
+		Name:       "quoter",
+		ValidUntil: 1767225600000,
+	}, agents[0])
+}
+
+func TestPreTransferCheck(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["preTransferCheck"] = `{"fee": "1.0", "isSanctioned": false, "userExists": false, "userHasSentTx": false}`
+	info := newTestInfo(t, fs)
+
+	check, err := info.PreTransferCheck("0x0000000000000000000000000000000000000002", "0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	assert.Equal(t, hyperliquid.PreTransferCheck{Fee: "1.0"}, *check)
+}
+
+func TestPerpsAtOpenInterestCap(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["perpsAtOpenInterestCap"] = `["BADGER", "CANTO", "FTM"]`
+	info := newTestInfo(t, fs)
+
+	coins, err := info.PerpsAtOpenInterestCap()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BADGER", "CANTO", "FTM"}, coins)
+}
+
+func TestPerpDexLimits(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["perpDexLimits"] = loadCassette(t, "perp_dex_limits.json")
+	info := newTestInfo(t, fs)
+
+	limits, err := info.PerpDexLimits("test")
+	require.NoError(t, err)
+	require.NotNil(t, limits)
+	assert.Equal(t, utils.DecimalString("10000000.0"), limits.TotalOiCap)
+	assert.Equal(t, [][2]string{{"test:ABC", "1000000.0"}, {"test:XYZ", "250000.0"}}, limits.CoinToOiCap)
+
+	fs.infoResponses["perpDexLimits"] = "null"
+	limits, err = info.PerpDexLimits("")
+	require.NoError(t, err)
+	assert.Nil(t, limits)
+}
+
+func TestTokenDetails(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["tokenDetails"] = loadCassette(t, "token_details_purr.json")
+	info := newTestInfo(t, fs)
+
+	// A canonical token, by tokenId
+	details, err := info.TokenDetails("0xc1fb593aeffbeb02f85e0308e9956a90")
+	require.NoError(t, err)
+	assert.Equal(t, "0xc1fb593aeffbeb02f85e0308e9956a90", fs.lastInfoRequest(t)["tokenId"])
+	assert.Equal(t, "PURR", details.Name)
+	assert.Equal(t, 5, details.WeiDecimals)
+	assert.Equal(t, utils.DecimalString("598015218.27536"), details.CirculatingSupply)
+	require.NotNil(t, details.MarkPx)
+	assert.Equal(t, "0.18517", *details.MarkPx)
+	assert.Nil(t, details.Deployer)
+
+	// An EVM-linked token, by name
+	fs.setInfoResponse("tokenDetails", loadCassette(t, "token_details_hfun.json"))
+	details, err = info.TokenDetails("HFUN")
+	require.NoError(t, err)
+	assert.Equal(t, "0xbaf265ef389da684513d98d68edf4eae", fs.lastInfoRequest(t)["tokenId"])
+	assert.Equal(t, "HFUN", details.Name)
+	assert.Equal(t, 2, details.SzDecimals)
+	require.NotNil(t, details.DeployTime)
+	assert.Equal(t, "2024-05-29T03:47:56.254", *details.DeployTime)
+	assert.NotEmpty(t, details.Genesis)
+
+	_, err = info.TokenDetails("NOPE")
+	require.Error(t, err)
+}
+
+func TestAssetMeta(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfo(t, fs)
+
+	btc, err := info.AssetMeta("BTC")
+	require.NoError(t, err)
+	assert.Equal(t, hyperliquid.AssetInfo{Name: "BTC", SzDecimals: 5, MaxLeverage: 40}, *btc)
+
+	atom, err := info.AssetMeta("ATOM")
+	require.NoError(t, err)
+	assert.Equal(t, 5, atom.MaxLeverage)
+	assert.True(t, atom.OnlyIsolated)
+
+	_, err = info.AssetMeta("PURR/USDC")
+	require.Error(t, err)
+}
+
+func TestSpotIndexNotation(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta_evm.json")
+	info := newTestInfo(t, fs)
+
+	// "@2" has no canonical name; it is also reachable as HFUN/PURR
+	for _, name := range []string{"@2", "HFUN/PURR"} {
+		asset, err := info.NameToAsset(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, 10002, asset, name)
+	}
+	coin, err := info.AssetToName(10002)
+	require.NoError(t, err)
+	assert.Equal(t, "@2", coin)
+
+	// The canonical PURR/USDC pair is "@0" too
+	asset, err := info.NameToAsset("@0")
+	require.NoError(t, err)
+	assert.Equal(t, 10000, asset)
+	coin, err = info.AssetToName(10000)
+	require.NoError(t, err)
+	assert.Equal(t, "PURR/USDC", coin)
+
+	coin, err = info.AssetToName(1)
+	require.NoError(t, err)
+	assert.Equal(t, "ETH", coin)
+
+	_, err = info.NameToAsset("@7")
+	require.Error(t, err)
+	_, err = info.AssetToName(10007)
+	require.Error(t, err)
+}
+
+func TestReverseLookups(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+	fs.infoResponses["perpDexs"] = `[null, {"name": "test", "full_name": "test dex", "deployer": "0x0000000000000000000000000000000000000001"}]`
+	fs.infoResponses["meta"] = loadCassette(t, "meta_test_dex.json")
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	info, err := hyperliquid.NewInfo(fs.URL, true, &meta, nil, []string{"", "test"}, 5*time.Second)
+	require.NoError(t, err)
+
+	for asset, name := range map[int]string{0: "BTC", 10000: "PURR/USDC", 10001: "@1", 110001: "test:XYZ"} {
+		got, err := info.AssetToName(asset)
+		require.NoError(t, err, asset)
+		assert.Equal(t, name, got)
+
+		roundTrip, err := info.NameToAsset(got)
+		require.NoError(t, err, name)
+		assert.Equal(t, asset, roundTrip)
+	}
+
+	for name, decimals := range map[string]int{"BTC": 5, "PURR/USDC": 0, "@1": 2, "test:ABC": 3} {
+		got, err := info.SzDecimals(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, decimals, got, name)
+	}
+	_, err = info.SzDecimals("NOPE")
+	require.Error(t, err)
+
+	assert.False(t, info.IsSpotAsset(2))
+	assert.True(t, info.IsSpotAsset(10001))
+	assert.False(t, info.IsSpotAsset(110001))
+}
+
+func TestRefreshMeta(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfo(t, fs)
+
+	_, err := info.NameToAsset("NEWCOIN")
+	require.Error(t, err)
+
+	fs.setInfoResponse("meta", loadCassette(t, "meta_new_listing.json"))
+	require.NoError(t, info.RefreshMeta())
+	asset, err := info.NameToAsset("NEWCOIN")
+	require.NoError(t, err)
+	assert.Equal(t, 3, asset)
+	newCoin, err := info.AssetMeta("NEWCOIN")
+	require.NoError(t, err)
+	assert.Equal(t, 3, newCoin.MaxLeverage)
+
+	// Spot lookups survive a perp refresh and vice versa
+	asset, err = info.NameToAsset("PURR/USDC")
+	require.NoError(t, err)
+	assert.Equal(t, 10000, asset)
+	require.NoError(t, info.RefreshSpotMeta())
+	_, err = info.NameToAsset("NEWCOIN")
+	require.NoError(t, err)
+}
+
+func TestAutoRefreshMeta(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfo(t, fs)
+	fs.setInfoResponse("meta", loadCassette(t, "meta_new_listing.json"))
+
+	_, err := info.NameToAsset("NEWCOIN")
+	require.Error(t, err, "refresh is off by default")
+
+	info.SetAutoRefreshMeta(true)
+	asset, err := info.NameToAsset("NEWCOIN")
+	require.NoError(t, err)
+	assert.Equal(t, 3, asset)
+
+	// Unknown names do not refresh again within the cooldown
+	requests := len(fs.infoRequests)
+	_, err = info.NameToAsset("NOPE")
+	require.Error(t, err)
+	assert.Equal(t, requests, len(fs.infoRequests))
+}
+
+// TestConcurrentLookupsDuringRefresh is meant to be run with -race
+func TestConcurrentLookupsDuringRefresh(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.setInfoResponse("meta", loadCassette(t, "meta_new_listing.json"))
+	info := newTestInfo(t, fs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				_, err := info.NameToAsset("BTC")
+				assert.NoError(t, err)
+				_, err = info.AssetMeta("ETH")
+				assert.NoError(t, err)
+				_, err = info.TokenByEvmContract("0xa320D9f65ec992EfF38622c63627856382Db726c")
+				assert.NoError(t, err)
+				_, err = info.NameToAsset("NOPE")
+				assert.Error(t, err)
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		info.SetAutoRefreshMeta(i%2 == 0)
+		require.NoError(t, info.RefreshMeta())
+		require.NoError(t, info.RefreshSpotMeta())
+	}
+	wg.Wait()
+}
+
+func TestValidatorSummaries(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["validatorSummaries"] = loadCassette(t, "validator_summaries.json")
+	info := newTestInfo(t, fs)
+
+	validators, err := info.ValidatorSummaries()
+	require.NoError(t, err)
+	require.Len(t, validators, 2)
+	assert.Equal(t, "ValiDAO", validators[0].Name)
+	assert.Equal(t, utils.DecimalString("0.04"), validators[0].Commission)
+	assert.Equal(t, int64(1203456789012345), validators[0].Stake)
+	assert.Equal(t, utils.DecimalString("0.0236"), validators[0].Stats["week"].PredictedApr)
+	assert.Nil(t, validators[0].UnjailableAfter)
+	assert.True(t, validators[1].IsJailed)
+	require.NotNil(t, validators[1].UnjailableAfter)
+}
+
+func TestDelegatorHistory(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["delegatorHistory"] = loadCassette(t, "delegator_history.json")
+	info := newTestInfo(t, fs)
+	user := "0x0000000000000000000000000000000000000001"
+
+	events, err := info.DelegatorHistory(user, 0, nil)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+	require.NotNil(t, events[0].Delta.CDeposit)
+	assert.Equal(t, utils.DecimalString("10000.0"), events[0].Delta.CDeposit.Amount)
+	require.NotNil(t, events[2].Delta.Delegate)
+	assert.True(t, events[2].Delta.Delegate.IsUndelegate)
+	assert.Nil(t, events[2].Delta.Withdrawal)
+	require.NotNil(t, events[3].Delta.Withdrawal)
+	assert.Equal(t, "initiated", events[3].Delta.Withdrawal.Phase)
+
+	end := int64(1736380391353)
+	events, err = info.DelegatorHistory(user, 1735380391353, &end)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, int64(1735380391353), events[0].Time)
+	assert.Equal(t, end, events[1].Time)
+}
+
+func TestStakingQueries(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["delegatorSummary"] = `{"delegated": "12060.16529862", "undelegated": "0.0", "totalPendingWithdrawal": "2500.0", "nPendingWithdrawals": 1}`
+	fs.infoResponses["delegations"] = `[{"validator": "0x000000000056f99d36b6f2e0c51fd41496bbacb8", "amount": "12060.16529862", "lockedUntilTimestamp": 1735466781353}]`
+	fs.infoResponses["delegatorRewards"] = `[{"time": 1736121600000, "source": "delegation", "totalAmount": "0.73117184"}, {"time": 1736121600000, "source": "commission", "totalAmount": "130.85545146"}]`
+	info := newTestInfo(t, fs)
+	user := "0x0000000000000000000000000000000000000001"
+
+	summary, err := info.UserStakingSummary(user)
+	require.NoError(t, err)
+	assert.Equal(t, hyperliquid.DelegatorSummary{Delegated: "12060.16529862", Undelegated: "0.0", TotalPendingWithdrawal: "2500.0", NPendingWithdrawals: 1}, *summary)
+
+	delegations, err := info.UserStakingDelegations(user)
+	require.NoError(t, err)
+	require.Len(t, delegations, 1)
+	assert.Equal(t, int64(1735466781353), delegations[0].LockedUntilTimestamp)
+
+	rewards, err := info.UserStakingRewards(user)
+	require.NoError(t, err)
+	require.Len(t, rewards, 2)
+	assert.Equal(t, "commission", rewards[1].Source)
+	assert.Equal(t, utils.DecimalString("130.85545146"), rewards[1].TotalAmount)
+}
+
+func TestSpotClearinghouseState(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotClearinghouseState"] = loadCassette(t, "spot_clearinghouse_state.json")
+	info := newTestInfo(t, fs)
+
+	state, err := info.SpotClearinghouseState("0x0000000000000000000000000000000000000001")
+	require.NoError(t, err)
+	require.Len(t, state.Balances, 2)
+	assert.Equal(t, hyperliquid.SpotBalance{Coin: "PURR", Token: 1, Hold: "24.0", Total: "2000.123456789", EntryNtl: "1234.56"}, state.Balances[1])
+
+	total, err := info.SpotBalanceTotal(state.Balances[0])
+	require.NoError(t, err)
+	assert.Equal(t, 14.625485, total)
+
+	// PURR has 5 wei decimals
+	total, err = info.SpotBalanceTotal(state.Balances[1])
+	require.NoError(t, err)
+	assert.Equal(t, 2000.12346, total)
+
+	_, err = info.SpotBalanceTotal(hyperliquid.SpotBalance{Coin: "NONE", Token: 42, Total: "1"})
+	assert.Error(t, err)
+}
+
+func TestInfoSpotWei(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = `{
+  "universe": [{"name": "PURR/USDC", "tokens": [1, 0], "index": 0, "isCanonical": true}],
+  "tokens": [
+    {"name": "USDC", "szDecimals": 8, "weiDecimals": 8, "index": 0, "tokenId": "0x6d1e7cde53ba9467b783cb7c530ce054", "isCanonical": true},
+    {"name": "PURR", "szDecimals": 0, "weiDecimals": 5, "index": 1, "tokenId": "0xc1fb593aeffbeb02f85e0308e9956a90", "isCanonical": true},
+    {"name": "WETH", "szDecimals": 4, "weiDecimals": 18, "index": 2, "tokenId": "0x0000000000000000000000000000000a", "isCanonical": false}
+  ]
+}`
+	info := newTestInfo(t, fs)
+
+	wei, err := info.SpotToWei("WETH", "12345.678901234567890123")
+	require.NoError(t, err)
+	assert.Equal(t, "12345678901234567890123", wei.String())
+
+	amount, err := info.WeiToSpot("WETH", wei)
+	require.NoError(t, err)
+	assert.Equal(t, "12345.678901234567890123", amount)
+
+	wei, err = info.SpotToWei("PURR", "2000.12345")
+	require.NoError(t, err)
+	assert.Equal(t, "200012345", wei.String())
+
+	_, err = info.SpotToWei("PURR", "2000.123456789")
+	assert.Error(t, err, "PURR has 5 wei decimals")
+	_, err = info.SpotToWei("NONE", "1")
+	assert.Error(t, err)
+	_, err = info.WeiToSpot("NONE", wei)
+	assert.Error(t, err)
+}
+
+func TestL2Snapshot(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["l2Book"] = loadCassette(t, "l2_book_btc.json")
+	info := newTestInfo(t, fs)
+
+	snapshot, err := info.L2Snapshot("BTC")
+	require.NoError(t, err)
+	assert.Equal(t, "BTC", snapshot.Coin)
+	assert.Equal(t, int64(1754450974231), snapshot.Time)
+	require.Len(t, snapshot.Levels[0], 3)
+	require.Len(t, snapshot.Levels[1], 3)
+	assert.Equal(t, utils.L2Level{Px: "113378.0", Sz: "0.1", N: 1}, snapshot.Levels[1][0])
+
+	px, err := snapshot.Levels[0][0].Price()
+	require.NoError(t, err)
+	assert.Equal(t, 113377.0, px)
+	sz, err := snapshot.Levels[0][1].Size()
+	require.NoError(t, err)
+	assert.Equal(t, 1.2, sz)
+}
+
+func TestCandlesSnapshot(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["candleSnapshot"] = loadCassette(t, "candles_btc.json")
+	info := newTestInfo(t, fs)
+
+	candles, err := info.CandlesSnapshot("BTC", "1h", 1754449200000, 1754456399999)
+	require.NoError(t, err)
+	require.Len(t, candles, 2)
+	assert.Equal(t, utils.Candle{
+		OpenTime:  1754449200000,
+		CloseTime: 1754452799999,
+		Coin:      "BTC",
+		Interval:  "1h",
+		Open:      "113620.0",
+		Close:     "113377.0",
+		High:      "113890.0",
+		Low:       "113105.0",
+		Volume:    "512.34561",
+		Trades:    10487,
+	}, candles[0])
+
+	open, high, low, close, volume, err := candles[1].OHLCV()
+	require.NoError(t, err)
+	assert.Equal(t, []float64{113378, 113600, 113250, 113512, 201.00003}, []float64{open, high, low, close, volume})
+
+	_, err = info.CandlesSnapshot("BTC", "2m", 0, 1)
+	assert.ErrorContains(t, err, "unsupported candle interval")
+
+	_, _, _, _, _, err = utils.Candle{Open: "x"}.OHLCV()
+	assert.Error(t, err)
+}
+
+func TestSubscribeCandles(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	_, err := info.SubscribeCandles("BTC", "2m", func(utils.Candle) {})
+	assert.ErrorContains(t, err, "unsupported candle interval")
+	_, err = info.SubscribeCandles("UNKNOWN", "1m", func(utils.Candle) {})
+	assert.ErrorContains(t, err, "coin not found")
+
+	candles := make(chan utils.Candle, 8)
+	_, err = info.SubscribeCandles("BTC", "1m", func(candle utils.Candle) { candles <- candle })
+	require.NoError(t, err)
+	conn := receiveConn(t, fs)
+	subscription := receiveSubscriptions(t, fs, 1)[0]
+	assert.Equal(t, map[string]interface{}{"type": "candle", "coin": "BTC", "interval": "1m"}, subscription)
+
+	send := func(openTime int64, close string) {
+		t.Helper()
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{
+			"channel": "candle",
+			"data": map[string]interface{}{
+				"t": openTime, "T": openTime + 59999, "s": "BTC", "i": "1m",
+				"o": "113000.0", "c": close, "h": "113500.0", "l": "112900.0", "v": "12.5", "n": 42,
+			},
+		}))
+	}
+	receive := func() utils.Candle {
+		t.Helper()
+		select {
+		case candle := <-candles:
+			return candle
+		case <-time.After(5 * time.Second):
+			t.Fatal("candle not delivered")
+			return utils.Candle{}
+		}
+	}
+
+	describe := func(candle utils.Candle) string {
+		return fmt.Sprintf("%s:%v", candle.Close, candle.Closed)
+	}
+
+	// A candle past its close time closes without a next one, and late
+	// updates to it are dropped
+	current := time.Now().Truncate(time.Minute).UnixMilli()
+	past := current - 10*60000
+	send(past, "112900.0")
+	assert.Equal(t, "112900.0:false", describe(receive()))
+	assert.Equal(t, "112900.0:true", describe(receive()))
+	send(past, "112950.0")
+
+	// Candles still open are closed when the next one starts
+	send(current, "113100.0")
+	send(current, "113200.0")
+	send(current+60000, "113300.0")
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, describe(receive()))
+	}
+	assert.Equal(t, []string{"113100.0:false", "113200.0:false", "113200.0:true", "113300.0:false"}, got)
+}
+
+func TestUserFills(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userFills"] = loadCassette(t, "user_fills.json")
+	fs.infoResponses["userFillsByTime"] = loadCassette(t, "user_fills.json")
+	info := newTestInfo(t, fs)
+
+	fills, err := info.UserFills("0x1719884eb866cb12b2287399b15f7db5e7d775ea")
+	require.NoError(t, err)
+	require.Len(t, fills, 2)
+
+	fill := fills[0]
+	assert.Equal(t, "BTC", fill.Coin)
+	assert.Equal(t, utils.SideBid, fill.Side)
+	assert.Equal(t, 9007199254740993, fill.Oid)
+	assert.Equal(t, 284620517355312, fill.Tid)
+	require.NotNil(t, fill.BuilderFee)
+	assert.Equal(t, "0.011337", *fill.BuilderFee)
+	require.NotNil(t, fill.TwapID)
+	assert.Equal(t, int64(3156), *fill.TwapID)
+	assert.Nil(t, fill.Liquidation)
+
+	liquidation := fills[1]
+	assert.Nil(t, liquidation.BuilderFee)
+	assert.Nil(t, liquidation.TwapID)
+	require.NotNil(t, liquidation.Liquidation)
+	require.NotNil(t, liquidation.Liquidation.LiquidatedUser)
+	assert.Equal(t, "0x1719884eb866cb12b2287399b15f7db5e7d775ea", *liquidation.Liquidation.LiquidatedUser)
+	assert.Equal(t, "2890.9", liquidation.Liquidation.MarkPx)
+	assert.Equal(t, "market", liquidation.Liquidation.Method)
+
+	endTime := int64(1754451000000)
+	fills, err = info.UserFillsByTime("0x1719884eb866cb12b2287399b15f7db5e7d775ea", 1754450000000, &endTime)
+	require.NoError(t, err)
+	require.Len(t, fills, 2)
+	assert.NotContains(t, fs.lastInfoRequest(t), "aggregateByTime")
+}
+
+func TestUserFillsAggregateByTime(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userFills"] = loadCassette(t, "user_fills_aggregated.json")
+	fs.infoResponses["userFillsByTime"] = loadCassette(t, "user_fills_aggregated.json")
+	info := newTestInfo(t, fs)
+
+	fills, err := info.UserFills("0x1719884eb866cb12b2287399b15f7db5e7d775ea", hyperliquid.FillsOptions{AggregateByTime: true})
+	require.NoError(t, err)
+	require.Len(t, fills, 1)
+	assert.Equal(t, "0.03", fills[0].Sz)
+	assert.Equal(t, 0, fills[0].Tid)
+	assert.Equal(t, true, fs.lastInfoRequest(t)["aggregateByTime"])
+
+	_, err = info.UserFillsByTime("0x1719884eb866cb12b2287399b15f7db5e7d775ea", 1754450000000, nil, hyperliquid.FillsOptions{AggregateByTime: true})
+	require.NoError(t, err)
+	request := fs.lastInfoRequest(t)
+	assert.Equal(t, "userFillsByTime", request["type"])
+	assert.Equal(t, true, request["aggregateByTime"])
+}
+
+func TestFundingHistory(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["fundingHistory"] = loadCassette(t, "funding_history.json")
+	info := newTestInfo(t, fs)
+
+	history, err := info.FundingHistory("ETH", 1683849600000, nil)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, hyperliquid.FundingHistoryEntry{
+		Coin:        "ETH",
+		FundingRate: "-0.00022196",
+		Premium:     "-0.00052196",
+		Time:        1683849600076,
+	}, history[0])
+
+	rate, err := history[1].FundingRate.Float()
+	require.NoError(t, err)
+	assert.Equal(t, 0.0000125, rate)
+
+	_, more := history.NextStartTime()
+	assert.False(t, more)
+
+	// A full page points at its last entry to continue from
+	entries := make([]string, hyperliquid.FundingPageLimit)
+	for i := range entries {
+		entries[i] = fmt.Sprintf(`{"coin": "ETH", "fundingRate": "0.0000125", "premium": "0.0", "time": %d}`, 1683849600000+int64(i)*3600000)
+	}
+	fs.setInfoResponse("fundingHistory", "["+strings.Join(entries, ",")+"]")
+	history, err = info.FundingHistory("ETH", 1683849600000, nil)
+	require.NoError(t, err)
+	next, more := history.NextStartTime()
+	assert.True(t, more)
+	assert.Equal(t, int64(1683849600000+499*3600000), next)
+}
+
+func TestUserFundingHistory(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["userFunding"] = loadCassette(t, "user_funding.json")
+	info := newTestInfo(t, fs)
+
+	history, err := info.UserFundingHistory("0x1719884eb866cb12b2287399b15f7db5e7d775ea", 1681222254000, nil)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, hyperliquid.UserFundingDelta{
+		Coin:        "ETH",
+		FundingRate: "0.0000417",
+		Szi:         "49.1477",
+		Usdc:        "-3.625312",
+		Time:        1681222254710,
+		Hash:        "0xa166e3fa63c25663024b03f2e0da011a00307e4017465df020210d3d432e7cb8",
+	}, history[0])
+
+	usdc, err := history[1].Usdc.Float()
+	require.NoError(t, err)
+	assert.Equal(t, 0.566891, usdc)
+	_, more := history.NextStartTime()
+	assert.False(t, more)
+}
+
+func TestHistoricalOrders(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["historicalOrders"] = loadCassette(t, "historical_orders.json")
+	info := newTestInfo(t, fs)
+
+	orders, err := info.HistoricalOrders("0x1719884eb866cb12b2287399b15f7db5e7d775ea")
+	require.NoError(t, err)
+	require.Len(t, orders, 2)
+
+	filled := orders[0]
+	assert.Equal(t, "filled", filled.Status)
+	assert.Equal(t, int64(1724361546645), filled.StatusTimestamp)
+	assert.Equal(t, "ETH", filled.Order.Coin)
+	assert.Equal(t, utils.SideAsk, filled.Order.Side)
+	assert.Equal(t, "0.0076", filled.Order.OrigSz)
+	assert.Equal(t, "Market", filled.Order.OrderType)
+	require.NotNil(t, filled.Order.Tif)
+	assert.Equal(t, "FrontendMarket", *filled.Order.Tif)
+	assert.True(t, filled.Order.ReduceOnly)
+	assert.Nil(t, filled.Order.Cloid)
+
+	canceled := orders[1]
+	assert.Equal(t, "marginCanceled", canceled.Status)
+	assert.True(t, canceled.Order.IsTrigger)
+	assert.Equal(t, "61000.0", canceled.Order.TriggerPx)
+	assert.Nil(t, canceled.Order.Tif)
+
+	cloid := "0x00000000000000000000000000000001"
+	assert.Equal(t, utils.OrderUpdate{
+		Order: utils.OrderInfo{
+			Coin:      "BTC",
+			Side:      utils.SideBid,
+			LimitPx:   "60000.0",
+			Sz:        "0.01",
+			Oid:       2,
+			Timestamp: 1724361600000,
+			OrigSz:    "0.01",
+			Cloid:     &cloid,
+		},
+		Status:          "marginCanceled",
+		StatusTimestamp: 1724361700000,
+	}, canceled.OrderUpdate())
+}
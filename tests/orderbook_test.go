@@ -0,0 +1,188 @@
+// Package tests - Order book functionality tests
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadL2Book(t *testing.T, name string) utils.L2BookData {
+	t.Helper()
+	var snapshot utils.L2BookData
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, name)), &snapshot))
+	return snapshot
+}
+
+func TestSimulateMarketOrder(t *testing.T) {
+	book := hyperliquid.NewOrderBook(loadL2Book(t, "l2_book_btc.json"))
+
+	tests := []struct {
+		name     string
+		isBuy    bool
+		sz       float64
+		expected hyperliquid.FillEstimate
+	}{
+		{"Buy within first level", true, 0.05, hyperliquid.FillEstimate{AvgPx: 113378, WorstPx: 113378, FilledSz: 0.05, Levels: 1}},
+		{"Buy across levels", true, 0.3, hyperliquid.FillEstimate{AvgPx: (0.1*113378 + 0.2*113380) / 0.3, WorstPx: 113380, FilledSz: 0.3, Levels: 2}},
+		{"Sell across levels", false, 1.0, hyperliquid.FillEstimate{AvgPx: (0.5*113377 + 0.5*113376) / 1.0, WorstPx: 113376, FilledSz: 1.0, Levels: 2}},
+		{"Buy beyond thin book", true, 1.0, hyperliquid.FillEstimate{AvgPx: (0.1*113378 + 0.2*113380 + 0.3*113390) / 0.6, WorstPx: 113390, FilledSz: 0.6, RemainingSz: 0.4, Levels: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimate, err := book.SimulateMarketOrder(tt.isBuy, tt.sz)
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expected.AvgPx, estimate.AvgPx, 1e-9)
+			assert.Equal(t, tt.expected.WorstPx, estimate.WorstPx)
+			assert.InDelta(t, tt.expected.FilledSz, estimate.FilledSz, 1e-12)
+			assert.InDelta(t, tt.expected.RemainingSz, estimate.RemainingSz, 1e-12)
+			assert.Equal(t, tt.expected.Levels, estimate.Levels)
+		})
+	}
+
+	_, err := book.SimulateMarketOrder(true, 0)
+	assert.Error(t, err)
+}
+
+func TestSimulateMarketOrderOneSidedBook(t *testing.T) {
+	snapshot := loadL2Book(t, "l2_book_btc.json")
+	snapshot.Levels[1] = nil
+	book := hyperliquid.NewOrderBook(snapshot)
+
+	estimate, err := book.SimulateMarketOrder(true, 0.5)
+	require.NoError(t, err)
+	assert.Zero(t, estimate.FilledSz)
+	assert.Zero(t, estimate.AvgPx)
+	assert.Equal(t, 0.5, estimate.RemainingSz)
+
+	estimate, err = book.SimulateMarketOrder(false, 0.5)
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, estimate.FilledSz)
+	assert.Equal(t, 113377.0, estimate.AvgPx)
+}
+
+func TestOrderBookAccessors(t *testing.T) {
+	book := hyperliquid.NewOrderBook(loadL2Book(t, "l2_book_btc.json"))
+
+	bid, ok := book.BestBid()
+	require.True(t, ok)
+	assert.Equal(t, "113377.0", bid.Px)
+	ask, ok := book.BestAsk()
+	require.True(t, ok)
+	assert.Equal(t, "113378.0", ask.Px)
+	mid, ok := book.Mid()
+	require.True(t, ok)
+	assert.Equal(t, 113377.5, mid)
+	assert.Equal(t, uint64(1), book.Seq())
+
+	bids, asks := book.Levels(2)
+	assert.Len(t, bids, 2)
+	assert.Equal(t, "113380.0", asks[1].Px)
+	bids[0].Px = "0"
+	bid, _ = book.BestBid()
+	assert.Equal(t, "113377.0", bid.Px, "levels are copies")
+	bids, asks = book.Levels(0)
+	assert.Len(t, bids, 3)
+	assert.Len(t, asks, 3)
+
+	snapshot := loadL2Book(t, "l2_book_btc.json")
+	snapshot.Levels[1] = nil
+	book.ApplySnapshot(snapshot)
+	assert.Equal(t, uint64(2), book.Seq())
+	_, ok = book.BestAsk()
+	assert.False(t, ok)
+	_, ok = book.Mid()
+	assert.False(t, ok)
+}
+
+func TestLiveOrderBook(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	clock := &manualClock{now: time.Unix(1700000000, 0)}
+	book := hyperliquid.NewLiveOrderBook(info, "BTC")
+	book.SetClock(clock)
+	book.SetStaleTimeout(10 * time.Second)
+	updates := make(chan uint64, 4)
+	book.OnUpdate(func(book *hyperliquid.OrderBook) { updates <- book.Seq() })
+	require.NoError(t, book.Start())
+	require.Error(t, book.Start(), "already started")
+	t.Cleanup(func() { _ = book.Stop() })
+
+	conn := receiveConn(t, fs)
+	assert.Equal(t, "BTC", receiveSubscriptions(t, fs, 1)[0]["coin"])
+	send := func(ms int64) {
+		snapshot := loadL2Book(t, "l2_book_btc.json")
+		snapshot.Time = ms
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"channel": "l2Book", "data": snapshot}))
+	}
+	waitForUpdate := func(seq uint64) {
+		t.Helper()
+		select {
+		case got := <-updates:
+			assert.Equal(t, seq, got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("book not updated")
+		}
+	}
+
+	send(2000)
+	waitForUpdate(1)
+	mid, ok := book.Mid()
+	require.True(t, ok)
+	assert.Equal(t, 113377.5, mid)
+
+	// Out of order snapshots are dropped
+	send(1000)
+	send(3000)
+	waitForUpdate(2)
+	assert.Equal(t, int64(3000), book.Time())
+
+	// A book that goes quiet resubscribes
+	clock.waitForTimer(t)
+	clock.Advance(11 * time.Second)
+	assert.Equal(t, "BTC", receiveSubscriptions(t, fs, 1)[0]["coin"])
+	send(4000)
+	waitForUpdate(3)
+
+	require.NoError(t, book.Stop())
+}
+
+func TestInfoSimulateMarketOrder(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["l2Book"] = loadCassette(t, "l2_book_btc.json")
+	info := newTestInfo(t, fs)
+
+	estimate, err := info.SimulateMarketOrder(context.Background(), "BTC", true, 0.1)
+	require.NoError(t, err)
+	assert.Equal(t, 113378.0, estimate.AvgPx)
+
+	_, err = info.SimulateMarketOrder(context.Background(), "UNKNOWN", true, 0.1)
+	assert.Error(t, err)
+}
+
+func TestMarketOpenImpactCheck(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["l2Book"] = loadCassette(t, "l2_book_btc.json")
+	fs.infoResponses["allMids"] = loadCassette(t, "all_mids.json")
+	exchange, _ := newTestExchange(t, fs, nil)
+	exchange.SetImpactCheck(true)
+
+	_, err := exchange.MarketOpen("BTC", true, 1.0, nil, 0.01, nil, nil)
+	var impactErr *hyperliquid.ImpactExceededError
+	require.True(t, errors.As(err, &impactErr))
+	assert.Equal(t, 0.4, impactErr.Estimate.RemainingSz)
+	assert.Empty(t, fs.exchangeRequests)
+
+	_, err = exchange.MarketOpen("BTC", true, 0.2, nil, 0.01, nil, nil)
+	require.NoError(t, err)
+	assert.Len(t, fs.exchangeRequests, 1)
+}
@@ -0,0 +1,179 @@
+// Package tests - History pagination tests
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// pagingServer answers userFillsByTime and userFunding like the API does:
+// entries from startTime to endTime, oldest first, at most a page of them
+type pagingServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	fills    []utils.Fill
+	funding  []map[string]interface{}
+	requests int
+}
+
+func newPagingServer(t *testing.T) *pagingServer {
+	t.Helper()
+	ps := &pagingServer{}
+	ps.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload struct {
+			Type      string `json:"type"`
+			StartTime int64  `json:"startTime"`
+			EndTime   *int64 `json:"endTime"`
+		}
+		_ = json.Unmarshal(body, &payload)
+		inRange := func(t int64) bool {
+			return t >= payload.StartTime && (payload.EndTime == nil || t <= *payload.EndTime)
+		}
+
+		ps.mu.Lock()
+		defer ps.mu.Unlock()
+		ps.requests++
+		var page []interface{}
+		switch payload.Type {
+		case "userFillsByTime":
+			for _, fill := range ps.fills {
+				if inRange(fill.Time) && len(page) < hyperliquid.FillsPageLimit {
+					page = append(page, fill)
+				}
+			}
+		case "userFunding":
+			for _, delta := range ps.funding {
+				if inRange(delta["time"].(int64)) && len(page) < hyperliquid.FundingPageLimit {
+					page = append(page, delta)
+				}
+			}
+		default:
+			http.Error(w, `{"msg":"unexpected info request"}`, http.StatusBadRequest)
+			return
+		}
+		if page == nil {
+			page = []interface{}{}
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	}))
+	t.Cleanup(ps.Close)
+	return ps
+}
+
+func newPagingInfo(t *testing.T, ps *pagingServer) *hyperliquid.Info {
+	t.Helper()
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	var spotMeta hyperliquid.SpotMeta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "spot_meta.json")), &spotMeta))
+	info, err := hyperliquid.NewInfo(ps.URL, true, &meta, &spotMeta, nil, 5*time.Second)
+	require.NoError(t, err)
+	return info
+}
+
+// fillsAt returns n fills, with tids continuing from tid, at the given time
+func fillsAt(time int64, tid int, n int) []utils.Fill {
+	fills := make([]utils.Fill, n)
+	for i := range fills {
+		fills[i] = utils.Fill{Coin: "BTC", Time: time, Tid: tid + i}
+	}
+	return fills
+}
+
+func TestUserFillsByTimePaged(t *testing.T) {
+	ps := newPagingServer(t)
+	// 4500 fills, one per millisecond except for five sharing the end of the
+	// first page and three sharing the end of the second
+	for i := 0; i < 1998; i++ {
+		ps.fills = append(ps.fills, fillsAt(int64(1000+i), i, 1)...)
+	}
+	ps.fills = append(ps.fills, fillsAt(5000, 10000, 5)...)
+	for i := 0; i < 1994; i++ {
+		ps.fills = append(ps.fills, fillsAt(int64(6000+i), 20000+i, 1)...)
+	}
+	ps.fills = append(ps.fills, fillsAt(9000, 30000, 3)...)
+	for i := 0; i < 500; i++ {
+		ps.fills = append(ps.fills, fillsAt(int64(10000+i), 40000+i, 1)...)
+	}
+	info := newPagingInfo(t, ps)
+
+	var pages [][]utils.Fill
+	seen := make(map[int]bool)
+	err := info.UserFillsByTimePaged("0x0000000000000000000000000000000000000001", 0, nil, func(fills []utils.Fill) error {
+		pages = append(pages, fills)
+		for _, fill := range fills {
+			require.False(t, seen[fill.Tid], "fill %d returned twice", fill.Tid)
+			seen[fill.Tid] = true
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	require.Len(t, seen, len(ps.fills))
+	require.Equal(t, 3, ps.requests)
+
+	// endTime stops the walk, and an error from fn ends it early
+	ps.requests = 0
+	end := int64(5000)
+	total := 0
+	require.NoError(t, info.UserFillsByTimePaged("0x0000000000000000000000000000000000000001", 0, &end, func(fills []utils.Fill) error {
+		total += len(fills)
+		return nil
+	}))
+	require.Equal(t, 2003, total)
+
+	stop := errors.New("stop")
+	err = info.UserFillsByTimePaged("0x0000000000000000000000000000000000000001", 0, nil, func([]utils.Fill) error {
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+}
+
+func TestUserFillsByTimePagedSameMillisecond(t *testing.T) {
+	ps := newPagingServer(t)
+	// More fills in one millisecond than fit in a page
+	ps.fills = append(ps.fills, fillsAt(1000, 0, hyperliquid.FillsPageLimit+10)...)
+	ps.fills = append(ps.fills, fillsAt(1001, 50000, 2)...)
+	info := newPagingInfo(t, ps)
+
+	total := 0
+	err := info.UserFillsByTimePaged("0x0000000000000000000000000000000000000001", 0, nil, func(fills []utils.Fill) error {
+		total += len(fills)
+		return nil
+	})
+	require.NoError(t, err)
+	// The walk moves past the crowded millisecond instead of looping on it
+	require.Equal(t, hyperliquid.FillsPageLimit+2, total)
+	require.LessOrEqual(t, ps.requests, 3)
+}
+
+func TestUserFundingHistoryPaged(t *testing.T) {
+	ps := newPagingServer(t)
+	for i := 0; i < 1200; i++ {
+		ps.funding = append(ps.funding, map[string]interface{}{
+			"time":  int64(3600000 * i),
+			"hash":  "0x0000000000000000000000000000000000000000000000000000000000000000",
+			"delta": map[string]interface{}{"type": "funding", "coin": "BTC", "usdc": "-0.1", "szi": "0.1", "fundingRate": "0.0000125"},
+		})
+	}
+	info := newPagingInfo(t, ps)
+
+	var sizes []int
+	err := info.UserFundingHistoryPaged("0x0000000000000000000000000000000000000001", 0, nil, func(history hyperliquid.UserFundingHistory) error {
+		sizes = append(sizes, len(history))
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{500, 499, 201}, sizes)
+}
@@ -0,0 +1,447 @@
+// Package tests - User stream tests
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const streamUser = "0x1719884eb866cb12b2287399b15f7db5e7d775ea"
+
+func streamFill(tid int, t int64) map[string]interface{} {
+	return map[string]interface{}{
+		"coin": "BTC", "px": "100000.0", "sz": "0.001", "side": "B", "time": t,
+		"startPosition": "0.0", "dir": "Open Long", "closedPnl": "0.0",
+		"hash": "0x0", "oid": tid * 10, "crossed": true, "fee": "0.01", "tid": tid, "feeToken": "USDC",
+	}
+}
+
+func streamOrderUpdate(oid int, status string, t int64) map[string]interface{} {
+	return map[string]interface{}{
+		"order": map[string]interface{}{
+			"coin": "BTC", "side": "B", "limitPx": "100000.0", "sz": "0.001",
+			"oid": oid, "timestamp": int64(1500), "origSz": "0.001",
+		},
+		"status":          status,
+		"statusTimestamp": t,
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func sendFills(t *testing.T, conn *websocket.Conn, isSnapshot bool, fills ...map[string]interface{}) {
+	t.Helper()
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"channel": "userFills",
+		"data":    map[string]interface{}{"user": streamUser, "isSnapshot": isSnapshot, "fills": fills},
+	}))
+}
+
+// describeEvent renders an event compactly for comparing delivered streams
+func describeEvent(event hyperliquid.UserStreamEvent) string {
+	switch event.Type {
+	case hyperliquid.UserStreamFill:
+		return fmt.Sprintf("fill:%d", event.Fill.Tid)
+	case hyperliquid.UserStreamOrderUpdate:
+		return fmt.Sprintf("order:%d:%s", event.OrderUpdate.Order.Oid, event.OrderUpdate.Status)
+	case hyperliquid.UserStreamResynced:
+		return fmt.Sprintf("resynced:%d", event.Recovered)
+	default:
+		return fmt.Sprintf("failed:%v", event.Err)
+	}
+}
+
+func TestSubscribeUserEvents(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	events := make(chan utils.UserEventsData, 4)
+	_, err := info.SubscribeUserEvents(streamUser, func(data utils.UserEventsData) { events <- data })
+	require.NoError(t, err)
+	conn := receiveConn(t, fs)
+	assert.Equal(t, "userEvents", receiveSubscriptions(t, fs, 1)[0]["type"])
+
+	receive := func(cassette string) utils.UserEventsData {
+		t.Helper()
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(loadCassette(t, cassette))))
+		select {
+		case data := <-events:
+			return data
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not delivered", cassette)
+			return utils.UserEventsData{}
+		}
+	}
+
+	data := receive("user_events_fills.json")
+	require.Len(t, data.Fills, 1)
+	assert.Equal(t, 932114850163474, data.Fills[0].Tid)
+	assert.Nil(t, data.Funding)
+
+	data = receive("user_events_funding.json")
+	assert.Empty(t, data.Fills)
+	require.NotNil(t, data.Funding)
+	assert.Equal(t, utils.UserEventFunding{Time: 1754452800000, Coin: "ETH", Usdc: "-0.417982", Szi: "1.2", FundingRate: "0.0000125"}, *data.Funding)
+
+	data = receive("user_events_liquidation.json")
+	require.NotNil(t, data.Liquidation)
+	assert.Equal(t, int64(281474976710657), data.Liquidation.Lid)
+	assert.Equal(t, "0x2e3d94f0562703b25c83308a05046ddaf9a8dd14", data.Liquidation.Liquidator)
+	assert.Equal(t, streamUser, data.Liquidation.LiquidatedUser)
+	assert.Equal(t, "10234.56", data.Liquidation.LiquidatedNtlPos)
+	assert.Equal(t, "512.33", data.Liquidation.LiquidatedAccountValue)
+
+	data = receive("user_events_non_user_cancel.json")
+	assert.Equal(t, []utils.NonUserCancel{{Coin: "BTC", Oid: 77738308}, {Coin: "ETH", Oid: 77738309}}, data.NonUserCancels)
+	assert.Nil(t, data.Liquidation)
+}
+
+func TestSubscribeOrderUpdates(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	batches := make(chan []utils.OrderUpdate, 2)
+	_, err := info.SubscribeOrderUpdates(streamUser, func(updates []utils.OrderUpdate) { batches <- updates })
+	require.NoError(t, err)
+	conn := receiveConn(t, fs)
+	assert.Equal(t, "orderUpdates", receiveSubscriptions(t, fs, 1)[0]["type"])
+
+	receive := func(cassette string) []utils.OrderUpdate {
+		t.Helper()
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(loadCassette(t, cassette))))
+		select {
+		case updates := <-batches:
+			return updates
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s not delivered", cassette)
+			return nil
+		}
+	}
+
+	cloid := "0x00000000000000000000000000000001"
+	updates := receive("order_updates.json")
+	require.Len(t, updates, 2)
+	assert.Equal(t, utils.OrderUpdate{
+		Order: utils.OrderInfo{
+			Coin: "BTC", Side: "B", LimitPx: "110000.0", Sz: "0.001", Oid: 39870233412,
+			Timestamp: 1754450974231, OrigSz: "0.001", Cloid: &cloid,
+		},
+		Status:          "open",
+		StatusTimestamp: 1754450974231,
+	}, updates[0])
+	assert.Equal(t, "filled", updates[1].Status)
+	assert.Nil(t, updates[1].Order.Cloid)
+
+	// A single update outside an array
+	updates = receive("order_updates_single.json")
+	require.Len(t, updates, 1)
+	assert.Equal(t, 39870233412, updates[0].Order.Oid)
+	assert.Equal(t, "canceled", updates[0].Status)
+	assert.Equal(t, int64(1754451012006), updates[0].StatusTimestamp)
+}
+
+func TestUserStreamRecoversDroppedFrames(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+	fs.infoResponses["userFillsByTime"] = mustJSON(t, []interface{}{streamFill(1, 1000)})
+	fs.infoResponses["historicalOrders"] = "[]"
+
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	info, err := hyperliquid.NewInfo(fs.URL, false, &meta, nil, nil, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = info.DisconnectWebSocket() })
+
+	var mu sync.Mutex
+	var events []hyperliquid.UserStreamEvent
+	stream := hyperliquid.NewUserStream(info, streamUser, 1000, func(event hyperliquid.UserStreamEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	})
+	require.NoError(t, stream.Start())
+	waitForEvents := func(n int) {
+		require.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(events) >= n
+		}, 5*time.Second, 10*time.Millisecond)
+	}
+
+	conn := <-fs.wsConns
+	for i := 0; i < 2; i++ {
+		select {
+		case <-fs.wsSubscriptions:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for subscriptions")
+		}
+	}
+
+	// Initial snapshot overlaps the REST catch-up. Each channel is dispatched
+	// on its own, so wait for one before sending on the other.
+	sendFills(t, conn, true, streamFill(1, 1000))
+	waitForEvents(1)
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"channel": "orderUpdates",
+		"data":    []interface{}{streamOrderUpdate(7, "open", 1500)},
+	}))
+	waitForEvents(2)
+	sendFills(t, conn, false, streamFill(2, 2000))
+	waitForEvents(3)
+
+	// Fills 3 and 4 and the order fill are dropped while disconnected; the
+	// server only has them over REST, alongside events already delivered
+	fs.setInfoResponse("userFillsByTime", mustJSON(t, []interface{}{
+		streamFill(2, 2000), streamFill(3, 3000), streamFill(4, 4000),
+	}))
+	fs.setInfoResponse("historicalOrders", mustJSON(t, []interface{}{
+		streamOrderUpdate(7, "filled", 4000), streamOrderUpdate(5, "canceled", 500),
+	}))
+	sendFills(t, conn, true, streamFill(1, 1000), streamFill(2, 2000), streamFill(3, 3000), streamFill(4, 4000))
+
+	// Live traffic resumes, replaying the last fill
+	sendFills(t, conn, false, streamFill(4, 4000), streamFill(5, 5000))
+
+	expected := []string{
+		"fill:1", "order:7:open", "fill:2",
+		"fill:3", "fill:4", "order:7:filled", "resynced:3",
+		"fill:5",
+	}
+	waitForEvents(len(expected))
+
+	mu.Lock()
+	defer mu.Unlock()
+	var delivered []string
+	for i, event := range events {
+		assert.Equal(t, uint64(i+1), event.Seq)
+		delivered = append(delivered, describeEvent(event))
+	}
+	assert.Equal(t, expected, delivered)
+	assert.Equal(t, int64(5000), stream.LastFillTime())
+}
+
+// serveFillHistory answers userFillsByTime like the API does: the fills
+// from startTime on, oldest first, at most a page of them
+func serveFillHistory(fs *fakeServer, fills []map[string]interface{}) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.infoReply = func(payload map[string]interface{}) string {
+		if payload["type"] != "userFillsByTime" {
+			return ""
+		}
+		startTime := int64(payload["startTime"].(float64))
+		page := []interface{}{}
+		for _, fill := range fills {
+			if fill["time"].(int64) >= startTime && len(page) < hyperliquid.FillsPageLimit {
+				page = append(page, fill)
+			}
+		}
+		data, _ := json.Marshal(page)
+		return string(data)
+	}
+}
+
+// fillHistory returns n fills, with tids continuing from tid, one per
+// millisecond from time on, or all at time if shared
+func fillHistory(time int64, tid int, n int, shared bool) []map[string]interface{} {
+	fills := make([]map[string]interface{}, n)
+	for i := range fills {
+		t := time
+		if !shared {
+			t += int64(i)
+		}
+		fills[i] = streamFill(tid+i, t)
+	}
+	return fills
+}
+
+func TestUserStreamRecoversFullPages(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["historicalOrders"] = "[]"
+	// More fills than one page holds were made before the stream started
+	serveFillHistory(fs, fillHistory(1000, 1, hyperliquid.FillsPageLimit+5, false))
+	info := newTestInfoWithWS(t, fs)
+
+	events := make(chan hyperliquid.UserStreamEvent, 3*hyperliquid.FillsPageLimit)
+	stream := hyperliquid.NewUserStream(info, streamUser, 1000, func(event hyperliquid.UserStreamEvent) {
+		events <- event
+	})
+	require.NoError(t, stream.Start())
+	conn := receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 2)
+	receive := func() hyperliquid.UserStreamEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event delivered")
+			return hyperliquid.UserStreamEvent{}
+		}
+	}
+
+	// The catch-up walks past the full first page
+	sendFills(t, conn, true)
+	for tid := 1; tid <= hyperliquid.FillsPageLimit+5; tid++ {
+		event := receive()
+		require.Equal(t, hyperliquid.UserStreamFill, event.Type)
+		require.Equal(t, tid, event.Fill.Tid)
+	}
+	lastTime := stream.LastFillTime()
+	assert.Equal(t, int64(1000+hyperliquid.FillsPageLimit+4), lastTime)
+
+	// While disconnected, more fills than a page holds shared a millisecond,
+	// so some cannot be recovered and the resync reports the gap
+	crowded := fillHistory(lastTime+1, 10000, hyperliquid.FillsPageLimit+10, true)
+	serveFillHistory(fs, append(crowded, streamFill(20000, lastTime+2)))
+	sendFills(t, conn, true)
+	for i := 0; i < hyperliquid.FillsPageLimit+1; i++ {
+		require.Equal(t, hyperliquid.UserStreamFill, receive().Type)
+	}
+	event := receive()
+	require.Equal(t, hyperliquid.UserStreamResyncFailed, event.Type)
+	assert.ErrorIs(t, event.Err, hyperliquid.ErrUserStreamGap)
+
+	// Historical orders only reach back to the most recent ones
+	serveFillHistory(fs, nil)
+	orders := make([]interface{}, hyperliquid.HistoricalOrdersLimit)
+	for i := range orders {
+		orders[i] = streamOrderUpdate(i+1, "filled", lastTime+20000+int64(i))
+	}
+	fs.setInfoResponse("historicalOrders", mustJSON(t, orders))
+	sendFills(t, conn, true)
+	for i := 0; i < hyperliquid.HistoricalOrdersLimit; i++ {
+		require.Equal(t, hyperliquid.UserStreamOrderUpdate, receive().Type)
+	}
+	event = receive()
+	require.Equal(t, hyperliquid.UserStreamResyncFailed, event.Type)
+	assert.ErrorIs(t, event.Err, hyperliquid.ErrUserStreamGap)
+	require.NoError(t, stream.Stop())
+}
+
+func TestFillTracker(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["spotMeta"] = loadCassette(t, "spot_meta.json")
+	fs.infoResponses["userFills"] = mustJSON(t, []interface{}{streamFill(2, 2000), streamFill(1, 1000)})
+
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	info, err := hyperliquid.NewInfo(fs.URL, false, &meta, nil, nil, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = info.DisconnectWebSocket() })
+
+	batches := make(chan []int, 8)
+	tracker := hyperliquid.NewFillTracker(info, streamUser, func(fills []utils.Fill) {
+		var tids []int
+		for _, fill := range fills {
+			tids = append(tids, fill.Tid)
+		}
+		batches <- tids
+	})
+	receive := func() []int {
+		t.Helper()
+		select {
+		case tids := <-batches:
+			return tids
+		case <-time.After(5 * time.Second):
+			t.Fatal("no fills delivered")
+			return nil
+		}
+	}
+
+	// The REST backfill is delivered oldest first
+	backfill, err := info.UserFills(streamUser)
+	require.NoError(t, err)
+	tracker.Backfill(backfill)
+	assert.Equal(t, []int{1, 2}, receive())
+
+	require.NoError(t, tracker.Start())
+	conn := receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 1)
+
+	// The snapshot overlaps the backfill; live fills may repeat
+	sendFills(t, conn, true, streamFill(1, 1000), streamFill(2, 2000), streamFill(3, 3000))
+	assert.Equal(t, []int{3}, receive())
+	sendFills(t, conn, false, streamFill(3, 3000), streamFill(4, 4000))
+	assert.Equal(t, []int{4}, receive())
+
+	// Fills 5 and 6 were missed while disconnected; the snapshot after the
+	// reconnect only has the most recent
+	fs.setInfoResponse("userFillsByTime", mustJSON(t, []interface{}{streamFill(4, 4000), streamFill(6, 6000), streamFill(5, 5000)}))
+	sendFills(t, conn, true, streamFill(6, 6000), streamFill(7, 7000))
+	assert.Equal(t, []int{5, 6, 7}, receive())
+	request := fs.lastInfoRequest(t)
+	assert.Equal(t, "userFillsByTime", request["type"])
+	assert.Equal(t, float64(0), request["startTime"], "looks back from the last fill")
+	assert.Equal(t, int64(7000), tracker.LastFillTime())
+
+	// Fills aggregated by time have no tid and are told apart by time
+	aggregated := func(t int64) map[string]interface{} {
+		fill := streamFill(0, t)
+		fill["oid"] = 99
+		return fill
+	}
+	sendFills(t, conn, false, aggregated(8000), aggregated(9000), aggregated(8000))
+	assert.Equal(t, []int{0, 0}, receive())
+	assert.Equal(t, int64(9000), tracker.LastFillTime())
+
+	require.NoError(t, tracker.Stop())
+	assert.Empty(t, batches)
+}
+
+func TestFillTrackerResyncsFullPages(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	batches := make(chan []utils.Fill, 4)
+	tracker := hyperliquid.NewFillTracker(info, streamUser, func(fills []utils.Fill) { batches <- fills })
+	receive := func() []utils.Fill {
+		t.Helper()
+		select {
+		case fills := <-batches:
+			return fills
+		case <-time.After(5 * time.Second):
+			t.Fatal("no fills delivered")
+			return nil
+		}
+	}
+	require.NoError(t, tracker.Start())
+	conn := receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 1)
+	sendFills(t, conn, true, streamFill(1, 1000))
+	require.Len(t, receive(), 1)
+
+	// More fills than one page holds were missed while disconnected
+	serveFillHistory(fs, fillHistory(1000, 1, hyperliquid.FillsPageLimit+5, false))
+	sendFills(t, conn, true)
+	fills := receive()
+	require.Len(t, fills, hyperliquid.FillsPageLimit+4)
+	for i, fill := range fills {
+		require.Equal(t, i+2, fill.Tid)
+	}
+	assert.Equal(t, int64(1000+hyperliquid.FillsPageLimit+4), tracker.LastFillTime())
+
+	// Fills crowded into one millisecond beyond a page cannot all be fetched
+	lastTime := tracker.LastFillTime()
+	serveFillHistory(fs, fillHistory(lastTime+1, 10000, hyperliquid.FillsPageLimit+10, true))
+	assert.Error(t, tracker.Resync(context.Background()))
+	assert.Len(t, receive(), hyperliquid.FillsPageLimit)
+
+	require.NoError(t, tracker.Stop())
+	assert.Empty(t, batches)
+}
@@ -0,0 +1,1508 @@
+// Package tests - WebSocket manager tests
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receiveSubscriptions waits for n subscribe requests on the fake server
+func receiveSubscriptions(t testing.TB, fs *fakeServer, n int) []map[string]interface{} {
+	t.Helper()
+	var subscriptions []map[string]interface{}
+	for len(subscriptions) < n {
+		select {
+		case subscription := <-fs.wsSubscriptions:
+			subscriptions = append(subscriptions, subscription)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for subscriptions, got %v", subscriptions)
+		}
+	}
+	return subscriptions
+}
+
+func receiveConn(t testing.TB, fs *fakeServer) *websocket.Conn {
+	t.Helper()
+	select {
+	case conn := <-fs.wsConns:
+		return conn
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a WebSocket connection")
+		return nil
+	}
+}
+
+// unsubscribe unsubscribes and reports whether the subscription existed
+func unsubscribe(t testing.TB, ws *hyperliquid.WebSocketManager, subscription hyperliquid.Subscription, id int) bool {
+	t.Helper()
+	unsubscribed, err := ws.Unsubscribe(subscription, id)
+	require.NoError(t, err)
+	return unsubscribed
+}
+
+func sendBook(t testing.TB, conn *websocket.Conn, coin string) {
+	t.Helper()
+	sendBookAt(t, conn, coin, 1)
+}
+
+func sendBookAt(t testing.TB, conn *websocket.Conn, coin string, time int) {
+	t.Helper()
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"channel": "l2Book",
+		"data":    map[string]interface{}{"coin": coin, "time": time, "levels": []interface{}{[]interface{}{}, []interface{}{}}},
+	}))
+}
+
+func sendTrade(t testing.TB, conn *websocket.Conn, coin string) {
+	t.Helper()
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{
+		"channel": "trades",
+		"data":    []interface{}{map[string]interface{}{"coin": coin, "px": "3000.0"}},
+	}))
+}
+
+// bookTime returns the time of an l2Book message
+func bookTime(msg hyperliquid.WsMsg) int {
+	return int(msg.Data.(map[string]interface{})["time"].(float64))
+}
+
+func TestTypedSubscriptionsWire(t *testing.T) {
+	user := "0x0000000000000000000000000000000000000001"
+	tests := []struct {
+		subscription utils.Subscription
+		expected     utils.WsSubscription
+	}{
+		{utils.AllMidsSubscription{}, utils.WsSubscription{Type: utils.SubTypeAllMids}},
+		{utils.BboSubscription{Coin: "ETH"}, utils.WsSubscription{Type: utils.SubTypeBbo, Coin: "ETH"}},
+		{utils.L2BookSubscription{Coin: "BTC"}, utils.WsSubscription{Type: utils.SubTypeL2Book, Coin: "BTC"}},
+		{utils.TradesSubscription{Coin: "BTC"}, utils.WsSubscription{Type: utils.SubTypeTrades, Coin: "BTC"}},
+		{utils.UserEventsSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeUserEvents, User: user}},
+		{utils.UserFillsSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeUserFills, User: user}},
+		{utils.CandleSubscription{Coin: "BTC", Interval: "1m"}, utils.WsSubscription{Type: utils.SubTypeCandle, Coin: "BTC", Interval: "1m"}},
+		{utils.OrderUpdatesSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeOrderUpdates, User: user}},
+		{utils.UserFundingsSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeUserFundings, User: user}},
+		{utils.UserNonFundingLedgerUpdatesSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeUserNonFundingLedgerUpdates, User: user}},
+		{utils.WebData2Subscription{User: user}, utils.WsSubscription{Type: utils.SubTypeWebData2, User: user}},
+		{utils.ActiveAssetCtxSubscription{Coin: "BTC"}, utils.WsSubscription{Type: utils.SubTypeActiveAssetCtx, Coin: "BTC"}},
+		{utils.ActiveAssetDataSubscription{User: user, Coin: "BTC"}, utils.WsSubscription{Type: utils.SubTypeActiveAssetData, User: user, Coin: "BTC"}},
+		{utils.AllMidsSubscription{Dex: "xyz"}, utils.WsSubscription{Type: utils.SubTypeAllMids, Dex: "xyz"}},
+		{utils.NotificationSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeNotification, User: user}},
+		{utils.UserTwapSliceFillsSubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeUserTwapSliceFills, User: user}},
+		{utils.UserTwapHistorySubscription{User: user}, utils.WsSubscription{Type: utils.SubTypeUserTwapHistory, User: user}},
+		{utils.UnknownSubscription{}, utils.WsSubscription{Type: utils.SubTypeUnknown}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, tt.subscription.Wire())
+		assert.Equal(t, tt.expected.Type, tt.subscription.GetType())
+	}
+
+	// The deprecated names are the same types
+	var legacy hyperliquid.Subscription = utils.WsSubscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+	assert.Equal(t, utils.L2BookSubscription{Coin: "BTC"}.Wire(), legacy)
+}
+
+func TestTypedSubscription(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	// Spot pair names are remapped as for untyped subscriptions
+	books := make(chan hyperliquid.WsMsg, 1)
+	id, err := info.Subscribe(utils.L2BookSubscription{Coin: "HFUN/USDC"}, func(msg hyperliquid.WsMsg) { books <- msg })
+	require.NoError(t, err)
+	conn := receiveConn(t, fs)
+	assert.Equal(t, "@1", receiveSubscriptions(t, fs, 1)[0]["coin"])
+	sendBook(t, conn, "@1")
+	select {
+	case msg := <-books:
+		assert.Equal(t, "l2Book", msg.GetChannel())
+	case <-time.After(5 * time.Second):
+		t.Fatal("book message not delivered")
+	}
+
+	unsubscribed, err := info.Unsubscribe(utils.L2BookSubscription{Coin: "HFUN/USDC"}, id)
+	require.NoError(t, err)
+	assert.True(t, unsubscribed)
+}
+
+func TestAllMidsDex(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+
+	mids := map[string]chan utils.AllMidsData{"": make(chan utils.AllMidsData, 2), "xyz": make(chan utils.AllMidsData, 2)}
+	for dex, ch := range mids {
+		ch := ch
+		_, err := ws.SubscribeCtx(context.Background(), utils.AllMidsSubscription{Dex: dex}, func(msg hyperliquid.WsMsg) {
+			var data utils.AllMidsData
+			raw, _ := json.Marshal(msg.Data)
+			_ = json.Unmarshal(raw, &data)
+			ch <- data
+		})
+		require.NoError(t, err, "both dexs are subscribed at once")
+	}
+	var dexs []interface{}
+	for _, subscription := range receiveSubscriptions(t, fs, 2) {
+		dexs = append(dexs, subscription["dex"])
+	}
+	assert.ElementsMatch(t, []interface{}{nil, "xyz"}, dexs, "the default dex is sent without a dex")
+
+	// Messages are told apart by their dex field or their coins' prefix
+	for _, data := range []map[string]interface{}{
+		{"mids": map[string]string{"BTC": "50000.0", "@1": "10.0"}},
+		{"mids": map[string]string{"xyz:BTC": "51000.0"}},
+		{"dex": "xyz", "mids": map[string]string{}},
+	} {
+		require.NoError(t, conn.WriteJSON(map[string]interface{}{"channel": "allMids", "data": data}))
+	}
+	receive := func(dex string) utils.AllMidsData {
+		select {
+		case data := <-mids[dex]:
+			return data
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no mids for dex %q", dex)
+			return utils.AllMidsData{}
+		}
+	}
+	assert.Equal(t, "50000.0", receive("").Mids["BTC"])
+	assert.Equal(t, "51000.0", receive("xyz").Mids["xyz:BTC"])
+	assert.Equal(t, "xyz", receive("xyz").Dex)
+	assert.Empty(t, mids[""])
+
+	// Info sends builder dex coins by their dex-qualified names
+	fs.setInfoResponse("perpDexs", `[null, {"name": "xyz"}]`)
+	fs.setInfoResponse("meta", loadCassette(t, "meta_builder_dex.json"))
+	fs.setInfoResponse("spotMeta", loadCassette(t, "spot_meta.json"))
+	var meta hyperliquid.Meta
+	require.NoError(t, json.Unmarshal([]byte(loadCassette(t, "meta.json")), &meta))
+	info, err := hyperliquid.NewInfo(fs.URL, false, &meta, nil, []string{"", "xyz"}, 5*time.Second)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = info.DisconnectWebSocket() })
+	receiveConn(t, fs)
+	_, err = info.Subscribe(utils.L2BookSubscription{Coin: "xyz:BTC"}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	_, err = info.Subscribe(utils.AllMidsSubscription{Dex: "xyz"}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	subscriptions := receiveSubscriptions(t, fs, 2)
+	assert.Equal(t, "xyz:BTC", subscriptions[0]["coin"])
+	assert.Equal(t, "xyz", subscriptions[1]["dex"])
+}
+
+func TestUnknownChannels(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(10*time.Millisecond, 10*time.Millisecond)
+	t.Cleanup(ws.Stop)
+	var mu sync.Mutex
+	var raw []string
+	ws.OnRawMessage(func(message json.RawMessage) {
+		var msg struct {
+			Channel string `json:"channel"`
+		}
+		_ = json.Unmarshal(message, &msg)
+		mu.Lock()
+		defer mu.Unlock()
+		raw = append(raw, msg.Channel)
+	})
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	ctx := context.Background()
+
+	received := map[string]chan string{}
+	subscribe := func(name string, subscription utils.Subscription) {
+		ch := make(chan string, 4)
+		received[name] = ch
+		_, err := ws.SubscribeCtx(ctx, subscription, func(msg hyperliquid.WsMsg) { ch <- msg.Channel })
+		require.NoError(t, err, name)
+	}
+	user := "0x00000000000000000000000000000000000000AB"
+	subscribe("unknown", utils.UnknownSubscription{})
+	subscribe("twapStates", utils.WsSubscription{Type: "twapStates", User: user})
+	subscribe("notification", utils.NotificationSubscription{User: user})
+	subscribe("twapSliceFills", utils.UserTwapSliceFillsSubscription{User: user})
+	subscribe("twapHistory", utils.UserTwapHistorySubscription{User: user})
+	var types []interface{}
+	for _, subscription := range receiveSubscriptions(t, fs, 4) {
+		types = append(types, subscription["type"])
+	}
+	assert.ElementsMatch(t, []interface{}{"twapStates", "notification", "userTwapSliceFills", "userTwapHistory"}, types, "unknown is not sent")
+
+	for _, msg := range []map[string]interface{}{
+		{"channel": "twapStates", "data": map[string]interface{}{"user": user, "states": []interface{}{}}},
+		{"channel": "brandNew", "data": map[string]interface{}{}},
+		{"channel": "notification", "data": map[string]interface{}{"notification": "Order filled"}},
+		{"channel": "userTwapSliceFills", "data": map[string]interface{}{"user": strings.ToLower(user), "isSnapshot": true, "twapSliceFills": []interface{}{}}},
+		{"channel": "userTwapHistory", "data": map[string]interface{}{"user": user, "isSnapshot": true, "history": []interface{}{}}},
+		{"channel": "pong"},
+	} {
+		require.NoError(t, conn.WriteJSON(msg))
+	}
+	collect := func(name string, n int) []string {
+		var channels []string
+		for i := 0; i < n; i++ {
+			select {
+			case channel := <-received[name]:
+				channels = append(channels, channel)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s received %v, expected %d messages", name, channels, n)
+			}
+		}
+		return channels
+	}
+	// Unknown channels reach the catch-all, and their own subscribers
+	assert.Equal(t, []string{"twapStates", "brandNew"}, collect("unknown", 2))
+	assert.Equal(t, []string{"twapStates"}, collect("twapStates", 1))
+	assert.Equal(t, []string{"notification"}, collect("notification", 1))
+	assert.Equal(t, []string{"userTwapSliceFills"}, collect("twapSliceFills", 1))
+	assert.Equal(t, []string{"userTwapHistory"}, collect("twapHistory", 1))
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(raw) > 0 && raw[len(raw)-1] == "pong"
+	}, 5*time.Second, 5*time.Millisecond)
+	mu.Lock()
+	assert.Equal(t, []string{"", "subscriptionResponse", "subscriptionResponse", "subscriptionResponse", "subscriptionResponse",
+		"twapStates", "brandNew", "notification", "userTwapSliceFills", "userTwapHistory", "pong"}, raw, "the tap sees every message")
+	mu.Unlock()
+
+	// After a reconnect only the server-side subscriptions are re-sent
+	conn.Close()
+	conn = receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 4)
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"channel": "brandNew", "data": map[string]interface{}{}}))
+	assert.Equal(t, []string{"brandNew"}, collect("unknown", 1))
+	_, err := ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.AllMids}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	assert.Equal(t, "allMids", receiveSubscriptions(t, fs, 1)[0]["type"])
+	assert.Empty(t, fs.wsSubscriptions)
+}
+
+func TestSubscribeBBOAndActiveAssetCtx(t *testing.T) {
+	fs := newFakeServer(t)
+	info := newTestInfoWithWS(t, fs)
+
+	_, err := info.SubscribeBBO("UNKNOWN", func(utils.BboData) {})
+	assert.ErrorContains(t, err, "coin not found")
+
+	bbos := make(chan utils.BboData, 2)
+	perpCtxs := make(chan utils.ActiveAssetCtx, 1)
+	spotCtxs := make(chan utils.ActiveSpotAssetCtx, 1)
+	for _, name := range []string{"BTC", "HFUN/USDC"} {
+		_, err = info.SubscribeBBO(name, func(data utils.BboData) { bbos <- data })
+		require.NoError(t, err)
+		_, err = info.SubscribeActiveAssetCtx(name,
+			func(data utils.ActiveAssetCtx) { perpCtxs <- data },
+			func(data utils.ActiveSpotAssetCtx) { spotCtxs <- data })
+		require.NoError(t, err)
+	}
+	conn := receiveConn(t, fs)
+
+	// Spot pair names are sent as their coin
+	var coins []string
+	for _, subscription := range receiveSubscriptions(t, fs, 4) {
+		coins = append(coins, fmt.Sprintf("%s:%s", subscription["type"], subscription["coin"]))
+	}
+	assert.ElementsMatch(t, []string{"bbo:BTC", "activeAssetCtx:BTC", "bbo:@1", "activeAssetCtx:@1"}, coins)
+
+	for _, cassette := range []string{"bbo_btc.json", "bbo_spot.json", "active_asset_ctx_perp.json", "active_asset_ctx_spot.json"} {
+		require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(loadCassette(t, cassette))))
+	}
+	timeout := time.After(5 * time.Second)
+	for i := 0; i < 4; i++ {
+		select {
+		case bbo := <-bbos:
+			switch bbo.Coin {
+			case "BTC":
+				require.NotNil(t, bbo.Bbo[1])
+				assert.Equal(t, "113378.0", bbo.Bbo[1].Px)
+			case "@1":
+				assert.Equal(t, "0.07312", bbo.Bbo[0].Px)
+				assert.Nil(t, bbo.Bbo[1], "no asks")
+			}
+		case perp := <-perpCtxs:
+			assert.Equal(t, "BTC", perp.Coin)
+			assert.Equal(t, "113352.0", perp.Ctx.OraclePx)
+			require.NotNil(t, perp.Ctx.ImpactPxs)
+			assert.Equal(t, [2]string{"113376.0", "113379.0"}, *perp.Ctx.ImpactPxs)
+		case spot := <-spotCtxs:
+			assert.Equal(t, "@1", spot.Coin)
+			assert.Equal(t, "997802.44", spot.Ctx.CirculatingSupply)
+		case <-timeout:
+			t.Fatalf("only %d of 4 messages delivered", i)
+		}
+	}
+}
+
+// connectProxy is an HTTP CONNECT proxy that records the targets it tunnels to
+type connectProxy struct {
+	listener net.Listener
+	targets  chan string
+}
+
+func newConnectProxy(t *testing.T) *connectProxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	p := &connectProxy{listener: listener, targets: make(chan string, 4)}
+	t.Cleanup(func() { listener.Close() })
+	go p.serve()
+	return p
+}
+
+func (p *connectProxy) serve() {
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go p.tunnel(client)
+	}
+}
+
+func (p *connectProxy) tunnel(client net.Conn) {
+	defer client.Close()
+	request, err := http.ReadRequest(bufio.NewReader(client))
+	if err != nil || request.Method != http.MethodConnect {
+		return
+	}
+	target, err := net.Dial("tcp", request.Host)
+	if err != nil {
+		return
+	}
+	defer target.Close()
+	p.targets <- request.Host
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		return
+	}
+	go func() { _, _ = io.Copy(target, client) }()
+	_, _ = io.Copy(client, target)
+}
+
+func TestWebSocketManagerOptions(t *testing.T) {
+	fs := newFakeServer(t)
+	headers := make(chan http.Header, 1)
+	handler := fs.Config.Handler
+	fs.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ws" {
+			headers <- r.Header.Clone()
+		}
+		handler.ServeHTTP(w, r)
+	})
+	proxy := newConnectProxy(t)
+	proxyURL, err := url.Parse("http://" + proxy.listener.Addr().String())
+	require.NoError(t, err)
+
+	ws := hyperliquid.NewWebSocketManager(fs.URL, hyperliquid.WSOptions{
+		Dialer:       &websocket.Dialer{Proxy: http.ProxyURL(proxyURL), HandshakeTimeout: 5 * time.Second},
+		Header:       http.Header{"User-Agent": []string{"hyperliquid-go-test"}},
+		WriteTimeout: time.Second,
+	})
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+
+	// Dialed through the proxy, with the headers
+	select {
+	case target := <-proxy.targets:
+		assert.Equal(t, strings.TrimPrefix(fs.URL, "http://"), target)
+	case <-time.After(5 * time.Second):
+		t.Fatal("connection did not go through the proxy")
+	}
+	assert.Equal(t, "hyperliquid-go-test", (<-headers).Get("User-Agent"))
+
+	// Subscriptions work through the tunnel
+	books := make(chan struct{}, 1)
+	_, err = ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(hyperliquid.WsMsg) {
+		books <- struct{}{}
+	})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+	sendBook(t, fs.lastWsConn(t), "BTC")
+	select {
+	case <-books:
+	case <-time.After(5 * time.Second):
+		t.Fatal("book update did not arrive through the proxy")
+	}
+}
+
+func TestWebSocketManagerReadTimeout(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL, hyperliquid.WSOptions{ReadTimeout: 50 * time.Millisecond})
+	ws.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	t.Cleanup(ws.Stop)
+	disconnects := make(chan error, 1)
+	ws.OnDisconnect(func(err error) {
+		select {
+		case disconnects <- err:
+		default:
+		}
+	})
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+
+	// Nothing is sent, so the read times out and the manager reconnects
+	select {
+	case err := <-disconnects:
+		var netErr net.Error
+		require.ErrorAs(t, err, &netErr)
+		assert.True(t, netErr.Timeout())
+	case <-time.After(5 * time.Second):
+		t.Fatal("read did not time out")
+	}
+	receiveConn(t, fs)
+}
+
+func TestWebSocketManagerReconnects(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	t.Cleanup(ws.Stop)
+
+	books := make(chan string, 16)
+	trades := make(chan string, 16)
+	disconnects := make(chan error, 1)
+	reconnects := make(chan struct{}, 1)
+
+	// Queued before the connection is up
+	bookSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+	bookID, err := ws.Subscribe(bookSub, func(msg hyperliquid.WsMsg) { books <- msg.Channel })
+	require.NoError(t, err)
+
+	var tradesID int
+	ws.OnDisconnect(func(err error) {
+		// Subscribing during the gap is queued until the new connection opens
+		tradesID, _ = ws.Subscribe(hyperliquid.Subscription{Type: hyperliquid.Trades, Coin: "ETH"}, func(msg hyperliquid.WsMsg) {
+			trades <- msg.Channel
+		})
+		disconnects <- err
+	})
+	ws.OnReconnect(func() { reconnects <- struct{}{} })
+
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	assert.Equal(t, "l2Book", receiveSubscriptions(t, fs, 1)[0]["type"])
+	sendBook(t, conn, "BTC")
+	select {
+	case <-books:
+	case <-time.After(5 * time.Second):
+		t.Fatal("book message not delivered")
+	}
+
+	// Drop the connection mid-stream
+	require.NoError(t, conn.Close())
+	select {
+	case err := <-disconnects:
+		assert.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("disconnect not reported")
+	}
+
+	conn = receiveConn(t, fs)
+	var types []string
+	for _, subscription := range receiveSubscriptions(t, fs, 2) {
+		types = append(types, subscription["type"].(string))
+	}
+	assert.ElementsMatch(t, []string{"l2Book", "trades"}, types)
+	select {
+	case <-reconnects:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconnect not reported")
+	}
+
+	sendBook(t, conn, "BTC")
+	sendTrade(t, conn, "ETH")
+	for _, ch := range []chan string{books, trades} {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatal("message not delivered after reconnect")
+		}
+	}
+
+	// Subscription IDs from before the drop still unsubscribe
+	assert.True(t, unsubscribe(t, ws, bookSub, bookID))
+	assert.True(t, unsubscribe(t, ws, hyperliquid.Subscription{Type: hyperliquid.Trades, Coin: "ETH"}, tradesID))
+}
+
+// stallingConn is a net.Conn whose writes after the handshake block until
+// it is closed, when stall is set
+type stallingConn struct {
+	net.Conn
+	stall     bool
+	handshake sync.Once
+	stalled   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (c *stallingConn) Write(b []byte) (int, error) {
+	handshake := false
+	c.handshake.Do(func() { handshake = true })
+	if !c.stall || handshake {
+		return c.Conn.Write(b)
+	}
+	select {
+	case c.stalled <- struct{}{}:
+	default:
+	}
+	<-c.closed
+	return 0, net.ErrClosed
+}
+
+func (c *stallingConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return c.Conn.Close()
+}
+
+func TestWebSocketManagerResubscribeDoesNotHoldLock(t *testing.T) {
+	fs := newFakeServer(t)
+	stalled := make(chan struct{}, 1)
+	var dials atomic.Int32
+	dialer := &websocket.Dialer{
+		HandshakeTimeout: 5 * time.Second,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			// The connection after the first drop stalls on its resubscribe
+			return &stallingConn{Conn: conn, stall: dials.Add(1) == 2, stalled: stalled, closed: make(chan struct{})}, nil
+		},
+	}
+	ws := hyperliquid.NewWebSocketManager(fs.URL, hyperliquid.WSOptions{Dialer: dialer})
+	ws.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	t.Cleanup(ws.Stop)
+
+	_, err := ws.Subscribe(hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 1)
+
+	require.NoError(t, conn.Close())
+	select {
+	case <-stalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("resubscribe not written")
+	}
+
+	// The getters do not wait for the stalled write
+	done := make(chan struct{})
+	go func() {
+		ws.Healthy()
+		ws.LastMessageTime()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("getters blocked by the stalled resubscribe")
+	}
+}
+
+// logRecorder is a slog.Handler recording the entries at or above its level
+type logRecorder struct {
+	level   slog.Level
+	mu      sync.Mutex
+	entries []slog.Record
+}
+
+func (r *logRecorder) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= r.level
+}
+
+func (r *logRecorder) Handle(_ context.Context, record slog.Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, record.Clone())
+	return nil
+}
+
+func (r *logRecorder) WithAttrs([]slog.Attr) slog.Handler { return r }
+func (r *logRecorder) WithGroup(string) slog.Handler      { return r }
+
+// levels returns the level of every entry recorded with message
+func (r *logRecorder) levels(message string) []slog.Level {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var levels []slog.Level
+	for _, entry := range r.entries {
+		if entry.Message == message {
+			levels = append(levels, entry.Level)
+		}
+	}
+	return levels
+}
+
+// waitFor waits until an entry with message is recorded and returns its level
+func (r *logRecorder) waitFor(t testing.TB, message string) slog.Level {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if levels := r.levels(message); len(levels) > 0 {
+			return levels[0]
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("%q not logged", message)
+	return 0
+}
+
+func TestWebSocketManagerLogging(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.wsReply = func(subscription map[string]interface{}) interface{} {
+		if subscription["coin"] == "NOPE" {
+			return map[string]interface{}{
+				"channel": "error",
+				"data":    `Invalid subscription {"type":"l2Book","coin":"NOPE"}`,
+			}
+		}
+		return map[string]interface{}{
+			"channel": "subscriptionResponse",
+			"data":    map[string]interface{}{"method": "subscribe", "subscription": subscription},
+		}
+	}
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	t.Cleanup(ws.Stop)
+
+	// Warnings and errors are logged like with the default logger
+	warnings := &logRecorder{level: slog.LevelWarn}
+	ws.SetLogger(slog.New(warnings))
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	ctx := context.Background()
+
+	books := make(chan struct{}, 16)
+	bookSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+	_, err := ws.SubscribeCtx(ctx, bookSub, func(hyperliquid.WsMsg) { books <- struct{}{} })
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+	sendBook(t, conn, "BTC")
+	select {
+	case <-books:
+	case <-time.After(5 * time.Second):
+		t.Fatal("book message not delivered")
+	}
+
+	_, err = ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "NOPE"}, func(hyperliquid.WsMsg) {})
+	require.Error(t, err)
+	receiveSubscriptions(t, fs, 1)
+	assert.Equal(t, slog.LevelError, warnings.waitFor(t, "WebSocket error"))
+
+	require.NoError(t, conn.Close())
+	assert.Equal(t, slog.LevelWarn, warnings.waitFor(t, "WebSocket reconnecting"))
+	conn = receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 1)
+
+	// Messages are only logged at debug level
+	assert.Empty(t, warnings.levels("Received message"))
+	debug := &logRecorder{level: slog.LevelDebug}
+	ws.SetLogger(slog.New(debug))
+	sendBook(t, conn, "BTC")
+	assert.Equal(t, slog.LevelDebug, debug.waitFor(t, "Received message"))
+	assert.Empty(t, warnings.levels("Received message"))
+}
+
+func TestWebSocketManagerUnsubscribeWhileQueued(t *testing.T) {
+	ws := hyperliquid.NewWebSocketManager("http://127.0.0.1:1")
+	t.Cleanup(ws.Stop)
+
+	subscription := hyperliquid.Subscription{Type: hyperliquid.AllMids}
+	id, err := ws.Subscribe(subscription, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+
+	// A failed first connect keeps the subscription queued
+	require.Error(t, ws.Start())
+	unsubscribed, err := ws.Unsubscribe(subscription, id)
+	require.NoError(t, err)
+	assert.True(t, unsubscribed)
+	_, err = ws.Unsubscribe(subscription, id)
+	assert.ErrorIs(t, err, hyperliquid.ErrNotConnected)
+}
+
+func TestWebSocketManagerDuplicateSubscription(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+	user := "0x0000000000000000000000000000000000000001"
+	eventsSub := hyperliquid.Subscription{Type: hyperliquid.UserEvents, User: user}
+
+	// Checked against queued subscriptions
+	id, err := ws.Subscribe(eventsSub, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	_, err = ws.Subscribe(eventsSub, func(hyperliquid.WsMsg) {})
+	assert.ErrorIs(t, err, hyperliquid.ErrDuplicateSubscription)
+
+	// And active ones
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 1)
+	_, err = ws.SubscribeCtx(context.Background(), eventsSub, func(hyperliquid.WsMsg) {})
+	assert.ErrorIs(t, err, hyperliquid.ErrDuplicateSubscription)
+	ordersSub := hyperliquid.Subscription{Type: hyperliquid.OrderUpdates, User: user}
+	_, err = ws.SubscribeCtx(context.Background(), ordersSub, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+
+	// Free again once unsubscribed
+	assert.True(t, unsubscribe(t, ws, eventsSub, id))
+	_, err = ws.SubscribeCtx(context.Background(), eventsSub, func(hyperliquid.WsMsg) {})
+	assert.NoError(t, err)
+}
+
+func TestWebSocketManagerSubscriptionAcks(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.wsReply = func(subscription map[string]interface{}) interface{} {
+		switch subscription["coin"] {
+		case "NOPE":
+			return map[string]interface{}{
+				"channel": "error",
+				"data":    `Invalid subscription {"type":"l2Book","coin":"NOPE"}`,
+			}
+		case "SLOW":
+			return nil
+		}
+		return map[string]interface{}{
+			"channel": "subscriptionResponse",
+			"data":    map[string]interface{}{"method": "subscribe", "subscription": subscription},
+		}
+	}
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+	ctx := context.Background()
+
+	// Acknowledged
+	bookSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+	id, err := ws.SubscribeCtx(ctx, bookSub, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	assert.NotZero(t, id)
+	receiveSubscriptions(t, fs, 1)
+
+	// A second callback on the same channel needs no new subscription
+	secondID, err := ws.SubscribeCtx(ctx, bookSub, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	assert.NotEqual(t, id, secondID)
+	select {
+	case subscription := <-fs.wsSubscriptions:
+		t.Fatalf("unexpected subscribe request %v", subscription)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Rejected by the server
+	badSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "NOPE"}
+	_, err = ws.SubscribeCtx(ctx, badSub, func(hyperliquid.WsMsg) {})
+	var subscriptionErr *hyperliquid.SubscriptionError
+	require.ErrorAs(t, err, &subscriptionErr)
+	assert.Equal(t, badSub, subscriptionErr.Subscription)
+	assert.Contains(t, subscriptionErr.Message, "Invalid subscription")
+	receiveSubscriptions(t, fs, 1)
+
+	// Never acknowledged
+	slowSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "SLOW"}
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	_, err = ws.SubscribeCtx(timeoutCtx, slowSub, func(hyperliquid.WsMsg) {})
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	receiveSubscriptions(t, fs, 1)
+
+	// Only the acknowledged subscriptions remain
+	assert.True(t, unsubscribe(t, ws, bookSub, id))
+	assert.True(t, unsubscribe(t, ws, bookSub, secondID))
+	assert.False(t, unsubscribe(t, ws, slowSub, 0))
+}
+
+func TestWebSocketManagerRestart(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ctx := context.Background()
+	bookSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+
+	for run := 0; run < 2; run++ {
+		require.NoError(t, ws.Start())
+		require.Error(t, ws.Start(), "already started")
+		conn := receiveConn(t, fs)
+
+		books := make(chan struct{}, 1)
+		id, err := ws.SubscribeCtx(ctx, bookSub, func(hyperliquid.WsMsg) { books <- struct{}{} })
+		require.NoError(t, err)
+		receiveSubscriptions(t, fs, 1)
+		sendBook(t, conn, "BTC")
+		select {
+		case <-books:
+		case <-time.After(5 * time.Second):
+			t.Fatal("book message not delivered")
+		}
+
+		ws.Stop()
+		ws.Stop()
+		assert.False(t, ws.Healthy())
+		// Subscriptions do not outlive the run
+		_, err = ws.Unsubscribe(bookSub, id)
+		assert.ErrorIs(t, err, hyperliquid.ErrNotConnected)
+	}
+}
+
+func TestWebSocketManagerStopFailsPendingSubscriptions(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.wsReply = func(map[string]interface{}) interface{} { return nil }
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.AllMids}, func(hyperliquid.WsMsg) {})
+		errs <- err
+	}()
+	receiveSubscriptions(t, fs, 1)
+	ws.Stop()
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, hyperliquid.ErrWebSocketStopped)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending subscription not failed by Stop")
+	}
+}
+
+func TestWebSocketManagerClose(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(10*time.Millisecond, 10*time.Millisecond)
+	t.Cleanup(ws.Stop)
+	disconnects := make(chan error, 1)
+	ws.OnDisconnect(func(err error) { disconnects <- err })
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	var delivered atomic.Int32
+	bookID, err := ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(hyperliquid.WsMsg) {
+		<-release
+		delivered.Add(1)
+	})
+	require.NoError(t, err)
+	_, err = ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.Trades, Coin: "ETH"}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 2)
+
+	// One message is in the callback and two are queued behind it
+	for i := 0; i < 3; i++ {
+		sendBook(t, conn, "BTC")
+	}
+	require.Eventually(t, func() bool {
+		stats, _ := ws.DispatchStats(bookID)
+		return stats.Queued == 2
+	}, 5*time.Second, 5*time.Millisecond)
+
+	closed := make(chan error, 1)
+	go func() { closed <- ws.Close(ctx) }()
+
+	// Every channel is unsubscribed while the callback is still busy
+	unsubscribed := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case sub := <-fs.wsUnsubscriptions:
+			unsubscribed[sub["type"].(string)] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("unsubscribe not sent")
+		}
+	}
+	assert.Equal(t, map[string]bool{"l2Book": true, "trades": true}, unsubscribed)
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the queued messages were handled")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// The queue drains, then the connection is closed without reconnecting
+	close(release)
+	select {
+	case err := <-closed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return")
+	}
+	assert.Equal(t, int32(3), delivered.Load())
+	select {
+	case closeErr := <-fs.wsCloses:
+		assert.Equal(t, websocket.CloseNormalClosure, closeErr.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("close frame not sent")
+	}
+	select {
+	case <-fs.wsConns:
+		t.Fatal("reconnected after Close")
+	case err := <-disconnects:
+		t.Fatalf("OnDisconnect called after Close: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.False(t, ws.Healthy())
+	require.NoError(t, ws.Close(ctx), "closing a stopped manager does nothing")
+}
+
+func TestWebSocketManagerCloseTimeout(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+
+	stuck := make(chan struct{})
+	t.Cleanup(func() { close(stuck) })
+	entered := make(chan struct{}, 1)
+	_, err := ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(hyperliquid.WsMsg) {
+		entered <- struct{}{}
+		<-stuck
+	})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+	sendBook(t, conn, "BTC")
+	<-entered
+
+	// A stuck callback only holds Close up until ctx is done
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, ws.Close(ctx), context.DeadlineExceeded)
+	assert.False(t, ws.Healthy())
+	require.NoError(t, ws.Start(), "the manager is stopped and can be started again")
+	receiveConn(t, fs)
+	ws.Stop()
+}
+
+func TestWebSocketManagerUnsubscribeAll(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+
+	// Queued before the manager is started, waiting for an acknowledgement
+	queued := make(chan error, 1)
+	go func() {
+		_, err := ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.Trades, Coin: "ETH"}, func(hyperliquid.WsMsg) {})
+		queued <- err
+	}()
+	var queuedErr error
+	require.Eventually(t, func() bool {
+		assert.NoError(t, ws.UnsubscribeAll())
+		select {
+		case queuedErr = <-queued:
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, queuedErr, hyperliquid.ErrSubscriptionRemoved)
+
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	books := make(chan struct{}, 1)
+	bookSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+	bookID, err := ws.SubscribeCtx(context.Background(), bookSub, func(hyperliquid.WsMsg) { books <- struct{}{} })
+	require.NoError(t, err)
+	subscriptions := receiveSubscriptions(t, fs, 1)
+	assert.Equal(t, "l2Book", subscriptions[0]["type"], "nothing queued was sent")
+
+	require.NoError(t, ws.UnsubscribeAll())
+	select {
+	case sub := <-fs.wsUnsubscriptions:
+		assert.Equal(t, "l2Book", sub["type"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("unsubscribe not sent")
+	}
+	sendBook(t, conn, "BTC")
+	select {
+	case <-books:
+		t.Fatal("callback called after UnsubscribeAll")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.False(t, unsubscribe(t, ws, bookSub, bookID))
+
+	// The connection stays open for new subscriptions
+	_, err = ws.SubscribeCtx(context.Background(), bookSub, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+}
+
+func TestWebSocketManagerStopLeavesNoGoroutines(t *testing.T) {
+	fs := newFakeServer(t)
+	before := runtime.NumGoroutine()
+
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(time.Hour, time.Hour)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	_, err := ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.AllMids}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+
+	// Disconnected and waiting to reconnect
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool { return !ws.Healthy() }, 5*time.Second, time.Millisecond)
+
+	ws.Stop()
+	require.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestWebSocketURL(t *testing.T) {
+	for _, tc := range []struct {
+		baseURL string
+		wsURL   string
+	}{
+		{"http://localhost", "ws://localhost/ws"},
+		{"https://api.hyperliquid.xyz", "wss://api.hyperliquid.xyz/ws"},
+		{"http://127.0.0.1:3001", "ws://127.0.0.1:3001/ws"},
+		{"https://api.hyperliquid-testnet.xyz/", "wss://api.hyperliquid-testnet.xyz/ws"},
+		{"https://proxy.example.com:8443/hyperliquid/", "wss://proxy.example.com:8443/hyperliquid/ws"},
+	} {
+		wsURL, err := hyperliquid.WebSocketURL(tc.baseURL)
+		require.NoError(t, err, tc.baseURL)
+		assert.Equal(t, tc.wsURL, wsURL, tc.baseURL)
+	}
+
+	for _, baseURL := range []string{"api.hyperliquid.xyz", "ftp://api.hyperliquid.xyz", "https://"} {
+		_, err := hyperliquid.WebSocketURL(baseURL)
+		assert.Error(t, err, baseURL)
+	}
+}
+
+func TestWebSocketURLOverride(t *testing.T) {
+	fs := newFakeServer(t)
+	wsURL, err := hyperliquid.WebSocketURL(fs.URL)
+	require.NoError(t, err)
+
+	// The base URL is unreachable; the override is used instead
+	ws := hyperliquid.NewWebSocketManager("http://127.0.0.1:1")
+	ws.SetURL(wsURL)
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+
+	info := newTestInfo(t, fs)
+	require.NoError(t, info.ConnectWebSocket(wsURL))
+	receiveConn(t, fs)
+	require.NoError(t, info.DisconnectWebSocket())
+}
+
+// manualClock fires timers only when the test advances it past their deadline
+type manualClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []manualTimer
+}
+
+type manualTimer struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, manualTimer{deadline: c.now.Add(d), ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward and fires the timers that are due
+func (c *manualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.deadline.After(c.now) {
+			pending = append(pending, timer)
+		} else {
+			timer.ch <- c.now
+		}
+	}
+	c.timers = pending
+}
+
+// waitForTimer blocks until something waits on the clock
+func (c *manualClock) waitForTimer(t *testing.T) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.timers) > 0
+	}, 5*time.Second, time.Millisecond)
+}
+
+func TestWebSocketManagerReplacesStaleConnection(t *testing.T) {
+	fs := newFakeServer(t)
+	clock := &manualClock{now: time.Unix(1700000000, 0)}
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetClock(clock)
+	ws.SetReconnectBackoff(10*time.Millisecond, 50*time.Millisecond)
+	t.Cleanup(ws.Stop)
+
+	disconnects := make(chan error, 1)
+	ws.OnDisconnect(func(err error) { disconnects <- err })
+
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	require.Eventually(t, ws.Healthy, 5*time.Second, time.Millisecond)
+	connected := ws.LastMessageTime()
+
+	// Traffic within the window keeps the connection
+	for i := 0; i < 11; i++ {
+		clock.waitForTimer(t)
+		clock.Advance(5 * time.Second)
+	}
+	sendBook(t, conn, "BTC")
+	require.Eventually(t, func() bool {
+		return ws.LastMessageTime().Equal(connected.Add(55 * time.Second))
+	}, 5*time.Second, time.Millisecond)
+	for i := 0; i < 12; i++ {
+		clock.waitForTimer(t)
+		clock.Advance(5 * time.Second)
+	}
+	assert.True(t, ws.Healthy())
+	select {
+	case err := <-disconnects:
+		t.Fatalf("connection closed while live: %v", err)
+	default:
+	}
+
+	// A connection silent for longer than the window is replaced
+	clock.waitForTimer(t)
+	clock.Advance(5 * time.Second)
+	select {
+	case err := <-disconnects:
+		assert.ErrorIs(t, err, hyperliquid.ErrConnectionStale)
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale connection not closed")
+	}
+	assert.False(t, ws.Healthy())
+
+	receiveConn(t, fs)
+	require.Eventually(t, ws.Healthy, 5*time.Second, time.Millisecond)
+	assert.Equal(t, clock.Now(), ws.LastMessageTime())
+}
+
+func TestWebSocketManagerSlowCallbackDoesNotBlockOthers(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	ctx := context.Background()
+
+	release := make(chan struct{})
+	books := make(chan int, 16)
+	_, err := ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(msg hyperliquid.WsMsg) {
+		<-release
+		books <- bookTime(msg)
+	})
+	require.NoError(t, err)
+	trades := make(chan struct{}, 1)
+	_, err = ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.Trades, Coin: "ETH"}, func(hyperliquid.WsMsg) {
+		trades <- struct{}{}
+	})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 2)
+
+	for i := 1; i <= 5; i++ {
+		sendBookAt(t, conn, "BTC", i)
+	}
+	sendTrade(t, conn, "ETH")
+	select {
+	case <-trades:
+	case <-time.After(5 * time.Second):
+		t.Fatal("trades held up by the blocked book callback")
+	}
+
+	// The book callback still gets every message, in order
+	close(release)
+	for i := 1; i <= 5; i++ {
+		select {
+		case got := <-books:
+			assert.Equal(t, i, got)
+		case <-time.After(5 * time.Second):
+			t.Fatal("book message not delivered")
+		}
+	}
+}
+
+func TestWebSocketManagerCallbackPanics(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+	type failure struct {
+		identifier string
+		err        error
+	}
+	failures := make(chan failure, 4)
+	ws.SetErrorHandler(func(identifier string, err error) { failures <- failure{identifier, err} })
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+
+	bookSub := hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}
+	_, err := ws.SubscribeCtx(context.Background(), bookSub, func(hyperliquid.WsMsg) { panic("boom") })
+	require.NoError(t, err)
+	healthy := make(chan struct{}, 4)
+	_, err = ws.SubscribeCtx(context.Background(), bookSub, func(hyperliquid.WsMsg) { healthy <- struct{}{} })
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 1)
+
+	// Every message panics in one callback and still reaches the other
+	for i := 0; i < 2; i++ {
+		sendBook(t, conn, "BTC")
+		select {
+		case f := <-failures:
+			assert.Equal(t, "l2Book:btc", f.identifier)
+			var panicErr *hyperliquid.CallbackPanicError
+			require.ErrorAs(t, f.err, &panicErr)
+			assert.Equal(t, "boom", panicErr.Value)
+			assert.NotEmpty(t, panicErr.Stack)
+		case <-time.After(5 * time.Second):
+			t.Fatal("panic not reported")
+		}
+		select {
+		case <-healthy:
+		case <-time.After(5 * time.Second):
+			t.Fatal("healthy callback stopped receiving")
+		}
+	}
+
+	// The manager is not left locked
+	_, err = ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.AllMids}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	assert.True(t, ws.Healthy())
+}
+
+// recordingObserver records the events a WebSocketManager reports
+type recordingObserver struct {
+	hyperliquid.NopWSObserver
+	mu         sync.Mutex
+	received   map[string]int
+	dispatched map[string]int
+	panicked   []string
+	reconnects int
+	pongs      int
+}
+
+func (o *recordingObserver) MessageReceived(channel string, bytes int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.received[channel]++
+}
+
+func (o *recordingObserver) MessageDispatched(identifier string, latency time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.dispatched[identifier]++
+}
+
+func (o *recordingObserver) CallbackPanicked(identifier string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.panicked = append(o.panicked, identifier)
+}
+
+func (o *recordingObserver) Reconnected() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.reconnects++
+}
+
+func (o *recordingObserver) PongReceived() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pongs++
+}
+
+func TestWebSocketManagerStats(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(10*time.Millisecond, 10*time.Millisecond)
+	t.Cleanup(ws.Stop)
+	observer := &recordingObserver{received: map[string]int{}, dispatched: map[string]int{}}
+	ws.SetObserver(observer)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	assert.Empty(t, ws.Stats().MessagesReceived)
+	assert.True(t, ws.Stats().LastPongAt.IsZero())
+
+	books := make(chan struct{}, 4)
+	_, err := ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(hyperliquid.WsMsg) {
+		books <- struct{}{}
+	})
+	require.NoError(t, err)
+	_, err = ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.AllMids}, func(hyperliquid.WsMsg) {
+		panic("boom")
+	})
+	require.NoError(t, err)
+	receiveSubscriptions(t, fs, 2)
+
+	sendBook(t, conn, "BTC")
+	sendBook(t, conn, "BTC")
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"channel": "allMids", "data": map[string]interface{}{"mids": map[string]string{}}}))
+	require.NoError(t, conn.WriteJSON(map[string]interface{}{"channel": "pong"}))
+	for i := 0; i < 2; i++ {
+		<-books
+	}
+	require.Eventually(t, func() bool {
+		stats := ws.Stats()
+		return stats.CallbackPanics == 1 && stats.MessagesReceived["pong"] == 1
+	}, 5*time.Second, 5*time.Millisecond)
+
+	stats := ws.Stats()
+	assert.Equal(t, map[string]uint64{"subscriptionResponse": 2, "l2Book": 2, "allMids": 1, "pong": 1}, stats.MessagesReceived)
+	assert.Greater(t, stats.BytesReceived, uint64(100))
+	assert.False(t, stats.LastPongAt.IsZero())
+	assert.Zero(t, stats.Reconnects)
+	assert.Zero(t, stats.QueueDrops)
+
+	// The connection drops and is replaced
+	conn.Close()
+	receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 2)
+	require.Eventually(t, func() bool { return ws.Stats().Reconnects == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	assert.Equal(t, 2, observer.received["l2Book"])
+	assert.Equal(t, 2, observer.dispatched["l2Book:btc"])
+	assert.Equal(t, 1, observer.dispatched["allMids"])
+	assert.Equal(t, []string{"allMids"}, observer.panicked)
+	assert.Equal(t, 1, observer.pongs)
+	assert.Equal(t, 1, observer.reconnects)
+}
+
+func TestWebSocketManagerOverflowPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		policy    hyperliquid.OverflowPolicy
+		delivered []int
+	}{
+		{"drop oldest", hyperliquid.OverflowDropOldest, []int{1, 4, 5}},
+		{"drop newest", hyperliquid.OverflowDropNewest, []int{1, 2, 3}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := newFakeServer(t)
+			ws := hyperliquid.NewWebSocketManager(fs.URL)
+			ws.SetDispatchQueueSize(2)
+			ws.SetOverflowPolicy(tc.policy)
+			t.Cleanup(ws.Stop)
+			require.NoError(t, ws.Start())
+			conn := receiveConn(t, fs)
+
+			started := make(chan struct{}, 1)
+			release := make(chan struct{})
+			var mu sync.Mutex
+			var delivered []int
+			id, err := ws.SubscribeCtx(context.Background(), hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(msg hyperliquid.WsMsg) {
+				select {
+				case started <- struct{}{}:
+				default:
+				}
+				<-release
+				mu.Lock()
+				defer mu.Unlock()
+				delivered = append(delivered, bookTime(msg))
+			})
+			require.NoError(t, err)
+			receiveSubscriptions(t, fs, 1)
+
+			// The first message occupies the callback, two fill the queue and
+			// two overflow
+			sendBookAt(t, conn, "BTC", 1)
+			<-started
+			for i := 2; i <= 5; i++ {
+				sendBookAt(t, conn, "BTC", i)
+			}
+			require.Eventually(t, func() bool {
+				stats, ok := ws.DispatchStats(id)
+				return ok && stats.Dropped == 2 && stats.Queued == 2
+			}, 5*time.Second, time.Millisecond)
+			assert.Equal(t, uint64(2), ws.Stats().QueueDrops)
+
+			close(release)
+			require.Eventually(t, func() bool {
+				stats, _ := ws.DispatchStats(id)
+				return stats.Delivered == 3
+			}, 5*time.Second, time.Millisecond)
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, tc.delivered, delivered)
+		})
+	}
+}
+
+// BenchmarkWebSocketFlood measures how fast a flood of book updates is
+// delivered while a trades callback sleeps on every message
+func BenchmarkWebSocketFlood(b *testing.B) {
+	fs := newFakeServer(b)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetLogger(nil)
+	defer ws.Stop()
+	require.NoError(b, ws.Start())
+	conn := receiveConn(b, fs)
+	ctx := context.Background()
+
+	books := make(chan struct{}, 1024)
+	_, err := ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.L2Book, Coin: "BTC"}, func(hyperliquid.WsMsg) {
+		books <- struct{}{}
+	})
+	require.NoError(b, err)
+	// Only the slow subscription may shed messages
+	ws.SetOverflowPolicy(hyperliquid.OverflowDropOldest)
+	_, err = ws.SubscribeCtx(ctx, hyperliquid.Subscription{Type: hyperliquid.Trades, Coin: "ETH"}, func(hyperliquid.WsMsg) {
+		time.Sleep(10 * time.Millisecond)
+	})
+	require.NoError(b, err)
+	receiveSubscriptions(b, fs, 2)
+
+	b.ResetTimer()
+	go func() {
+		for i := 0; i < b.N; i++ {
+			book := map[string]interface{}{"coin": "BTC", "time": i, "levels": []interface{}{[]interface{}{}, []interface{}{}}}
+			if conn.WriteJSON(map[string]interface{}{"channel": "l2Book", "data": book}) != nil {
+				return
+			}
+			if i%10 == 0 {
+				trade := []interface{}{map[string]interface{}{"coin": "ETH", "px": "3000.0"}}
+				if conn.WriteJSON(map[string]interface{}{"channel": "trades", "data": trade}) != nil {
+					return
+				}
+			}
+		}
+	}()
+	for i := 0; i < b.N; i++ {
+		<-books
+	}
+}
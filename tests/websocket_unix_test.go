@@ -0,0 +1,39 @@
+//go:build unix
+
+// Package tests - WebSocket manager resource usage tests
+package tests
+
+import (
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cpuTime returns the CPU time the process has used
+func cpuTime(t *testing.T) time.Duration {
+	t.Helper()
+	var usage syscall.Rusage
+	require.NoError(t, syscall.Getrusage(syscall.RUSAGE_SELF, &usage))
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
+
+func TestWebSocketManagerIdlesWhileDisconnected(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	ws.SetReconnectBackoff(time.Hour, time.Hour)
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	conn := receiveConn(t, fs)
+	require.Eventually(t, ws.Healthy, 5*time.Second, time.Millisecond)
+
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool { return !ws.Healthy() }, 5*time.Second, time.Millisecond)
+
+	start := cpuTime(t)
+	time.Sleep(500 * time.Millisecond)
+	assert.Less(t, cpuTime(t)-start, 100*time.Millisecond)
+}
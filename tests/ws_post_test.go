@@ -0,0 +1,155 @@
+// Package tests - WebSocket post request tests
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTestWebSocket connects a WebSocket manager to the fake server and
+// waits until it is ready to post
+func startTestWebSocket(t *testing.T, fs *fakeServer) *hyperliquid.WebSocketManager {
+	t.Helper()
+	ws := hyperliquid.NewWebSocketManager(fs.URL)
+	t.Cleanup(ws.Stop)
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+	require.Eventually(t, ws.Healthy, 5*time.Second, time.Millisecond)
+	return ws
+}
+
+func TestPostRequestInfo(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.infoResponses["allMids"] = `{"BTC": "113377.0"}`
+	ws := startTestWebSocket(t, fs)
+
+	raw, err := ws.PostRequest(context.Background(), hyperliquid.PostInfo, map[string]interface{}{"type": "allMids"})
+	require.NoError(t, err)
+	var response struct {
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &response))
+	assert.Equal(t, "allMids", response.Type)
+	assert.Equal(t, map[string]string{"BTC": "113377.0"}, response.Data)
+	assert.Equal(t, "allMids", fs.lastInfoRequest(t)["type"])
+}
+
+func TestPostRequestConcurrent(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.wsPostReply = func(request map[string]interface{}) interface{} {
+		coin := request["payload"].(map[string]interface{})["coin"]
+		return map[string]interface{}{
+			"type":    "info",
+			"payload": map[string]interface{}{"type": "l2Book", "data": map[string]interface{}{"coin": coin}},
+		}
+	}
+	ws := startTestWebSocket(t, fs)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(coin string) {
+			defer wg.Done()
+			raw, err := ws.PostRequest(context.Background(), hyperliquid.PostInfo, map[string]interface{}{"type": "l2Book", "coin": coin})
+			if !assert.NoError(t, err) {
+				return
+			}
+			var response struct {
+				Data struct {
+					Coin string `json:"coin"`
+				} `json:"data"`
+			}
+			assert.NoError(t, json.Unmarshal(raw, &response))
+			assert.Equal(t, coin, response.Data.Coin)
+		}(fmt.Sprintf("COIN%d", i))
+	}
+	wg.Wait()
+}
+
+func TestPostRequestFailures(t *testing.T) {
+	fs := newFakeServer(t)
+	posted := make(chan struct{}, 1)
+	fs.wsPostReply = func(request map[string]interface{}) interface{} {
+		switch request["payload"].(map[string]interface{})["type"] {
+		case "bad":
+			return map[string]interface{}{"type": "error", "payload": "invalid request"}
+		default:
+			posted <- struct{}{}
+			return nil
+		}
+	}
+	ws := startTestWebSocket(t, fs)
+	ctx := context.Background()
+
+	// Error response
+	_, err := ws.PostRequest(ctx, hyperliquid.PostInfo, map[string]interface{}{"type": "bad"})
+	var postErr *hyperliquid.PostError
+	require.ErrorAs(t, err, &postErr)
+	assert.Equal(t, "invalid request", postErr.Message)
+
+	// No response before the deadline
+	timeoutCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = ws.PostRequest(timeoutCtx, hyperliquid.PostInfo, map[string]interface{}{"type": "slow"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	<-posted
+
+	// Connection lost while waiting
+	errs := make(chan error, 1)
+	go func() {
+		_, err := ws.PostRequest(ctx, hyperliquid.PostInfo, map[string]interface{}{"type": "slow"})
+		errs <- err
+	}()
+	<-posted
+	conn := fs.lastWsConn(t)
+	require.NoError(t, conn.Close())
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, hyperliquid.ErrPostInterrupted)
+	case <-time.After(5 * time.Second):
+		t.Fatal("pending post not failed on disconnect")
+	}
+}
+
+func TestOrderViaWS(t *testing.T) {
+	fs := newFakeServer(t)
+	fs.exchangeResponse = `{"status": "ok", "response": {"type": "order", "data": {"statuses": [{"resting": {"oid": 9007199254740993}}]}}}`
+	exchange, privateKey := newTestExchange(t, fs, nil)
+	orderType := utils.OrderType{Limit: &utils.LimitOrderType{TIF: utils.TIFGtc}}
+
+	_, err := exchange.OrderViaWS("BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	require.Error(t, err, "no WebSocket set")
+
+	ws := startTestWebSocket(t, fs)
+	exchange.SetWebSocket(ws)
+	response, err := exchange.OrderViaWS("BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, response.Response.Data.Statuses, 1)
+	assert.Equal(t, 9007199254740993, response.Response.Data.Statuses[0].Resting.Oid)
+
+	// Signed exactly as over HTTP
+	payload := fs.lastExchangeRequest(t)
+	asset, err := newTestInfo(t, fs).NameToAsset("BTC")
+	require.NoError(t, err)
+	wire, err := utils.OrderRequestToOrderWire(utils.OrderRequest{Coin: "BTC", IsBuy: true, Sz: 0.01, LimitPx: 100000, OrderType: orderType}, asset)
+	require.NoError(t, err)
+	action := utils.OrderWiresToOrderAction([]utils.OrderWire{*wire}, nil, utils.GroupingNA)
+	signer, err := utils.RecoverAgentOrUserFromL1Action(action, postedSignature(t, payload), nil, uint64(payload["nonce"].(float64)), nil, false)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.PubkeyToAddress(privateKey.PublicKey), signer)
+
+	_, err = exchange.Order("BTC", true, 0.01, 100000, orderType, false, nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, payload["action"], fs.lastExchangeRequest(t)["action"])
+}
@@ -0,0 +1,148 @@
+// Package tests - Sharded WebSocket manager tests
+package tests
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid"
+	"github.com/hyperliquid-go/hyperliquid-go/hyperliquid/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resubscribedChannels closes conn and returns the channels re-sent on the
+// connection replacing it, sorted
+func resubscribedChannels(t *testing.T, fs *fakeServer, conn *websocket.Conn) (*websocket.Conn, string) {
+	t.Helper()
+	conn.Close()
+	replacement := receiveConn(t, fs)
+	var channels []string
+	for {
+		select {
+		case sub := <-fs.wsSubscriptions:
+			channel := sub["type"].(string)
+			if coin, ok := sub["coin"].(string); ok {
+				channel += ":" + coin
+			}
+			channels = append(channels, channel)
+		case <-time.After(200 * time.Millisecond):
+			sort.Strings(channels)
+			return replacement, strings.Join(channels, ",")
+		}
+	}
+}
+
+func TestShardedWebSocketManager(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewShardedWebSocketManager(fs.URL, 3)
+	ws.SetReconnectBackoff(10*time.Millisecond, 10*time.Millisecond)
+	t.Cleanup(ws.Stop)
+	reconnects := make(chan int, 16)
+	ws.OnReconnect(func(connection int) { reconnects <- connection })
+	require.NoError(t, ws.Start())
+	conns := []*websocket.Conn{receiveConn(t, fs), receiveConn(t, fs), receiveConn(t, fs)}
+
+	ctx := context.Background()
+	user := "0x0000000000000000000000000000000000000001"
+	subscriptions := []utils.Subscription{
+		utils.UserFillsSubscription{User: user},
+		utils.OrderUpdatesSubscription{User: user},
+		utils.L2BookSubscription{Coin: "BTC"},
+		utils.L2BookSubscription{Coin: "ETH"},
+		utils.L2BookSubscription{Coin: "SOL"},
+		utils.L2BookSubscription{Coin: "BTC"},
+	}
+	var ids []int
+	unique := map[int]bool{}
+	for _, subscription := range subscriptions {
+		id, err := ws.SubscribeCtx(ctx, subscription, func(hyperliquid.WsMsg) {})
+		require.NoError(t, err)
+		ids = append(ids, id)
+		unique[id] = true
+	}
+	assert.Len(t, unique, len(subscriptions), "IDs are unique across connections")
+	receiveSubscriptions(t, fs, 5)
+
+	// User channels share the first connection; books are balanced
+	stats := ws.ConnectionStats()
+	require.Len(t, stats, 3)
+	assert.Equal(t, []int{2, 2, 1}, []int{stats[0].Channels, stats[1].Channels, stats[2].Channels})
+	assert.Equal(t, []int{2, 3, 1}, []int{stats[0].Subscriptions, stats[1].Subscriptions, stats[2].Subscriptions})
+	for _, connection := range stats {
+		assert.True(t, connection.Healthy)
+	}
+
+	// Dropping each connection in turn shows which channels it carries,
+	// and a second round shows they do not move
+	var layout []string
+	for round := 0; round < 2; round++ {
+		var channels []string
+		for i, conn := range conns {
+			var resent string
+			conns[i], resent = resubscribedChannels(t, fs, conn)
+			channels = append(channels, resent)
+		}
+		sort.Strings(channels)
+		if round == 0 {
+			layout = channels
+			continue
+		}
+		assert.Equal(t, layout, channels)
+	}
+	assert.ElementsMatch(t, []string{"l2Book:BTC,l2Book:SOL", "l2Book:ETH", "orderUpdates,userFills"}, layout)
+	seen := map[int]bool{}
+	for len(seen) < 3 {
+		select {
+		case connection := <-reconnects:
+			seen[connection] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("not every connection reported its reconnect")
+		}
+	}
+
+	// Unsubscribing frees a channel only once its last subscription is gone
+	unsubscribed, err := ws.Unsubscribe(utils.L2BookSubscription{Coin: "BTC"}, ids[5])
+	require.NoError(t, err)
+	assert.True(t, unsubscribed)
+	stats = ws.ConnectionStats()
+	assert.Equal(t, 2, stats[1].Channels)
+	assert.Equal(t, 2, stats[1].Subscriptions)
+
+	unsubscribed, err = ws.Unsubscribe(utils.L2BookSubscription{Coin: "BTC"}, 1000)
+	require.NoError(t, err)
+	assert.False(t, unsubscribed, "unknown ID")
+
+	require.NoError(t, ws.UnsubscribeAll())
+	for _, connection := range ws.ConnectionStats() {
+		assert.Zero(t, connection.Channels)
+		assert.Zero(t, connection.Subscriptions)
+	}
+}
+
+func TestShardedWebSocketManagerPinsUserChannels(t *testing.T) {
+	fs := newFakeServer(t)
+	ws := hyperliquid.NewShardedWebSocketManager(fs.URL, 2)
+	t.Cleanup(ws.Stop)
+
+	// Queued before Start, like on a single connection
+	_, err := ws.Subscribe(utils.UserEventsSubscription{User: "0x1"}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+	_, err = ws.Subscribe(utils.UserEventsSubscription{User: "0x1"}, func(hyperliquid.WsMsg) {})
+	assert.ErrorIs(t, err, hyperliquid.ErrDuplicateSubscription)
+	_, err = ws.Subscribe(utils.AllMidsSubscription{}, func(hyperliquid.WsMsg) {})
+	require.NoError(t, err)
+
+	stats := ws.ConnectionStats()
+	assert.Equal(t, 1, stats[0].Channels)
+	assert.Equal(t, 1, stats[1].Channels)
+
+	require.NoError(t, ws.Start())
+	receiveConn(t, fs)
+	receiveConn(t, fs)
+	receiveSubscriptions(t, fs, 2)
+}